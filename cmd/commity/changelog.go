@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/hluaguo/commity/internal/changelog"
+	"github.com/hluaguo/commity/internal/git"
+)
+
+// runChangelog prints the conventional commits in a range as a Keep a
+// Changelog release, optionally with AI-written section summaries
+func runChangelog(configPath string, args []string) error {
+	fs := flag.NewFlagSet("changelog", flag.ExitOnError)
+	summary := fs.Bool("summary", false, "add an AI-written summary to each section")
+	version := fs.String("version", "", "release name for the heading (default: the range end if it is a tag)")
+	all := fs.Bool("all", false, "also list docs, chores and other commits under Other")
+	_ = fs.Parse(args)
+
+	// Allow flags after the range as well
+	spec := ""
+	if fs.NArg() > 0 {
+		spec = fs.Arg(0)
+		_ = fs.Parse(fs.Args()[1:])
+	}
+
+	repo, err := git.New()
+	if err != nil {
+		return err
+	}
+	if spec == "" {
+		tag, err := repo.LatestTag()
+		if err != nil {
+			return err
+		}
+		spec = tag + "..HEAD"
+	}
+
+	entries, err := repo.LogRange(spec)
+	if err != nil {
+		return err
+	}
+	sections := changelog.Group(entries, *all)
+	if len(sections) == 0 {
+		return fmt.Errorf("no conventional commits in %s", spec)
+	}
+
+	name := *version
+	if _, end, ok := strings.Cut(spec, ".."); ok && name == "" && end != "" && end != "HEAD" {
+		name = end
+	}
+	date := time.Now()
+	if len(entries) > 0 {
+		date = entries[len(entries)-1].Time
+	}
+
+	if *summary {
+		s, err := newAISession(configPath)
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		for i := range sections {
+			lines := make([]string, len(sections[i].Commits))
+			for j, c := range sections[i].Commits {
+				lines[j] = c.Line()
+			}
+			fmt.Fprintf(os.Stderr, "Summarizing %s...\n", sections[i].Title)
+			if sections[i].Summary, err = s.client.SummarizeSection(ctx, sections[i].Title, lines); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Print(changelog.Markdown(name, date, sections))
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// runConfig reads and changes settings without the TUI, for scripts and
+// dotfile managers
+func runConfig(configPath string, args []string) error {
+	path := configPath
+	if path == "" {
+		path = config.ConfigPath()
+	}
+
+	action := "list"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+	switch action {
+	case "path":
+		fmt.Println(path)
+		return nil
+	case "list":
+		return configList(configPath)
+	case "get":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: commity config get <key>")
+		}
+		return configGet(configPath, args[0])
+	case "set":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: commity config set <key> <value>")
+		}
+		return configSet(path, args[0], args[1])
+	case "edit":
+		return configEdit(configPath, path)
+	}
+	return fmt.Errorf("unknown config command %q (want get, set, list, edit or path)", action)
+}
+
+// configList prints every effective setting, defaults included, with API
+// keys masked
+func configList(configPath string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	settings, err := cfg.Settings()
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		value := config.FormatValue(s.Value)
+		if strings.HasSuffix(s.Key, "api_key") && s.Value != "" {
+			value = `"********"`
+		}
+		fmt.Printf("%s = %s\n", s.Key, value)
+	}
+	return nil
+}
+
+// configGet prints one setting; strings are printed without quotes so the
+// output can be used as is in scripts
+func configGet(configPath, key string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	value, ok := cfg.Lookup(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	if s, ok := value.(string); ok {
+		fmt.Println(s)
+	} else {
+		fmt.Println(config.FormatValue(value))
+	}
+	return nil
+}
+
+// configSet changes one setting in the config file, leaving the rest of the
+// file as written
+func configSet(path, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	out, err := config.SetValue(data, key, value)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0644)
+}
+
+// configEdit opens the config file in $VISUAL or $EDITOR and checks that it
+// still loads afterwards
+func configEdit(configPath, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Editors may be configured with arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor failed: %w", err)
+	}
+	if _, err := config.Load(configPath); err != nil {
+		return fmt.Errorf("%s is no longer valid: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/git"
+	"github.com/hluaguo/commity/internal/tui"
+)
+
+// doctor collects the results of `commity doctor`
+type doctor struct {
+	failed bool
+}
+
+func (d *doctor) ok(format string, args ...any) {
+	fmt.Printf("✓ "+format+"\n", args...)
+}
+
+func (d *doctor) warn(format string, args ...any) {
+	fmt.Printf("! "+format+"\n", args...)
+}
+
+func (d *doctor) fail(format string, args ...any) {
+	d.failed = true
+	fmt.Printf("✗ "+format+"\n", args...)
+}
+
+// hint explains how to fix the previous result
+func (d *doctor) hint(format string, args ...any) {
+	fmt.Printf("  → "+format+"\n", args...)
+}
+
+// runDoctor checks git, the repository, the config and the AI endpoint, and
+// says what to change for each problem found
+func runDoctor(configPath string) error {
+	d := &doctor{}
+
+	if version, err := git.Version(); err != nil {
+		d.fail("git is not installed or not on PATH")
+		d.hint("install git from https://git-scm.com")
+	} else {
+		d.ok("git %s", version)
+	}
+
+	repo, err := git.New()
+	if err != nil {
+		d.warn("not inside a git repository")
+		d.hint("run commity doctor in a repository to check its settings too")
+	} else {
+		d.ok("repository %s", repo.Path())
+	}
+
+	path := configPath
+	if path == "" {
+		path = config.ConfigPath()
+	}
+	if _, err := os.Stat(path); err != nil {
+		d.fail("no config at %s", path)
+		d.hint("run commity to set it up, or create it with commity config set")
+		return d.result()
+	}
+	problems, err := config.Problems(configPath)
+	if err != nil {
+		d.fail("config %s can't be read: %v", path, err)
+		d.hint("fix the syntax with commity config edit")
+		return d.result()
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		d.fail("config %s can't be loaded: %v", path, err)
+		return d.result()
+	}
+	if !slices.Contains(tui.GetThemeNames(), cfg.UI.Theme) {
+		problems = append(problems, fmt.Sprintf("ui.theme %q is unknown, so the default is used; use one of %v", cfg.UI.Theme, tui.GetThemeNames()))
+	}
+	if len(problems) == 0 {
+		d.ok("config %s", path)
+	} else {
+		d.fail("config %s", path)
+		for _, p := range problems {
+			d.hint("%s", p)
+		}
+	}
+
+	// Check the AI settings commity would use here
+	host := ""
+	if repo != nil {
+		if name := cfg.SelectProfile(repo.Path(), git.RemoteSlug(repo.RemoteURL("origin"))); name != "" {
+			if cfg, err = cfg.WithProfile(name); err != nil {
+				d.fail("%v", err)
+				return d.result()
+			}
+			d.ok("profile %s", name)
+		}
+		if cfg, err = cfg.WithRepo(repo.Path()); err != nil {
+			d.fail("%v", err)
+			return d.result()
+		}
+		if cfg.Repo.File != "" {
+			d.ok("repository settings from %s", cfg.Repo.File)
+		}
+		host = repo.RemoteHost()
+	}
+	aiCfg, err := cfg.EffectiveAI(host)
+	if err != nil {
+		d.fail("%v", err)
+		return d.result()
+	}
+	client, err := ai.New(&aiCfg)
+	if err != nil {
+		d.fail("AI client: %v", err)
+		return d.result()
+	}
+
+	d.keys(aiCfg)
+
+	// The client bounds each attempt by timeout_seconds
+	start := time.Now()
+	if err := client.Ping(context.Background()); err != nil {
+		d.fail("%s model %s: %v", aiCfg.Provider, client.Model(), err)
+		if hint := ai.PingHint(err, aiCfg); hint != "" {
+			d.hint("%s", hint)
+		}
+		return d.result()
+	}
+	d.ok("%s model %s answered in %s", aiCfg.Provider, client.Model(), time.Since(start).Round(time.Millisecond))
+	return d.result()
+}
+
+// keys lists the usage tracked for each key when several are rotated
+func (d *doctor) keys(aiCfg config.AIConfig) {
+	keys, err := aiCfg.ResolveAPIKeys()
+	if err != nil || len(keys) < 2 {
+		return
+	}
+	usage, err := ai.LoadKeyUsage()
+	if err != nil {
+		d.warn("key usage in %s can't be read: %v", ai.KeyUsagePath(), err)
+		return
+	}
+	d.ok("%d API keys, rotated by %s", len(keys), aiCfg.KeyRotation)
+	for _, key := range keys {
+		u := usage[ai.KeyFingerprint(key)]
+		fmt.Printf("  %s  %d requests, %d over quota\n", ai.KeyHint(key), u.Requests, u.RateLimited)
+	}
+}
+
+func (d *doctor) result() error {
+	if d.failed {
+		return fmt.Errorf("some checks failed")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hluaguo/commity/internal/auth"
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// runLogin signs in to a hosted gateway with the OAuth device flow and
+// stores the tokens for later runs
+func runLogin(configPath string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	// Log in to the gateway of a profile chosen with --profile
+	if name := os.Getenv(config.ProfileEnv); name != "" {
+		if cfg, err = cfg.WithProfile(name); err != nil {
+			return err
+		}
+	}
+	if !cfg.AI.OAuth.Enabled() {
+		return fmt.Errorf("OAuth not configured. Set client_id, device_auth_url and token_url under [ai.oauth] in %s", config.ConfigPath())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	flow := auth.NewFlow(cfg.AI.OAuth, &http.Client{Timeout: time.Duration(cfg.AI.TimeoutSeconds) * time.Second})
+	dc, err := flow.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	if dc.VerificationURIComplete != "" {
+		fmt.Printf("Open %s to log in\n", dc.VerificationURIComplete)
+		fmt.Printf("and confirm the code %s\n", dc.UserCode)
+	} else {
+		fmt.Printf("Open %s and enter the code %s\n", dc.VerificationURI, dc.UserCode)
+	}
+	fmt.Println("Waiting for approval...")
+
+	token, err := flow.Wait(ctx, dc)
+	if err != nil {
+		return err
+	}
+	if err := auth.Save(cfg.AI.OAuth, token); err != nil {
+		return fmt.Errorf("failed to store credentials: %w", err)
+	}
+
+	fmt.Printf("Logged in. Credentials stored in %s\n", auth.CredentialsPath())
+	return nil
+}
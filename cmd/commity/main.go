@@ -1,15 +1,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/hluaguo/commity/internal/ai"
 	"github.com/hluaguo/commity/internal/config"
 	"github.com/hluaguo/commity/internal/git"
+	"github.com/hluaguo/commity/internal/telemetry"
 	"github.com/hluaguo/commity/internal/tui"
 )
 
@@ -18,20 +22,108 @@ var version = "0.1.0"
 func main() {
 	configPath := flag.String("config", "", "config file path")
 	showVersion := flag.Bool("version", false, "show version")
+	preset := flag.String("select", "", "apply a saved file selection preset")
+	amend := flag.Bool("amend", false, "reword the last commit from its diff")
+	compare := flag.Bool("compare", false, "generate with the [compare] model too and pick the better message")
+	profile := flag.String("profile", "", "apply a [profiles.<name>] table instead of the profile rules")
+	noCache := flag.Bool("no-cache", false, "always ask the model, even for a diff it already wrote a message for")
+	debug := flag.Bool("debug", false, "record prompts, request parameters and raw replies to "+ai.DebugLogPath())
 	flag.Parse()
 
+	// Subcommands pick the profile up like one set with COMMITY_PROFILE
+	if *profile != "" {
+		os.Setenv(config.ProfileEnv, *profile)
+	}
+
 	if *showVersion {
 		fmt.Printf("commity v%s\n", version)
 		os.Exit(0)
 	}
 
-	if err := run(*configPath); err != nil {
+	if *debug {
+		closeLog, err := openDebugLog()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		defer closeLog()
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "login":
+		err = runLogin(*configPath)
+	case "undo":
+		err = runUndo(*configPath)
+	case "today":
+		err = runToday(*configPath, flag.Args()[1:])
+	case "reword":
+		err = runReword(*configPath, flag.Args()[1:])
+	case "pr":
+		err = runPR(*configPath, flag.Args()[1:])
+	case "changelog":
+		err = runChangelog(*configPath, flag.Args()[1:])
+	case "telemetry":
+		err = runTelemetry(*configPath, flag.Args()[1:])
+	case "config":
+		err = runConfig(*configPath, flag.Args()[1:])
+	case "doctor":
+		err = runDoctor(*configPath)
+	case "":
+		err = run(*configPath, *preset, *amend, *compare, *noCache)
+	default:
+		err = fmt.Errorf("unknown command %q", flag.Arg(0))
+	}
+	recordUsage(*configPath, flag.Arg(0), err)
+	if *debug {
+		fmt.Fprintf(os.Stderr, "Debug log: %s\n", ai.DebugLogPath())
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run(configPath string) error {
+// openDebugLog starts a fresh debug log for this run. It contains diffs,
+// so only the owner can read it.
+func openDebugLog() (func(), error) {
+	path := ai.DebugLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open debug log: %w", err)
+	}
+	ai.SetDebugLog(f)
+	return func() {
+		ai.SetDebugLog(nil)
+		f.Close()
+	}, nil
+}
+
+// recordUsage counts the subcommand and any error category when telemetry
+// is enabled, and sends the counters if a report is due
+func recordUsage(configPath, command string, err error) {
+	cfg, loadErr := config.Load(configPath)
+	if loadErr != nil || !cfg.Telemetry.Enabled {
+		return
+	}
+	switch command {
+	case "":
+		command = "tui"
+	case "login", "undo", "today", "reword", "pr", "changelog", "telemetry", "config", "doctor":
+	default:
+		command = "unknown" // never record what was typed
+	}
+	telemetry.Count(cfg.Telemetry, "command."+command)
+	if err != nil {
+		telemetry.Count(cfg.Telemetry, "error."+telemetry.Categorize(err))
+	}
+	_ = telemetry.Flush(cfg.Telemetry, version, time.Now())
+}
+
+func run(configPath, preset string, amend, compare, noCache bool) error {
 	// Check if first run
 	isFirstRun := !config.Exists()
 
@@ -47,13 +139,36 @@ func run(configPath string) error {
 		return err
 	}
 
-	// Initialize AI client (may be nil if first run with no API key)
+	// --profile, COMMITY_PROFILE or the profile rules pick settings
+	if name := cfg.SelectProfile(repo.Path(), git.RemoteSlug(repo.RemoteURL("origin"))); name != "" {
+		if cfg, err = cfg.WithProfile(name); err != nil {
+			return err
+		}
+	}
+	if cfg, err = cfg.WithRepo(repo.Path()); err != nil {
+		return err
+	}
+	repo.SetSign(cfg.Commit.Sign)
+
+	// Initialize AI client (may be nil if first run or no API key)
 	var aiClient *ai.Client
+	var offlineErr error
 	if !isFirstRun {
-		aiClient, err = ai.New(&cfg.AI)
+		// Host policies may force a provider based on the origin remote
+		aiCfg, err := cfg.EffectiveAI(repo.RemoteHost())
 		if err != nil {
 			return err
 		}
+		aiClient, err = ai.New(&aiCfg)
+		switch {
+		case errors.Is(err, ai.ErrNoAPIKey):
+			// Still usable offline, with heuristic messages
+			offlineErr = err
+		case err != nil:
+			return err
+		case !noCache:
+			aiClient.SetCacheDir(ai.ResultCacheDir())
+		}
 	}
 
 	// Initialize TUI model
@@ -62,6 +177,38 @@ func run(configPath string) error {
 		return err
 	}
 
+	if offlineErr != nil {
+		model.SetOffline(offlineErr)
+	}
+
+	if compare && aiClient != nil {
+		compareCfg, err := cfg.CompareAI(repo.RemoteHost())
+		if err != nil {
+			return err
+		}
+		compareClient, err := ai.New(&compareCfg)
+		if err != nil {
+			return err
+		}
+		if !noCache {
+			compareClient.SetCacheDir(ai.ResultCacheDir())
+		}
+		model.SetCompare(compareClient)
+	}
+
+	// Apply saved file selection
+	if preset != "" && !isFirstRun {
+		if err := model.ApplyPreset(preset); err != nil {
+			return err
+		}
+	}
+
+	if amend && !isFirstRun {
+		if err := model.StartAmend(); err != nil {
+			return err
+		}
+	}
+
 	// Run TUI
 	p := tea.NewProgram(model)
 	if _, err := p.Run(); err != nil {
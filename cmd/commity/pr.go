@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"github.com/hluaguo/commity/internal/ai"
+)
+
+// runPR writes a pull request title and description for the current branch
+// and optionally opens the pull request with the GitHub CLI
+func runPR(configPath string, args []string) error {
+	fs := flag.NewFlagSet("pr", flag.ExitOnError)
+	baseFlag := fs.String("base", "", "branch the pull request targets")
+	create := fs.Bool("create", false, "open the pull request with gh pr create")
+	draft := fs.Bool("draft", false, "open it as a draft (with --create)")
+	_ = fs.Parse(args)
+
+	s, err := newAISession(configPath)
+	if err != nil {
+		return err
+	}
+
+	base := *baseFlag
+	if base == "" {
+		base = s.cfg.PR.Base
+	}
+	if base == "" {
+		if base, err = s.repo.DefaultBranch(); err != nil {
+			return err
+		}
+	}
+
+	changes, err := s.repo.ChangesSince(base)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Describing %d commits against %s...\n", len(changes.Subjects), base)
+	pr, err := s.client.PullRequest(ctx, ai.PRContext{
+		Base:               base,
+		Subjects:           changes.Subjects,
+		Files:              changes.Files,
+		Diff:               s.outbound(changes.Diff),
+		CustomInstructions: s.cfg.AI.CustomInstructions,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s\n\n%s\n", pr.Title, pr.Body)
+	if !*create {
+		return nil
+	}
+
+	if _, err := exec.LookPath("gh"); err != nil {
+		return fmt.Errorf("gh not found; install the GitHub CLI to create pull requests")
+	}
+	ghArgs := []string{"pr", "create", "--title", pr.Title, "--body", pr.Body,
+		"--base", strings.TrimPrefix(base, "origin/")}
+	if *draft {
+		ghArgs = append(ghArgs, "--draft")
+	}
+	cmd := exec.Command("gh", ghArgs...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gh pr create failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/store"
+	"github.com/hluaguo/commity/internal/tui"
+)
+
+// rewordFeedback steers regeneration for commits that already exist
+const rewordFeedback = "This commit already exists and its message may be a placeholder such as \"wip\". Describe what the diff actually does."
+
+// runReword generates better messages for existing commits, shows them
+// next to the originals and rewrites the commits the user accepts
+func runReword(configPath string, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: commity reword [sha|HEAD~N..]")
+	}
+	spec := ""
+	if len(args) == 1 {
+		spec = args[0]
+	}
+
+	s, err := newAISession(configPath)
+	if err != nil {
+		return err
+	}
+	cfg, repo := s.cfg, s.repo
+
+	hashes, err := repo.RewordCommits(spec)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rewords := make([]tui.Reword, 0, len(hashes))
+	for i, hash := range hashes {
+		fmt.Printf("Generating %d/%d (%s)...\n", i+1, len(hashes), hash[:7])
+		rw, err := generateReword(ctx, s, hash)
+		if err != nil {
+			return err
+		}
+		rewords = append(rewords, rw)
+	}
+
+	ok, err := tui.ReviewRewords(cfg.UI.Theme, rewords, repo.IsPushed(hashes[0]))
+	if err != nil || !ok {
+		return err
+	}
+
+	messages := make(map[string]string)
+	for _, rw := range rewords {
+		if rw.After != rw.Before {
+			messages[rw.Hash] = rw.After
+		}
+	}
+	if len(messages) == 0 {
+		fmt.Println("Nothing reworded")
+		return nil
+	}
+
+	ok, err = tui.ConfirmRewrite(cfg.UI.Theme, fmt.Sprintf("Reword %d commits?", len(messages)), tui.GuardBranch(repo))
+	if err != nil || !ok {
+		return err
+	}
+
+	oldHead, err := repo.HeadHash()
+	if err != nil {
+		return err
+	}
+	if _, ok := messages[oldHead]; ok && len(messages) == 1 {
+		// Only HEAD changes; a plain amend is enough
+		err = repo.AmendCommit(messages[oldHead], cfg.Commit.NoVerify)
+	} else {
+		err = repo.RewriteMessages(messages)
+	}
+	if err != nil {
+		return err
+	}
+
+	newHead, err := repo.HeadHash()
+	if err != nil {
+		return err
+	}
+	// Keep `commity undo` pointing at the rewritten commit
+	if state, err := store.Load(repo.Path()); err == nil && state.LastCommit == oldHead {
+		state.LastCommit = newHead
+		_ = state.Save()
+	}
+
+	fmt.Printf("Reworded %d commits, HEAD is now %s (previous HEAD in ORIG_HEAD)\n", len(messages), newHead[:7])
+	return nil
+}
+
+// generateReword asks the AI for a new message for one existing commit
+func generateReword(ctx context.Context, s *aiSession, hash string) (tui.Reword, error) {
+	cfg, repo := s.cfg, s.repo
+	before, err := repo.CommitMessage(hash)
+	if err != nil {
+		return tui.Reword{}, err
+	}
+	files, err := repo.CommitFiles(hash)
+	if err != nil {
+		return tui.Reword{}, err
+	}
+	diff, err := repo.DiffOfCommit(hash)
+	if err != nil {
+		return tui.Reword{}, err
+	}
+
+	commit := cfg.CommitRules()
+	var types []string
+	if commit.Conventional {
+		types = commit.TypeNames()
+	}
+	result, err := s.client.GenerateCommitMessage(ctx, ai.PromptContext{
+		Files:              files,
+		Diff:               s.outbound(diff),
+		Conventional:       commit.Conventional,
+		Types:              types,
+		TypeDescriptions:   commit.TypeDescriptions(),
+		Language:           commit.Language,
+		CustomInstructions: cfg.AI.CustomInstructions,
+		PreviousMsg:        before,
+		Feedback:           rewordFeedback,
+		Exclude:            cfg.AI.Exclude,
+		Single:             true,
+		Subject: ai.SubjectRules{
+			Types:       types,
+			MaxLength:   commit.SubjectMaxLength,
+			Prefix:      commit.SubjectPrefix,
+			Emoji:       commit.EmojiFor(),
+			RequireBody: commit.RequireBodyFor,
+			Scopes:      commit.Scopes,
+			ScopeEnum:   commit.ScopeEnum,
+		},
+	})
+	if err != nil {
+		return tui.Reword{}, fmt.Errorf("failed to reword %s: %w", hash[:7], err)
+	}
+	return tui.Reword{Hash: hash, Before: before, After: result.Commits[0].String()}, nil
+}
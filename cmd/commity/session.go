@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/git"
+	"github.com/hluaguo/commity/internal/security"
+)
+
+// aiSession holds what one-shot AI commands such as reword and pr need
+type aiSession struct {
+	cfg    *config.Config
+	ai     config.AIConfig // effective settings after host policies
+	repo   *git.Repository
+	client *ai.Client
+}
+
+// newAISession loads the config for the current repository, applying
+// profile rules and host policies, and creates the AI client
+func newAISession(configPath string) (*aiSession, error) {
+	if !config.Exists() {
+		return nil, fmt.Errorf("no config found; run commity once to set it up")
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	repo, err := git.New()
+	if err != nil {
+		return nil, err
+	}
+	if name := cfg.SelectProfile(repo.Path(), git.RemoteSlug(repo.RemoteURL("origin"))); name != "" {
+		if cfg, err = cfg.WithProfile(name); err != nil {
+			return nil, err
+		}
+	}
+	if cfg, err = cfg.WithRepo(repo.Path()); err != nil {
+		return nil, err
+	}
+	repo.SetSign(cfg.Commit.Sign)
+	aiCfg, err := cfg.EffectiveAI(repo.RemoteHost())
+	if err != nil {
+		return nil, err
+	}
+	client, err := ai.New(&aiCfg)
+	if err != nil {
+		return nil, err
+	}
+	return &aiSession{cfg: cfg, ai: aiCfg, repo: repo, client: client}, nil
+}
+
+// outbound prepares a diff for the AI. Files under [privacy] never_send are
+// summarized and secrets are redacted for remote models, since there is no
+// screen to confirm them on.
+func (s *aiSession) outbound(diff string) string {
+	diff = ai.Withhold(diff, s.cfg.Privacy.NeverSend)
+	if s.cfg.AI.Secrets == config.SecretsOff || s.ai.IsLocal() {
+		return diff
+	}
+	redacted, _ := security.Redact(diff)
+	return redacted
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/telemetry"
+)
+
+// runTelemetry shows or changes whether anonymous usage counters are kept
+func runTelemetry(configPath string, args []string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	action := "status"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch action {
+	case "status":
+		return telemetryStatus(cfg.Telemetry)
+	case "enable", "disable":
+		cfg.Telemetry.Enabled = action == "enable"
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("Telemetry %sd\n", action)
+		if cfg.Telemetry.Enabled {
+			fmt.Println(telemetry.Description)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown telemetry command %q (want status, enable or disable)", action)
+}
+
+func telemetryStatus(cfg config.TelemetryConfig) error {
+	if !cfg.Enabled {
+		fmt.Println("Telemetry is disabled. Enable it with: commity telemetry enable")
+		fmt.Println(telemetry.Description)
+		return nil
+	}
+	fmt.Println("Telemetry is enabled.")
+	fmt.Println(telemetry.Description)
+	if cfg.Endpoint == "" {
+		fmt.Println("No endpoint is configured, so counters are only kept locally.")
+	} else {
+		fmt.Printf("Reports are sent to %s at most once a day.\n", cfg.Endpoint)
+	}
+
+	counters, err := telemetry.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", telemetry.Path(), err)
+	}
+	if len(counters.Counts) == 0 {
+		fmt.Println("\nNothing counted since the last report.")
+		return nil
+	}
+	fmt.Println("\nCounted since the last report:")
+	for _, name := range slices.Sorted(maps.Keys(counters.Counts)) {
+		fmt.Printf("  %-28s %d\n", name, counters.Counts[name])
+	}
+	if !counters.LastSent.IsZero() {
+		fmt.Printf("\nLast report: %s\n", counters.LastSent.Format(time.DateTime))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/git"
+)
+
+// runToday lists the commits made today in the configured repositories and
+// optionally asks the AI for a standup summary of them
+func runToday(configPath string, args []string) error {
+	fs := flag.NewFlagSet("today", flag.ExitOnError)
+	summary := fs.Bool("summary", false, "write a standup paragraph with the AI")
+	_ = fs.Parse(args)
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	dirs, err := todayRepos(cfg)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var repos []ai.StandupRepo
+	var hosts []string
+	total := 0
+	for _, dir := range dirs {
+		entries, err := git.Log(dir, midnight, git.UserEmail(dir))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			continue
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		name := filepath.Base(dir)
+		fmt.Printf("%s (%d)\n", name, len(entries))
+		repo := ai.StandupRepo{Name: name}
+		for _, e := range entries {
+			fmt.Printf("  %s %s %s\n", e.Time.Local().Format("15:04"), e.Hash[:min(7, len(e.Hash))], e.Subject)
+			repo.Subjects = append(repo.Subjects, e.Subject)
+		}
+		fmt.Println()
+
+		repos = append(repos, repo)
+		hosts = append(hosts, git.RemoteHostOf(dir))
+		total += len(entries)
+	}
+
+	if total == 0 {
+		fmt.Println("No commits today")
+		return nil
+	}
+	if !*summary {
+		return nil
+	}
+
+	aiCfg, err := standupAI(cfg, hosts)
+	if err != nil {
+		return err
+	}
+	client, err := ai.New(&aiCfg)
+	if err != nil {
+		return err
+	}
+	text, err := client.Standup(context.Background(), repos)
+	if err != nil {
+		return err
+	}
+	fmt.Println(text)
+	return nil
+}
+
+// todayRepos returns the configured repositories, or the current one
+func todayRepos(cfg *config.Config) ([]string, error) {
+	if len(cfg.Today.Repos) == 0 {
+		repo, err := git.New()
+		if err != nil {
+			return nil, fmt.Errorf("not a git repository and no [today] repos configured")
+		}
+		return []string{repo.Path()}, nil
+	}
+
+	home, _ := os.UserHomeDir()
+	dirs := make([]string, 0, len(cfg.Today.Repos))
+	for _, r := range cfg.Today.Repos {
+		if rest, ok := strings.CutPrefix(r, "~/"); ok {
+			r = filepath.Join(home, rest)
+		}
+		dirs = append(dirs, r)
+	}
+	return dirs, nil
+}
+
+// standupAI picks the AI settings for a summary spanning several
+// repositories. Host policies of every repository apply, and a local
+// provider forced by any of them wins so no subject leaves the machine
+// against a policy.
+func standupAI(cfg *config.Config, hosts []string) (config.AIConfig, error) {
+	chosen := cfg.AI
+	for _, host := range hosts {
+		effective, err := cfg.EffectiveAI(host)
+		if err != nil {
+			return effective, err
+		}
+		if effective.IsLocal() {
+			return effective, nil
+		}
+		if cfg.PolicyFor(host) != nil {
+			chosen = effective
+		}
+	}
+	return chosen, nil
+}
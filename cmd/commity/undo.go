@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/git"
+	"github.com/hluaguo/commity/internal/store"
+	"github.com/hluaguo/commity/internal/tui"
+)
+
+// runUndo soft-resets the last commit made by commity, keeping its changes
+// staged, and reopens the TUI so it can be committed again
+func runUndo(configPath string) error {
+	repo, err := git.New()
+	if err != nil {
+		return err
+	}
+	state, err := store.Load(repo.Path())
+	if err != nil {
+		return fmt.Errorf("failed to load repository state: %w", err)
+	}
+	if state.LastCommit == "" {
+		return fmt.Errorf("no commit made by commity to undo")
+	}
+
+	// Never reset a commit commity didn't make
+	head, err := repo.HeadHash()
+	if err != nil {
+		return err
+	}
+	if head != state.LastCommit {
+		return fmt.Errorf("HEAD %s is not the last commit made by commity (%s); undo it with git reset --soft HEAD~1", head[:7], state.LastCommit[:7])
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	ok, err := tui.ConfirmRewrite(cfg.UI.Theme, fmt.Sprintf("Undo %s?", head[:7]), tui.GuardBranch(repo))
+	if err != nil || !ok {
+		return err
+	}
+
+	if err := repo.UndoLastCommit(); err != nil {
+		return err
+	}
+	state.LastCommit = ""
+	if err := state.Save(); err != nil {
+		return fmt.Errorf("failed to save repository state: %w", err)
+	}
+
+	fmt.Printf("Undid %s, its changes are staged\n", head[:7])
+	// The message just undone is cached for this diff; ask for a new one
+	return run(configPath, "", false, false, true)
+}
@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const branchSystemPrompt = `You name git branches. Given a change, reply with a branch name of
+2-5 lowercase words joined by hyphens that says what the change does, such
+as add-oauth-login or fix-empty-config-crash. Don't add a prefix like
+feat/. Reply with the name only.`
+
+// Branch name limits
+const (
+	maxBranchLength    = 50   // characters of the name, excluding the prefix
+	branchDiffTokens   = 2000 // the subjects carry most of the meaning
+	branchTypeVariable = "{type}"
+)
+
+// BuildBranchPrompt describes a change by its commit subjects, files and
+// a short excerpt of its diff
+func BuildBranchPrompt(subjects, files []string, diff string) string {
+	var sb strings.Builder
+	sb.WriteString("Commit messages:\n")
+	for _, s := range subjects {
+		sb.WriteString("- " + s + "\n")
+	}
+	sb.WriteString("\nFiles changed:\n")
+	for _, f := range files {
+		sb.WriteString("- " + f + "\n")
+	}
+	if diff != "" {
+		sb.WriteString("\nDiff:\n```\n")
+		sb.WriteString(truncateDiff(diff, branchDiffTokens, ""))
+		sb.WriteString("\n```\n")
+	}
+	return sb.String()
+}
+
+// SuggestBranchName asks the model for a kebab-case branch name
+func (c *Client) SuggestBranchName(ctx context.Context, subjects, files []string, diff string) (string, error) {
+	resp, err := c.call(ctx, branchSystemPrompt, BuildBranchPrompt(subjects, files, diff), nil)
+	if err != nil {
+		return "", fmt.Errorf("AI request failed: %w", err)
+	}
+	name := BranchName(resp.Content, "", "")
+	if name == "" {
+		return "", errNoResponse
+	}
+	return name, nil
+}
+
+// BranchName turns text into a kebab-case branch name behind prefix. A
+// "{type}" in the prefix is replaced by commitType; the separator after an
+// empty type is dropped.
+func BranchName(text, prefix, commitType string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(line) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+		} else if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(sb.String(), "-")
+	if len(name) > maxBranchLength {
+		name = name[:maxBranchLength]
+		if i := strings.LastIndexByte(name, '-'); i > 0 {
+			name = name[:i]
+		}
+	}
+	if name == "" {
+		return ""
+	}
+
+	if commitType == "" {
+		prefix = strings.ReplaceAll(prefix, branchTypeVariable+"/", "")
+	}
+	return strings.ReplaceAll(prefix, branchTypeVariable, commitType) + name
+}
@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/adrg/xdg"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// cacheMaxAge is how long a cached result is kept
+const cacheMaxAge = 7 * 24 * time.Hour
+
+// ResultCacheDir returns the directory holding generated messages, keyed by
+// everything sent to the model
+func ResultCacheDir() string {
+	return filepath.Join(xdg.CacheHome, "commity", "results")
+}
+
+// SetCacheDir makes the client reuse results for identical requests stored
+// in dir, so relaunching on the same diff costs no tokens. An empty dir
+// turns caching off, which is the default.
+func (c *Client) SetCacheDir(dir string) {
+	c.cacheDir = dir
+}
+
+// CacheDir returns the directory set with SetCacheDir
+func (c *Client) CacheDir() string {
+	return c.cacheDir
+}
+
+// cachedResult is what is stored for a request: the messages before
+// subject rules and trailers are applied
+type cachedResult struct {
+	Commits []CommitMessage `json:"commits"`
+	IsSplit bool            `json:"is_split"`
+}
+
+// cacheKey identifies a request by the model, its settings and the exact
+// prompts and tools sent
+func (c *Client) cacheKey(system, prompt string, tools []openai.Tool) string {
+	h := sha256.New()
+	params, _ := json.Marshal(c.params)
+	schemas, _ := json.Marshal(tools)
+	for _, part := range []string{c.providerName, c.model, strconv.FormatBool(c.structured), string(params), system, prompt, string(schemas)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cached returns the stored result for key, if any
+func (c *Client) cached(key string) (*GenerateResult, bool) {
+	if c.cacheDir == "" {
+		return nil, false
+	}
+	path := filepath.Join(c.cacheDir, key+".json")
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > cacheMaxAge {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry cachedResult
+	if err := json.Unmarshal(data, &entry); err != nil || len(entry.Commits) == 0 {
+		return nil, false
+	}
+	return &GenerateResult{Commits: entry.Commits, IsSplit: entry.IsSplit, Cached: true}, true
+}
+
+// storeCached saves result under key and drops expired entries. Failures
+// only cost a later request, so they are ignored.
+func (c *Client) storeCached(key string, result *GenerateResult) {
+	if c.cacheDir == "" {
+		return
+	}
+	data, err := json.Marshal(cachedResult{Commits: result.Commits, IsSplit: result.IsSplit})
+	if err != nil {
+		return
+	}
+	// Entries contain commit messages about private code
+	if err := os.MkdirAll(c.cacheDir, 0700); err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(c.cacheDir, key+".json"), data, 0600)
+
+	entries, err := os.ReadDir(c.cacheDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > cacheMaxAge {
+			os.Remove(filepath.Join(c.cacheDir, e.Name()))
+		}
+	}
+}
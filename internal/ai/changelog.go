@@ -0,0 +1,26 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const changelogSystemPrompt = `You write release notes for end users. Given the entries of one
+changelog section, write 1-3 plain sentences summarizing what changed for
+users, grouping related entries and leaving out internal details. Don't
+invent changes that aren't listed. Reply with the sentences only.`
+
+// SummarizeSection asks the model for a short summary of a changelog section
+func (c *Client) SummarizeSection(ctx context.Context, title string, entries []string) (string, error) {
+	prompt := fmt.Sprintf("Section: %s\n\nEntries:\n- %s\n", title, strings.Join(entries, "\n- "))
+	resp, err := c.call(ctx, changelogSystemPrompt, prompt, nil)
+	if err != nil {
+		return "", fmt.Errorf("AI request failed: %w", err)
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", errNoResponse
+	}
+	return summary, nil
+}
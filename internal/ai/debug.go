@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// debugLog receives every exchange with a model when --debug is on
+var debugLog struct {
+	sync.Mutex
+	w io.Writer
+}
+
+// DebugLogPath returns the file --debug writes to. It holds diffs, so it is
+// readable by the owner only.
+func DebugLogPath() string {
+	return filepath.Join(xdg.StateHome, "commity", "debug.log")
+}
+
+// SetDebugLog makes all clients record their prompts, request parameters
+// and raw replies to w; nil turns recording off
+func SetDebugLog(w io.Writer) {
+	debugLog.Lock()
+	defer debugLog.Unlock()
+	debugLog.w = w
+}
+
+// call sends a chat request to the provider and records it when debugging
+func (c *Client) call(ctx context.Context, system, user string, tools []openai.Tool) (*chatResponse, error) {
+	start := time.Now()
+	resp, err := c.provider.chat(ctx, system, user, tools)
+	c.logExchange(system, user, tools, false, resp, err, time.Since(start))
+	return resp, err
+}
+
+// logExchange writes one request and its reply to the debug log
+func (c *Client) logExchange(system, user string, tools []openai.Tool, structured bool, resp *chatResponse, err error, elapsed time.Duration) {
+	debugLog.Lock()
+	defer debugLog.Unlock()
+	if debugLog.w == nil {
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "=== %s %s %s\n", time.Now().Format(time.RFC3339), c.providerName, c.model)
+	var names []string
+	for _, t := range tools {
+		if t.Function != nil {
+			names = append(names, t.Function.Name)
+		}
+	}
+	fmt.Fprintf(&sb, "tools: %s  structured outputs: %t\n", strings.Join(names, ", "), structured)
+	if len(c.params) > 0 {
+		params, _ := json.Marshal(c.params)
+		fmt.Fprintf(&sb, "params: %s\n", params)
+	}
+	fmt.Fprintf(&sb, "--- system (~%d tokens)\n%s\n", EstimateTokensFor(c.model, system), system)
+	fmt.Fprintf(&sb, "--- user (~%d tokens)\n%s\n", EstimateTokensFor(c.model, user), user)
+
+	if err != nil {
+		fmt.Fprintf(&sb, "--- error after %s\n%v\n\n", elapsed.Round(time.Millisecond), err)
+		io.WriteString(debugLog.w, sb.String())
+		return
+	}
+	fmt.Fprintf(&sb, "--- reply after %s (%d prompt + %d completion tokens reported)\n",
+		elapsed.Round(time.Millisecond), resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	for _, call := range resp.ToolCalls {
+		fmt.Fprintf(&sb, "tool call %s: %s\n", call.Name, call.Arguments)
+	}
+	if resp.Content != "" {
+		fmt.Fprintf(&sb, "content: %s\n", resp.Content)
+	}
+	sb.WriteString("\n")
+	io.WriteString(debugLog.w, sb.String())
+}
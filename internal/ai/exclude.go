@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hluaguo/commity/internal/glob"
+)
+
+// summarizeExcluded replaces the diff of files matching the exclude patterns
+// with a one-line summary. Lockfiles and generated code otherwise eat the
+// whole truncation budget without telling the model anything useful.
+func summarizeExcluded(diff string, patterns []string) string {
+	return summarizeMatching(diff, patterns, "content excluded from prompt")
+}
+
+// Withhold replaces the diff of files matching the privacy patterns with
+// their name and line counts, so their contents never leave the machine
+func Withhold(diff string, patterns []string) string {
+	return summarizeMatching(diff, patterns, "content withheld by privacy policy")
+}
+
+func summarizeMatching(diff string, patterns []string, note string) string {
+	if len(patterns) == 0 || diff == "" {
+		return diff
+	}
+
+	var sb strings.Builder
+	for _, section := range splitByFiles(diff) {
+		path := diffPath(section)
+		if path == "" || !glob.MatchAny(patterns, path) {
+			sb.WriteString(section)
+			continue
+		}
+
+		added, removed := countChanges(section)
+		sb.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", path, path))
+		sb.WriteString(fmt.Sprintf("[%s: +%d -%d lines]\n", note, added, removed))
+	}
+	return sb.String()
+}
+
+// diffPath extracts the file path from a per-file diff section
+func diffPath(section string) string {
+	for _, line := range strings.Split(section, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			if i := strings.LastIndex(line, " b/"); i != -1 {
+				return line[i+3:]
+			}
+		}
+		if strings.HasPrefix(line, "+++ ") {
+			return strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+		}
+	}
+	return ""
+}
+
+// countChanges counts added and removed lines in a diff section
+func countChanges(section string) (added, removed int) {
+	for _, line := range strings.Split(section, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Gemini defaults (Google AI Studio)
+const (
+	GeminiDefaultURL   = "https://generativelanguage.googleapis.com/v1beta"
+	GeminiDefaultModel = "gemini-2.5-flash"
+)
+
+// geminiProvider talks to the Gemini generateContent API
+type geminiProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	http    *http.Client
+}
+
+func newGeminiProvider(baseURL, apiKey, model string, httpClient *http.Client) *geminiProvider {
+	if baseURL == "" {
+		baseURL = GeminiDefaultURL
+	}
+	if model == "" {
+		model = GeminiDefaultModel
+	}
+	return &geminiProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		http:    httpClient,
+	}
+}
+
+type geminiPart struct {
+	Text         string              `json:"text,omitempty"`
+	FunctionCall *geminiFunctionCall `json:"functionCall,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// GeminiFunctionDeclaration is the Gemini equivalent of an OpenAI function tool
+type GeminiFunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent `json:"contents"`
+	Tools             []geminiTool    `json:"tools,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content geminiContent `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// GeminiFunctionDeclarations maps OpenAI tool definitions to Gemini
+// function declarations. Both use JSON schema for parameters.
+func GeminiFunctionDeclarations(tools []openai.Tool) []GeminiFunctionDeclaration {
+	var decls []GeminiFunctionDeclaration
+	for _, t := range tools {
+		if t.Function == nil {
+			continue
+		}
+		decls = append(decls, GeminiFunctionDeclaration{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+		})
+	}
+	return decls
+}
+
+func (p *geminiProvider) chat(ctx context.Context, system, user string, tools []openai.Tool) (*chatResponse, error) {
+	req := geminiRequest{
+		SystemInstruction: &geminiContent{Parts: []geminiPart{{Text: system}}},
+		Contents: []geminiContent{
+			{Role: "user", Parts: []geminiPart{{Text: user}}},
+		},
+	}
+	if decls := GeminiFunctionDeclarations(tools); len(decls) > 0 {
+		req.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", p.baseURL, url.PathEscape(p.model))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	httpResp, err := p.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	raw, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(raw, &e) == nil && e.Error.Message != "" {
+			return nil, fmt.Errorf("gemini: %s", e.Error.Message)
+		}
+		return nil, fmt.Errorf("gemini: unexpected status %s", httpResp.Status)
+	}
+
+	var resp geminiResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Candidates) == 0 {
+		return nil, errNoResponse
+	}
+
+	result := &chatResponse{
+		Usage: Usage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+		},
+	}
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if part.FunctionCall != nil {
+			result.ToolCalls = append(result.ToolCalls, ToolCall{
+				Name:      part.FunctionCall.Name,
+				Arguments: string(part.FunctionCall.Args),
+			})
+			continue
+		}
+		result.Content += part.Text
+	}
+	return result, nil
+}
@@ -0,0 +1,284 @@
+package ai
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Fallbacks used when generation exceeds its deadline or fails
+const (
+	FallbackShortPrompt = "short prompt" // retried with a reduced diff
+	FallbackHeuristic   = "heuristic"    // built locally from the diff
+	FallbackOffline     = "offline"      // built locally, the AI could not be used
+)
+
+// shortPromptTokens is the diff budget of the retry after a missed deadline
+const shortPromptTokens = 1000
+
+// HeuristicMessage builds a commit message from the changed file names
+// alone, for when the AI cannot answer in time
+func HeuristicMessage(files []string, conventional bool) CommitMessage {
+	msg := CommitMessage{Subject: heuristicSubject(files), Files: files}
+	if conventional {
+		msg.Type = heuristicType(files)
+	}
+	return msg
+}
+
+// HeuristicFromDiff builds a commit message locally from the diff: the type
+// from the paths and whether files were created, the subject from the most
+// changed file and the body from the diff stat. Without a diff it falls
+// back to HeuristicMessage.
+func HeuristicFromDiff(files []string, diff string, conventional bool) CommitMessage {
+	changes := diffChanges(diff)
+	if len(changes) == 0 {
+		return HeuristicMessage(files, conventional)
+	}
+
+	created := !slices.ContainsFunc(changes, func(c fileChange) bool { return !c.created })
+	deleted := !slices.ContainsFunc(changes, func(c fileChange) bool { return !c.deleted })
+	verb := "update"
+	switch {
+	case created:
+		verb = "add"
+	case deleted:
+		verb = "remove"
+	}
+
+	primary := slices.MaxFunc(changes, func(a, b fileChange) int {
+		return cmp.Compare(a.added+a.removed, b.added+b.removed)
+	})
+	subject := verb + " " + path.Base(primary.path)
+	switch others := len(changes) - 1; others {
+	case 0:
+	case 1:
+		subject += " and 1 other file"
+	default:
+		subject += fmt.Sprintf(" and %d other files", others)
+	}
+
+	added, removed := 0, 0
+	for _, c := range changes {
+		added += c.added
+		removed += c.removed
+	}
+	msg := CommitMessage{
+		Subject: subject,
+		Body:    fmt.Sprintf("%d %s changed, %d insertions(+), %d deletions(-)", len(changes), plural(len(changes), "file"), added, removed),
+		Files:   files,
+	}
+	if conventional {
+		paths := make([]string, len(changes))
+		for i, c := range changes {
+			paths[i] = c.path
+		}
+		msg.Type, _ = TypeHint(paths)
+		switch {
+		case msg.Type != "":
+		case created:
+			msg.Type = "feat"
+		default:
+			msg.Type = "chore"
+		}
+	}
+	return msg
+}
+
+// fileChange is what the heuristic knows about one file of a diff
+type fileChange struct {
+	path             string
+	added, removed   int
+	created, deleted bool
+}
+
+func diffChanges(diff string) []fileChange {
+	var changes []fileChange
+	for _, section := range splitByFiles(diff) {
+		p := diffPath(section)
+		if p == "" {
+			continue
+		}
+		added, removed := countChanges(section)
+		changes = append(changes, fileChange{
+			path:    p,
+			added:   added,
+			removed: removed,
+			created: strings.Contains(section, "\nnew file mode"),
+			deleted: strings.Contains(section, "\ndeleted file mode"),
+		})
+	}
+	return changes
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// Offline returns a locally built result for when the AI could not be used
+// at all; err says why and is shown next to the message
+func Offline(pc PromptContext, err error) *GenerateResult {
+	msg := HeuristicFromDiff(pc.Files, pc.Diff, pc.Conventional)
+	return &GenerateResult{
+		Commits:  []CommitMessage{AddTrailers(EnforceSubject(msg, pc.Subject), pc.Trailers)},
+		Fallback: FallbackOffline,
+		Err:      err,
+	}
+}
+
+func heuristicType(files []string) string {
+	switch ClassifyChanges(files) {
+	case ChangeDocs:
+		return "docs"
+	case ChangeConfig:
+		return "chore"
+	}
+	if len(files) == 0 {
+		return "chore"
+	}
+	for _, f := range files {
+		if !isTestFile(f) {
+			return "chore"
+		}
+	}
+	return "test"
+}
+
+func heuristicSubject(files []string) string {
+	switch len(files) {
+	case 0:
+		return "update files"
+	case 1:
+		return "update " + path.Base(files[0])
+	}
+	if dir := commonDir(files); dir != "" {
+		return fmt.Sprintf("update %d files in %s", len(files), dir)
+	}
+	return fmt.Sprintf("update %d files", len(files))
+}
+
+// commonDir returns the deepest directory containing all files, or ""
+func commonDir(files []string) string {
+	dir := path.Dir(files[0])
+	for _, f := range files[1:] {
+		for dir != "." && dir != "/" && !strings.HasPrefix(f, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// isTestFile reports whether path looks like a test file
+func isTestFile(p string) bool {
+	base := path.Base(p)
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(base, "test_")
+}
+
+// ciFiles and buildFiles name files that belong to the ci and build types
+var (
+	ciFiles    = []string{".gitlab-ci.yml", ".travis.yml", "Jenkinsfile", "azure-pipelines.yml", "bitbucket-pipelines.yml"}
+	ciDirs     = []string{".github/workflows/", ".circleci/", ".buildkite/"}
+	buildFiles = []string{"Makefile", "Dockerfile", "go.mod", "go.sum", "package.json", "package-lock.json", "pnpm-lock.yaml", "yarn.lock", "Cargo.toml", "Cargo.lock", "pom.xml", "build.gradle", "CMakeLists.txt", ".goreleaser.yml", ".goreleaser.yaml"}
+)
+
+// typeHints are checked in order; a hint applies when every file matches
+var typeHints = []struct {
+	typ, reason string
+	match       func(string) bool
+}{
+	{"test", "only test files changed", func(p string) bool {
+		return isTestFile(p) || underDir(p, "test", "tests", "__tests__", "testdata")
+	}},
+	{"docs", "only documentation changed", func(p string) bool {
+		return isDocFile(p) || underDir(p, "docs", "doc")
+	}},
+	{"ci", "only CI configuration changed", func(p string) bool {
+		return slices.Contains(ciFiles, path.Base(p)) || slices.ContainsFunc(ciDirs, func(d string) bool { return strings.HasPrefix(p, d) })
+	}},
+	{"build", "only build files and dependency manifests changed", func(p string) bool {
+		return slices.Contains(buildFiles, path.Base(p))
+	}},
+}
+
+// TypeHint guesses the commit type from the paths alone and says why, or
+// returns "" when they don't point to one type. It is a hint for the model,
+// which sees the diff and may know better.
+func TypeHint(files []string) (typ, reason string) {
+	if len(files) == 0 {
+		return "", ""
+	}
+	for _, h := range typeHints {
+		if !slices.ContainsFunc(files, func(f string) bool { return !h.match(f) }) {
+			return h.typ, h.reason
+		}
+	}
+	return "", ""
+}
+
+// underDir reports whether p lies below a directory with one of the names
+func underDir(p string, names ...string) bool {
+	parts := strings.Split(path.Dir(p), "/")
+	return slices.ContainsFunc(parts, func(d string) bool { return slices.Contains(names, d) })
+}
+
+// typePrecedence orders commit types by how much they say about a change
+var typePrecedence = []string{"feat", "fix", "perf", "refactor", "test", "docs", "style", "build", "ci", "chore"}
+
+// MergeCommits collapses split commits into one without asking the model
+// again. The most significant type and the first subject are kept, and
+// every original subject is listed in the body.
+func MergeCommits(commits []CommitMessage) CommitMessage {
+	if len(commits) == 0 {
+		return CommitMessage{}
+	}
+	if len(commits) == 1 {
+		return commits[0]
+	}
+
+	merged := CommitMessage{Type: commits[0].Type, Scope: commits[0].Scope, Subject: commits[0].Subject, Prefix: commits[0].Prefix, Trailers: commits[0].Trailers}
+	rank := func(t string) int {
+		if i := slices.Index(typePrecedence, t); i != -1 {
+			return i
+		}
+		return len(typePrecedence)
+	}
+
+	var body []string
+	for _, c := range commits {
+		if rank(c.Type) < rank(merged.Type) {
+			merged.Type = c.Type
+		}
+		if c.Scope != merged.Scope {
+			merged.Scope = "" // a scope only holds when all commits share it
+		}
+		if c.IsBreaking() {
+			merged.Breaking = true
+			if c.BreakingDescription != "" {
+				merged.BreakingDescription = strings.TrimSpace(merged.BreakingDescription + " " + c.BreakingDescription)
+			}
+		}
+		line := "- " + c.Subject
+		if c.Type != "" {
+			line = fmt.Sprintf("- %s: %s", c.Type, c.Subject)
+		}
+		body = append(body, line)
+		for _, f := range c.Files {
+			if !slices.Contains(merged.Files, f) {
+				merged.Files = append(merged.Files, f)
+			}
+		}
+	}
+	merged.Body = strings.Join(body, "\n")
+	return merged
+}
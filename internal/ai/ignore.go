@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const ignoreSystemPrompt = `You maintain .gitignore files. Given untracked paths that look like
+build output, dependencies or editor leftovers, reply with gitignore
+patterns that ignore them and similar files in the future. Prefer general
+patterns (dist/, *.log) over exact paths, one pattern per line, with no
+comments or explanation.`
+
+// SuggestIgnorePatterns asks the model for gitignore patterns covering the
+// paths. Only the paths are sent, never file contents.
+func (c *Client) SuggestIgnorePatterns(ctx context.Context, paths []string) ([]string, error) {
+	prompt := "Untracked paths:\n- " + strings.Join(paths, "\n- ")
+	resp, err := c.call(ctx, ignoreSystemPrompt, prompt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("AI request failed: %w", err)
+	}
+	patterns := ParseIgnorePatterns(resp.Content)
+	if len(patterns) == 0 {
+		return nil, errNoResponse
+	}
+	return patterns, nil
+}
+
+// ParseIgnorePatterns extracts patterns from a reply, tolerating list
+// markers, code fences and comments
+func ParseIgnorePatterns(content string) []string {
+	var patterns []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "- ")
+		line = strings.TrimPrefix(line, "* ")
+		line = strings.Trim(line, "`")
+		if line == "" || strings.HasPrefix(line, "#") || strings.Contains(line, " ") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// keyCooldown is how long a key that hit its quota is passed over, also
+// across runs
+const keyCooldown = time.Minute
+
+// KeyUsage is what is tracked locally for an API key. It is stored by
+// fingerprint, so the key itself never ends up on disk.
+type KeyUsage struct {
+	Hint        string    `json:"hint"` // the end of the key, see KeyHint
+	Requests    int       `json:"requests"`
+	RateLimited int       `json:"rate_limited"`
+	LastUsed    time.Time `json:"last_used,omitzero"`
+	LastLimited time.Time `json:"last_limited,omitzero"`
+}
+
+// KeyUsagePath returns the file holding the usage of each API key
+func KeyUsagePath() string {
+	return filepath.Join(xdg.StateHome, "commity", "keys.json")
+}
+
+// LoadKeyUsage reads the usage of each key by fingerprint. A missing file
+// yields no usage.
+func LoadKeyUsage() (map[string]KeyUsage, error) {
+	usage := make(map[string]KeyUsage)
+	data, err := os.ReadFile(KeyUsagePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return usage, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func saveKeyUsage(usage map[string]KeyUsage) error {
+	path := KeyUsagePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// KeyFingerprint identifies key in the usage file
+func KeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeyHint shows the last characters of key, e.g. "...f3a9"
+func KeyHint(key string) string {
+	if len(key) <= 8 {
+		return "..."
+	}
+	return "..." + key[len(key)-4:]
+}
+
+// keyTransport authorizes each request with one of several API keys. A key
+// answered with 429 is passed over for keyCooldown and the request is sent
+// again with the next one; the retry transport only backs off once every
+// key has hit its quota.
+type keyTransport struct {
+	base       http.RoundTripper
+	keys       []string
+	roundRobin bool
+	header     string // the header carrying the key
+	prefix     string // e.g. "Bearer "
+
+	mu      sync.Mutex
+	next    int
+	limited []time.Time // when each key last hit its quota
+}
+
+// newKeyTransport rotates the keys of cfg on requests sent through base
+func newKeyTransport(base http.RoundTripper, cfg *config.AIConfig) *keyTransport {
+	t := &keyTransport{
+		base:       base,
+		keys:       cfg.APIKeys,
+		roundRobin: cfg.KeyRotation == config.RotateRoundRobin,
+		header:     "Authorization",
+		prefix:     "Bearer ",
+		limited:    make([]time.Time, len(cfg.APIKeys)),
+	}
+	switch cfg.Provider {
+	case config.ProviderGemini:
+		t.header, t.prefix = "x-goog-api-key", ""
+	case config.ProviderAzure:
+		t.header, t.prefix = "api-key", ""
+	}
+	// Start past keys that hit their quota in an earlier run
+	if usage, err := LoadKeyUsage(); err == nil {
+		for i, key := range t.keys {
+			t.limited[i] = usage[KeyFingerprint(key)].LastLimited
+		}
+	}
+	return t
+}
+
+// pick returns the key to use next: the first one, from the current key
+// on, that is not cooling down
+func (t *keyTransport) pick() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	chosen := t.next
+	for i := range t.keys {
+		k := (t.next + i) % len(t.keys)
+		if time.Since(t.limited[k]) >= keyCooldown {
+			chosen = k
+			break
+		}
+	}
+	t.next = chosen
+	if t.roundRobin {
+		t.next = (chosen + 1) % len(t.keys)
+	}
+	return chosen
+}
+
+// record counts a request made with key i, best effort
+func (t *keyTransport) record(i int, limited bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if limited {
+		t.limited[i] = now
+		if t.next == i {
+			t.next = (i + 1) % len(t.keys)
+		}
+	}
+
+	usage, err := LoadKeyUsage()
+	if err != nil {
+		return
+	}
+	fp := KeyFingerprint(t.keys[i])
+	u := usage[fp]
+	u.Hint = KeyHint(t.keys[i])
+	u.Requests++
+	u.LastUsed = now
+	if limited {
+		u.RateLimited++
+		u.LastLimited = now
+	}
+	usage[fp] = u
+	_ = saveKeyUsage(usage)
+}
+
+func (t *keyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for tries := 1; ; tries++ {
+		i := t.pick()
+		r := req.Clone(req.Context())
+		if tries > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		r.Header.Set(t.header, t.prefix+t.keys[i])
+
+		resp, err := t.base.RoundTrip(r)
+		limited := err == nil && resp.StatusCode == http.StatusTooManyRequests
+		t.record(i, limited)
+		if !limited || tries >= len(t.keys) {
+			return resp, err
+		}
+		// Requests with a body can only be sent again if it can be replayed
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultContextLines is how much unchanged context MinimizeDiff keeps
+// around each change unless configured otherwise
+const DefaultContextLines = 1
+
+// MinimizeDiff shrinks a diff for the prompt without losing what changed.
+// Unchanged context beyond contextLines around each change is dropped,
+// hunks that only move lines are collapsed to a note, and edits that only
+// change whitespace are removed. Hunk headers are kept, so hunk numbers
+// still match the original diff.
+func MinimizeDiff(diff string, contextLines int) string {
+	if diff == "" {
+		return diff
+	}
+	var sb strings.Builder
+	for _, section := range splitByFiles(diff) {
+		sb.WriteString(minimizeFile(section, max(contextLines, 0)))
+	}
+	return sb.String()
+}
+
+// minimizeFile minimizes each hunk of a per-file diff section
+func minimizeFile(section string, contextLines int) string {
+	var sb strings.Builder
+	var hunk []string
+	flush := func() {
+		if len(hunk) > 0 {
+			sb.WriteString(minimizeHunk(hunk, contextLines))
+		}
+		hunk = nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(section, "\n"), "\n")
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			flush()
+			hunk = []string{line}
+		case hunk != nil:
+			hunk = append(hunk, line)
+		default:
+			sb.WriteString(line + "\n")
+		}
+	}
+	flush()
+	return sb.String()
+}
+
+// minimizeHunk applies the whitespace, move and context passes to one hunk,
+// given as its header followed by its body
+func minimizeHunk(hunk []string, contextLines int) string {
+	header, body := hunk[0], hunk[1:]
+
+	var removed, added []string
+	for _, l := range body {
+		if strings.HasPrefix(l, "-") {
+			removed = append(removed, l[1:])
+		} else if strings.HasPrefix(l, "+") {
+			added = append(added, l[1:])
+		}
+	}
+	if len(removed) > 0 && sameLines(removed, added) {
+		return fmt.Sprintf("%s\n[%d lines moved, content unchanged]\n", header, len(added))
+	}
+
+	body, whitespace := dropWhitespaceChanges(body)
+
+	var sb strings.Builder
+	sb.WriteString(header + "\n")
+	for _, l := range trimContext(body, contextLines) {
+		sb.WriteString(l + "\n")
+	}
+	if whitespace > 0 {
+		sb.WriteString(fmt.Sprintf("[%d whitespace-only changed lines omitted]\n", whitespace))
+	}
+	return sb.String()
+}
+
+// sameLines reports whether two line sets hold the same lines in any order
+func sameLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// dropWhitespaceChanges turns removed/added runs of equal length that only
+// differ in whitespace into context, returning how many lines it affected
+func dropWhitespaceChanges(body []string) ([]string, int) {
+	var out []string
+	count := 0
+	for i := 0; i < len(body); {
+		if !strings.HasPrefix(body[i], "-") {
+			out = append(out, body[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(body) && strings.HasPrefix(body[i], "-") {
+			i++
+		}
+		mid := i
+		for i < len(body) && strings.HasPrefix(body[i], "+") {
+			i++
+		}
+		minus, plus := body[start:mid], body[mid:i]
+		if len(minus) != len(plus) || !whitespaceOnly(minus, plus) {
+			out = append(out, body[start:i]...)
+			continue
+		}
+		for _, l := range plus {
+			out = append(out, " "+l[1:])
+		}
+		count += len(plus)
+	}
+	return out, count
+}
+
+func whitespaceOnly(minus, plus []string) bool {
+	for j := range minus {
+		if strings.Join(strings.Fields(minus[j][1:]), "") != strings.Join(strings.Fields(plus[j][1:]), "") {
+			return false
+		}
+	}
+	return true
+}
+
+// trimContext keeps at most n unchanged lines before and after each change
+// and replaces longer unchanged runs with a marker
+func trimContext(body []string, n int) []string {
+	isContext := func(l string) bool {
+		return !strings.HasPrefix(l, "+") && !strings.HasPrefix(l, "-") && !strings.HasPrefix(l, `\`)
+	}
+
+	var out []string
+	for i := 0; i < len(body); {
+		if !isContext(body[i]) {
+			out = append(out, body[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(body) && isContext(body[i]) {
+			i++
+		}
+		run := body[start:i]
+		keepBefore, keepAfter := n, n
+		if start == 0 {
+			keepBefore = 0 // nothing changed above this run
+		}
+		if i == len(body) {
+			keepAfter = 0 // nothing changed below this run
+		}
+		if len(run) <= keepBefore+keepAfter+1 {
+			out = append(out, run...)
+			continue
+		}
+		out = append(out, run[:keepBefore]...)
+		out = append(out, fmt.Sprintf("... [%d unchanged lines] ...", len(run)-keepBefore-keepAfter))
+		out = append(out, run[len(run)-keepAfter:]...)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// modelLister is implemented by backends that can list their models
+type modelLister interface {
+	listModels(ctx context.Context) ([]string, error)
+}
+
+// ListModels returns the models the endpoint offers, sorted by name
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+	lister, ok := c.provider.(modelLister)
+	if !ok {
+		return nil, errors.New("this provider can't list its models")
+	}
+	models, err := lister.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(models)
+	return models, nil
+}
+
+func (p *openaiProvider) listModels(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	list, err := p.client.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list.Models))
+	for _, m := range list.Models {
+		names = append(names, m.ID)
+	}
+	return names, nil
+}
+
+func (p *ollamaProvider) listModels(ctx context.Context) ([]string, error) {
+	return ListOllamaModels(ctx, p.baseURL)
+}
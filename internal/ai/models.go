@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"strings"
+)
+
+// Diff token budgets
+const (
+	DefaultDiffTokens = 3000 // budget when the model's context window is unknown
+	reservedTokens    = 4096 // system prompt, instructions and the response
+)
+
+// contextWindows maps model name prefixes to context window sizes in tokens.
+// The longest matching prefix wins, so specific entries override families.
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo": 16385,
+	"gpt-4":         8192,
+	"gpt-4-32k":     32768,
+	"gpt-4-turbo":   128000,
+	"gpt-4o":        128000,
+	"gpt-4.1":       1047576,
+	"gpt-5":         400000,
+	"o1":            200000,
+	"o3":            200000,
+	"o4":            200000,
+	"claude":        200000,
+	"gemini-1.5":    1048576,
+	"gemini-2":      1048576,
+	"llama3":        8192,
+	"llama3.1":      131072,
+	"llama3.2":      131072,
+	"llama3.3":      131072,
+	"mistral":       32768,
+	"qwen2.5":       32768,
+	"deepseek":      65536,
+}
+
+// charsPerToken maps model name prefixes to the average number of
+// characters per token their tokenizer produces on diffs. Tokenizers with
+// smaller vocabularies split text into more tokens.
+var charsPerToken = map[string]float64{
+	"gpt-3.5-turbo": 3.6,
+	"gpt-4":         3.6,
+	"gpt-4o":        4,
+	"gpt-4.1":       4,
+	"gpt-5":         4,
+	"o1":            4,
+	"o3":            4,
+	"o4":            4,
+	"claude":        3.5,
+	"gemini":        4,
+	"llama3":        4,
+	"mistral":       3.2,
+	"qwen":          4,
+	"deepseek":      3.8,
+}
+
+// defaultCharsPerToken is the ratio used for models of unknown families
+const defaultCharsPerToken = 4
+
+// lookupModel returns the value of the longest prefix of model in table.
+// Provider prefixes (openai/gpt-4o) and Ollama tags (llama3.1:8b) are
+// ignored.
+func lookupModel[V any](table map[string]V, model string) (V, bool) {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+
+	best := ""
+	var value V
+	for prefix, v := range table {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best, value = prefix, v
+		}
+	}
+	return value, best != ""
+}
+
+// ContextWindow returns the context window of a model, if known
+func ContextWindow(model string) (int, bool) {
+	return lookupModel(contextWindows, model)
+}
+
+// CharsPerToken returns the average characters per token of the model's
+// tokenizer, or defaultCharsPerToken when its family is unknown
+func CharsPerToken(model string) float64 {
+	if ratio, ok := lookupModel(charsPerToken, model); ok {
+		return ratio
+	}
+	return defaultCharsPerToken
+}
+
+// DiffTokenBudget returns how many diff tokens fit in the model's context
+// window. A positive limit caps the budget to keep costs predictable.
+func DiffTokenBudget(model string, limit int) int {
+	budget := DefaultDiffTokens
+	if window, ok := ContextWindow(model); ok {
+		budget = max(window-reservedTokens, DefaultDiffTokens)
+	}
+	if limit > 0 {
+		budget = min(budget, limit)
+	}
+	return budget
+}
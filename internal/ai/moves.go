@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// minMovedLines is how many removed lines must reappear in another file
+// before the change counts as a move
+const minMovedLines = 3
+
+// Move is code that left one file and arrived in another
+type Move struct {
+	From string
+	To   string
+}
+
+// MoveHistory is a move with the recent commits of both files, newest
+// first, as printed by git log --oneline
+type MoveHistory struct {
+	Move
+	FromLog []string
+	ToLog   []string
+}
+
+// DetectMoves finds renames and files whose removed lines reappear as added
+// lines in another file of the diff. Blank lines and lone braces don't count.
+func DetectMoves(diff string) []Move {
+	var moves []Move
+	removed := make(map[string]map[string]bool)
+	added := make(map[string]map[string]bool)
+	var order []string
+
+	for _, section := range splitByFiles(diff) {
+		path := diffPath(section)
+		if path == "" {
+			continue
+		}
+		order = append(order, path)
+		removed[path], added[path] = make(map[string]bool), make(map[string]bool)
+		for _, line := range strings.Split(section, "\n") {
+			switch {
+			case strings.HasPrefix(line, "rename from "):
+				moves = append(moves, Move{From: strings.TrimPrefix(line, "rename from "), To: path})
+			case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			case strings.HasPrefix(line, "-"):
+				if l := strings.TrimSpace(line[1:]); len(l) > 3 {
+					removed[path][l] = true
+				}
+			case strings.HasPrefix(line, "+"):
+				if l := strings.TrimSpace(line[1:]); len(l) > 3 {
+					added[path][l] = true
+				}
+			}
+		}
+	}
+
+	for _, from := range order {
+		for _, to := range order {
+			if from == to || len(removed[from]) < minMovedLines {
+				continue
+			}
+			shared := 0
+			for l := range removed[from] {
+				if added[to][l] {
+					shared++
+				}
+			}
+			if shared >= minMovedLines {
+				moves = append(moves, Move{From: from, To: to})
+			}
+		}
+	}
+	return moves
+}
+
+// writeMoveHistory renders the history of moved code for the prompt
+func writeMoveHistory(sb *strings.Builder, history []MoveHistory) {
+	if len(history) == 0 {
+		return
+	}
+	sb.WriteString("\nCode moved between files. Recent history of each, to explain the move's intent:\n")
+	for _, h := range history {
+		sb.WriteString(fmt.Sprintf("- %s -> %s\n", h.From, h.To))
+		for _, f := range []struct {
+			path string
+			log  []string
+		}{{h.From, h.FromLog}, {h.To, h.ToLog}} {
+			if len(f.log) == 0 {
+				sb.WriteString(fmt.Sprintf("  %s: no earlier commits\n", f.path))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("  %s:\n", f.path))
+			for _, l := range f.log {
+				sb.WriteString("    " + l + "\n")
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// BodyWidth is the column commit bodies are wrapped at
+const BodyWidth = 72
+
+// MessageWarning is a problem with a commit message found before committing
+type MessageWarning struct {
+	Text    string
+	Fixable bool // FixMessage corrects it
+}
+
+// imperativeVerbs are verbs whose past, third-person and -ing forms are
+// recognised at the start of a subject
+var imperativeVerbs = []string{
+	"add", "allow", "avoid", "bump", "change", "clean", "create", "delete",
+	"deprecate", "disable", "document", "drop", "enable", "ensure", "extract",
+	"fix", "handle", "implement", "improve", "introduce", "make", "merge",
+	"move", "optimize", "prevent", "refactor", "release", "remove", "rename",
+	"replace", "revert", "rewrite", "simplify", "support", "tidy", "update",
+	"upgrade", "use",
+}
+
+// nonImperative maps other forms of imperativeVerbs to the verb
+var nonImperative = func() map[string]string {
+	forms := make(map[string]string)
+	for _, v := range imperativeVerbs {
+		stem := strings.TrimSuffix(v, "e")
+		switch {
+		case strings.HasSuffix(v, "y"):
+			forms[strings.TrimSuffix(v, "y")+"ies"] = v
+			forms[strings.TrimSuffix(v, "y")+"ied"] = v
+		case strings.HasSuffix(v, "x") || strings.HasSuffix(v, "sh") || strings.HasSuffix(v, "ch"):
+			forms[v+"es"] = v
+			forms[v+"ed"] = v
+		default:
+			forms[v+"s"] = v
+			forms[stem+"ed"] = v
+		}
+		forms[stem+"ing"] = v
+	}
+	// Doubled consonants and irregular pasts
+	for form, v := range map[string]string{
+		"dropped": "drop", "dropping": "drop", "bumped": "bump", "made": "make",
+		"rewrote": "rewrite", "rewritten": "rewrite", "tidying": "tidy",
+	} {
+		forms[form] = v
+	}
+	return forms
+}()
+
+// trailerLine matches "Key: value" footers such as Co-authored-by
+var trailerLine = regexp.MustCompile(`^[A-Za-z][\w-]*: `)
+
+// conventionalHeader matches "type(scope)!: subject"
+var conventionalHeader = regexp.MustCompile(`^([A-Za-z][\w-]*)(?:\(([^()]+)\))?(!)?: (.+)$`)
+
+// breakingFooter starts the footer describing a breaking change
+const breakingFooter = "BREAKING CHANGE: "
+
+// ParseMessage turns the text of a message, e.g. as edited by hand, back
+// into its parts: the required prefix and emoji of the rules, the type,
+// scope and breaking mark of a conventional first line, the body, and a
+// final paragraph of trailers and BREAKING CHANGE. String on the result
+// gives the text back.
+func ParseMessage(text string, r SubjectRules) CommitMessage {
+	var c CommitMessage
+	header, rest, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if r.Prefix != "" && strings.HasPrefix(header, r.Prefix) {
+		c.Prefix = r.Prefix
+		header = strings.TrimPrefix(header, r.Prefix)
+	}
+	if emoji := r.withEmoji(CommitMessage{Subject: header}); emoji.Emoji != "" && emoji.Subject != header {
+		c.Emoji = emoji.Emoji
+		header = emoji.Subject
+	}
+	c.Subject = header
+	if m := conventionalHeader.FindStringSubmatch(header); m != nil {
+		c.Type, c.Scope, c.Breaking, c.Subject = m[1], m[2], m[3] != "", m[4]
+	}
+
+	paragraphs := strings.Split(strings.TrimSpace(rest), "\n\n")
+	if last := paragraphs[len(paragraphs)-1]; last != "" && isFooter(last) {
+		paragraphs = paragraphs[:len(paragraphs)-1]
+		for _, line := range strings.Split(last, "\n") {
+			if desc, ok := strings.CutPrefix(line, breakingFooter); ok {
+				c.BreakingDescription = desc
+				continue
+			}
+			c.Trailers = append(c.Trailers, line)
+		}
+	}
+	c.Body = strings.TrimSpace(strings.Join(paragraphs, "\n\n"))
+	return c
+}
+
+// isFooter reports whether every line of a paragraph is a trailer
+func isFooter(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLine.MatchString(line) && !strings.HasPrefix(line, breakingFooter) {
+			return false
+		}
+	}
+	return true
+}
+
+// headerPrefix matches what precedes the description in a typed first line,
+// e.g. "[PROJ-1] ✨ feat(api)!: "
+var headerPrefix = regexp.MustCompile(`^[^:]{0,40}?[\w)!]: `)
+
+// LintMessage checks a message against the usual conventions and the rules
+func LintMessage(c CommitMessage, r SubjectRules) []MessageWarning {
+	c = splitEdited(c)
+	var warnings []MessageWarning
+	if n := len(c.Header()); n > r.Limit() {
+		warnings = append(warnings, MessageWarning{fmt.Sprintf("first line is %d characters, over %d", n, r.Limit()), true})
+	}
+
+	desc := description(c)
+	if word, _, _ := strings.Cut(desc, " "); nonImperative[strings.ToLower(word)] != "" {
+		warnings = append(warnings, MessageWarning{fmt.Sprintf("use the imperative mood: %q, not %q", nonImperative[strings.ToLower(word)], word), true})
+	}
+	if strings.HasSuffix(strings.TrimSpace(desc), ".") {
+		warnings = append(warnings, MessageWarning{"subject ends with a period", true})
+	}
+
+	for i, line := range strings.Split(c.Body, "\n") {
+		if len(line) > BodyWidth {
+			warnings = append(warnings, MessageWarning{fmt.Sprintf("body line %d is %d characters; wrap at %d", i+1, len(line), BodyWidth), wrappable(line)})
+		}
+	}
+
+	if c.Type != "" && len(r.Types) > 0 && !slices.Contains(r.Types, c.Type) {
+		warnings = append(warnings, MessageWarning{fmt.Sprintf("type %q is not one of %s", c.Type, strings.Join(r.Types, ", ")), false})
+	}
+	if names := r.scopeNames(); c.Scope != "" && len(names) > 0 && !slices.Contains(names, c.Scope) {
+		warnings = append(warnings, MessageWarning{fmt.Sprintf("scope %q is not configured", c.Scope), true})
+	}
+	return warnings
+}
+
+// FixMessage corrects the fixable warnings of LintMessage
+func FixMessage(c CommitMessage, r SubjectRules) CommitMessage {
+	c = splitEdited(c)
+	prefix := ""
+	if c.Type == "" {
+		prefix = headerPrefix.FindString(c.Subject)
+	}
+	desc := strings.TrimRight(strings.TrimSpace(strings.TrimPrefix(c.Subject, prefix)), ".")
+	if word, rest, _ := strings.Cut(desc, " "); nonImperative[strings.ToLower(word)] != "" {
+		verb := nonImperative[strings.ToLower(word)]
+		if word[0] >= 'A' && word[0] <= 'Z' {
+			verb = strings.ToUpper(verb[:1]) + verb[1:]
+		}
+		desc = strings.TrimSpace(verb + " " + rest)
+	}
+	c.Subject = prefix + desc
+	c.Body = WrapBody(c.Body, BodyWidth)
+	return EnforceSubject(c, r)
+}
+
+// WrapBody wraps lines longer than width at word boundaries. Continuation
+// lines of list items are indented under the item's text.
+func WrapBody(body string, width int) string {
+	var out []string
+	for _, line := range strings.Split(body, "\n") {
+		if len(line) <= width || !wrappable(line) {
+			out = append(out, line)
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		trimmed := line[indent:]
+		hang := indent
+		if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") {
+			hang += 2
+		}
+		current := line[:indent]
+		for _, word := range strings.Fields(trimmed) {
+			if len(current) > hang && len(current)+1+len(word) > width {
+				out = append(out, current)
+				current = strings.Repeat(" ", hang)
+			}
+			if len(current) > 0 && !strings.HasSuffix(current, " ") {
+				current += " "
+			}
+			current += word
+		}
+		out = append(out, current)
+	}
+	return strings.Join(out, "\n")
+}
+
+// wrappable reports whether a long line may be wrapped: URLs, indented code
+// and trailers are kept intact
+func wrappable(line string) bool {
+	if strings.Contains(line, "://") || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") || trailerLine.MatchString(line) {
+		return false
+	}
+	return strings.Contains(strings.TrimSpace(line), " ")
+}
+
+// description returns the subject without a typed prefix written by hand
+func description(c CommitMessage) string {
+	if c.Type != "" {
+		return c.Subject
+	}
+	return strings.TrimPrefix(c.Subject, headerPrefix.FindString(c.Subject))
+}
+
+// splitEdited turns a hand-edited message, kept whole in Subject, into a
+// subject and a body so both can be checked
+func splitEdited(c CommitMessage) CommitMessage {
+	subject, rest, ok := strings.Cut(c.Subject, "\n")
+	if !ok {
+		return c
+	}
+	c.Subject = subject
+	c.Body = strings.Trim(strings.TrimSpace(rest)+"\n\n"+c.Body, "\n")
+	return c
+}
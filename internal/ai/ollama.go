@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// OllamaDefaultURL is the address of a local Ollama server
+const OllamaDefaultURL = "http://localhost:11434"
+
+// ollamaProvider talks to the native Ollama API (/api/chat)
+type ollamaProvider struct {
+	baseURL string
+	model   string
+	http    *http.Client
+}
+
+func newOllamaProvider(baseURL, model string, httpClient *http.Client) *ollamaProvider {
+	return &ollamaProvider{
+		baseURL: OllamaURL(baseURL),
+		model:   model,
+		http:    httpClient,
+	}
+}
+
+// OllamaURL resolves the Ollama server address: explicit value,
+// then OLLAMA_HOST, then the local default.
+func OllamaURL(baseURL string) string {
+	if baseURL == "" {
+		baseURL = os.Getenv("OLLAMA_HOST")
+	}
+	if baseURL == "" {
+		return OllamaDefaultURL
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		baseURL = "http://" + baseURL
+	}
+	return strings.TrimRight(baseURL, "/")
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []openai.Tool   `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Error           string        `json:"error"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+func (p *ollamaProvider) chat(ctx context.Context, system, user string, tools []openai.Tool) (*chatResponse, error) {
+	var resp ollamaChatResponse
+	err := p.post(ctx, "/api/chat", ollamaChatRequest{
+		Model: p.model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Tools:  tools,
+		Stream: false,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("ollama: %s", resp.Error)
+	}
+
+	result := &chatResponse{
+		Content: resp.Message.Content,
+		Usage: Usage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+		},
+	}
+	for _, tc := range resp.Message.ToolCalls {
+		// Ollama returns arguments as a JSON object rather than a string
+		args := string(tc.Function.Arguments)
+		var s string
+		if json.Unmarshal(tc.Function.Arguments, &s) == nil {
+			args = s
+		}
+		result.ToolCalls = append(result.ToolCalls, ToolCall{
+			Name:      tc.Function.Name,
+			Arguments: args,
+		})
+	}
+	return result, nil
+}
+
+func (p *ollamaProvider) post(ctx context.Context, path string, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama unreachable at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	return decodeOllamaResponse(resp, out)
+}
+
+func decodeOllamaResponse(resp *http.Response, out any) error {
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(raw, &e) == nil && e.Error != "" {
+			return fmt.Errorf("ollama: %s", e.Error)
+		}
+		return fmt.Errorf("ollama: unexpected status %s", resp.Status)
+	}
+
+	return json.Unmarshal(raw, out)
+}
+
+// ListOllamaModels returns the names of models installed on the Ollama server
+func ListOllamaModels(ctx context.Context, baseURL string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, OllamaURL(baseURL)+"/api/tags", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama unreachable at %s: %w", OllamaURL(baseURL), err)
+	}
+	defer resp.Body.Close()
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := decodeOllamaResponse(resp, &tags); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
@@ -1,195 +1,630 @@
 package ai
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	openai "github.com/sashabaranov/go-openai"
 
+	"github.com/hluaguo/commity/internal/auth"
 	"github.com/hluaguo/commity/internal/config"
 )
 
+// AzureDefaultAPIVersion is the first GA Azure OpenAI api-version with tool calling
+const AzureDefaultAPIVersion = "2024-10-21"
+
+var errNoResponse = errors.New("no response from AI")
+
+// ErrNoAPIKey means no API key was configured for a provider that needs one
+var ErrNoAPIKey = errors.New("API key not configured")
+
 type Client struct {
-	client *openai.Client
-	model  string
+	provider      provider
+	model         string
+	maxDiffTokens int
+	minimize      bool // minimize diffs, see MinimizeDiff
+	contextLines  int
+	deadline      time.Duration
+	structured    bool // use JSON schema responses instead of function calling
+	templates     *PromptTemplates
+	providerName  string         // for the debug log
+	params        map[string]any // extra request fields, see RequestParams
+	cacheDir      string         // where results are cached, see SetCacheDir
 }
 
 // CommitMessage is the structured output from the AI tool call
 type CommitMessage struct {
-	Type    string   `json:"type"`    // feat, fix, docs, etc.
-	Subject string   `json:"subject"` // commit subject line
-	Body    string   `json:"body"`    // optional commit body
-	Files   []string `json:"files"`   // files for this commit (used in split)
+	Type     string      `json:"type"`            // feat, fix, docs, etc.
+	Scope    string      `json:"scope,omitempty"` // e.g. "api" in "feat(api): ..."
+	Subject  string      `json:"subject"`         // commit subject line
+	Body     string      `json:"body"`            // optional commit body
+	Files    []string    `json:"files"`           // files for this commit (used in split)
+	Hunks    []FileHunks `json:"hunks,omitempty"` // hunk subsets of files shared with other commits
+	Prefix   string      `json:"-"`               // required first-line prefix, see SubjectRules
+	Emoji    string      `json:"-"`               // gitmoji before the type, see SubjectRules
+	Trailers []string    `json:"-"`               // e.g. "Signed-off-by: Name <email>", see AddTrailers
+
+	Breaking            bool   `json:"breaking,omitempty"`             // renders "type!:"
+	BreakingDescription string `json:"breaking_description,omitempty"` // renders a BREAKING CHANGE footer
+}
+
+// FileHunks selects hunks of a file by their 1-based position in its diff
+type FileHunks struct {
+	File  string `json:"file"`
+	Hunks []int  `json:"hunks"`
+}
+
+// HunksFor returns the hunks of file assigned to this commit, or nil when
+// the whole file belongs to it
+func (c *CommitMessage) HunksFor(file string) []int {
+	for _, h := range c.Hunks {
+		if h.File == file {
+			return h.Hunks
+		}
+	}
+	return nil
+}
+
+// IsBreaking reports whether the commit breaks compatibility
+func (c *CommitMessage) IsBreaking() bool {
+	return c.Breaking || c.BreakingDescription != ""
 }
 
 func (c *CommitMessage) String() string {
-	msg := ""
+	msg := c.Prefix
+	if c.Emoji != "" {
+		msg += c.Emoji + " "
+	}
 	if c.Type != "" {
-		msg = c.Type + ": "
+		msg += c.Type
+		if c.Scope != "" {
+			msg += "(" + c.Scope + ")"
+		}
+		if c.IsBreaking() {
+			msg += "!"
+		}
+		msg += ": "
 	}
 	msg += c.Subject
 	if c.Body != "" {
 		msg += "\n\n" + c.Body
 	}
+	// Without a type the footer is the only place the break is recorded
+	var footer []string
+	desc := c.BreakingDescription
+	if desc == "" && c.Breaking && c.Type == "" {
+		desc = c.Subject
+	}
+	if desc != "" {
+		footer = append(footer, "BREAKING CHANGE: "+desc)
+	}
+	footer = append(footer, c.Trailers...)
+	if len(footer) > 0 {
+		msg += "\n\n" + strings.Join(footer, "\n")
+	}
 	return msg
 }
 
+// AddTrailers appends trailers the message doesn't have yet
+func AddTrailers(c CommitMessage, trailers []string) CommitMessage {
+	c.Trailers = slices.Clone(c.Trailers)
+	for _, t := range trailers {
+		if !slices.ContainsFunc(c.Trailers, func(o string) bool { return strings.EqualFold(o, t) }) {
+			c.Trailers = append(c.Trailers, t)
+		}
+	}
+	return c
+}
+
 // SplitCommits represents multiple commits for split mode
 type SplitCommits struct {
 	Commits []CommitMessage `json:"commits"`
 }
 
-// Tool definition for single commit
-var commitTool = openai.Tool{
-	Type: openai.ToolTypeFunction,
-	Function: &openai.FunctionDefinition{
-		Name:        "submit_commit",
-		Description: "Submit a single commit for all changes. Use this when all changes are related.",
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"type": map[string]any{
-					"type":        "string",
-					"description": "Commit type (feat, fix, docs, style, refactor, test, chore, etc)",
-				},
-				"subject": map[string]any{
-					"type":        "string",
-					"description": "Short commit subject line WITHOUT the type prefix (max 72 chars). Example: 'add user authentication' not 'feat: add user authentication'",
-				},
-				"body": map[string]any{
-					"type":        "string",
-					"description": "Optional longer description",
+// Breaking change fields shared by the commit tools
+var (
+	breakingProperty = map[string]any{
+		"type":        "boolean",
+		"description": "True only if the change breaks compatibility for users of the code (removed or renamed public API, changed signatures, config or CLI flags)",
+	}
+	breakingDescriptionProperty = map[string]any{
+		"type":        "string",
+		"description": "For breaking changes: what breaks and how to migrate, one or two sentences",
+	}
+)
+
+// newCommitTool defines the tool for a single commit
+func newCommitTool(rules SubjectRules) openai.Tool {
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "submit_commit",
+			Description: "Submit a single commit for all changes. Use this when all changes are related.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"type": rules.typeProperty(),
+					"subject": map[string]any{
+						"type":        "string",
+						"description": rules.subjectDescription(),
+					},
+					"body": map[string]any{
+						"type":        "string",
+						"description": rules.bodyDescription(),
+					},
+					"scope": map[string]any{
+						"type":        "string",
+						"description": rules.scopeDescription(),
+					},
+					"breaking":             breakingProperty,
+					"breaking_description": breakingDescriptionProperty,
 				},
+				"required": []string{"type", "subject"},
 			},
-			"required": []string{"type", "subject"},
 		},
-	},
-}
-
-// Tool definition for split commits
-var splitCommitsTool = openai.Tool{
-	Type: openai.ToolTypeFunction,
-	Function: &openai.FunctionDefinition{
-		Name:        "split_commits",
-		Description: "Split changes into multiple logical commits. Use this when changes are unrelated and should be separate commits.",
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"commits": map[string]any{
-					"type":        "array",
-					"description": "Array of commits, each with its own message and files",
-					"items": map[string]any{
-						"type": "object",
-						"properties": map[string]any{
-							"type": map[string]any{
-								"type":        "string",
-								"description": "Commit type (feat, fix, docs, style, refactor, test, chore)",
-							},
-							"subject": map[string]any{
-								"type":        "string",
-								"description": "Short commit subject line WITHOUT the type prefix (max 72 chars). Example: 'add user authentication' not 'feat: add user authentication'",
-							},
-							"body": map[string]any{
-								"type":        "string",
-								"description": "Optional longer description",
-							},
-							"files": map[string]any{
-								"type":        "array",
-								"items":       map[string]any{"type": "string"},
-								"description": "List of file paths for this commit",
+	}
+}
+
+// newSplitCommitsTool defines the tool for split commits
+func newSplitCommitsTool(rules SubjectRules) openai.Tool {
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "split_commits",
+			Description: "Split changes into multiple logical commits. Use this when changes are unrelated and should be separate commits.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"commits": map[string]any{
+						"type":        "array",
+						"description": "Array of commits, each with its own message and files",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"type": rules.typeProperty(),
+								"subject": map[string]any{
+									"type":        "string",
+									"description": rules.subjectDescription(),
+								},
+								"body": map[string]any{
+									"type":        "string",
+									"description": rules.bodyDescription(),
+								},
+								"scope": map[string]any{
+									"type":        "string",
+									"description": rules.scopeDescription(),
+								},
+								"breaking":             breakingProperty,
+								"breaking_description": breakingDescriptionProperty,
+								"files": map[string]any{
+									"type":        "array",
+									"items":       map[string]any{"type": "string"},
+									"description": "List of file paths for this commit",
+								},
+								"hunks": map[string]any{
+									"type":        "array",
+									"description": "Only for files whose changes belong to different commits: which of the file's hunks go into this commit",
+									"items": map[string]any{
+										"type": "object",
+										"properties": map[string]any{
+											"file": map[string]any{
+												"type":        "string",
+												"description": "File path, also listed in files",
+											},
+											"hunks": map[string]any{
+												"type":        "array",
+												"items":       map[string]any{"type": "integer"},
+												"description": "Hunk numbers, counting the file's @@ headers from 1",
+											},
+										},
+										"required": []string{"file", "hunks"},
+									},
+								},
 							},
+							"required": []string{"type", "subject", "files"},
 						},
-						"required": []string{"type", "subject", "files"},
 					},
 				},
+				"required": []string{"commits"},
 			},
-			"required": []string{"commits"},
 		},
-	},
+	}
 }
 
 func New(cfg *config.AIConfig) (*Client, error) {
-	if cfg.APIKey == "" {
-		return nil, fmt.Errorf("API key not configured. Set OPENAI_API_KEY or configure in ~/.config/commity/config.toml")
+	// Resolve the keys on a copy so a key from a command or the keychain
+	// never ends up in a saved config
+	keys, err := cfg.ResolveAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+	resolved := *cfg
+	resolved.APIKey, resolved.APIKeys = "", keys
+	if len(keys) > 0 {
+		resolved.APIKey = keys[0]
+	}
+	cfg = &resolved
+
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.model = cfg.Model
+	if c.model == "" {
+		// Providers with implicit models
+		switch cfg.Provider {
+		case config.ProviderGemini:
+			c.model = GeminiDefaultModel
+		case config.ProviderAzure:
+			c.model = cfg.Deployment
+		}
+	}
+	c.maxDiffTokens = cfg.MaxDiffTokens
+	c.minimize = cfg.MinimizeDiff
+	c.contextLines = cfg.ContextLines
+	c.deadline = time.Duration(cfg.DeadlineSeconds) * time.Second
+	if c.templates, err = LoadPromptTemplates(cfg); err != nil {
+		return nil, err
 	}
+	c.providerName = cmp.Or(cfg.Provider, config.ProviderOpenAI)
+	c.params = RequestParams(cfg)
 
-	clientCfg := openai.DefaultConfig(cfg.APIKey)
+	if _, ok := c.provider.(structuredProvider); ok {
+		switch cfg.StructuredOutputs {
+		case config.StructuredOn:
+			c.structured = true
+		case config.StructuredOff:
+		default:
+			c.structured = SupportsStructuredOutputs(c.model)
+		}
+	}
+	return c, nil
+}
+
+func newClient(cfg *config.AIConfig) (*Client, error) {
+	switch cfg.Provider {
+	case config.ProviderOllama:
+		return &Client{provider: newOllamaProvider(cfg.BaseURL, cfg.Model, newHTTPClient(cfg))}, nil
+	case config.ProviderGemini:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("%w. Set GEMINI_API_KEY or configure in ~/.config/commity/config.toml", ErrNoAPIKey)
+		}
+		return &Client{provider: newGeminiProvider(cfg.BaseURL, cfg.APIKey, cfg.Model, newHTTPClient(cfg))}, nil
+	case config.ProviderAzure:
+		return newAzureClient(cfg)
+	case "", config.ProviderOpenAI:
+		if cfg.APIKey == "" && cfg.OAuth.Enabled() {
+			return newOAuthClient(cfg)
+		}
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("%w. Set OPENAI_API_KEY or configure in ~/.config/commity/config.toml", ErrNoAPIKey)
+		}
+
+		clientCfg := openai.DefaultConfig(cfg.APIKey)
+		clientCfg.HTTPClient = newHTTPClient(cfg)
+		if cfg.BaseURL != "" {
+			clientCfg.BaseURL = cfg.BaseURL
+		}
+
+		return &Client{provider: &openaiProvider{
+			client: openai.NewClientWithConfig(clientCfg),
+			model:  cfg.Model,
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q", cfg.Provider)
+	}
+}
+
+// newOAuthClient builds a client for a gateway logged into with commity
+// login. The bearer token is set per request so it can be refreshed.
+func newOAuthClient(cfg *config.AIConfig) (*Client, error) {
+	source, err := auth.NewSource(cfg.OAuth, &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := newHTTPClient(cfg)
+	httpClient.Transport = &authTransport{base: httpClient.Transport, source: source}
+
+	clientCfg := openai.DefaultConfig("")
+	clientCfg.HTTPClient = httpClient
 	if cfg.BaseURL != "" {
 		clientCfg.BaseURL = cfg.BaseURL
 	}
 
-	return &Client{
+	return &Client{provider: &openaiProvider{
 		client: openai.NewClientWithConfig(clientCfg),
 		model:  cfg.Model,
-	}, nil
+	}}, nil
+}
+
+// authTransport authorizes each request with a current OAuth token
+type authTransport struct {
+	base   http.RoundTripper
+	source *auth.Source
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.AccessToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(req, token, req.Body)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// A token rejected before its expiry was revoked: refresh once and
+	// send again, if the body can be replayed
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	token, err = t.source.Renew(req.Context(), token)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	body := req.Body
+	if req.GetBody != nil {
+		if body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return t.send(req, token, body)
+}
+
+// send sends req with token and body
+func (t *authTransport) send(req *http.Request, token string, body io.ReadCloser) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.Body = body
+	r.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(r)
+}
+
+// newAzureClient builds a client for Azure OpenAI, whose URLs are
+// {endpoint}/openai/deployments/{deployment}/chat/completions?api-version=...
+// and which authenticates with an api-key header instead of a bearer token.
+func newAzureClient(cfg *config.AIConfig) (*Client, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("%w. Set AZURE_OPENAI_API_KEY or configure in ~/.config/commity/config.toml", ErrNoAPIKey)
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("Azure endpoint not configured. Set AZURE_OPENAI_ENDPOINT or base_url (https://<resource>.openai.azure.com)")
+	}
+
+	clientCfg := openai.DefaultAzureConfig(cfg.APIKey, cfg.BaseURL)
+	clientCfg.HTTPClient = newHTTPClient(cfg)
+	clientCfg.APIVersion = AzureDefaultAPIVersion
+	if cfg.APIVersion != "" {
+		clientCfg.APIVersion = cfg.APIVersion
+	}
+
+	model := cfg.Model
+	if cfg.Deployment != "" {
+		deployment := cfg.Deployment
+		clientCfg.AzureModelMapperFunc = func(string) string { return deployment }
+		if model == "" {
+			model = deployment
+		}
+	}
+
+	return &Client{provider: &openaiProvider{
+		client: openai.NewClientWithConfig(clientCfg),
+		model:  model,
+	}}, nil
 }
 
 // GenerateResult represents the AI's response - either single or split commits
 type GenerateResult struct {
-	Commits []CommitMessage
-	IsSplit bool
+	Commits  []CommitMessage
+	IsSplit  bool
+	Usage    Usage
+	Fallback string // FallbackShortPrompt or FallbackHeuristic when the deadline was hit, FallbackOffline
+	Cached   bool   // reused from an identical earlier request, see SetCacheDir
+	Err      error  // why the AI could not be used, with FallbackOffline
 }
 
-func (c *Client) GenerateCommitMessage(ctx context.Context, files []string, diff string, conventional bool, types []string, customInstructions string, previousMsg string, feedback string) (*GenerateResult, error) {
-	prompt := BuildPrompt(files, diff, conventional, types, customInstructions, previousMsg, feedback)
+// Templates returns the configured prompt templates, nil for the built-in
+// prompts
+func (c *Client) Templates() *PromptTemplates {
+	return c.templates
+}
 
-	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: c.model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: SystemPrompt(),
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Tools: []openai.Tool{commitTool, splitCommitsTool},
-	})
+// Model returns the configured model name
+func (c *Client) Model() string {
+	return c.model
+}
 
+// GenerateCommitMessage asks the AI for commit messages. With a deadline
+// configured, a slow first attempt is cancelled and retried with a shorter
+// prompt; if that also runs out of time, a heuristic message is returned.
+func (c *Client) GenerateCommitMessage(ctx context.Context, pc PromptContext) (*GenerateResult, error) {
+	result, err := c.generateWithin(ctx, pc)
 	if err != nil {
-		return nil, fmt.Errorf("AI request failed: %w", err)
+		return nil, err
+	}
+	// A model may still answer with several commits when one is required
+	if pc.Single && len(result.Commits) > 1 {
+		result.Commits = []CommitMessage{MergeCommits(result.Commits)}
+		result.IsSplit = false
+	}
+	for i := range result.Commits {
+		result.Commits[i] = AddTrailers(EnforceSubject(result.Commits[i], pc.Subject), pc.Trailers)
 	}
+	return result, nil
+}
 
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("no response from AI")
+// generateWithin applies the configured deadline to generation
+func (c *Client) generateWithin(ctx context.Context, pc PromptContext) (*GenerateResult, error) {
+	if c.deadline <= 0 {
+		return c.generate(ctx, pc)
 	}
 
-	choice := resp.Choices[0]
+	deadline := time.Now().Add(c.deadline)
+	// The full prompt gets two thirds of the time, the retry the rest
+	firstCtx, cancel := context.WithDeadline(ctx, deadline.Add(-c.deadline/3))
+	result, err := c.generate(firstCtx, pc)
+	timedOut := firstCtx.Err() != nil
+	cancel()
+	if err == nil || !timedOut || ctx.Err() != nil {
+		return result, err
+	}
 
-	// Check for tool call
-	if len(choice.Message.ToolCalls) > 0 {
-		toolCall := choice.Message.ToolCalls[0]
+	short := pc
+	short.MaxDiffTokens = shortPromptTokens
+	retryCtx, cancel := context.WithDeadline(ctx, deadline)
+	result, err = c.generate(retryCtx, short)
+	timedOut = retryCtx.Err() != nil
+	cancel()
+	if err == nil {
+		result.Fallback = FallbackShortPrompt
+		return result, nil
+	}
+	if !timedOut || ctx.Err() != nil {
+		return nil, err
+	}
 
-		switch toolCall.Function.Name {
-		case "submit_commit":
-			var commit CommitMessage
-			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &commit); err != nil {
-				return nil, fmt.Errorf("failed to parse commit message: %w", err)
-			}
-			commit.Files = files // single commit uses all files
-			return &GenerateResult{
-				Commits: []CommitMessage{commit},
-				IsSplit: false,
-			}, nil
+	return &GenerateResult{
+		Commits:  []CommitMessage{HeuristicFromDiff(pc.Files, pc.Diff, pc.Conventional)},
+		Fallback: FallbackHeuristic,
+	}, nil
+}
 
-		case "split_commits":
-			var split SplitCommits
-			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &split); err != nil {
-				return nil, fmt.Errorf("failed to parse split commits: %w", err)
-			}
-			return &GenerateResult{
-				Commits: split.Commits,
-				IsSplit: true,
-			}, nil
+// generate performs a single generation request
+func (c *Client) generate(ctx context.Context, pc PromptContext) (*GenerateResult, error) {
+	if pc.Model == "" {
+		pc.Model = c.model
+	}
+	if pc.MaxDiffTokens == 0 {
+		pc.MaxDiffTokens = c.maxDiffTokens
+	}
+	if c.minimize {
+		pc.Minimize = true
+		pc.ContextLines = c.contextLines
+	}
+	files := pc.Files
+	system, prompt, err := c.templates.Prompts(pc)
+	if err != nil {
+		return nil, err
+	}
+	rules := pc.Subject
+	if pc.Conventional {
+		rules.Types = pc.Types
+	}
+
+	// Docs-only and config-only changes get a shorter prompt without splitting
+	kind := ClassifyChanges(files)
+	tools := []openai.Tool{newCommitTool(rules), newSplitCommitsTool(rules)}
+	if kind != ChangeCode || pc.Single {
+		tools = tools[:1]
+	}
+
+	key := c.cacheKey(system, prompt, tools)
+	if !pc.NoCache {
+		if result, ok := c.cached(key); ok {
+			return result, nil
+		}
+	}
+
+	resp, err := c.chat(ctx, system, prompt, tools, rules)
+	if err != nil {
+		return nil, err
+	}
+	result, err := parseResponse(resp, files)
+	usage := responseUsage(resp, c.model, system+prompt)
+
+	// Send invalid tool calls back once instead of failing outright
+	for attempt := 0; attempt < maxRepairAttempts; attempt++ {
+		problems := responseProblems(resp, result, err, files, rules)
+		if len(problems) == 0 {
+			break
+		}
+		repair := repairPrompt(prompt, resp, problems)
+		if resp, err = c.chat(ctx, system, repair, tools, rules); err != nil {
+			return nil, err
+		}
+		result, err = parseResponse(resp, files)
+		usage = usage.Add(responseUsage(resp, c.model, system+repair))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Usage = usage
+	c.storeCached(key, result)
+	return result, nil
+}
+
+// chat sends one request and wraps transport errors for the user. With
+// structured outputs the JSON reply is converted to the matching tool call.
+func (c *Client) chat(ctx context.Context, system, prompt string, tools []openai.Tool, rules SubjectRules) (*chatResponse, error) {
+	var resp *chatResponse
+	var err error
+	if sp, ok := c.provider.(structuredProvider); ok && c.structured {
+		start := time.Now()
+		resp, err = sp.chatStructured(ctx, system, prompt, structuredFormat(rules))
+		c.logExchange(system, prompt, tools, true, resp, err, time.Since(start))
+		if err == nil {
+			resp.ToolCalls = StructuredToolCalls(resp.Content, len(tools) > 1)
+			resp.Content = ""
+		}
+	} else {
+		resp, err = c.call(ctx, system, prompt, tools)
+	}
+	if err != nil {
+		if errors.Is(err, errNoResponse) {
+			return nil, err
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("AI request timed out (timeout_seconds, max_retries in [ai] config): %w", err)
+		}
+		return nil, fmt.Errorf("AI request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// responseUsage returns the reported usage, or an estimate from the
+// exchanged text when the backend did not report any
+func responseUsage(resp *chatResponse, model, prompt string) Usage {
+	if resp.Usage.PromptTokens > 0 {
+		return resp.Usage
+	}
+	completion := resp.Content
+	for _, tc := range resp.ToolCalls {
+		completion += tc.Arguments
+	}
+	return Usage{
+		PromptTokens:     EstimateTokensFor(model, prompt),
+		CompletionTokens: EstimateTokensFor(model, completion),
+		Estimated:        true,
+	}
+}
+
+// errNoCommitCall means a reply contained no commit tool calls
+var errNoCommitCall = errors.New("no commit tool call")
+
+// parseResponse converts a provider reply into commit messages
+func parseResponse(resp *chatResponse, files []string) (*GenerateResult, error) {
+	if len(resp.ToolCalls) > 0 {
+		result, err := ParseToolCalls(resp.ToolCalls, files)
+		if !errors.Is(err, errNoCommitCall) {
+			return result, err
 		}
 	}
 
 	// Fallback to content if no tool call
-	if choice.Message.Content != "" {
-		content := choice.Message.Content
+	if resp.Content != "" {
+		content := resp.Content
 
 		// Try to parse as JSON (AI sometimes returns JSON without tool call)
 		var commit CommitMessage
@@ -213,3 +648,91 @@ func (c *Client) GenerateCommitMessage(ctx context.Context, files []string, diff
 
 	return nil, fmt.Errorf("AI did not return a commit message")
 }
+
+// ParseToolCalls merges all tool calls of a reply into one result. Some
+// models emit several submit_commit calls, or repeat a call, instead of a
+// single split_commits call.
+func ParseToolCalls(calls []ToolCall, files []string) (*GenerateResult, error) {
+	var commits []CommitMessage
+	split := false
+
+	for _, tc := range calls {
+		var parsed []CommitMessage
+		switch tc.Name {
+		case "submit_commit":
+			var commit CommitMessage
+			if err := json.Unmarshal([]byte(tc.Arguments), &commit); err != nil {
+				return nil, fmt.Errorf("failed to parse commit message: %w", err)
+			}
+			parsed = []CommitMessage{commit}
+		case "split_commits":
+			var s SplitCommits
+			if err := json.Unmarshal([]byte(tc.Arguments), &s); err != nil {
+				return nil, fmt.Errorf("failed to parse split commits: %w", err)
+			}
+			parsed = s.Commits
+			split = true
+		default:
+			continue
+		}
+
+		// Drop exact repeats of earlier commits
+		for _, c := range parsed {
+			if !slices.ContainsFunc(commits, func(o CommitMessage) bool { return sameCommit(o, c) }) {
+				commits = append(commits, c)
+			}
+		}
+	}
+
+	if len(commits) == 0 && !split {
+		return nil, errNoCommitCall
+	}
+
+	// Several single commits form a split only if each says which files it covers
+	if !split && len(commits) > 1 && !slices.ContainsFunc(commits, func(c CommitMessage) bool { return len(c.Files) == 0 }) {
+		split = true
+	}
+	if !split {
+		commit := foldCommits(commits)
+		commit.Files = files // single commit uses all files
+		return &GenerateResult{Commits: []CommitMessage{commit}}, nil
+	}
+	return &GenerateResult{Commits: commits, IsSplit: true}, nil
+}
+
+// foldCommits makes one commit of calls that don't say which files they
+// cover. The first keeps its header; every other header and body goes into
+// its body, so nothing the model wrote is lost.
+func foldCommits(commits []CommitMessage) CommitMessage {
+	commit := commits[0]
+	var body []string
+	if commit.Body != "" {
+		body = append(body, commit.Body)
+	}
+	for _, c := range commits[1:] {
+		line := "- " + c.Subject
+		if c.Type != "" {
+			line = fmt.Sprintf("- %s: %s", c.Type, c.Subject)
+		}
+		if c.Body != "" {
+			line += "\n\n" + c.Body
+		}
+		body = append(body, line)
+		if c.IsBreaking() {
+			commit.Breaking = true
+			if c.BreakingDescription != "" {
+				commit.BreakingDescription = strings.TrimSpace(commit.BreakingDescription + " " + c.BreakingDescription)
+			}
+		}
+	}
+	commit.Body = strings.Join(body, "\n\n")
+	return commit
+}
+
+func sameCommit(a, b CommitMessage) bool {
+	return a.Type == b.Type && a.Scope == b.Scope && a.Subject == b.Subject && a.Body == b.Body &&
+		a.Breaking == b.Breaking && a.BreakingDescription == b.BreakingDescription &&
+		slices.Equal(a.Files, b.Files) && slices.EqualFunc(a.Hunks, b.Hunks, func(x, y FileHunks) bool {
+		return x.File == y.File && slices.Equal(x.Hunks, y.Hunks)
+	})
+}
@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// RequestParams returns the fields added to each chat request body for the
+// sampling settings and extra_params of cfg, named as the provider expects
+// them. extra_params go last and win; nested tables are merged.
+func RequestParams(cfg *config.AIConfig) map[string]any {
+	sampling := make(map[string]any)
+	names := [3]string{"temperature", "top_p", "max_tokens"}
+	switch cfg.Provider {
+	case config.ProviderOllama:
+		names = [3]string{"temperature", "top_p", "num_predict"}
+	case config.ProviderGemini:
+		names = [3]string{"temperature", "topP", "maxOutputTokens"}
+	}
+	if cfg.Temperature != nil {
+		sampling[names[0]] = *cfg.Temperature
+	}
+	if cfg.TopP != nil {
+		sampling[names[1]] = *cfg.TopP
+	}
+	if cfg.MaxTokens > 0 {
+		sampling[names[2]] = cfg.MaxTokens
+	}
+
+	params := make(map[string]any)
+	if len(sampling) > 0 {
+		switch cfg.Provider {
+		case config.ProviderOllama:
+			params["options"] = sampling
+		case config.ProviderGemini:
+			params["generationConfig"] = sampling
+		default:
+			params = sampling
+		}
+	}
+	mergeInto(params, cfg.ExtraParams)
+	return params
+}
+
+// mergeInto copies src into dst, merging tables present in both
+func mergeInto(dst, src map[string]any) {
+	for k, v := range src {
+		sub, ok := v.(map[string]any)
+		if existing, isMap := dst[k].(map[string]any); ok && isMap {
+			merged := maps.Clone(existing)
+			mergeInto(merged, sub)
+			dst[k] = merged
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// MergeParams adds params to a JSON request body
+func MergeParams(body []byte, params map[string]any) ([]byte, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	mergeInto(req, params)
+	return json.Marshal(req)
+}
+
+// paramsTransport adds request parameters to chat requests. Doing it on the
+// wire reaches every backend alike, including options the OpenAI client
+// library has no field for or drops when zero, such as temperature = 0.
+type paramsTransport struct {
+	base   http.RoundTripper
+	params map[string]any
+}
+
+func (t *paramsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.Body == nil || !isChatPath(req.URL.Path) {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if body, err = MergeParams(body, t.params); err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return t.base.RoundTrip(r)
+}
+
+// isChatPath reports whether path is the chat endpoint of a provider
+func isChatPath(path string) bool {
+	return strings.HasSuffix(path, "/chat/completions") ||
+		strings.HasSuffix(path, "/api/chat") ||
+		strings.HasSuffix(path, ":generateContent")
+}
@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/telemetry"
+)
+
+// Ping sends the smallest possible chat request, to check that the endpoint,
+// key and model work before anything depends on them
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.call(ctx, "Reply with OK.", "OK", nil)
+	return err
+}
+
+// PingHint suggests what to change in cfg after Ping failed with err, or
+// returns "" when there's nothing specific to suggest
+func PingHint(err error, cfg config.AIConfig) string {
+	switch telemetry.Categorize(err) {
+	case "auth":
+		return "check the API key (api_key, api_key_cmd, keychain or the provider's environment variable)"
+	case "network":
+		if cfg.Provider == config.ProviderOllama {
+			return "check that Ollama is running and base_url (or OLLAMA_HOST) points to it"
+		}
+		return "check base_url and your network connection"
+	case "timeout":
+		return fmt.Sprintf("no answer within %ds; check base_url or raise timeout_seconds", cfg.TimeoutSeconds)
+	case "rate_limit":
+		return "the endpoint is rate limiting this key; wait a moment or check its quota"
+	}
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "404") || strings.Contains(msg, "not found") || strings.Contains(msg, "model") {
+		return fmt.Sprintf("check that model %q exists on this endpoint", cfg.Model)
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const prSystemPrompt = `You write pull request descriptions for software engineers.
+Given the commits of a branch and its diff against the base branch, call
+submit_pull_request with:
+- title: imperative mood, max 72 characters, no period at end
+- body: GitHub markdown with a short summary paragraph of why the change
+  is needed, then a "## Changes" list of the notable changes. Don't invent
+  testing that isn't visible in the diff.`
+
+// PullRequest is a generated pull request title and description
+type PullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// PRContext holds what a pull request description is built from
+type PRContext struct {
+	Base               string   // branch the pull request targets
+	Subjects           []string // commit subjects on the branch, oldest first
+	Files              []string
+	Diff               string
+	CustomInstructions string
+}
+
+var prTool = openai.Tool{
+	Type: openai.ToolTypeFunction,
+	Function: &openai.FunctionDefinition{
+		Name:        "submit_pull_request",
+		Description: "Submit the pull request title and description.",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"title": map[string]any{
+					"type":        "string",
+					"description": "Pull request title (imperative mood, max 72 chars)",
+				},
+				"body": map[string]any{
+					"type":        "string",
+					"description": "Pull request description in GitHub markdown",
+				},
+			},
+			"required": []string{"title", "body"},
+		},
+	},
+}
+
+// BuildPRPrompt renders the user prompt for a pull request description
+func BuildPRPrompt(pc PRContext, model string, maxDiffTokens int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Describe this branch as a pull request into %s.\n\n", pc.Base))
+	sb.WriteString("Commits:\n")
+	for _, s := range pc.Subjects {
+		sb.WriteString("- " + s + "\n")
+	}
+	sb.WriteString("\nFiles changed:\n")
+	for _, f := range pc.Files {
+		sb.WriteString("- " + f + "\n")
+	}
+	sb.WriteString("\nDiff:\n```\n")
+	sb.WriteString(truncateDiff(pc.Diff, DiffTokenBudget(model, maxDiffTokens), model))
+	sb.WriteString("\n```\n")
+	if pc.CustomInstructions != "" {
+		sb.WriteString(fmt.Sprintf("\nAdditional instructions: %s\n", pc.CustomInstructions))
+	}
+	return sb.String()
+}
+
+// PullRequest asks the model for a pull request title and description
+func (c *Client) PullRequest(ctx context.Context, pc PRContext) (*PullRequest, error) {
+	if c.minimize {
+		pc.Diff = MinimizeDiff(pc.Diff, c.contextLines)
+	}
+	resp, err := c.call(ctx, prSystemPrompt, BuildPRPrompt(pc, c.model, c.maxDiffTokens), []openai.Tool{prTool})
+	if err != nil {
+		return nil, fmt.Errorf("AI request failed: %w", err)
+	}
+	return ParsePullRequest(resp.ToolCalls, resp.Content)
+}
+
+// ParsePullRequest reads the submit_pull_request call, or falls back to a
+// plain reply whose first line is the title
+func ParsePullRequest(calls []ToolCall, content string) (*PullRequest, error) {
+	for _, tc := range calls {
+		if tc.Name != prTool.Function.Name {
+			continue
+		}
+		var pr PullRequest
+		if err := json.Unmarshal([]byte(tc.Arguments), &pr); err != nil {
+			return nil, fmt.Errorf("failed to parse pull request: %w", err)
+		}
+		if strings.TrimSpace(pr.Title) == "" {
+			return nil, fmt.Errorf("the pull request title is empty")
+		}
+		pr.Title = strings.TrimSpace(pr.Title)
+		pr.Body = strings.TrimSpace(pr.Body)
+		return &pr, nil
+	}
+
+	title, body, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	title = strings.TrimSpace(strings.TrimLeft(title, "# "))
+	if title == "" {
+		return nil, errNoResponse
+	}
+	return &PullRequest{Title: title, Body: strings.TrimSpace(body)}, nil
+}
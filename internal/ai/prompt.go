@@ -2,15 +2,16 @@ package ai
 
 import (
 	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
 // Truncation limits (exported for testing)
 const (
-	MaxDiffLines = 600   // only truncate if diff exceeds this many lines
-	MaxDiffSize  = 12000 // max total diff size in characters
-	ShowLines    = 100   // lines to show in each segment
-	SkipLines    = 50    // lines to skip between segments
+	ShowLines = 100 // lines to show in each segment
+	SkipLines = 50  // lines to skip between segments
 )
 
 const systemPrompt = `You are an expert software engineer who writes clear, professional git commit messages. Your goal is to help developers maintain a clean, atomic git history.
@@ -29,10 +30,22 @@ PREFER split_commits when you see:
 
 Use submit_commit ONLY when ALL changes serve a single, cohesive purpose.
 
+If one file contains changes for different commits, list the file in each of
+those commits and use hunks to say which of its hunks belong to each commit,
+numbering the file's @@ headers from 1. Every hunk must go to exactly one commit.
+
 ## Commit Message Format
 - Subject: imperative mood, max 72 characters, no period at end
 - Body (optional): wrapped at 72 characters, explains why not what
 
+## Breaking Changes
+Set breaking and describe the break in breaking_description when the diff
+breaks compatibility for users of the code, e.g.:
+- exported functions, types, fields or endpoints removed or renamed
+- changed function signatures, return types or response formats
+- removed or renamed config keys, CLI flags or environment variables
+Internal refactors, additions and changes to unexported code are not breaking.
+
 ## Examples
 
 Good single-line commits:
@@ -51,52 +64,268 @@ exponential backoff to improve reliability.
 - split_commits: Use this for most cases with multiple distinct changes (PREFERRED)
 - submit_commit: Use only when all changes are tightly related to one purpose`
 
+// focusedSystemPrompt is used for docs-only and config-only changes, which
+// never need the split reasoning of the full prompt
+const focusedSystemPrompt = `You are an expert software engineer who writes clear, professional git commit messages.
+
+## Your Task
+Summarize the provided diff as a single commit using submit_commit. Be precise about what was documented or configured.
+
+## Commit Message Format
+- Subject: imperative mood, max 72 characters, no period at end
+- Body (optional): wrapped at 72 characters, explains why not what`
+
+// ChangeKind classifies the selected files to pick a specialized prompt
+type ChangeKind int
+
+const (
+	ChangeCode   ChangeKind = iota // source code or a mix of kinds
+	ChangeDocs                     // documentation only
+	ChangeConfig                   // configuration only
+)
+
+var docExtensions = map[string]bool{
+	".md": true, ".mdx": true, ".rst": true, ".txt": true, ".adoc": true,
+}
+
+var configExtensions = map[string]bool{
+	".toml": true, ".yaml": true, ".yml": true, ".json": true,
+	".ini": true, ".cfg": true, ".conf": true, ".properties": true,
+}
+
+// ClassifyChanges reports whether all files are documentation or configuration
+func ClassifyChanges(files []string) ChangeKind {
+	if len(files) == 0 {
+		return ChangeCode
+	}
+
+	allDocs, allConfig := true, true
+	for _, f := range files {
+		allDocs = allDocs && isDocFile(f)
+		allConfig = allConfig && isConfigFile(f)
+	}
+
+	switch {
+	case allDocs:
+		return ChangeDocs
+	case allConfig:
+		return ChangeConfig
+	default:
+		return ChangeCode
+	}
+}
+
+func isDocFile(path string) bool {
+	name := strings.ToUpper(filepath.Base(path))
+	if strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "CHANGELOG") {
+		return true
+	}
+	return docExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+func isConfigFile(path string) bool {
+	name := filepath.Base(path)
+	// Dotfiles such as .gitignore, .editorconfig or .golangci.yml
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	return configExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// PromptContext holds everything the user prompt is built from
+type PromptContext struct {
+	Files              []string          // selected file paths
+	Diff               string            // combined diff of the selected files
+	Conventional       bool              // use conventional commit format
+	Types              []string          // allowed conventional commit types
+	TypeDescriptions   map[string]string // what each type is for, listed in the prompt
+	CustomInstructions string            // user instructions from config
+	PreviousMsg        string            // message being regenerated, if any
+	Feedback           string            // user feedback for regeneration
+	Model              string            // model name, used to size the diff budget
+	MaxDiffTokens      int               // optional cap on diff tokens (0 = model window)
+	Exclude            []string          // patterns whose diff is replaced by a summary
+	Minimize           bool              // shrink the diff with MinimizeDiff before budgeting
+	ContextLines       int               // context kept when minimizing
+	Single             bool              // a single commit is required, e.g. when amending
+	Moves              []MoveHistory     // code moved between files, with file history
+	Examples           []string          // recent commit subjects of the repository, as style examples
+	Language           string            // language to write messages in; empty means English
+	Subject            SubjectRules
+	Trailers           []string // appended to every message, never sent to the model
+	Branch             string   // current branch, for context
+	Ticket             string   // ticket ID found in the branch name
+	TicketAdded        bool     // a trailer or subject prefix adds the ticket, so the model leaves it out
+	NoCache            bool     // ask the model even when a cached result exists
+
+	// Set for a delta prompt: the earlier proposal and the files added to
+	// the selection since. Diff then only covers Added.
+	Previous []CommitMessage
+	Added    []string
+}
+
 func BuildPrompt(files []string, diff string, conventional bool, types []string, customInstructions string, previousMsg string, feedback string) string {
+	return BuildPromptFrom(PromptContext{
+		Files:              files,
+		Diff:               diff,
+		Conventional:       conventional,
+		Types:              types,
+		CustomInstructions: customInstructions,
+		PreviousMsg:        previousMsg,
+		Feedback:           feedback,
+	})
+}
+
+// BuildPromptFrom renders the user prompt for a prompt context
+func BuildPromptFrom(pc PromptContext) string {
 	var sb strings.Builder
 
 	// Check if this is a regeneration request
-	if previousMsg == "" {
+	if len(pc.Previous) > 0 {
+		writeDelta(&sb, pc)
+	} else if pc.PreviousMsg == "" {
 		sb.WriteString("Generate a commit message for these changes:\n\n")
 	} else {
 		sb.WriteString("The user wants you to regenerate the commit message.\n\n")
-		sb.WriteString(fmt.Sprintf("Previous message:\n```\n%s\n```\n\n", previousMsg))
-		if feedback != "" {
-			sb.WriteString(fmt.Sprintf("User feedback: %s\n\n", feedback))
+		sb.WriteString(fmt.Sprintf("Previous message:\n```\n%s\n```\n\n", pc.PreviousMsg))
+		if pc.Feedback != "" {
+			sb.WriteString(fmt.Sprintf("User feedback: %s\n\n", pc.Feedback))
 		}
 		sb.WriteString("Generate an improved commit message based on the feedback.\n\n")
 	}
 
+	if pc.Branch != "" {
+		sb.WriteString(fmt.Sprintf("Branch: %s\n", pc.Branch))
+	}
+	if pc.Ticket != "" && pc.TicketAdded {
+		sb.WriteString(fmt.Sprintf("Ticket: %s (added to the message automatically; don't repeat it)\n", pc.Ticket))
+	} else if pc.Ticket != "" {
+		sb.WriteString(fmt.Sprintf("Ticket: %s (reference it in the message)\n", pc.Ticket))
+	}
+	if pc.Branch != "" || pc.Ticket != "" {
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString("Files changed:\n")
-	for _, f := range files {
+	for _, f := range pc.Files {
 		sb.WriteString(fmt.Sprintf("- %s\n", f))
 	}
 
-	sb.WriteString("\nDiff:\n```\n")
-	sb.WriteString(truncateDiff(diff))
+	if len(pc.Previous) > 0 {
+		sb.WriteString("\nDiff of the added files (the others are unchanged since your proposal):\n```\n")
+	} else {
+		sb.WriteString("\nDiff:\n```\n")
+	}
+	sb.WriteString(promptDiff(pc))
 	sb.WriteString("\n```\n")
+	writeMoveHistory(&sb, pc.Moves)
 
-	if conventional {
-		sb.WriteString(fmt.Sprintf("\nUse conventional commit format with one of these types: %s\n", strings.Join(types, ", ")))
+	if pc.Conventional {
+		writeTypes(&sb, pc.Types, pc.TypeDescriptions)
+	}
+	writeExamples(&sb, pc.Examples)
+	if pc.Language != "" {
+		sb.WriteString(fmt.Sprintf("\nWrite the subject and body in %s. Keep the type and scope in English.\n", pc.Language))
+	}
+
+	if pc.Subject.MaxLength > 0 || pc.Subject.Prefix != "" {
+		sb.WriteString(fmt.Sprintf("\nKeep the first line (type and subject) within %d characters.", pc.Subject.modelLimit()))
+		if pc.Subject.Prefix != "" {
+			sb.WriteString(fmt.Sprintf(" The required prefix %q is added automatically; don't include it.", pc.Subject.Prefix))
+		}
+		sb.WriteString("\n")
 	}
 
-	if customInstructions != "" {
-		sb.WriteString(fmt.Sprintf("\nAdditional instructions: %s\n", customInstructions))
+	if len(pc.Subject.RequireBody) > 0 {
+		sb.WriteString(fmt.Sprintf("\nCommits of type %s must have a body explaining why the change was made, not just what changed.\n", strings.Join(pc.Subject.RequireBody, ", ")))
 	}
+	writeEmojiGuide(&sb, pc.Subject.Emoji)
 
-	sb.WriteString("\nAnalyze the changes and decide: use `submit_commit` for related changes, or `split_commits` if changes should be separate commits.")
+	if pc.CustomInstructions != "" {
+		sb.WriteString(fmt.Sprintf("\nAdditional instructions: %s\n", pc.CustomInstructions))
+	}
+
+	writeTypeHint(&sb, pc)
+	writeScopes(&sb, pc.Subject, pc.Files)
+
+	switch ClassifyChanges(pc.Files) {
+	case ChangeDocs:
+		sb.WriteString("\nThese changes only touch documentation. Use `submit_commit`")
+		if pc.Conventional {
+			sb.WriteString(" with type `docs`")
+		}
+		sb.WriteString(" and describe what was documented.")
+	case ChangeConfig:
+		sb.WriteString("\nThese changes only touch configuration. Use `submit_commit`")
+		if pc.Conventional {
+			sb.WriteString(" with type `chore` (or `build`/`ci` if allowed and more precise)")
+		}
+		sb.WriteString(" and name the setting that changed.")
+	default:
+		if pc.Single {
+			sb.WriteString("\nDescribe all changes in a single commit with `submit_commit`.")
+			break
+		}
+		sb.WriteString("\nAnalyze the changes and decide: use `submit_commit` for related changes, or `split_commits` if changes should be separate commits.")
+	}
 
 	return sb.String()
 }
 
+// promptDiff returns the diff as sent: excluded files summarized, minimized
+// if asked for, and truncated to the token budget
+func promptDiff(pc PromptContext) string {
+	diff := summarizeExcluded(pc.Diff, pc.Exclude)
+	if pc.Minimize {
+		diff = MinimizeDiff(diff, pc.ContextLines)
+	}
+	return truncateDiff(diff, DiffTokenBudget(pc.Model, pc.MaxDiffTokens), pc.Model)
+}
+
+// writeExamples shows recent subjects so messages match the repository's
+// conventions
+func writeExamples(sb *strings.Builder, subjects []string) {
+	if len(subjects) == 0 {
+		return
+	}
+	sb.WriteString("\nRecent commit subjects in this repository. Match their style (tense, scopes, casing), not their content:\n")
+	for _, s := range subjects {
+		sb.WriteString(fmt.Sprintf("- %s\n", s))
+	}
+}
+
+// writeDelta asks for the earlier proposal to be revised for added files
+// rather than generated again from the whole diff
+func writeDelta(sb *strings.Builder, pc PromptContext) {
+	sb.WriteString("You previously proposed these commits:\n\n")
+	for _, c := range pc.Previous {
+		// Prefixes, emoji and trailers are added to the model's reply again
+		c.Prefix, c.Emoji, c.Trailers = "", "", nil
+		sb.WriteString(fmt.Sprintf("```\n%s\n```\nFiles: %s\n\n", c.String(), strings.Join(c.Files, ", ")))
+	}
+	sb.WriteString(fmt.Sprintf("These files have been added to the selection since: %s.\n", strings.Join(pc.Added, ", ")))
+	sb.WriteString("Return the complete set of commits for all files. Keep the earlier commits as they are, adding the new changes to the one they belong to, or to a commit of their own if they are unrelated.\n\n")
+}
+
 func SystemPrompt() string {
 	return systemPrompt
 }
 
+// SystemPromptFor returns the shorter focused prompt for docs-only and
+// config-only changes, and the full prompt otherwise
+func SystemPromptFor(kind ChangeKind) string {
+	if kind == ChangeCode {
+		return systemPrompt
+	}
+	return focusedSystemPrompt
+}
+
 // truncateDiff intelligently truncates a diff while preserving context.
-// Only applies truncation if the diff exceeds MaxDiffLines.
-func truncateDiff(diff string) string {
-	lineCount := strings.Count(diff, "\n")
-	if lineCount <= MaxDiffLines {
+// Only applies truncation if the diff exceeds the token budget, counted
+// with the tokenizer ratio of the model.
+func truncateDiff(diff string, budget int, model string) string {
+	limit := int(float64(budget) * CharsPerToken(model))
+	if len(diff) <= limit {
 		return diff
 	}
 
@@ -107,8 +336,8 @@ func truncateDiff(diff string) string {
 		truncatedFile := truncateFile(file)
 		result.WriteString(truncatedFile)
 
-		// Stop if we've exceeded the overall limit
-		if result.Len() > MaxDiffSize {
+		// Stop if we've exceeded the overall budget
+		if result.Len() > limit {
 			result.WriteString("\n... (remaining files truncated) ...")
 			break
 		}
@@ -215,3 +444,46 @@ func truncateHunk(lines []string) string {
 
 	return result.String()
 }
+
+// writeTypes lists the allowed types, one per line when they are described
+func writeTypes(sb *strings.Builder, types []string, descriptions map[string]string) {
+	if len(descriptions) == 0 {
+		sb.WriteString(fmt.Sprintf("\nUse conventional commit format with one of these types: %s\n", strings.Join(types, ", ")))
+		return
+	}
+	sb.WriteString("\nUse conventional commit format with one of these types:\n")
+	for _, t := range types {
+		if desc := descriptions[t]; desc != "" {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", t, desc))
+		} else {
+			sb.WriteString("- " + t + "\n")
+		}
+	}
+}
+
+// writeTypeHint suggests the type the file paths point to, if it's allowed.
+// Docs-only changes get their own instruction.
+func writeTypeHint(sb *strings.Builder, pc PromptContext) {
+	if !pc.Conventional || ClassifyChanges(pc.Files) == ChangeDocs {
+		return
+	}
+	typ, reason := TypeHint(pc.Files)
+	if typ == "" || (len(pc.Types) > 0 && !slices.Contains(pc.Types, typ)) {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("\nHint from the file paths: %s, so the type is likely `%s`. Use another type if the diff says otherwise.\n", reason, typ))
+}
+
+// writeEmojiGuide lists the gitmoji the model may start subjects with
+func writeEmojiGuide(sb *strings.Builder, emoji map[string]string) {
+	if len(emoji) == 0 {
+		return
+	}
+	var pairs []string
+	for _, t := range slices.Sorted(maps.Keys(emoji)) {
+		pairs = append(pairs, emoji[t]+" "+t)
+	}
+	sb.WriteString("\nCommits get a gitmoji before the type, by default the one of the type. If another fits the change better (e.g. a security fix), start the subject with it. Choose only from: ")
+	sb.WriteString(strings.Join(pairs, ", "))
+	sb.WriteString("\n")
+}
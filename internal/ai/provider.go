@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"context"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ToolCall is a provider-agnostic function call returned by the model
+type ToolCall struct {
+	Name      string
+	Arguments string // raw JSON arguments
+}
+
+// chatResponse is the normalized reply from a provider
+type chatResponse struct {
+	ToolCalls []ToolCall
+	Content   string
+	Usage     Usage // zero when the backend does not report usage
+}
+
+// provider sends a single system + user prompt to a model backend.
+// Tools are described with the OpenAI schema and translated by each backend.
+type provider interface {
+	chat(ctx context.Context, system, user string, tools []openai.Tool) (*chatResponse, error)
+}
+
+// openaiProvider talks to any OpenAI-compatible chat completions endpoint
+type openaiProvider struct {
+	client *openai.Client
+	model  string
+}
+
+func (p *openaiProvider) chat(ctx context.Context, system, user string, tools []openai.Tool) (*chatResponse, error) {
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: p.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: system,
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: user,
+			},
+		},
+		Tools: tools,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, errNoResponse
+	}
+
+	msg := resp.Choices[0].Message
+	result := &chatResponse{
+		Content: msg.Content,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+		},
+	}
+	result.ToolCalls = ToolCallsFromOpenAI(msg)
+	return result, nil
+}
+
+// ToolCallsFromOpenAI extracts the function calls of an OpenAI chat message
+func ToolCallsFromOpenAI(msg openai.ChatCompletionMessage) []ToolCall {
+	var calls []ToolCall
+	for _, tc := range msg.ToolCalls {
+		calls = append(calls, ToolCall{
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+	return calls
+}
+
+func (p *openaiProvider) chatStructured(ctx context.Context, system, user string, format *openai.ChatCompletionResponseFormat) (*chatResponse, error) {
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: p.model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: system,
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: user,
+			},
+		},
+		ResponseFormat: format,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, errNoResponse
+	}
+
+	return &chatResponse{
+		Content: resp.Choices[0].Message.Content,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+		},
+	}, nil
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// maxRetryDelay caps both exponential backoff and server Retry-After hints
+const maxRetryDelay = 60 * time.Second
+
+// retryTransport retries requests that fail with 429 or 5xx responses or
+// transient network errors, honoring Retry-After. Each attempt gets its own
+// timeout so a hung proxy doesn't consume the whole retry budget.
+type retryTransport struct {
+	base       http.RoundTripper
+	timeout    time.Duration
+	maxRetries int
+	backoff    time.Duration
+}
+
+// newHTTPClient builds the HTTP client shared by all providers
+func newHTTPClient(cfg *config.AIConfig) *http.Client {
+	base := http.DefaultTransport
+	if params := RequestParams(cfg); len(params) > 0 {
+		base = &paramsTransport{base: base, params: params}
+	}
+	if len(cfg.APIKeys) > 1 {
+		base = newKeyTransport(base, cfg)
+	}
+	return &http.Client{
+		Transport: &retryTransport{
+			base:       base,
+			timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+			maxRetries: cfg.MaxRetries,
+			backoff:    time.Duration(cfg.BackoffSeconds * float64(time.Second)),
+		},
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.roundTripOnce(req)
+
+		if attempt >= t.maxRetries || req.Context().Err() != nil || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		// Requests with a body can only be retried if it can be replayed
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		delay := Backoff(t.backoff, attempt)
+		if resp != nil {
+			if d, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				delay = min(d, maxRetryDelay)
+			}
+			// Drain so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// roundTripOnce performs a single attempt with a fresh body and timeout
+func (t *retryTransport) roundTripOnce(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(r)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// Keep the attempt context alive until the caller finishes reading
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// shouldRetry reports whether a response or transport error is transient
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// Backoff returns the exponential delay before retry number attempt (0-based)
+func Backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	delay := base << attempt
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
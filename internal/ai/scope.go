@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hluaguo/commity/internal/glob"
+)
+
+// scopeOf returns the scope of the most specific pattern matching file, or ""
+func (r SubjectRules) scopeOf(file string) string {
+	best := ""
+	for _, pattern := range slices.Sorted(maps.Keys(r.Scopes)) {
+		if len(pattern) > len(best) && glob.Match(pattern, file) {
+			best = pattern
+		}
+	}
+	return r.Scopes[best]
+}
+
+// InferScope returns the scope all files map to, or "" when they map to
+// several scopes or none
+func (r SubjectRules) InferScope(files []string) string {
+	scope := ""
+	for i, f := range files {
+		s := r.scopeOf(f)
+		if s == "" || (i > 0 && s != scope) {
+			return ""
+		}
+		scope = s
+	}
+	return scope
+}
+
+// withScope replaces the model's scope with the one the files map to. A
+// scope that isn't configured is dropped, so models can't invent new ones.
+func (r SubjectRules) withScope(c CommitMessage) CommitMessage {
+	if len(r.Scopes) == 0 && len(r.ScopeEnum) == 0 {
+		return c
+	}
+	if scope := r.InferScope(c.Files); scope != "" {
+		c.Scope = scope
+	} else if !slices.Contains(r.scopeNames(), c.Scope) {
+		c.Scope = ""
+	}
+	return c
+}
+
+// scopeDescription describes the scope field in tool schemas
+func (r SubjectRules) scopeDescription() string {
+	if len(r.scopeNames()) == 0 {
+		return "Optional scope in parentheses after the type, e.g. 'api' for 'feat(api): ...'; usually empty"
+	}
+	return "Scope of the change, one of: " + strings.Join(r.scopeNames(), ", ") + "; empty when the files span several scopes"
+}
+
+// scopeNames returns the configured scopes, sorted and without repeats
+func (r SubjectRules) scopeNames() []string {
+	names := slices.AppendSeq(slices.Clone(r.ScopeEnum), maps.Values(r.Scopes))
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// writeScopes tells the model which scopes the selected files belong to
+func writeScopes(sb *strings.Builder, r SubjectRules, files []string) {
+	if len(r.scopeNames()) == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("\nUse only these scopes: %s.", strings.Join(r.scopeNames(), ", ")))
+	if len(r.Scopes) == 0 {
+		sb.WriteString(" Leave the scope empty when none fits.\n")
+		return
+	}
+	sb.WriteString(" Scopes of the changed files:\n")
+	for _, f := range files {
+		scope := r.scopeOf(f)
+		if scope == "" {
+			scope = "(none)"
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", f, scope))
+	}
+	sb.WriteString("A commit whose files span several scopes has no scope.\n")
+}
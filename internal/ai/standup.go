@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const standupSystemPrompt = `You write short standup updates for software engineers.
+Given the commits someone made today, grouped by repository, write one
+paragraph of 2-4 sentences in the first person describing what they worked
+on. Group related commits into themes instead of listing each one, and
+don't invent work that isn't in the commits. Reply with the paragraph only.`
+
+// StandupRepo lists the commit subjects made in one repository
+type StandupRepo struct {
+	Name     string
+	Subjects []string
+}
+
+// BuildStandupPrompt formats the day's commits for the standup summary
+func BuildStandupPrompt(repos []StandupRepo) string {
+	var sb strings.Builder
+	sb.WriteString("Commits made today:\n")
+	for _, r := range repos {
+		if len(r.Subjects) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n## %s\n", r.Name))
+		for _, s := range r.Subjects {
+			sb.WriteString("- " + s + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Standup asks the model for a standup paragraph summarizing the commits
+func (c *Client) Standup(ctx context.Context, repos []StandupRepo) (string, error) {
+	resp, err := c.call(ctx, standupSystemPrompt, BuildStandupPrompt(repos), nil)
+	if err != nil {
+		return "", fmt.Errorf("AI request failed: %w", err)
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", errNoResponse
+	}
+	return summary, nil
+}
@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// structuredProvider is implemented by backends that can constrain replies
+// to a JSON schema (OpenAI structured outputs) instead of function calling
+type structuredProvider interface {
+	chatStructured(ctx context.Context, system, user string, format *openai.ChatCompletionResponseFormat) (*chatResponse, error)
+}
+
+// structuredModels lists model prefixes with structured output support.
+// The longest matching prefix wins, so false entries exclude older snapshots.
+var structuredModels = map[string]bool{
+	"gpt-4o":            true,
+	"gpt-4o-2024-05-13": false,
+	"gpt-4.1":           true,
+	"gpt-5":             true,
+	"o1":                true,
+	"o1-preview":        false,
+	"o1-mini":           false,
+	"o3":                true,
+	"o4":                true,
+}
+
+// SupportsStructuredOutputs reports whether a model is known to accept
+// response_format with a strict JSON schema
+func SupportsStructuredOutputs(model string) bool {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+
+	best, supported := "", false
+	for prefix, ok := range structuredModels {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+			best, supported = prefix, ok
+		}
+	}
+	return supported
+}
+
+// commitsSchema is the strict JSON schema for structured replies. Strict
+// mode requires every property, so optional fields are empty when unused.
+// The verbs are filled with the type property, the scope description, the
+// subject length limit and the body description.
+const commitsSchema = `{
+  "type": "object",
+  "properties": {
+    "commits": {
+      "type": "array",
+      "description": "One commit when all changes serve one purpose, several for a split",
+      "items": {
+        "type": "object",
+        "properties": {
+          "type": %s,
+          "scope": {"type": "string", "description": "%s"},
+          "subject": {"type": "string", "description": "Short subject WITHOUT the type prefix (%s)"},
+          "body": {"type": "string", "description": "%s"},
+          "breaking": {"type": "boolean", "description": "True only if the change breaks compatibility for users of the code"},
+          "breaking_description": {"type": "string", "description": "For breaking changes, what breaks and how to migrate; otherwise empty"},
+          "files": {"type": "array", "items": {"type": "string"}, "description": "File paths for this commit"},
+          "hunks": {
+            "type": "array",
+            "description": "Only for files shared with other commits: which of the file's hunks go here, numbering its @@ headers from 1",
+            "items": {
+              "type": "object",
+              "properties": {
+                "file": {"type": "string"},
+                "hunks": {"type": "array", "items": {"type": "integer"}}
+              },
+              "required": ["file", "hunks"],
+              "additionalProperties": false
+            }
+          }
+        },
+        "required": ["type", "scope", "subject", "body", "breaking", "breaking_description", "files", "hunks"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["commits"],
+  "additionalProperties": false
+}`
+
+// structuredFormat requests a commit plan matching commitsSchema
+func structuredFormat(rules SubjectRules) *openai.ChatCompletionResponseFormat {
+	limit := fmt.Sprintf("the whole 'type: subject' line max %d chars", rules.modelLimit())
+	typeProp, _ := json.Marshal(rules.typeProperty())
+	body := "Longer description, or empty"
+	if len(rules.RequireBody) > 0 {
+		body = rules.bodyDescription() + "; empty when optional"
+	}
+	return &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   "commit_plan",
+			Schema: json.RawMessage(fmt.Sprintf(commitsSchema, typeProp, rules.scopeDescription(), limit, body)),
+			Strict: true,
+		},
+	}
+}
+
+// StructuredToolCalls converts a structured reply into the equivalent tool
+// call so parsing, validation and repair are shared with function calling
+func StructuredToolCalls(content string, allowSplit bool) []ToolCall {
+	var split SplitCommits
+	if err := json.Unmarshal([]byte(content), &split); err != nil {
+		// Let the parser report the malformed arguments
+		return []ToolCall{{Name: "split_commits", Arguments: content}}
+	}
+
+	if len(split.Commits) == 1 || (!allowSplit && len(split.Commits) > 0) {
+		args, _ := json.Marshal(split.Commits[0])
+		return []ToolCall{{Name: "submit_commit", Arguments: string(args)}}
+	}
+	return []ToolCall{{Name: "split_commits", Arguments: content}}
+}
@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DefaultSubjectLength is the first-line limit when none is configured
+const DefaultSubjectLength = 72
+
+// SubjectRules constrain the first line of commit messages, e.g. for merge
+// queues that truncate at 50 characters or require a ticket prefix, and
+// which types need a body
+type SubjectRules struct {
+	MaxLength   int               // limit of the whole first line; 0 means DefaultSubjectLength
+	Prefix      string            // text the first line must start with, e.g. "[PROJ-123] "
+	Emoji       map[string]string // gitmoji by commit type; nil leaves messages without emoji
+	RequireBody []string          // types whose commits must explain why in the body
+	Scopes      map[string]string // path glob to scope, e.g. "pkg/api/**" = "api"
+	ScopeEnum   []string          // further allowed scopes, e.g. commitlint's scope-enum
+	Types       []string          // allowed conventional types, enforced as an enum when set
+}
+
+// Limit returns the effective first-line limit
+func (r SubjectRules) Limit() int {
+	if r.MaxLength > 0 {
+		return r.MaxLength
+	}
+	return DefaultSubjectLength
+}
+
+// modelLimit is the room left for "type: subject" after the prefix and emoji
+func (r SubjectRules) modelLimit() int {
+	return max(r.Limit()-len(r.Prefix)-r.emojiRoom(), 1)
+}
+
+// emojiRoom is the space the widest configured emoji and its blank take up
+func (r SubjectRules) emojiRoom() int {
+	room := 0
+	for _, e := range r.Emoji {
+		room = max(room, len(e)+1)
+	}
+	return room
+}
+
+// withEmoji sets the emoji of the message: one from the table the model put
+// at the start of the subject, or else the one of its type
+func (r SubjectRules) withEmoji(c CommitMessage) CommitMessage {
+	if r.Emoji == nil {
+		return c
+	}
+	emoji := slices.Collect(maps.Values(r.Emoji))
+	// Longest first, so an emoji isn't cut off its variation selector
+	slices.SortFunc(emoji, func(a, b string) int { return len(b) - len(a) })
+	for _, e := range emoji {
+		if e != "" && strings.HasPrefix(c.Subject, e) {
+			c.Emoji = e
+			c.Subject = strings.TrimSpace(strings.TrimPrefix(c.Subject, e))
+			return c
+		}
+	}
+	c.Emoji = r.Emoji[c.Type]
+	return c
+}
+
+// subjectDescription describes the subject field in tool schemas
+func (r SubjectRules) subjectDescription() string {
+	return fmt.Sprintf("Short commit subject line WITHOUT the type prefix (the whole 'type: subject' line max %d chars). Example: 'add user authentication' not 'feat: add user authentication'", r.modelLimit())
+}
+
+// typeProperty is the type field of tool schemas, limited to the allowed
+// types when there are any
+func (r SubjectRules) typeProperty() map[string]any {
+	prop := map[string]any{
+		"type":        "string",
+		"description": "Commit type (feat, fix, docs, style, refactor, test, chore, etc)",
+	}
+	if len(r.Types) > 0 {
+		prop["description"] = "Commit type, see the type list in the prompt"
+		prop["enum"] = r.Types
+	}
+	return prop
+}
+
+// TypeProblems reports commits whose type isn't one of the allowed types
+func TypeProblems(commits []CommitMessage, r SubjectRules) []string {
+	if len(r.Types) == 0 {
+		return nil
+	}
+	var problems []string
+	for i, c := range commits {
+		if c.Type != "" && !slices.Contains(r.Types, c.Type) {
+			problems = append(problems, fmt.Sprintf("commit %d has type %q; use one of %s", i+1, c.Type, strings.Join(r.Types, ", ")))
+		}
+	}
+	return problems
+}
+
+// bodyDescription describes the body field in tool schemas
+func (r SubjectRules) bodyDescription() string {
+	if len(r.RequireBody) == 0 {
+		return "Optional longer description"
+	}
+	return fmt.Sprintf("Why the change was made; required for %s commits, optional otherwise", strings.Join(r.RequireBody, ", "))
+}
+
+// Header returns the first line of the message
+func (c *CommitMessage) Header() string {
+	header, _, _ := strings.Cut(c.String(), "\n")
+	return header
+}
+
+// SubjectProblems reports first lines over the limit, phrased for the model
+func SubjectProblems(commits []CommitMessage, r SubjectRules) []string {
+	var problems []string
+	for i, c := range commits {
+		c = r.withEmoji(c)
+		c.Prefix, c.Emoji = "", ""
+		if n := len(c.Header()); n > r.modelLimit() {
+			problems = append(problems, fmt.Sprintf("commit %d's first line %q is %d characters; shorten it to at most %d", i+1, c.Header(), n, r.modelLimit()))
+		}
+	}
+	return problems
+}
+
+// BodyProblems reports commits of types in RequireBody without a body
+func BodyProblems(commits []CommitMessage, r SubjectRules) []string {
+	var problems []string
+	for i, c := range commits {
+		if slices.Contains(r.RequireBody, c.Type) && strings.TrimSpace(c.Body) == "" {
+			problems = append(problems, fmt.Sprintf("commit %d is a %s commit without a body; add one explaining why the change was made", i+1, c.Type))
+		}
+	}
+	return problems
+}
+
+// EnforceSubject adds the required prefix and emoji, corrects the scope and
+// shortens the subject until the first line fits the limit
+func EnforceSubject(c CommitMessage, r SubjectRules) CommitMessage {
+	c = r.withScope(r.withEmoji(c))
+	if r.Prefix != "" {
+		// Models sometimes copy the prefix into the subject
+		c.Subject = strings.TrimSpace(strings.TrimPrefix(c.Subject, strings.TrimSpace(r.Prefix)))
+		c.Prefix = r.Prefix
+	}
+	if over := len(c.Header()) - r.Limit(); over > 0 {
+		c.Subject = ShortenSubject(c.Subject, len(c.Subject)-over)
+	}
+	return c
+}
+
+// ShortenSubject cuts a subject to at most max bytes at a word boundary,
+// dropping trailing punctuation and dangling connectives
+func ShortenSubject(subject string, max int) string {
+	subject = strings.TrimRight(strings.TrimSpace(subject), ".")
+	if len(subject) <= max {
+		return subject
+	}
+	if max <= 0 {
+		return ""
+	}
+
+	cut := subject[:max]
+	if i := strings.LastIndex(cut, " "); i > 0 && subject[max] != ' ' {
+		cut = cut[:i]
+	}
+	words := strings.Fields(cut)
+	for len(words) > 1 {
+		switch strings.ToLower(words[len(words)-1]) {
+		case "and", "or", "with", "for", "to", "of", "in", "the", "a", "an":
+			words = words[:len(words)-1]
+			continue
+		}
+		break
+	}
+	return strings.TrimRight(strings.Join(words, " "), ",;:-")
+}
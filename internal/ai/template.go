@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// PromptData is what the templates of system_prompt_file and prompt_file are
+// rendered with, e.g. "{{.Default}}\nAlways mention {{.Ticket}}."
+type PromptData struct {
+	Default            string            // the built-in prompt the template replaces
+	Files              []string          // selected file paths
+	Diff               string            // diff as sent: exclusions summarized, minimized and truncated
+	Kind               string            // "code", "docs" or "config", see ClassifyChanges
+	Conventional       bool              // conventional commit format is on
+	Types              []string          // allowed commit types
+	TypeDescriptions   map[string]string // what each type is for
+	Scopes             []string          // allowed scopes, if restricted
+	Branch             string            // current branch
+	Ticket             string            // ticket ID found in the branch name
+	Language           string            // language to write messages in; empty means English
+	MaxLength          int               // limit of the first line the model writes
+	Examples           []string          // recent commit subjects of the repository
+	CustomInstructions string
+	PreviousMsg        string // message being regenerated, if any
+	Feedback           string // user feedback for regeneration
+	Single             bool   // only submit_commit is offered
+}
+
+// PromptTemplates replace the built-in prompts. A nil template, or nil
+// PromptTemplates, keeps the built-in one.
+type PromptTemplates struct {
+	System *template.Template
+	User   *template.Template
+}
+
+// LoadPromptTemplates parses the prompt templates configured in cfg, or
+// returns nil when there are none
+func LoadPromptTemplates(cfg *config.AIConfig) (*PromptTemplates, error) {
+	if cfg.SystemPromptFile == "" && cfg.PromptFile == "" {
+		return nil, nil
+	}
+	system, err := loadTemplate("system_prompt_file", cfg.SystemPromptFile)
+	if err != nil {
+		return nil, err
+	}
+	user, err := loadTemplate("prompt_file", cfg.PromptFile)
+	if err != nil {
+		return nil, err
+	}
+	return &PromptTemplates{System: system, User: user}, nil
+}
+
+func loadTemplate(key, path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(config.ExpandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	tmpl, err := template.New(key).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return tmpl, nil
+}
+
+// Prompts returns the system and user prompts for pc, rendered from the
+// templates where there are any
+func (t *PromptTemplates) Prompts(pc PromptContext) (system, user string, err error) {
+	kind := ClassifyChanges(pc.Files)
+	system, user = SystemPromptFor(kind), BuildPromptFrom(pc)
+	if t == nil || (t.System == nil && t.User == nil) {
+		return system, user, nil
+	}
+
+	data := PromptData{
+		Files:              pc.Files,
+		Diff:               promptDiff(pc),
+		Kind:               kindName(kind),
+		Conventional:       pc.Conventional,
+		Types:              pc.Types,
+		TypeDescriptions:   pc.TypeDescriptions,
+		Scopes:             pc.Subject.scopeNames(),
+		Branch:             pc.Branch,
+		Ticket:             pc.Ticket,
+		Language:           pc.Language,
+		MaxLength:          pc.Subject.modelLimit(),
+		Examples:           pc.Examples,
+		CustomInstructions: pc.CustomInstructions,
+		PreviousMsg:        pc.PreviousMsg,
+		Feedback:           pc.Feedback,
+		Single:             pc.Single || kind != ChangeCode,
+	}
+	if system, err = render(t.System, data, system); err != nil {
+		return "", "", err
+	}
+	if user, err = render(t.User, data, user); err != nil {
+		return "", "", err
+	}
+	return system, user, nil
+}
+
+// render executes tmpl with the built-in prompt as Default, or returns the
+// built-in prompt when there is no template
+func render(tmpl *template.Template, data PromptData, builtin string) (string, error) {
+	if tmpl == nil {
+		return builtin, nil
+	}
+	data.Default = builtin
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("%s: %w", tmpl.Name(), err)
+	}
+	return sb.String(), nil
+}
+
+func kindName(kind ChangeKind) string {
+	switch kind {
+	case ChangeDocs:
+		return "docs"
+	case ChangeConfig:
+		return "config"
+	}
+	return "code"
+}
@@ -0,0 +1,53 @@
+package ai
+
+import "math"
+
+// Usage reports token consumption of a generation
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	Estimated        bool // counted locally because the API reported no usage
+}
+
+// EstimateTokens approximates the number of tokens in text for a model of
+// an unknown family
+func EstimateTokens(text string) int {
+	return EstimateTokensFor("", text)
+}
+
+// EstimateTokensFor approximates the number of tokens in text with the
+// ratio of the model's family, see CharsPerToken
+func EstimateTokensFor(model, text string) int {
+	return int(math.Ceil(float64(len(text)) / CharsPerToken(model)))
+}
+
+// Add sums the usage of several requests
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + o.PromptTokens,
+		CompletionTokens: u.CompletionTokens + o.CompletionTokens,
+		Estimated:        u.Estimated || o.Estimated,
+	}
+}
+
+// Cost returns the price of the usage given prices per million tokens
+func (u Usage) Cost(inputPerMTok, outputPerMTok float64) float64 {
+	return (float64(u.PromptTokens)*inputPerMTok + float64(u.CompletionTokens)*outputPerMTok) / 1e6
+}
+
+// PlanEstimate predicts the API usage of acting on a generated commit plan
+type PlanEstimate struct {
+	Commits    int
+	Regenerate Usage // expected usage of regenerating the plan
+}
+
+// EstimatePlan predicts the API usage left for a generated result.
+// Commits are created locally, so accepting a plan makes no further
+// calls; a regeneration resends the same diff and costs about as much
+// as the original request, repair retry included.
+func EstimatePlan(result *GenerateResult) PlanEstimate {
+	return PlanEstimate{
+		Commits:    len(result.Commits),
+		Regenerate: result.Usage,
+	}
+}
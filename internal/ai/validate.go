@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxRepairAttempts is how often an invalid tool call is sent back for repair
+const maxRepairAttempts = 1
+
+// ValidateResult checks parsed tool arguments against the tool schemas and
+// the selection. It returns one message per problem, phrased for the model.
+func ValidateResult(result *GenerateResult, files []string) []string {
+	var problems []string
+
+	if len(result.Commits) == 0 {
+		return []string{"no commits were returned"}
+	}
+
+	for i, c := range result.Commits {
+		name := "the commit"
+		if result.IsSplit {
+			name = fmt.Sprintf("commit %d", i+1)
+		}
+		if strings.TrimSpace(c.Type) == "" {
+			problems = append(problems, fmt.Sprintf("%s is missing the required \"type\" field", name))
+		}
+		if strings.TrimSpace(c.Subject) == "" {
+			problems = append(problems, fmt.Sprintf("%s is missing the required \"subject\" field", name))
+		}
+		if !result.IsSplit {
+			continue
+		}
+		if len(c.Files) == 0 {
+			problems = append(problems, fmt.Sprintf("%s has no files", name))
+		}
+		for _, f := range c.Files {
+			if !slices.Contains(files, f) {
+				problems = append(problems, fmt.Sprintf("%s lists %q, which is not one of the changed files", name, f))
+			}
+		}
+		for _, h := range c.Hunks {
+			if !slices.Contains(c.Files, h.File) {
+				problems = append(problems, fmt.Sprintf("%s selects hunks of %q, which is not in its files", name, h.File))
+			}
+			if slices.ContainsFunc(h.Hunks, func(n int) bool { return n < 1 }) {
+				problems = append(problems, fmt.Sprintf("%s has hunk numbers below 1 for %q", name, h.File))
+			}
+		}
+	}
+	return problems
+}
+
+// responseProblems validates a provider reply. Free-form content replies
+// have no schema to check; only tool calls are validated.
+func responseProblems(resp *chatResponse, result *GenerateResult, parseErr error, files []string, rules SubjectRules) []string {
+	if len(resp.ToolCalls) == 0 {
+		return nil
+	}
+	if parseErr != nil {
+		return []string{fmt.Sprintf("the %s arguments are not valid JSON: %v", resp.ToolCalls[0].Name, parseErr)}
+	}
+	problems := append(ValidateResult(result, files), SubjectProblems(result.Commits, rules)...)
+	problems = append(problems, TypeProblems(result.Commits, rules)...)
+	return append(problems, BodyProblems(result.Commits, rules)...)
+}
+
+// repairPrompt asks the model to correct its previous tool call
+func repairPrompt(prompt string, resp *chatResponse, problems []string) string {
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\nYour previous response was invalid.\n")
+	if len(resp.ToolCalls) > 0 {
+		sb.WriteString(fmt.Sprintf("You called %s with:\n%s\n", resp.ToolCalls[0].Name, resp.ToolCalls[0].Arguments))
+	}
+	sb.WriteString("\nProblems:\n")
+	for _, p := range problems {
+		sb.WriteString("- " + p + "\n")
+	}
+	sb.WriteString("\nCall the tool again with corrected arguments. Only use file paths from the list of changed files.\n")
+	return sb.String()
+}
+
+// Coverage reports how a split's file sets deviate from the selection
+type Coverage struct {
+	Missing    []string // selected files in no commit
+	Duplicates []string // files listed whole in more than one commit
+	Unknown    []string // files that were not selected
+}
+
+// OK reports whether every selected file is in exactly one commit
+func (c Coverage) OK() bool {
+	return len(c.Missing) == 0 && len(c.Duplicates) == 0 && len(c.Unknown) == 0
+}
+
+// CheckCoverage compares the union of the commits' files with the
+// selection. A file may appear in several commits only when each of them
+// takes specific hunks of it.
+func CheckCoverage(commits []CommitMessage, files []string) Coverage {
+	var cov Coverage
+	seen := make(map[string]int)
+	whole := make(map[string]bool)
+	for _, c := range commits {
+		for _, f := range c.Files {
+			if !slices.Contains(files, f) {
+				if !slices.Contains(cov.Unknown, f) {
+					cov.Unknown = append(cov.Unknown, f)
+				}
+				continue
+			}
+			seen[f]++
+			if len(c.HunksFor(f)) == 0 {
+				whole[f] = true
+			}
+		}
+	}
+	for _, f := range files {
+		switch {
+		case seen[f] == 0:
+			cov.Missing = append(cov.Missing, f)
+		case seen[f] > 1 && whole[f]:
+			cov.Duplicates = append(cov.Duplicates, f)
+		}
+	}
+	return cov
+}
+
+// FixCoverage drops unknown files and the later listings of duplicated
+// files, removing commits left empty. Missing files are returned for the
+// user to assign.
+func FixCoverage(commits []CommitMessage, cov Coverage) ([]CommitMessage, []string) {
+	kept := make(map[string]bool)
+	var fixed []CommitMessage
+	for _, c := range commits {
+		c.Files = slices.DeleteFunc(slices.Clone(c.Files), func(f string) bool {
+			if slices.Contains(cov.Unknown, f) {
+				return true
+			}
+			if !slices.Contains(cov.Duplicates, f) {
+				return false
+			}
+			if kept[f] {
+				return true
+			}
+			kept[f] = true
+			return false
+		})
+		c.Hunks = slices.DeleteFunc(slices.Clone(c.Hunks), func(h FileHunks) bool {
+			return slices.Contains(cov.Duplicates, h.File) || !slices.Contains(c.Files, h.File)
+		})
+		if len(c.Files) > 0 {
+			fixed = append(fixed, c)
+		}
+	}
+	return fixed, slices.Clone(cov.Missing)
+}
@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// warmer is implemented by providers with a cheaper way to load a model
+// than a chat request
+type warmer interface {
+	warm(ctx context.Context) error
+}
+
+// WarmUp loads the model into memory with a tiny request, so the first real
+// generation doesn't pay for it. Meant for local servers, which unload idle
+// models.
+func (c *Client) WarmUp(ctx context.Context) error {
+	if w, ok := c.provider.(warmer); ok {
+		return w.warm(ctx)
+	}
+	return c.Ping(ctx)
+}
+
+type ollamaGenerateRequest struct {
+	Model string `json:"model"`
+}
+
+// warm loads the model without generating: Ollama treats a generate request
+// without a prompt as a load request
+func (p *ollamaProvider) warm(ctx context.Context) error {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := p.post(ctx, "/api/generate", ollamaGenerateRequest{Model: p.model}, &resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("ollama: %s", resp.Error)
+	}
+	return nil
+}
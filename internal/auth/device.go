@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+const (
+	deviceGrantType     = "urn:ietf:params:oauth:grant-type:device_code"
+	defaultPollInterval = 5 * time.Second
+	slowDownStep        = 5 * time.Second // added to the interval on slow_down (RFC 8628 3.5)
+)
+
+// errPending means the user has not approved the device yet
+var errPending = errors.New("authorization pending")
+
+// errSlowDown asks the client to poll less often
+var errSlowDown = errors.New("slow down")
+
+// DeviceCode is the gateway's answer to a device authorization request
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// Flow performs the OAuth 2.0 device authorization grant (RFC 8628)
+type Flow struct {
+	cfg    config.OAuthConfig
+	client *http.Client
+}
+
+func NewFlow(cfg config.OAuthConfig, client *http.Client) *Flow {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Flow{cfg: cfg, client: client}
+}
+
+// Start requests a device and user code to show to the user
+func (f *Flow) Start(ctx context.Context) (*DeviceCode, error) {
+	form := url.Values{"client_id": {f.cfg.ClientID}}
+	if len(f.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(f.cfg.Scopes, " "))
+	}
+
+	body, status, err := f.post(ctx, f.cfg.DeviceAuthURL, form)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("device authorization failed: %s", describeError(body, status))
+	}
+
+	var dc DeviceCode
+	if err := json.Unmarshal(body, &dc); err != nil {
+		return nil, fmt.Errorf("invalid device authorization response: %w", err)
+	}
+	if dc.DeviceCode == "" || dc.UserCode == "" {
+		return nil, fmt.Errorf("invalid device authorization response: missing codes")
+	}
+	return &dc, nil
+}
+
+// Wait polls the token endpoint until the user approves the device, the
+// code expires, or ctx is cancelled
+func (f *Flow) Wait(ctx context.Context, dc *DeviceCode) (*Token, error) {
+	interval := defaultPollInterval
+	if dc.Interval > 0 {
+		interval = time.Duration(dc.Interval) * time.Second
+	}
+	if dc.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(dc.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	form := url.Values{
+		"grant_type":  {deviceGrantType},
+		"device_code": {dc.DeviceCode},
+		"client_id":   {f.cfg.ClientID},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("device code expired, run commity login again")
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		token, err := f.requestToken(ctx, form)
+		switch {
+		case errors.Is(err, errPending):
+		case errors.Is(err, errSlowDown):
+			interval += slowDownStep
+		case err != nil:
+			return nil, err
+		default:
+			return token, nil
+		}
+	}
+}
+
+// Refresh exchanges a refresh token for a new access token. Gateways that
+// don't rotate refresh tokens keep the old one.
+func (f *Flow) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	token, err := f.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {f.cfg.ClientID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("token refresh failed: %w", err)
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func (f *Flow) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	body, status, err := f.post(ctx, f.cfg.TokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTokenResponse(body, status, time.Now())
+}
+
+func (f *Flow) post(ctx context.Context, endpoint string, form url.Values) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, resp.StatusCode, nil
+}
+
+// tokenResponse is a token endpoint reply, successful or not
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// ParseTokenResponse interprets a token endpoint reply received at now
+func ParseTokenResponse(body []byte, status int, now time.Time) (*Token, error) {
+	var resp tokenResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid token response (%d): %w", status, err)
+	}
+
+	switch resp.Error {
+	case "":
+	case "authorization_pending":
+		return nil, errPending
+	case "slow_down":
+		return nil, errSlowDown
+	case "access_denied":
+		return nil, fmt.Errorf("login was denied")
+	case "expired_token":
+		return nil, fmt.Errorf("device code expired, run commity login again")
+	default:
+		return nil, fmt.Errorf("%s", describeError(body, status))
+	}
+
+	if status != http.StatusOK || resp.AccessToken == "" {
+		return nil, fmt.Errorf("%s", describeError(body, status))
+	}
+
+	token := &Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		TokenType:    resp.TokenType,
+	}
+	if resp.ExpiresIn > 0 {
+		token.Expiry = now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// describeError summarizes an OAuth error reply for the user
+func describeError(body []byte, status int) string {
+	var resp tokenResponse
+	if json.Unmarshal(body, &resp) == nil && resp.Error != "" {
+		if resp.ErrorDescription != "" {
+			return fmt.Sprintf("%s: %s", resp.Error, resp.ErrorDescription)
+		}
+		return resp.Error
+	}
+	return fmt.Sprintf("unexpected status %d", status)
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/keychain"
+)
+
+// expirySkew refreshes tokens shortly before they expire so a request
+// doesn't start with a token that dies in flight
+const expirySkew = 30 * time.Second
+
+// Token is an OAuth access token with the refresh token used to renew it
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	Expiry       time.Time `json:"expiry,omitzero"` // zero if the token doesn't expire
+}
+
+// Expired reports whether the token must be refreshed before use at now
+func (t *Token) Expired(now time.Time) bool {
+	return !t.Expiry.IsZero() && now.Add(expirySkew).After(t.Expiry)
+}
+
+// storedToken is a token as kept in the credentials file. The refresh token
+// goes to the OS keychain when there is one and is left out of the file.
+type storedToken struct {
+	Token
+	RefreshInKeychain bool `json:"refresh_in_keychain,omitempty"`
+}
+
+// CredentialsPath returns the file holding tokens from commity login.
+// It is readable by the owner only.
+func CredentialsPath() string {
+	return filepath.Join(xdg.StateHome, "commity", "credentials.json")
+}
+
+// key identifies the gateway a token belongs to
+func key(cfg config.OAuthConfig) string {
+	return cfg.ClientID + "@" + cfg.TokenURL
+}
+
+// keychainAccount is the keychain account of a gateway's refresh token
+func keychainAccount(cfg config.OAuthConfig) string {
+	return "oauth:" + key(cfg)
+}
+
+func loadAll() (map[string]*storedToken, error) {
+	tokens := make(map[string]*storedToken)
+	data, err := os.ReadFile(CredentialsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", CredentialsPath(), err)
+	}
+	return tokens, nil
+}
+
+// Load returns the stored token for a gateway, or nil if not logged in
+func Load(cfg config.OAuthConfig) (*Token, error) {
+	tokens, err := loadAll()
+	if err != nil {
+		return nil, err
+	}
+	stored := tokens[key(cfg)]
+	if stored == nil {
+		return nil, nil
+	}
+	token := stored.Token
+	if stored.RefreshInKeychain {
+		if token.RefreshToken, err = keychain.Get(keychain.Service, keychainAccount(cfg)); err != nil {
+			return nil, fmt.Errorf("reading the refresh token: %w", err)
+		}
+	}
+	return &token, nil
+}
+
+// Save stores the token for a gateway, replacing any previous one. The
+// refresh token is kept in the OS keychain, or in the file when there is
+// no keychain to write to.
+func Save(cfg config.OAuthConfig, token *Token) error {
+	tokens, err := loadAll()
+	if err != nil {
+		return err
+	}
+	stored := &storedToken{Token: *token}
+	if token.RefreshToken != "" && keychain.Set(keychain.Service, keychainAccount(cfg), token.RefreshToken) == nil {
+		stored.RefreshToken = ""
+		stored.RefreshInKeychain = true
+	}
+	tokens[key(cfg)] = stored
+
+	path := CredentialsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a private temp file first so the credentials are never
+	// briefly world-readable or half-written
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".credentials-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Source hands out valid access tokens, refreshing and persisting them
+// when they expire
+type Source struct {
+	cfg   config.OAuthConfig
+	flow  *Flow
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewSource loads the stored token for the gateway
+func NewSource(cfg config.OAuthConfig, client *http.Client) (*Source, error) {
+	token, err := Load(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, fmt.Errorf("not logged in. Run commity login")
+	}
+	return &Source{cfg: cfg, flow: NewFlow(cfg, client), token: token}, nil
+}
+
+// AccessToken returns a token valid for at least the next few seconds
+func (s *Source) AccessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.token.Expired(time.Now()) {
+		return s.token.AccessToken, nil
+	}
+	return s.refresh(ctx)
+}
+
+// Renew replaces an access token the gateway rejected before its expiry,
+// e.g. because it was revoked. A token another request already replaced
+// is not refreshed again.
+func (s *Source) Renew(ctx context.Context, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.AccessToken != rejected {
+		return s.token.AccessToken, nil
+	}
+	return s.refresh(ctx)
+}
+
+// refresh exchanges the refresh token for a new access token and stores
+// it. The caller holds s.mu.
+func (s *Source) refresh(ctx context.Context) (string, error) {
+	if s.token.RefreshToken == "" {
+		return "", fmt.Errorf("login expired. Run commity login")
+	}
+
+	token, err := s.flow.Refresh(ctx, s.token.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("%w. Run commity login", err)
+	}
+	if err := Save(s.cfg, token); err != nil {
+		return "", fmt.Errorf("failed to store refreshed token: %w", err)
+	}
+	s.token = token
+	return token.AccessToken, nil
+}
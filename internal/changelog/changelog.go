@@ -0,0 +1,146 @@
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hluaguo/commity/internal/git"
+)
+
+// Commit is a conventional commit read from the log
+type Commit struct {
+	Hash     string
+	Type     string
+	Scope    string
+	Subject  string
+	Breaking bool
+}
+
+// Section is one Keep a Changelog heading with its commits
+type Section struct {
+	Title   string
+	Commits []Commit
+	Summary string // optional prose written by the AI
+}
+
+// headerPattern matches "type(scope)!: subject", optionally after a gitmoji
+var headerPattern = regexp.MustCompile(`^(?:[^\w\s]\S*\s+)?(\w+)(?:\(([^)]+)\))?(!)?:\s*(.+)$`)
+
+// Section titles in the order Keep a Changelog lists them
+const (
+	Added      = "Added"
+	Changed    = "Changed"
+	Deprecated = "Deprecated"
+	Removed    = "Removed"
+	Fixed      = "Fixed"
+	Security   = "Security"
+	Other      = "Other"
+)
+
+var sectionOrder = []string{Added, Changed, Deprecated, Removed, Fixed, Security, Other}
+
+// sectionOf maps commit types to sections; types not listed are left out
+// unless all commits are requested
+var sectionOf = map[string]string{
+	"feat":      Added,
+	"fix":       Fixed,
+	"perf":      Changed,
+	"refactor":  Changed,
+	"security":  Security,
+	"deprecate": Deprecated,
+}
+
+// Parse reads a conventional commit header and body. It reports false for
+// commits that don't follow the format.
+func Parse(hash, subject, body string) (Commit, bool) {
+	m := headerPattern.FindStringSubmatch(strings.TrimSpace(subject))
+	if m == nil {
+		return Commit{}, false
+	}
+	return Commit{
+		Hash:     hash,
+		Type:     strings.ToLower(m[1]),
+		Scope:    m[2],
+		Subject:  m[4],
+		Breaking: m[3] == "!" || strings.Contains(body, "BREAKING CHANGE:") || strings.Contains(body, "BREAKING-CHANGE:"),
+	}, true
+}
+
+// Group sorts log entries into sections, in Keep a Changelog order and by
+// scope within a section. Breaking changes always go under Changed. With
+// all set, other types and non-conventional commits are listed under Other.
+func Group(entries []git.LogEntry, all bool) []Section {
+	bySection := make(map[string][]Commit)
+	for _, e := range entries {
+		c, ok := Parse(e.Hash, e.Subject, e.Body)
+		if !ok {
+			if all {
+				bySection[Other] = append(bySection[Other], Commit{Hash: e.Hash, Subject: e.Subject})
+			}
+			continue
+		}
+		title, known := sectionOf[c.Type]
+		switch {
+		case c.Breaking:
+			title = Changed
+		case !known && all:
+			title = Other
+		case !known:
+			continue
+		}
+		bySection[title] = append(bySection[title], c)
+	}
+
+	var sections []Section
+	for _, title := range sectionOrder {
+		commits := bySection[title]
+		if len(commits) == 0 {
+			continue
+		}
+		sort.SliceStable(commits, func(i, j int) bool {
+			return commits[i].Scope < commits[j].Scope
+		})
+		sections = append(sections, Section{Title: title, Commits: commits})
+	}
+	return sections
+}
+
+// Line renders a commit as a changelog bullet without the leading dash
+func (c Commit) Line() string {
+	var sb strings.Builder
+	if c.Breaking {
+		sb.WriteString("**BREAKING** ")
+	}
+	if c.Scope != "" {
+		sb.WriteString(fmt.Sprintf("**%s:** ", c.Scope))
+	}
+	sb.WriteString(c.Subject)
+	if len(c.Hash) >= 7 {
+		sb.WriteString(fmt.Sprintf(" (%s)", c.Hash[:7]))
+	}
+	return sb.String()
+}
+
+// Markdown renders a release in Keep a Changelog format. An empty version
+// is rendered as Unreleased, without a date.
+func Markdown(version string, date time.Time, sections []Section) string {
+	var sb strings.Builder
+	if version == "" {
+		sb.WriteString("## [Unreleased]\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("## [%s] - %s\n", strings.TrimPrefix(version, "v"), date.Format("2006-01-02")))
+	}
+	for _, s := range sections {
+		sb.WriteString(fmt.Sprintf("\n### %s\n\n", s.Title))
+		if s.Summary != "" {
+			sb.WriteString(s.Summary + "\n\n")
+		}
+		for _, c := range s.Commits {
+			sb.WriteString("- " + c.Line() + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/hluaguo/commity/internal/keychain"
+)
+
+// ResolveAPIKey returns the API key: api_key or its environment variable
+// when set, otherwise the output of api_key_cmd, otherwise the keychain
+// entry. Load leaves the command and keychain alone so that they only run
+// when a client actually needs the key. An empty key with neither source
+// configured is not an error.
+func (a AIConfig) ResolveAPIKey() (string, error) {
+	if a.APIKey != "" {
+		return a.APIKey, nil
+	}
+	if a.APIKeyCmd != "" {
+		out, err := exec.Command("sh", "-c", a.APIKeyCmd).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				return "", fmt.Errorf("api_key_cmd failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return "", fmt.Errorf("api_key_cmd failed: %w", err)
+		}
+		key := strings.TrimSpace(string(out))
+		if key == "" {
+			return "", fmt.Errorf("api_key_cmd printed no key")
+		}
+		return key, nil
+	}
+	if a.Keychain {
+		key, err := keychain.Get(keychain.Service, a.KeychainAccount())
+		if errors.Is(err, keychain.ErrNotFound) {
+			return "", fmt.Errorf("no API key in the keychain for service %q, account %q", keychain.Service, a.KeychainAccount())
+		}
+		return key, err
+	}
+	return "", nil
+}
+
+// ResolveAPIKeys returns the key of ResolveAPIKey followed by api_keys,
+// without blanks or duplicates
+func (a AIConfig) ResolveAPIKeys() ([]string, error) {
+	key, err := a.ResolveAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, k := range append([]string{key}, a.APIKeys...) {
+		if k = strings.TrimSpace(k); k != "" && !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+// KeychainAccount returns the keychain account holding the provider's key,
+// stored under the "commity" service
+func (a AIConfig) KeychainAccount() string {
+	if a.Provider == "" {
+		return ProviderOpenAI
+	}
+	return a.Provider
+}
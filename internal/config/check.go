@@ -0,0 +1,95 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Problems lists what is wrong with the config file at path, as messages
+// that say how to fix it: keys commity doesn't know (usually typos, which
+// are otherwise silently ignored) and values it can't use. A missing file
+// has no problems; a file that doesn't parse is an error.
+func Problems(path string) ([]string, error) {
+	if path == "" {
+		path = ConfigPath()
+	}
+	cfg := Default()
+	md, err := toml.DecodeFile(path, cfg)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, k := range md.Undecoded() {
+		problems = append(problems, fmt.Sprintf("unknown setting %q is ignored; check its spelling and table", k.String()))
+	}
+	cfg.AI.applyEnv()
+	return append(problems, cfg.Problems()...), nil
+}
+
+// Problems lists settings of the config that commity can't use
+func (c *Config) Problems() []string {
+	var problems []string
+	ai := c.AI
+	if !slices.Contains(Providers(), ai.Provider) {
+		problems = append(problems, fmt.Sprintf("ai.provider %q is not supported; use one of %v", ai.Provider, Providers()))
+	}
+	switch ai.Provider {
+	case ProviderOpenAI, ProviderOllama:
+		if ai.Model == "" {
+			problems = append(problems, "ai.model is not set; set it (or OPENAI_MODEL) to the model to use, e.g. gpt-4o-mini")
+		}
+	case ProviderAzure:
+		if ai.Model == "" && ai.Deployment == "" {
+			problems = append(problems, "ai.deployment is not set; set it to your Azure OpenAI deployment name")
+		}
+	}
+	if ai.Secrets != SecretsRedact && ai.Secrets != SecretsConfirm && ai.Secrets != SecretsOff {
+		problems = append(problems, fmt.Sprintf("ai.secrets %q is not one of %q, %q or %q", ai.Secrets, SecretsRedact, SecretsConfirm, SecretsOff))
+	}
+	if ai.StructuredOutputs != StructuredAuto && ai.StructuredOutputs != StructuredOn && ai.StructuredOutputs != StructuredOff {
+		problems = append(problems, fmt.Sprintf("ai.structured_outputs %q is not one of %q, %q or %q", ai.StructuredOutputs, StructuredAuto, StructuredOn, StructuredOff))
+	}
+	if ai.KeyRotation != RotateFailover && ai.KeyRotation != RotateRoundRobin {
+		problems = append(problems, fmt.Sprintf("ai.key_rotation %q is not one of %q or %q", ai.KeyRotation, RotateFailover, RotateRoundRobin))
+	}
+	if p := c.Commit.Push; p != PushAsk && p != PushAlways && p != PushNever {
+		problems = append(problems, fmt.Sprintf("commit.push %q is not one of %q, %q or %q", p, PushAsk, PushAlways, PushNever))
+	}
+	if ai.APIKey != "" && ai.APIKeyCmd != "" {
+		problems = append(problems, "ai.api_key and ai.api_key_cmd are both set; api_key_cmd is never run")
+	}
+	keys := make(map[string]string)
+	for _, action := range slices.Sorted(maps.Keys(c.UI.Keys)) {
+		k := c.UI.Keys[action]
+		switch {
+		case !slices.Contains(KeyActions(), action):
+			problems = append(problems, fmt.Sprintf("ui.keys.%s is not an action; use one of %v", action, KeyActions()))
+		case k == "":
+			problems = append(problems, fmt.Sprintf("ui.keys.%s is empty; remove it to keep the default key", action))
+		case keys[k] != "":
+			problems = append(problems, fmt.Sprintf("ui.keys.%s and ui.keys.%s are both %q", keys[k], action, k))
+		default:
+			keys[k] = action
+		}
+	}
+	for name := range c.Profiles {
+		if _, err := c.WithProfile(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	for _, r := range c.ProfileRules {
+		if _, ok := c.Profiles[r.Profile]; !ok {
+			problems = append(problems, fmt.Sprintf("profile rule selects unknown profile %q", r.Profile))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commitlintFiles are the commitlint config files looked for, in
+// commitlint's order. package.json is checked for a "commitlint" key.
+var commitlintFiles = []string{
+	"package.json",
+	".commitlintrc", ".commitlintrc.json", ".commitlintrc.yaml", ".commitlintrc.yml",
+	".commitlintrc.js", ".commitlintrc.cjs", ".commitlintrc.mjs", ".commitlintrc.ts",
+	"commitlint.config.js", "commitlint.config.cjs", "commitlint.config.mjs", "commitlint.config.ts",
+}
+
+// Commitlint is the subset of a repository's commitlint rules that commity
+// follows, so its messages pass the team's commitlint check
+type Commitlint struct {
+	File            string   // config file the rules were read from
+	Types           []string // type-enum
+	Scopes          []string // scope-enum
+	HeaderMaxLength int      // header-max-length
+}
+
+// conventionalRules are the rules of @commitlint/config-conventional that
+// commity follows, used when a config extends it
+var conventionalRules = Commitlint{
+	Types:           []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"},
+	HeaderMaxLength: 100,
+}
+
+// commitlintConfig is the shape of JSON and YAML commitlint configs
+type commitlintConfig struct {
+	Extends any              `yaml:"extends"` // a string or a list
+	Rules   map[string][]any `yaml:"rules"`
+}
+
+// LoadCommitlint reads the commitlint config of the repository at dir. It
+// returns a zero Commitlint when there is none.
+func LoadCommitlint(dir string) (Commitlint, error) {
+	for _, name := range commitlintFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Commitlint{}, err
+		}
+
+		var cfg commitlintConfig
+		switch {
+		case name == "package.json":
+			var pkg struct {
+				Commitlint *json.RawMessage `json:"commitlint"`
+			}
+			if err := json.Unmarshal(data, &pkg); err != nil || pkg.Commitlint == nil {
+				continue
+			}
+			err = yaml.Unmarshal(*pkg.Commitlint, &cfg)
+		case isScript(name):
+			cfg = scriptCommitlint(string(data))
+		default:
+			// YAML is a superset of JSON
+			err = yaml.Unmarshal(data, &cfg)
+		}
+		if err != nil {
+			return Commitlint{}, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		lint := cfg.resolve()
+		lint.File = name
+		return lint, nil
+	}
+	return Commitlint{}, nil
+}
+
+// isScript reports whether a commitlint config file is JavaScript or TypeScript
+func isScript(name string) bool {
+	switch filepath.Ext(name) {
+	case ".js", ".cjs", ".mjs", ".ts":
+		return true
+	}
+	return false
+}
+
+// resolve applies the rules over those of an extended config-conventional
+func (cfg commitlintConfig) resolve() Commitlint {
+	var lint Commitlint
+	var extends []string
+	switch e := cfg.Extends.(type) {
+	case string:
+		extends = []string{e}
+	case []any:
+		for _, v := range e {
+			if s, ok := v.(string); ok {
+				extends = append(extends, s)
+			}
+		}
+	}
+	if slices.ContainsFunc(extends, func(e string) bool { return strings.Contains(e, "config-conventional") }) {
+		lint = conventionalRules
+		lint.Types = slices.Clone(lint.Types)
+	}
+
+	if value, on := ruleValue(cfg.Rules, "type-enum"); on {
+		lint.Types = stringList(value)
+	} else if isDisabled(cfg.Rules, "type-enum") {
+		lint.Types = nil
+	}
+	if value, on := ruleValue(cfg.Rules, "scope-enum"); on {
+		lint.Scopes = stringList(value)
+	} else if isDisabled(cfg.Rules, "scope-enum") {
+		lint.Scopes = nil
+	}
+	if value, on := ruleValue(cfg.Rules, "header-max-length"); on {
+		if n, ok := value.(int); ok {
+			lint.HeaderMaxLength = n
+		}
+	} else if isDisabled(cfg.Rules, "header-max-length") {
+		lint.HeaderMaxLength = 0
+	}
+	return lint
+}
+
+// ruleValue returns the value of an enabled "always" rule, written as
+// [level, applicability, value]
+func ruleValue(rules map[string][]any, name string) (any, bool) {
+	rule, ok := rules[name]
+	if !ok || len(rule) < 3 || isDisabled(rules, name) || rule[1] != "always" {
+		return nil, false
+	}
+	return rule[2], true
+}
+
+// isDisabled reports whether a rule is present and turned off with level 0
+func isDisabled(rules map[string][]any, name string) bool {
+	rule, ok := rules[name]
+	if !ok || len(rule) == 0 {
+		return false
+	}
+	switch level := rule[0].(type) {
+	case int:
+		return level == 0
+	case string:
+		// RuleConfigSeverity.Disabled in TypeScript configs
+		return strings.HasSuffix(level, "Disabled")
+	}
+	return false
+}
+
+// stringList returns the strings of a YAML list
+func stringList(value any) []string {
+	list, _ := value.([]any)
+	var out []string
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+var (
+	scriptExtends = regexp.MustCompile(`extends\s*:\s*(\[[^\]]*\]|['"][^'"]*['"])`)
+	scriptRule    = regexp.MustCompile(`['"]?((?:type|scope)-enum|header-max-length)['"]?\s*:\s*\[`)
+)
+
+// scriptCommitlint picks the extends and rules this subset needs out of a
+// JavaScript or TypeScript config without running it. Rules computed at
+// runtime are not seen.
+func scriptCommitlint(src string) commitlintConfig {
+	cfg := commitlintConfig{Rules: make(map[string][]any)}
+	if m := scriptExtends.FindStringSubmatch(src); m != nil {
+		yaml.Unmarshal([]byte(m[1]), &cfg.Extends)
+	}
+	for _, m := range scriptRule.FindAllStringSubmatchIndex(src, -1) {
+		name := src[m[2]:m[3]]
+		literal := bracketed(src[m[1]-1:])
+		// JS array literals of numbers and strings are YAML flow sequences
+		var rule []any
+		if yaml.Unmarshal([]byte(literal), &rule) == nil {
+			cfg.Rules[name] = rule
+		}
+	}
+	return cfg
+}
+
+// bracketed returns the [...] at the start of s, nested brackets included
+func bracketed(s string) string {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return s[:i+1]
+			}
+		}
+	}
+	return s
+}
@@ -1,39 +1,379 @@
 package config
 
 import (
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
 
 	"github.com/BurntSushi/toml"
 	"github.com/adrg/xdg"
 )
 
 type Config struct {
-	General GeneralConfig `toml:"general"`
-	AI      AIConfig      `toml:"ai"`
-	Commit  CommitConfig  `toml:"commit"`
-	UI      UIConfig      `toml:"ui"`
+	General   GeneralConfig   `toml:"general"`
+	AI        AIConfig        `toml:"ai"`
+	Commit    CommitConfig    `toml:"commit"`
+	UI        UIConfig        `toml:"ui"`
+	Privacy   PrivacyConfig   `toml:"privacy"`
+	Hooks     HooksConfig     `toml:"hooks"`
+	Today     TodayConfig     `toml:"today"`
+	Branch    BranchConfig    `toml:"branch"`
+	PR        PRConfig        `toml:"pr"`
+	Telemetry TelemetryConfig `toml:"telemetry"`
+	Compare   CompareConfig   `toml:"compare"`
+
+	Policies []HostPolicy `toml:"host_policies"` // per-remote-host overrides
+
+	Profiles     map[string]map[string]any `toml:"profiles"`      // named config overlays
+	ProfileRules []ProfileRule             `toml:"profile_rules"` // automatic profile selection
+
+	Repo       RepoConfig `toml:"-"` // the repository's team settings, see WithRepo
+	Commitlint Commitlint `toml:"-"` // the repository's commitlint rules, see CommitRules
+
+	profile string // applied profile, see WithProfile
+}
+
+// PrivacyConfig lists files that may be committed but whose contents are
+// never sent to the AI
+type PrivacyConfig struct {
+	NeverSend []string `toml:"never_send"` // gitignore-style patterns, e.g. "secrets/**", "*.pem"
+}
+
+// HooksConfig holds commands run around commits. Commands are text/template
+// strings expanded with the created commit, e.g. "notify.sh {{.Hash}}".
+type HooksConfig struct {
+	Post    []string `toml:"post"`    // run after each commit
+	Webhook string   `toml:"webhook"` // Slack or Discord URL notified after a session
+}
+
+// TodayConfig lists the repositories `commity today` reports on
+type TodayConfig struct {
+	Repos []string `toml:"repos"` // paths, "~/" expanded; defaults to the current repository
+}
+
+// BranchConfig controls the branch offered when committing on a protected
+// branch such as main
+type BranchConfig struct {
+	Protected []string `toml:"protected"` // branches that trigger the offer (empty disables it)
+	Prefix    string   `toml:"prefix"`    // e.g. "feat/"; "{type}" is replaced by the commit type
+}
+
+// Protects reports whether committing on branch should offer a new branch
+func (b BranchConfig) Protects(branch string) bool {
+	return slices.Contains(b.Protected, branch)
+}
+
+// CompareConfig names the second model of `commity --compare`. Empty fields
+// are taken from [ai] when the provider is the same.
+type CompareConfig struct {
+	Provider string `toml:"provider"`
+	Model    string `toml:"model"`
+	BaseURL  string `toml:"base_url"`
+	APIKey   string `toml:"api_key"`
+}
+
+// CompareAI returns the settings of the comparison model, with host
+// policies applied like EffectiveAI
+func (c *Config) CompareAI(host string) (AIConfig, error) {
+	cmp := c.Compare
+	if cmp.Model == "" && cmp.Provider == "" {
+		return AIConfig{}, fmt.Errorf("no model to compare with. Set model (and optionally provider) under [compare] in %s", ConfigPath())
+	}
+	alt := *c
+	if cmp.Provider != "" && cmp.Provider != c.AI.Provider {
+		// Another provider shares no endpoint, key or model with [ai]
+		alt.AI.Provider = cmp.Provider
+		alt.AI.BaseURL, alt.AI.APIKey, alt.AI.APIKeyCmd, alt.AI.Model, alt.AI.Deployment = "", "", "", "", ""
+		alt.AI.APIKeys = nil
+		alt.AI.applyEnv()
+	}
+	if cmp.Model != "" {
+		alt.AI.Model = cmp.Model
+	}
+	if cmp.BaseURL != "" {
+		alt.AI.BaseURL = cmp.BaseURL
+	}
+	if cmp.APIKey != "" {
+		alt.AI.APIKey, alt.AI.APIKeys = cmp.APIKey, nil
+	}
+	return alt.EffectiveAI(host)
+}
+
+// TelemetryConfig controls the opt-in anonymous usage counters
+type TelemetryConfig struct {
+	Enabled  bool   `toml:"enabled"`  // off unless turned on with `commity telemetry enable`
+	Endpoint string `toml:"endpoint"` // where reports are sent; counters stay local when empty
+}
+
+// PRConfig holds settings for `commity pr`
+type PRConfig struct {
+	Base string `toml:"base"` // branch pull requests target (default: origin's default branch)
+	Fork string `toml:"fork"` // remote of your fork; enables "commit & push" on the confirm screen
 }
 
 type UIConfig struct {
-	Theme string `toml:"theme"` // tokyonight, dracula, catppuccin, nord
+	Theme string            `toml:"theme"` // tokyonight, dracula, catppuccin, nord, solarized-light, github-light
+	Keys  map[string]string `toml:"keys"`  // action to key, see KeyActions
+}
+
+// Remappable TUI actions, the keys of [ui.keys]
+const (
+	KeyQuit       = "quit"
+	KeySettings   = "settings"
+	KeyEdit       = "edit"
+	KeyRegenerate = "regenerate"
+	KeySelectAll  = "select-all"
+	KeyDiff       = "diff-preview"
+	KeySkipHooks  = "skip-hooks"
+)
+
+// KeyActions returns the names of the actions [ui.keys] can remap
+func KeyActions() []string {
+	return []string{KeyQuit, KeySettings, KeyEdit, KeyRegenerate, KeySelectAll, KeyDiff, KeySkipHooks}
 }
 
 type GeneralConfig struct {
 	Mode           string `toml:"mode"`            // "auto" or "manual"
 	SplitThreshold int    `toml:"split_threshold"` // max files before suggesting split
+	PairTests      bool   `toml:"pair_tests"`      // also select the changed test of a selected file, and vice versa
+	Split          bool   `toml:"split"`           // let the AI split unrelated changes into several commits
+}
+
+// Supported AI providers
+const (
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+	ProviderGemini = "gemini"
+	ProviderAzure  = "azure"
+)
+
+// Providers returns the names of all supported AI providers.
+func Providers() []string {
+	return []string{ProviderOpenAI, ProviderOllama, ProviderGemini, ProviderAzure}
 }
 
+// Handling of secrets detected in diffs sent to remote models
+const (
+	SecretsRedact  = "redact"  // replace them with placeholders
+	SecretsConfirm = "confirm" // ask before sending
+	SecretsOff     = "off"     // send diffs unchanged
+)
+
+// Use of OpenAI structured outputs instead of function calling
+const (
+	StructuredAuto = "auto" // when the model is known to support them
+	StructuredOn   = "on"
+	StructuredOff  = "off"
+)
+
+// Use of several API keys, see AIConfig.APIKeys
+const (
+	RotateFailover   = "failover"    // stay on a key until it hits its quota
+	RotateRoundRobin = "round-robin" // take turns, passing over keys that hit their quota
+)
+
 type AIConfig struct {
-	Model              string `toml:"model"`
-	BaseURL            string `toml:"base_url"`
-	APIKey             string `toml:"api_key"`
-	CustomInstructions string `toml:"custom_instructions"` // custom prompt additions
+	Provider           string   `toml:"provider"` // "openai", "ollama", "gemini" or "azure"
+	Model              string   `toml:"model"`
+	BaseURL            string   `toml:"base_url"`
+	APIKey             string   `toml:"api_key"`
+	APIKeyCmd          string   `toml:"api_key_cmd"`         // command printing the key, run when a client is created
+	APIKeys            []string `toml:"api_keys"`            // more keys, moved to when one hits its quota (429)
+	KeyRotation        string   `toml:"key_rotation"`        // "failover" or "round-robin" across the keys
+	Keychain           bool     `toml:"keychain"`            // read the key from the OS keychain, see KeychainAccount
+	CustomInstructions string   `toml:"custom_instructions"` // custom prompt additions
+	APIVersion         string   `toml:"api_version"`         // Azure OpenAI api-version
+	Deployment         string   `toml:"deployment"`          // Azure OpenAI deployment name
+	InputCost          float64  `toml:"input_cost"`          // USD per 1M prompt tokens, for estimates
+	OutputCost         float64  `toml:"output_cost"`         // USD per 1M completion tokens, for estimates
+	TimeoutSeconds     int      `toml:"timeout_seconds"`     // per-attempt request timeout
+	MaxRetries         int      `toml:"max_retries"`         // retries on 429/5xx/network errors
+	BackoffSeconds     float64  `toml:"backoff_seconds"`     // initial retry delay, doubled per attempt
+	MaxDiffTokens      int      `toml:"max_diff_tokens"`     // cap on diff tokens (0 = model context window)
+	Exclude            []string `toml:"exclude"`             // files whose diff is summarized in the prompt
+	DeadlineSeconds    int      `toml:"deadline_seconds"`    // bound on total generation time (0 = none)
+	Secrets            string   `toml:"secrets"`             // "redact", "confirm" or "off"
+	StructuredOutputs  string   `toml:"structured_outputs"`  // "auto", "on" or "off"
+	MinimizeDiff       bool     `toml:"minimize_diff"`       // drop extra context, moves and whitespace-only edits from prompts
+	ContextLines       int      `toml:"context_lines"`       // unchanged lines kept around changes when minimizing
+	WarmUp             bool     `toml:"warm_up"`             // load local models when the TUI starts
+	StyleExamples      int      `toml:"style_examples"`      // recent commit subjects shown as style examples (0 = none)
+	SystemPromptFile   string   `toml:"system_prompt_file"`  // text/template replacing the system prompt, see ai.PromptData
+	PromptFile         string   `toml:"prompt_file"`         // text/template replacing the user prompt, see ai.PromptData
+	Temperature        *float64 `toml:"temperature"`         // sampling temperature (unset = provider default)
+	TopP               *float64 `toml:"top_p"`               // nucleus sampling (unset = provider default)
+	MaxTokens          int      `toml:"max_tokens"`          // cap on reply tokens (0 = provider default)
+
+	ExtraParams map[string]any    `toml:"extra_params"` // added to each chat request body, e.g. for LiteLLM or vLLM
+	Models      map[string]string `toml:"models"`       // aliases offered by the model switcher, e.g. { fast = "gpt-4o-mini" }
+
+	OAuth OAuthConfig `toml:"oauth"` // device flow login instead of an API key
+}
+
+// OAuthConfig describes a hosted gateway that supports the OAuth 2.0
+// device authorization grant, used by `commity login`
+type OAuthConfig struct {
+	ClientID      string   `toml:"client_id"`
+	DeviceAuthURL string   `toml:"device_auth_url"`
+	TokenURL      string   `toml:"token_url"`
+	Scopes        []string `toml:"scopes"`
+}
+
+// Enabled reports whether enough is configured to log in
+func (o OAuthConfig) Enabled() bool {
+	return o.ClientID != "" && o.DeviceAuthURL != "" && o.TokenURL != ""
 }
 
 type CommitConfig struct {
-	Conventional bool     `toml:"conventional"`
-	Types        []string `toml:"types"`
+	Conventional     bool              `toml:"conventional"`
+	Types            []CommitType      `toml:"types"`              // names, or {name, desc} tables
+	SubjectMaxLength int               `toml:"subject_max_length"` // first-line limit (0 = 72)
+	SubjectPrefix    string            `toml:"subject_prefix"`     // required first-line prefix, e.g. "[PROJ-123] "
+	Signoff          bool              `toml:"signoff"`            // add Signed-off-by with the git identity (DCO)
+	Sign             bool              `toml:"sign"`               // sign commits (-S); git's commit.gpgsign is honored either way
+	NoVerify         bool              `toml:"no_verify"`          // skip pre-commit and commit-msg hooks (--no-verify)
+	CoAuthors        []string          `toml:"co_authors"`         // "Name <email>", added as Co-authored-by
+	RefPattern       string            `toml:"ref_pattern"`        // regexp finding a ticket in the branch name, e.g. "[A-Z]+-[0-9]+"
+	RefTrailer       string            `toml:"ref_trailer"`        // trailer key for the ticket (default "Refs")
+	RefFooter        bool              `toml:"ref_footer"`         // add the ticket as a trailer
+	RefPrefix        string            `toml:"ref_prefix"`         // subject prefix for the ticket, e.g. "{ref}: "; empty leaves the subject alone
+	Trailers         []string          `toml:"trailers"`           // templates such as "Branch: {{.Branch}}", see TrailerData
+	RequireBodyFor   []string          `toml:"require_body_for"`   // types that must explain why in the body, e.g. ["feat", "fix"]
+	Scopes           map[string]string `toml:"scopes"`             // path glob to scope for monorepos, e.g. "pkg/api/**" = "api"
+	ScopeEnum        []string          `toml:"-"`                  // scopes allowed by commitlint, see CommitRules
+	Language         string            `toml:"language"`           // language of commit messages, e.g. "German"; empty means English
+	Emoji            bool              `toml:"emoji"`              // put a gitmoji before the type
+	EmojiMap         map[string]string `toml:"emoji_map"`          // overrides DefaultEmoji; "" drops a type's emoji
+	Push             string            `toml:"push"`               // after the last commit: "ask", "always" or "never"
+}
+
+// Push settings, see CommitConfig.Push
+const (
+	PushAsk    = "ask"
+	PushAlways = "always"
+	PushNever  = "never"
+)
+
+// DefaultEmoji maps commit types to gitmoji. Keys that aren't types offer
+// the model more specific choices.
+var DefaultEmoji = map[string]string{
+	"feat":     "✨",
+	"fix":      "🐛",
+	"docs":     "📝",
+	"style":    "🎨",
+	"refactor": "♻️",
+	"perf":     "⚡️",
+	"test":     "✅",
+	"build":    "📦️",
+	"ci":       "👷",
+	"chore":    "🔧",
+	"revert":   "⏪️",
+	"security": "🔒️",
+	"hotfix":   "🚑️",
+	"deps":     "⬆️",
+	"remove":   "🔥",
+}
+
+// EmojiFor returns the gitmoji table with emoji_map applied, or nil when
+// emoji are off
+func (c CommitConfig) EmojiFor() map[string]string {
+	if !c.Emoji {
+		return nil
+	}
+	table := maps.Clone(DefaultEmoji)
+	for t, e := range c.EmojiMap {
+		if e == "" {
+			delete(table, t)
+		} else {
+			table[t] = e
+		}
+	}
+	return table
+}
+
+// Ref returns the ticket ID ref_pattern finds in branch, or "" when there
+// is none or the pattern is invalid
+func (c CommitConfig) Ref(branch string) string {
+	if c.RefPattern == "" {
+		return ""
+	}
+	re, err := regexp.Compile(c.RefPattern)
+	if err != nil {
+		return ""
+	}
+	return re.FindString(branch)
+}
+
+// SubjectPrefixFor returns the required subject prefix with the ticket of
+// branch added when ref_prefix is set
+func (c CommitConfig) SubjectPrefixFor(branch string) string {
+	prefix := c.SubjectPrefix
+	if ref := c.Ref(branch); ref != "" && c.RefPrefix != "" {
+		prefix += strings.ReplaceAll(c.RefPrefix, "{ref}", ref)
+	}
+	return prefix
+}
+
+// TrailerData holds the fields available to trailer templates
+type TrailerData struct {
+	Branch   string
+	Session  string // ID shared by the commits of one commity run
+	Repo     string // repository directory name
+	Remote   string // origin as host/owner/repo
+	Identity string // committer as "Name <email>"
+}
+
+// TrailersFor returns the trailers every commit gets: the ticket found in the
+// branch, co-authors, the configured templates and the sign-off. An invalid
+// ref pattern adds no ticket; templates that fail or render an empty value
+// are skipped.
+func (c CommitConfig) TrailersFor(d TrailerData) []string {
+	var trailers []string
+	if ref := c.Ref(d.Branch); ref != "" && c.RefFooter {
+		key := c.RefTrailer
+		if key == "" {
+			key = "Refs"
+		}
+		trailers = append(trailers, key+": "+ref)
+	}
+	for _, a := range c.CoAuthors {
+		if a = strings.TrimSpace(a); a != "" {
+			trailers = append(trailers, "Co-authored-by: "+a)
+		}
+	}
+	for _, t := range c.Trailers {
+		if rendered, ok := renderTrailer(t, d); ok {
+			trailers = append(trailers, rendered)
+		}
+	}
+	if c.Signoff && d.Identity != "" {
+		trailers = append(trailers, "Signed-off-by: "+d.Identity)
+	}
+	return trailers
+}
+
+// renderTrailer expands a "Key: template" trailer, reporting false when it
+// is malformed or its value is empty
+func renderTrailer(trailer string, d TrailerData) (string, bool) {
+	tmpl, err := template.New("trailer").Option("missingkey=error").Parse(trailer)
+	if err != nil {
+		return "", false
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, d); err != nil {
+		return "", false
+	}
+	key, value, ok := strings.Cut(sb.String(), ":")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" || value == "" || strings.ContainsAny(key, " \n") {
+		return "", false
+	}
+	return key + ": " + value, true
 }
 
 // ConfigPath returns the path to the config file
@@ -52,19 +392,36 @@ func Default() *Config {
 		General: GeneralConfig{
 			Mode:           "auto",
 			SplitThreshold: 5,
+			PairTests:      true,
+			Split:          true,
 		},
 		AI: AIConfig{
-			Model:   "",
-			BaseURL: "",
-			APIKey:  "",
+			Provider:          ProviderOpenAI,
+			Model:             "",
+			BaseURL:           "",
+			APIKey:            "",
+			KeyRotation:       RotateFailover,
+			TimeoutSeconds:    60,
+			MaxRetries:        2,
+			BackoffSeconds:    1,
+			Exclude:           []string{"*.lock", "package-lock.json", "pnpm-lock.yaml", "go.sum"},
+			Secrets:           SecretsRedact,
+			StructuredOutputs: StructuredAuto,
+			ContextLines:      1,
+			StyleExamples:     10,
 		},
 		Commit: CommitConfig{
 			Conventional: true,
-			Types:        []string{"feat", "fix", "docs", "style", "refactor", "test", "chore"},
+			RefFooter:    true,
+			Push:         PushAsk,
+			Types:        []CommitType{{Name: "feat"}, {Name: "fix"}, {Name: "docs"}, {Name: "style"}, {Name: "refactor"}, {Name: "test"}, {Name: "chore"}},
 		},
 		UI: UIConfig{
 			Theme: "tokyonight",
 		},
+		Branch: BranchConfig{
+			Protected: []string{"main", "master"},
+		},
 	}
 }
 
@@ -84,21 +441,48 @@ func Load(path string) (*Config, error) {
 	}
 
 	// Environment variables take priority over config file
-	if v := os.Getenv("OPENAI_API_KEY"); v != "" {
-		cfg.AI.APIKey = v
-	}
-	if v := os.Getenv("OPENAI_BASE_URL"); v != "" {
-		cfg.AI.BaseURL = v
-	}
-	if v := os.Getenv("OPENAI_MODEL"); v != "" {
-		cfg.AI.Model = v
-	}
+	cfg.AI.applyEnv()
 
 	return cfg, nil
 }
 
-// Save writes the config to file
+// applyEnv overrides settings with the environment variables of the provider
+func (a *AIConfig) applyEnv() {
+	switch a.Provider {
+	case ProviderGemini:
+		if v := os.Getenv("GEMINI_API_KEY"); v != "" {
+			a.APIKey = v
+		}
+	case ProviderAzure:
+		if v := os.Getenv("AZURE_OPENAI_API_KEY"); v != "" {
+			a.APIKey = v
+		}
+		if v := os.Getenv("AZURE_OPENAI_ENDPOINT"); v != "" {
+			a.BaseURL = v
+		}
+	default:
+		if v := os.Getenv("OPENAI_API_KEY"); v != "" {
+			a.APIKey = v
+		}
+		if v := os.Getenv("OPENAI_BASE_URL"); v != "" {
+			a.BaseURL = v
+		}
+		if v := os.Getenv("OPENAI_MODEL"); v != "" {
+			a.Model = v
+		}
+	}
+}
+
+// Save writes the config to file. A config with a profile applied can't be
+// saved, as that would copy the profile's settings into the base config.
 func (c *Config) Save() error {
+	if c.profile != "" {
+		return fmt.Errorf("profile %q is active; edit %s to change its settings", c.profile, ConfigPath())
+	}
+	// Saving would copy the team's settings into the user's own config
+	if c.Repo.overrides() {
+		return fmt.Errorf("%s of this repository overrides settings; edit %s to change yours", c.Repo.File, ConfigPath())
+	}
 	path := ConfigPath()
 
 	// Create directory if not exists
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Setting is a config value addressed by its dotted key, e.g. "ai.model"
+type Setting struct {
+	Key   string
+	Value any
+}
+
+// Settings returns every value of the config, sorted by key. Tables are
+// flattened into dotted keys; arrays are kept whole.
+func (c *Config) Settings() ([]Setting, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if _, err := toml.Decode(buf.String(), &tree); err != nil {
+		return nil, err
+	}
+	var settings []Setting
+	flatten("", tree, &settings)
+	return settings, nil
+}
+
+func flatten(prefix string, tree map[string]any, out *[]Setting) {
+	for _, k := range slices.Sorted(maps.Keys(tree)) {
+		key := prefix + k
+		if sub, ok := tree[k].(map[string]any); ok {
+			flatten(key+".", sub, out)
+			continue
+		}
+		*out = append(*out, Setting{Key: key, Value: tree[k]})
+	}
+}
+
+// Lookup returns the value of the setting at the dotted key, which may also
+// name a whole table
+func (c *Config) Lookup(key string) (any, bool) {
+	settings, err := c.Settings()
+	if err != nil {
+		return nil, false
+	}
+	table := make(map[string]any)
+	for _, s := range settings {
+		if s.Key == key {
+			return s.Value, true
+		}
+		if rest, ok := strings.CutPrefix(s.Key, key+"."); ok {
+			table[rest] = s.Value
+		}
+	}
+	if len(table) > 0 {
+		return table, true
+	}
+	return nil, false
+}
+
+// FormatValue renders a setting as a TOML value, using inline tables for
+// tables
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = FormatValue(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case []map[string]any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = FormatValue(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]any:
+		parts := make([]string, 0, len(v))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			parts = append(parts, formatKey(k)+" = "+FormatValue(v[k]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(map[string]any{"v": v}); err != nil {
+		return fmt.Sprint(v)
+	}
+	_, value, _ := strings.Cut(strings.TrimSpace(buf.String()), " = ")
+	return value
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func formatKey(k string) string {
+	if bareKey.MatchString(k) {
+		return k
+	}
+	return FormatValue(k)
+}
+
+// SetValue returns the TOML document data with the dotted key set to value,
+// changing only the lines of that key so comments and settings commity
+// doesn't know stay as they are. The value is taken as a TOML literal
+// (true, 3, ["a", "b"]) when that fits the setting, and as a string
+// otherwise. Keys that aren't settings are refused.
+func SetValue(data []byte, key, value string) ([]byte, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+
+	var candidates []string
+	var literal map[string]any
+	if _, err := toml.Decode("v = "+value, &literal); err == nil {
+		candidates = append(candidates, value)
+	}
+	if _, isString := literal["v"].(string); !isString {
+		candidates = append(candidates, FormatValue(value))
+	}
+
+	var firstErr error
+	for _, v := range candidates {
+		out := setLine(string(data), key, v)
+		err := checkSetting(out, key)
+		if err == nil {
+			return []byte(out), nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// checkSetting verifies that doc decodes into a config and defines key
+func checkSetting(doc, key string) error {
+	md, err := toml.Decode(doc, Default())
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	for _, k := range md.Undecoded() {
+		if k.String() == key {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return nil
+}
+
+var (
+	tableHeader = regexp.MustCompile(`^\s*\[\s*([^\[\]]+?)\s*\]\s*(#.*)?$`)
+	arrayHeader = regexp.MustCompile(`^\s*\[\[`)
+	keyLine     = regexp.MustCompile(`^(\s*)([A-Za-z0-9_\-."' ]+?)\s*=`)
+)
+
+// setLine replaces the value of key in doc, or adds the key to its table,
+// creating the table at the end when there is none
+func setLine(doc, key, value string) string {
+	lines := strings.Split(doc, "\n")
+	table, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		table, name = key[:i], key[i+1:]
+	}
+
+	current := ""
+	tableEnd := -1 // last line of the key's table, when it has one
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if arrayHeader.MatchString(line) {
+			current = "\x00" // keys of arrays of tables can't be addressed
+			continue
+		}
+		if m := tableHeader.FindStringSubmatch(line); m != nil {
+			current = normalizeKey(m[1])
+			if current == table {
+				tableEnd = i
+			}
+			continue
+		}
+		// Comments may introduce the next table, so new keys go after
+		// the last value
+		if trimmed := strings.TrimSpace(line); current == table && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			tableEnd = i
+		}
+		m := keyLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		full := normalizeKey(m[2])
+		if current != "" {
+			full = current + "." + full
+		}
+		if current == "\x00" || full != key {
+			continue
+		}
+		end := valueEnd(lines, i)
+		replaced := m[0] + " " + value + trailingComment(lines[end], len(m[0])+1+len(value))
+		return strings.Join(slices.Replace(lines, i, end+1, replaced), "\n")
+	}
+
+	entry := formatKey(name) + " = " + value
+	if tableEnd >= 0 || table == "" {
+		return strings.Join(slices.Insert(lines, tableEnd+1, entry), "\n")
+	}
+
+	doc = strings.TrimRight(doc, "\n")
+	if doc != "" {
+		doc += "\n\n"
+	}
+	return doc + "[" + table + "]\n" + entry + "\n"
+}
+
+// normalizeKey turns a possibly quoted, spaced dotted key into a.b.c
+func normalizeKey(k string) string {
+	parts := strings.Split(k, ".")
+	for i, p := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(p), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// valueEnd returns the last line of the value starting on line start, which
+// spans several lines for multi-line arrays and strings
+func valueEnd(lines []string, start int) int {
+	for end := start; end < len(lines); end++ {
+		var v map[string]any
+		if _, err := toml.Decode(strings.Join(lines[start:end+1], "\n"), &v); err == nil {
+			return end
+		}
+	}
+	return start
+}
+
+// trailingComment returns the comment ending line, aligned at its column
+// when the new value leaves room, so that replacing a value keeps it
+func trailingComment(line string, width int) string {
+	for i := strings.IndexByte(line, '#'); i >= 0; {
+		var v map[string]any
+		if _, err := toml.Decode(line[:i], &v); err == nil {
+			return strings.Repeat(" ", max(i-width, 1)) + line[i:]
+		}
+		next := strings.IndexByte(line[i+1:], '#')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// HostPolicy adjusts AI settings for repositories whose origin remote is
+// hosted on a matching host, e.g. forcing a local provider for internal code.
+type HostPolicy struct {
+	Host      string `toml:"host"`       // host name or glob, e.g. "github.com" or "*.corp.example"
+	Provider  string `toml:"provider"`   // force this provider
+	Model     string `toml:"model"`      // force this model
+	BaseURL   string `toml:"base_url"`   // force this endpoint
+	LocalOnly bool   `toml:"local_only"` // refuse to send diffs to non-local endpoints
+}
+
+// PolicyFor returns the first policy matching host, or nil
+func (c *Config) PolicyFor(host string) *HostPolicy {
+	if host == "" {
+		return nil
+	}
+	for i := range c.Policies {
+		p := &c.Policies[i]
+		if ok, _ := path.Match(strings.ToLower(p.Host), strings.ToLower(host)); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// EffectiveAI returns the AI settings for a repository hosted on host, with
+// its policy applied. The config itself is left untouched so that saving
+// settings never persists policy overrides.
+func (c *Config) EffectiveAI(host string) (AIConfig, error) {
+	ai := c.AI
+
+	p := c.PolicyFor(host)
+	if p == nil {
+		return ai, nil
+	}
+
+	if p.Provider != "" && p.Provider != ai.Provider {
+		// Another provider shares no endpoint, key or model with [ai]
+		ai.Provider = p.Provider
+		ai.BaseURL, ai.APIKey, ai.APIKeyCmd, ai.Model, ai.Deployment = "", "", "", "", ""
+		ai.APIKeys = nil
+		ai.applyEnv()
+	}
+	if p.Model != "" {
+		ai.Model = p.Model
+	}
+	if p.BaseURL != "" {
+		ai.BaseURL = p.BaseURL
+	}
+
+	if p.LocalOnly && !ai.IsLocal() {
+		return ai, fmt.Errorf("policy for %s requires a local AI provider, but %q is configured", host, ai.Provider)
+	}
+	return ai, nil
+}
+
+// StaysLocal reports whether diffs of a repository hosted on host only reach
+// local models: [ai], and [compare] as well when compare is set. Settings
+// that can't be resolved count as remote.
+func (c *Config) StaysLocal(host string, compare bool) bool {
+	ai, err := c.EffectiveAI(host)
+	if err != nil || !ai.IsLocal() {
+		return false
+	}
+	if !compare {
+		return true
+	}
+	cmp, err := c.CompareAI(host)
+	return err == nil && cmp.IsLocal()
+}
+
+// IsLocal reports whether AI requests stay on this machine
+func (a AIConfig) IsLocal() bool {
+	base := a.BaseURL
+	if base == "" && a.Provider == ProviderOllama {
+		base = os.Getenv("OLLAMA_HOST")
+	}
+	if base == "" {
+		// Ollama defaults to localhost, every other provider to the cloud
+		return a.Provider == ProviderOllama
+	}
+
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		// Bare host:port as accepted for OLLAMA_HOST
+		u, err = url.Parse("http://" + base)
+		if err != nil {
+			return false
+		}
+	}
+
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/hluaguo/commity/internal/glob"
+)
+
+// ProfileRule selects a profile for repositories whose directory or origin
+// remote matches. Rules are tried in order; the first match wins.
+type ProfileRule struct {
+	Match   string `toml:"match"`   // directory glob, e.g. "~/work/**"
+	Remote  string `toml:"remote"`  // remote glob as host/path, e.g. "github.com/acme/*"
+	Profile string `toml:"profile"` // name of a [profiles.<name>] table
+}
+
+// ProfileEnv names the environment variable selecting a profile, which wins
+// over profile rules. The --profile flag sets it.
+const ProfileEnv = "COMMITY_PROFILE"
+
+// SelectProfile returns the profile to apply to the repository at dir: the
+// one named by COMMITY_PROFILE, or else the one picked by ProfileFor
+func (c *Config) SelectProfile(dir, remote string) string {
+	if name := os.Getenv(ProfileEnv); name != "" {
+		return name
+	}
+	return c.ProfileFor(dir, remote)
+}
+
+// ProfileFor returns the profile of the first rule matching the repository
+// at dir with the given remote ("host/owner/repo"), or "" if none match
+func (c *Config) ProfileFor(dir, remote string) string {
+	for _, r := range c.ProfileRules {
+		if r.Match != "" && matchPath(ExpandHome(r.Match), dir) {
+			return r.Profile
+		}
+		if r.Remote != "" && remote != "" && glob.Match(strings.ToLower(r.Remote), strings.ToLower(remote)) {
+			return r.Profile
+		}
+	}
+	return ""
+}
+
+// WithProfile returns a copy of the config with the named profile's
+// settings layered on top. Only keys set in the profile change.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+
+	// Round-trip the profile through TOML so it decodes onto the copy
+	// exactly like the config file did
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(profile); err != nil {
+		return nil, fmt.Errorf("invalid profile %q: %w", name, err)
+	}
+	out := *c
+	if _, err := toml.Decode(buf.String(), &out); err != nil {
+		return nil, fmt.Errorf("invalid profile %q: %w", name, err)
+	}
+	out.profile = name
+	return &out, nil
+}
+
+// Profile returns the name of the applied profile, or ""
+func (c *Config) Profile() string {
+	return c.profile
+}
+
+// matchPath matches an absolute directory against an absolute path glob
+func matchPath(pattern, dir string) bool {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	return glob.Match(strings.TrimPrefix(pattern, "/"), strings.TrimPrefix(dir, "/"))
+}
+
+// ExpandHome replaces a leading "~/" with the user's home directory
+func ExpandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
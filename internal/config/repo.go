@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// RepoFile holds team settings committed to the repository root
+const RepoFile = ".commity.toml"
+
+// pyprojectFile may hold the team settings in a [tool.commity] table instead
+const pyprojectFile = "pyproject.toml"
+
+// RepoConfig is the subset of settings a repository may set for everyone
+// working on it. It is deliberately small: a committed file must not be able
+// to change providers, keys or hook commands.
+type RepoConfig struct {
+	Split              *bool             `toml:"split"`               // allow split commits; unset leaves [general] split in charge
+	SplitNote          string            `toml:"split_note"`          // why, shown when split commits are off
+	Types              []CommitType      `toml:"types"`               // replace [commit] types
+	Scopes             map[string]string `toml:"scopes"`              // replace [commit.scopes]
+	Language           string            `toml:"language"`            // replace [commit] language
+	CustomInstructions string            `toml:"custom_instructions"` // replace [ai] custom_instructions
+	Exclude            []string          `toml:"exclude"`             // replace [ai] exclude
+
+	File string `toml:"-"` // where the settings were read from
+}
+
+// WithRepo returns a copy of the config with the team settings and the
+// commitlint rules of the repository at dir applied, if it has them
+func (c *Config) WithRepo(dir string) (*Config, error) {
+	out := *c
+	var err error
+	if out.Commitlint, err = LoadCommitlint(dir); err != nil {
+		return nil, err
+	}
+
+	repo, md, err := loadRepoConfig(dir)
+	if err != nil || repo.File == "" {
+		return &out, err
+	}
+	out.Repo = repo
+	if md.IsDefined(repo.key("types")...) {
+		out.Commit.Types = repo.Types
+	}
+	if md.IsDefined(repo.key("scopes")...) {
+		out.Commit.Scopes = repo.Scopes
+	}
+	if md.IsDefined(repo.key("language")...) {
+		out.Commit.Language = repo.Language
+	}
+	if md.IsDefined(repo.key("custom_instructions")...) {
+		out.AI.CustomInstructions = repo.CustomInstructions
+	}
+	if md.IsDefined(repo.key("exclude")...) {
+		out.AI.Exclude = repo.Exclude
+	}
+	return &out, nil
+}
+
+// loadRepoConfig reads .commity.toml, or else the [tool.commity] table of
+// pyproject.toml. File is empty when the repository has neither.
+func loadRepoConfig(dir string) (RepoConfig, toml.MetaData, error) {
+	var repo RepoConfig
+	md, err := toml.DecodeFile(filepath.Join(dir, RepoFile), &repo)
+	if err == nil {
+		repo.File = RepoFile
+		return repo, md, checkUndecoded(RepoFile, md.Undecoded())
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return repo, md, fmt.Errorf("failed to read %s: %w", RepoFile, err)
+	}
+
+	var pyproject struct {
+		Tool struct {
+			Commity *RepoConfig `toml:"commity"`
+		} `toml:"tool"`
+	}
+	md, err = toml.DecodeFile(filepath.Join(dir, pyprojectFile), &pyproject)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && pyproject.Tool.Commity == nil) {
+		return repo, md, nil
+	}
+	if err != nil {
+		return repo, md, fmt.Errorf("failed to read %s: %w", pyprojectFile, err)
+	}
+	// The rest of pyproject.toml belongs to other tools
+	var ours []toml.Key
+	for _, key := range md.Undecoded() {
+		if len(key) > 2 && key[0] == "tool" && key[1] == "commity" {
+			ours = append(ours, key)
+		}
+	}
+	repo = *pyproject.Tool.Commity
+	repo.File = pyprojectFile
+	return repo, md, checkUndecoded(pyprojectFile+" [tool.commity]", ours)
+}
+
+// checkUndecoded refuses settings outside RepoConfig rather than ignoring them
+func checkUndecoded(file string, undecoded []toml.Key) error {
+	if len(undecoded) > 0 {
+		return fmt.Errorf("%s: unsupported setting %q", file, undecoded[0].String())
+	}
+	return nil
+}
+
+// key returns the metadata key of a setting in the file it was read from
+func (r RepoConfig) key(name string) []string {
+	if r.File == pyprojectFile {
+		return []string{"tool", "commity", name}
+	}
+	return []string{name}
+}
+
+// overrides reports whether the repository replaces any of the user's settings
+func (r RepoConfig) overrides() bool {
+	return r.Types != nil || r.Scopes != nil || r.Language != "" || r.CustomInstructions != "" || r.Exclude != nil
+}
+
+// SplitAllowed reports whether changes may be split into several commits,
+// by the repository's .commity.toml or else [general] split
+func (c *Config) SplitAllowed() bool {
+	if c.Repo.Split != nil {
+		return *c.Repo.Split
+	}
+	return c.General.Split
+}
+
+// CommitRules returns the commit settings with the repository's commitlint
+// rules applied: its types, and a first-line limit no longer than its own
+func (c *Config) CommitRules() CommitConfig {
+	commit := c.Commit
+	lint := c.Commitlint
+	if len(lint.Types) > 0 {
+		// Keep the descriptions of configured types
+		commit.Conventional = true
+		commit.Types = make([]CommitType, len(lint.Types))
+		for i, name := range lint.Types {
+			commit.Types[i] = CommitType{Name: name}
+			for _, t := range c.Commit.Types {
+				if t.Name == name {
+					commit.Types[i] = t
+				}
+			}
+		}
+	}
+	limit := commit.SubjectMaxLength
+	if limit == 0 {
+		limit = 72 // see subject_max_length
+	}
+	if lint.HeaderMaxLength > 0 && lint.HeaderMaxLength < limit {
+		commit.SubjectMaxLength = lint.HeaderMaxLength
+	}
+	commit.ScopeEnum = lint.Scopes
+	return commit
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// CommitType is an allowed conventional commit type. In the config file it
+// is either a name, "feat", or a table, {name = "feat", desc = "user-facing feature"}.
+type CommitType struct {
+	Name string `toml:"name"`
+	Desc string `toml:"desc"` // what the type is for, shown to the AI
+}
+
+// typeDescriptions describe the common types when the config doesn't
+var typeDescriptions = map[string]string{
+	"feat":     "a new user-facing feature or capability",
+	"fix":      "a bug fix",
+	"docs":     "documentation only",
+	"style":    "formatting or whitespace, no change in behavior",
+	"refactor": "restructured code without a change in behavior",
+	"perf":     "a performance improvement",
+	"test":     "adding or correcting tests only",
+	"build":    "build system, packaging or dependency changes",
+	"ci":       "CI configuration and scripts",
+	"chore":    "maintenance that fits no other type",
+	"revert":   "reverts an earlier commit",
+}
+
+// UnmarshalTOML accepts a type name or a table with name and desc
+func (t *CommitType) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*t = CommitType{Name: v}
+	case map[string]any:
+		name, _ := v["name"].(string)
+		desc, _ := v["desc"].(string)
+		if name == "" {
+			return fmt.Errorf("commit type %v has no name", v)
+		}
+		*t = CommitType{Name: name, Desc: desc}
+	default:
+		return fmt.Errorf("commit type must be a name or a table, got %T", v)
+	}
+	return nil
+}
+
+// MarshalTOML writes undescribed types as plain names, so saved configs
+// keep the short form
+func (t CommitType) MarshalTOML() ([]byte, error) {
+	if t.Desc == "" {
+		return []byte(strconv.Quote(t.Name)), nil
+	}
+	return fmt.Appendf(nil, "{name = %s, desc = %s}", strconv.Quote(t.Name), strconv.Quote(t.Desc)), nil
+}
+
+// TypeNames returns the names of the allowed types
+func (c CommitConfig) TypeNames() []string {
+	names := make([]string, len(c.Types))
+	for i, t := range c.Types {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// TypeDescriptions returns what each allowed type is for, falling back to
+// built-in descriptions of the common types
+func (c CommitConfig) TypeDescriptions() map[string]string {
+	descs := make(map[string]string)
+	for _, t := range c.Types {
+		if desc := t.Desc; desc != "" {
+			descs[t.Name] = desc
+		} else if desc := typeDescriptions[t.Name]; desc != "" {
+			descs[t.Name] = desc
+		}
+	}
+	return descs
+}
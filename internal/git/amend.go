@@ -0,0 +1,74 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommitInfo describes an existing commit
+type CommitInfo struct {
+	Hash    string
+	Message string
+	Files   []string
+	Added   int
+	Removed int
+}
+
+// LastCommit returns the commit at HEAD with its files and line counts
+func (r *Repository) LastCommit() (*CommitInfo, error) {
+	hash, err := r.HeadHash()
+	if err != nil {
+		return nil, fmt.Errorf("no commit to amend: %w", err)
+	}
+
+	message, err := r.CommitMessage(hash)
+	if err != nil {
+		return nil, err
+	}
+	info := &CommitInfo{Hash: hash, Message: message}
+
+	// --root lists the files of an initial commit too
+	cmd := exec.Command("git", "diff-tree", "--root", "--no-commit-id", "--numstat", "--no-renames", "-r", hash)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff-tree failed: %w", err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		var a, d int
+		// Binary files report "-" and count as no lines
+		_, _ = fmt.Sscanf(fields[0], "%d", &a)
+		_, _ = fmt.Sscanf(fields[1], "%d", &d)
+		info.Added += a
+		info.Removed += d
+		info.Files = append(info.Files, fields[2])
+	}
+	return info, scanner.Err()
+}
+
+// DiffOfCommit returns the patch introduced by a commit
+func (r *Repository) DiffOfCommit(rev string) (string, error) {
+	cmd := exec.Command("git", "show", "--format=", "--patch", "--no-color", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git show failed: %w", err)
+	}
+	return string(out), nil
+}
+
+// AmendCommit replaces the message of the last commit. Staged changes are
+// left in the index rather than folded into the commit. noVerify skips the
+// commit hooks.
+func (r *Repository) AmendCommit(message string, noVerify bool) error {
+	cmd := exec.Command("git", r.commitArgs(noVerify, "commit", "--amend", "--only", "-m", message)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return r.commitError("git commit --amend", out)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// PushBranch pushes branch to remote, creating it there if needed, and
+// makes it the upstream of the local branch
+func (r *Repository) PushBranch(remote, branch string) error {
+	cmd := exec.Command("git", "push", "--set-upstream", remote, branch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git push to %s failed: %s", remote, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// UpstreamRemote returns the remote a fork's pull requests target: "upstream"
+// if it exists, otherwise "origin" unless that is the fork itself
+func (r *Repository) UpstreamRemote(fork string) string {
+	for _, name := range []string{"upstream", "origin"} {
+		if name != fork && r.RemoteURL(name) != "" {
+			return name
+		}
+	}
+	return fork
+}
+
+// CompareURL returns the page for opening a pull request from branch of the
+// fork into base of the upstream repository, given both remote URLs. It
+// returns "" for hosts whose URL scheme isn't known.
+func CompareURL(upstream, fork, base, branch string) string {
+	up, fk := RemoteSlug(upstream), RemoteSlug(fork)
+	if up == "" || fk == "" {
+		return ""
+	}
+	host, upPath, _ := strings.Cut(up, "/")
+	_, forkPath, _ := strings.Cut(fk, "/")
+
+	switch {
+	case strings.Contains(host, "github"):
+		head := branch
+		if forkPath != upPath {
+			// owner:repo:branch also works for forks that were renamed
+			head = strings.Replace(forkPath, "/", ":", 1) + ":" + branch
+		}
+		return fmt.Sprintf("https://%s/%s/compare/%s...%s?expand=1", host, upPath, base, head)
+	case strings.Contains(host, "gitlab"):
+		return fmt.Sprintf("https://%s/%s/-/merge_requests/new?merge_request%%5Bsource_branch%%5D=%s", host, forkPath, url.QueryEscape(branch))
+	}
+	return ""
+}
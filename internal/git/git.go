@@ -4,14 +4,20 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/hluaguo/commity/internal/glob"
 )
 
 const minStatusLineLength = 4 // "XY " + at least 1 char path
 
+// IgnoreFile lists paths, in gitignore syntax, that commity never offers
+const IgnoreFile = ".commityignore"
+
 // FileStatus represents the git status of a file in the working tree.
 type FileStatus struct {
 	Path   string
@@ -39,6 +45,7 @@ func (f FileStatus) StatusLabel() string {
 // Repository provides git operations for a local repository.
 type Repository struct {
 	path string
+	sign bool // pass -S to commits, see SetSign
 }
 
 func New() (*Repository, error) {
@@ -51,6 +58,20 @@ func New() (*Repository, error) {
 	return &Repository{path: strings.TrimSpace(string(out))}, nil
 }
 
+// Version returns the version of the installed git, e.g. "2.43.0"
+func Version() (string, error) {
+	out, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("git not found: %w", err)
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "git version "), nil
+}
+
+// Path returns the absolute path of the repository root
+func (r *Repository) Path() string {
+	return r.path
+}
+
 func (r *Repository) Status() ([]FileStatus, error) {
 	cmd := exec.Command("git", "status", "--porcelain=v1")
 	out, err := cmd.Output()
@@ -58,6 +79,8 @@ func (r *Repository) Status() ([]FileStatus, error) {
 		return nil, fmt.Errorf("git status failed: %w", err)
 	}
 
+	ignore := r.ignorePatterns()
+
 	var files []FileStatus
 	scanner := bufio.NewScanner(bytes.NewReader(out))
 	for scanner.Scan() {
@@ -98,9 +121,12 @@ func (r *Repository) Status() ([]FileStatus, error) {
 			info, err := os.Stat(path)
 			if err == nil && info.IsDir() {
 				// Expand directory into individual files
-				expandedFiles := expandDirectory(path, status, staged)
-				files = append(files, expandedFiles...)
-			} else {
+				for _, f := range expandDirectory(path, status, staged) {
+					if !glob.Ignored(ignore, f.Path) {
+						files = append(files, f)
+					}
+				}
+			} else if !glob.Ignored(ignore, path) {
 				files = append(files, FileStatus{
 					Path:   path,
 					Status: status,
@@ -113,6 +139,15 @@ func (r *Repository) Status() ([]FileStatus, error) {
 	return files, scanner.Err()
 }
 
+// ignorePatterns reads the repository's .commityignore, if any
+func (r *Repository) ignorePatterns() []string {
+	data, err := os.ReadFile(filepath.Join(r.path, IgnoreFile))
+	if err != nil {
+		return nil
+	}
+	return glob.ParseIgnore(string(data))
+}
+
 // expandDirectory recursively expands a directory into individual FileStatus entries
 func expandDirectory(dir string, status string, staged bool) []FileStatus {
 	var files []FileStatus
@@ -222,14 +257,223 @@ func (r *Repository) Add(files []string) error {
 	return nil
 }
 
-func (r *Repository) Commit(message string) error {
-	cmd := exec.Command("git", "commit", "-m", message)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("git commit failed: %w", err)
+// Unstage removes files from the index, keeping their working tree changes
+func (r *Repository) Unstage(files []string) error {
+	args := []string{"reset", "-q", "--"}
+	args = append(args, files...)
+	cmd := exec.Command("git", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git reset failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// HeadHash returns the full hash of the current commit
+func (r *Repository) HeadHash() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ShortStat summarizes the changes of commit hash, e.g.
+// "2 files changed, 10 insertions(+), 1 deletion(-)"
+func (r *Repository) ShortStat(hash string) (string, error) {
+	cmd := exec.Command("git", "show", "--shortstat", "--format=", hash)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git show --shortstat failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// StagedFiles returns the paths currently staged in the index
+func (r *Repository) StagedFiles() ([]string, error) {
+	cmd := exec.Command("git", "diff", "--cached", "--name-only")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff --cached failed: %w", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// Commit commits the index with message; noVerify skips the commit hooks
+func (r *Repository) Commit(message string, noVerify bool) error {
+	cmd := exec.Command("git", r.commitArgs(noVerify, "commit", "-m", message)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return r.commitError("git commit", out)
+	}
+	return nil
+}
+
+// Push pushes the current branch to its upstream. A branch without one is
+// pushed to PushRemote, which becomes its upstream.
+func (r *Repository) Push() error {
+	args := []string{"push"}
+	if !r.hasUpstream() {
+		remote := r.PushRemote()
+		if remote == "" {
+			return fmt.Errorf("no remote to push %s to", r.Branch())
+		}
+		args = append(args, "--set-upstream", remote, r.Branch())
+	}
+	cmd := exec.Command("git", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git push failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// hasUpstream reports whether the current branch tracks a remote branch
+func (r *Repository) hasUpstream() bool {
+	return exec.Command("git", "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}").Run() == nil
+}
+
+// PushRemote returns the remote Push sends the current branch to: the
+// remote of its upstream, or origin for a branch without one. It returns
+// "" when there is no such remote.
+func (r *Repository) PushRemote() string {
+	out, err := exec.Command("git", "config", "branch."+r.Branch()+".remote").Output()
+	if remote := strings.TrimSpace(string(out)); err == nil && remote != "" {
+		return remote
+	}
+	if r.RemoteURL("origin") != "" {
+		return "origin"
+	}
+	return ""
+}
+
+// UndoLastCommit removes the last commit but keeps its changes staged
+func (r *Repository) UndoLastCommit() error {
+	return r.ResetSoft("HEAD~1")
+}
+
+// ResetSoft moves the current branch to rev, keeping the changes of the
+// commits after it staged
+func (r *Repository) ResetSoft(rev string) error {
+	cmd := exec.Command("git", "reset", "--soft", rev)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git reset failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// IsShallow reports whether the repository is a shallow clone.
+// History-based features should degrade gracefully when it is.
+func (r *Repository) IsShallow() bool {
+	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository")
+	out, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) == "true"
+}
+
+// Deepen fetches additional commits of history for a shallow clone
+func (r *Repository) Deepen(commits int) error {
+	cmd := exec.Command("git", "fetch", fmt.Sprintf("--deepen=%d", commits))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git fetch --deepen failed: %s", strings.TrimSpace(string(out)))
 	}
 	return nil
 }
 
+// RemoteURL returns the URL of the named remote, or "" if it doesn't exist
+func (r *Repository) RemoteURL(name string) string {
+	cmd := exec.Command("git", "remote", "get-url", name)
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// RemoteHost returns the host of the origin remote, or "" without one
+func (r *Repository) RemoteHost() string {
+	return ParseRemoteHost(r.RemoteURL("origin"))
+}
+
+// ParseRemoteHost extracts the host from a remote URL. It understands
+// URLs (https://, ssh://, git://) and scp-like SSH syntax (git@host:org/repo).
+func ParseRemoteHost(remote string) string {
+	if remote == "" {
+		return ""
+	}
+
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return ""
+		}
+		return strings.ToLower(u.Hostname())
+	}
+
+	// scp-like: [user@]host:path
+	host, _, ok := strings.Cut(remote, ":")
+	if !ok {
+		return "" // local path
+	}
+	if i := strings.LastIndex(host, "@"); i != -1 {
+		host = host[i+1:]
+	}
+	return strings.ToLower(host)
+}
+
+// RemoteSlug returns a remote URL as host/path without a .git suffix,
+// e.g. "github.com/hluaguo/commity"
+func RemoteSlug(remote string) string {
+	host := ParseRemoteHost(remote)
+	if host == "" {
+		return ""
+	}
+	var p string
+	if strings.Contains(remote, "://") {
+		if u, err := url.Parse(remote); err == nil {
+			p = u.Path
+		}
+	} else {
+		_, p, _ = strings.Cut(remote, ":")
+	}
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	return host + "/" + p
+}
+
+// DefaultBranch returns the remote's default branch (e.g. "origin/main")
+// from origin/HEAD, falling back to common names when it isn't set.
+func (r *Repository) DefaultBranch() (string, error) {
+	cmd := exec.Command("git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+	if out, err := cmd.Output(); err == nil {
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	for _, ref := range []string{"origin/main", "origin/master", "main", "master"} {
+		cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref)
+		if err := cmd.Run(); err == nil {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("could not detect default branch (set origin/HEAD with 'git remote set-head origin --auto')")
+}
+
+// MergeBase returns the best common ancestor of HEAD and ref
+func (r *Repository) MergeBase(ref string) (string, error) {
+	cmd := exec.Command("git", "merge-base", "HEAD", ref)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git merge-base failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 func (r *Repository) Branch() string {
 	cmd := exec.Command("git", "branch", "--show-current")
 	out, err := cmd.Output()
@@ -239,6 +483,16 @@ func (r *Repository) Branch() string {
 	return strings.TrimSpace(string(out))
 }
 
+// CreateBranch creates a branch at HEAD and switches to it, carrying the
+// working tree and index along
+func (r *Repository) CreateBranch(name string) error {
+	cmd := exec.Command("git", "switch", "-c", name)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git switch failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 // DiffStats returns lines added and removed for the given files
 func (r *Repository) DiffStats(files []string) (added, removed int) {
 	// Get stats for staged + unstaged
@@ -284,3 +538,43 @@ func (r *Repository) DiffStats(files []string) (added, removed int) {
 
 	return added, removed
 }
+
+// IndexCounts summarizes the state of the index and working tree
+type IndexCounts struct {
+	Staged    int // files with staged changes
+	Unstaged  int // tracked files with unstaged changes
+	Untracked int
+}
+
+// IndexSummary counts staged, unstaged and untracked files. A file with
+// both staged and unstaged changes counts towards both.
+func (r *Repository) IndexSummary() (IndexCounts, error) {
+	cmd := exec.Command("git", "status", "--porcelain=v1", "--untracked-files=all")
+	out, err := cmd.Output()
+	if err != nil {
+		return IndexCounts{}, fmt.Errorf("git status failed: %w", err)
+	}
+	return CountStatus(string(out)), nil
+}
+
+// CountStatus counts files in git status --porcelain=v1 output
+func CountStatus(porcelain string) IndexCounts {
+	var c IndexCounts
+	for _, line := range strings.Split(porcelain, "\n") {
+		if len(line) < minStatusLineLength {
+			continue
+		}
+		x, y := line[0], line[1]
+		if x == '?' && y == '?' {
+			c.Untracked++
+			continue
+		}
+		if x != ' ' && x != '!' {
+			c.Staged++
+		}
+		if y != ' ' && y != '!' {
+			c.Unstaged++
+		}
+	}
+	return c
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Hunk is a single @@ section of a file diff
+type Hunk struct {
+	Header string   // "@@ -1,3 +1,4 @@ func main() {"
+	Lines  []string // context, added and removed lines
+}
+
+// Summary returns the first changed line of the hunk, for display
+func (h Hunk) Summary() string {
+	for _, l := range h.Lines {
+		if strings.HasPrefix(l, "+") || strings.HasPrefix(l, "-") {
+			return strings.TrimSpace(l[1:])
+		}
+	}
+	return ""
+}
+
+// FileDiff is the diff of one file split into hunks
+type FileDiff struct {
+	Path   string
+	Header []string // diff --git, index, --- and +++ lines
+	Hunks  []Hunk
+}
+
+// ParseDiff splits a unified diff into files and hunks
+func ParseDiff(diff string) []FileDiff {
+	var files []FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, FileDiff{Header: []string{line}})
+			file = &files[len(files)-1]
+			hunk = nil
+			if i := strings.LastIndex(line, " b/"); i != -1 {
+				file.Path = line[i+3:]
+			}
+		case file == nil:
+			continue
+		case strings.HasPrefix(line, "@@"):
+			file.Hunks = append(file.Hunks, Hunk{Header: line})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+		case hunk != nil:
+			hunk.Lines = append(hunk.Lines, line)
+		default:
+			file.Header = append(file.Header, line)
+		}
+	}
+	return files
+}
+
+// Patch builds a patch containing only the hunks at the given indexes
+func (f FileDiff) Patch(hunks []int) string {
+	var sb strings.Builder
+	for _, line := range f.Header {
+		sb.WriteString(line + "\n")
+	}
+	for _, i := range hunks {
+		if i < 0 || i >= len(f.Hunks) {
+			continue
+		}
+		sb.WriteString(f.Hunks[i].Header + "\n")
+		for _, line := range f.Hunks[i].Lines {
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// UnstagedDiff returns the working tree changes of a tracked file, split into hunks
+func (r *Repository) UnstagedDiff(path string) (*FileDiff, error) {
+	diff, err := r.Diff([]string{path}, false)
+	if err != nil {
+		return nil, err
+	}
+	files := ParseDiff(diff)
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return &files[0], nil
+}
+
+// ApplyCached stages a patch without touching the working tree
+func (r *Repository) ApplyCached(patch string) error {
+	cmd := exec.Command("git", "apply", "--cached", "-")
+	cmd.Stdin = strings.NewReader(patch)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git apply --cached failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
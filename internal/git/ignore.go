@@ -0,0 +1,124 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// junkDirs hold build output, dependencies or editor state
+var junkDirs = map[string]bool{
+	"node_modules": true, "dist": true, "build": true, "target": true,
+	"out": true, "bin": true, "obj": true, "__pycache__": true,
+	".idea": true, ".vscode": true, ".gradle": true, ".next": true,
+	"coverage": true, ".cache": true, ".pytest_cache": true, ".venv": true,
+}
+
+// junkNames match files that are generated or left behind by tools
+var junkNames = []string{
+	".DS_Store", "Thumbs.db", "*.log", "*.swp", "*.swo", "*~",
+	"*.pyc", "*.o", "*.class", "*.tmp", "*.bak",
+}
+
+// LooksLikeJunk reports whether a path looks like a build artifact or
+// editor leftover rather than something to commit
+func LooksLikeJunk(path string) bool {
+	return junkPattern(path) != ""
+}
+
+// JunkFiles returns the untracked files among paths that look like junk
+func JunkFiles(files []FileStatus, paths []string) []string {
+	var junk []string
+	for _, f := range files {
+		if f.Status == "??" && slices.Contains(paths, f.Path) && LooksLikeJunk(f.Path) {
+			junk = append(junk, f.Path)
+		}
+	}
+	return junk
+}
+
+// IgnorePatterns proposes gitignore patterns covering junk paths, used when
+// no AI suggestion is available
+func IgnorePatterns(paths []string) []string {
+	var patterns []string
+	for _, p := range paths {
+		pattern := junkPattern(p)
+		if pattern == "" {
+			pattern = "/" + strings.TrimSuffix(p, "/")
+		}
+		if !slices.Contains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// junkPattern returns the pattern that marks path as junk, or ""
+func junkPattern(path string) string {
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	for i, part := range parts {
+		// The last part is a directory only when status reports it with a slash
+		if junkDirs[part] && (i < len(parts)-1 || strings.HasSuffix(path, "/")) {
+			return part + "/"
+		}
+	}
+	base := parts[len(parts)-1]
+	for _, name := range junkNames {
+		if ok, _ := filepath.Match(name, base); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// AddIgnorePatterns appends patterns missing from the repository's root
+// .gitignore, creating it if needed, and returns the ones added
+func (r *Repository) AddIgnorePatterns(patterns []string) ([]string, error) {
+	path := filepath.Join(r.path, ".gitignore")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+
+	existing := strings.Split(string(data), "\n")
+	for i := range existing {
+		existing[i] = strings.TrimSpace(existing[i])
+	}
+	var added []string
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p != "" && !slices.Contains(existing, p) && !slices.Contains(added, p) {
+			added = append(added, p)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	content := string(data)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += strings.Join(added, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write .gitignore: %w", err)
+	}
+	return added, nil
+}
+
+// CommitGitignore commits the root .gitignore on its own, leaving anything
+// else staged out of the commit. noVerify skips the commit hooks.
+func (r *Repository) CommitGitignore(message string, noVerify bool) error {
+	cmd := exec.Command("git", "add", "--", ":/.gitignore")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git add failed: %s", strings.TrimSpace(string(out)))
+	}
+	cmd = exec.Command("git", r.commitArgs(noVerify, "commit", "-m", message, "--", ":/.gitignore")...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return r.commitError("git commit", out)
+	}
+	return nil
+}
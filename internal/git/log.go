@@ -0,0 +1,160 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// LogEntry is a commit listed by Log or LogRange
+type LogEntry struct {
+	Hash    string
+	Time    time.Time
+	Subject string
+	Body    string // only filled by LogRange
+}
+
+// logFormat separates fields with the unit separator, which can't appear
+// in a subject line
+const logFormat = "%H%x1f%aI%x1f%s"
+
+// Log lists the commits in the repository at dir authored by author since
+// the given time, newest first. An empty author lists everyone's commits.
+func Log(dir string, since time.Time, author string) ([]LogEntry, error) {
+	args := []string{"log", "--since=" + since.Format(time.RFC3339), "--format=" + logFormat}
+	if author != "" {
+		args = append(args, "--author="+author)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log in %s failed: %w", dir, err)
+	}
+	return ParseLog(string(out)), nil
+}
+
+// ParseLog reads entries printed with logFormat, skipping malformed lines
+func ParseLog(out string) []LogEntry {
+	var entries []LogEntry
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, LogEntry{Hash: fields[0], Time: t, Subject: fields[2]})
+	}
+	return entries
+}
+
+// logRecordFormat adds the body and ends each record with the record
+// separator, since bodies span lines
+const logRecordFormat = logFormat + "%x1f%b%x1e"
+
+// LogRange lists the commits in a revision range such as v1.2.0..HEAD with
+// their bodies, oldest first
+func (r *Repository) LogRange(spec string) ([]LogEntry, error) {
+	cmd := exec.Command("git", "log", "--reverse", "--no-merges", "--format="+logRecordFormat, spec)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("invalid range %q", spec)
+	}
+	return ParseLogRecords(string(out)), nil
+}
+
+// ParseLogRecords reads entries printed with logRecordFormat
+func ParseLogRecords(out string) []LogEntry {
+	var entries []LogEntry
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, LogEntry{
+			Hash:    fields[0],
+			Time:    t,
+			Subject: fields[2],
+			Body:    strings.TrimSpace(fields[3]),
+		})
+	}
+	return entries
+}
+
+// LatestTag returns the most recent tag reachable from HEAD
+func (r *Repository) LatestTag() (string, error) {
+	cmd := exec.Command("git", "describe", "--tags", "--abbrev=0")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("no tag found; pass a range such as v1.2.0..HEAD")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Identity returns the committer as "Name <email>", as used in sign-offs
+func Identity(dir string) string {
+	cmd := exec.Command("git", "var", "GIT_COMMITTER_IDENT")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	// The ident ends with a timestamp and zone after the email
+	ident := string(out)
+	if i := strings.LastIndex(ident, ">"); i != -1 {
+		return ident[:i+1]
+	}
+	return ""
+}
+
+// UserEmail returns the git user.email configured for the repository at dir
+func UserEmail(dir string) string {
+	cmd := exec.Command("git", "config", "user.email")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// RemoteHostOf returns the host of the origin remote of the repository at dir
+func RemoteHostOf(dir string) string {
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return ParseRemoteHost(strings.TrimSpace(string(out)))
+}
+
+// FileLog returns the last n commits touching path as "hash subject" lines,
+// newest first. Files without history yield none.
+func (r *Repository) FileLog(path string, n int) ([]string, error) {
+	cmd := exec.Command("git", "log", "--oneline", "-n", fmt.Sprint(n), "--", path)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+	return outputLines(out), nil
+}
+
+// RecentSubjects returns the subjects of the last n commits, newest first,
+// leaving out merges
+func (r *Repository) RecentSubjects(n int) ([]string, error) {
+	cmd := exec.Command("git", "log", "--no-merges", "--format=%s", "-n", fmt.Sprint(n))
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+	return outputLines(out), nil
+}
@@ -0,0 +1,59 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// BranchChanges is what the current branch adds on top of a base branch
+type BranchChanges struct {
+	Base     string
+	Subjects []string // commit subjects, oldest first
+	Files    []string
+	Diff     string
+}
+
+// ChangesSince collects the commits, files and diff of HEAD since it forked
+// from base. Uncommitted changes are not included.
+func (r *Repository) ChangesSince(base string) (*BranchChanges, error) {
+	fork, err := r.MergeBase(base)
+	if err != nil {
+		return nil, fmt.Errorf("no common history with %s: %w", base, err)
+	}
+	changes := &BranchChanges{Base: base}
+
+	cmd := exec.Command("git", "log", "--reverse", "--format=%s", fork+"..HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+	changes.Subjects = outputLines(out)
+	if len(changes.Subjects) == 0 {
+		return nil, fmt.Errorf("no commits on this branch since %s", base)
+	}
+
+	cmd = exec.Command("git", "diff", "--name-only", fork, "HEAD")
+	if out, err = cmd.Output(); err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	changes.Files = outputLines(out)
+
+	cmd = exec.Command("git", "diff", "--no-color", fork, "HEAD")
+	if out, err = cmd.Output(); err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	changes.Diff = string(out)
+	return changes, nil
+}
+
+// outputLines splits command output into its non-empty lines
+func outputLines(out []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// testMarkers are affixes that tie a test file to the source file it covers
+var testMarkers = []string{"_test", ".test", "_spec", ".spec", "test_"}
+
+// RelatedUntracked suggests untracked files that obviously belong with the
+// selected changes: siblings sharing a base name (foo.go → foo_test.go) and
+// files whose name is referenced in added lines of the diff (new fixtures).
+func RelatedUntracked(files []FileStatus, selected []string, diff string) []string {
+	isSelected := make(map[string]bool)
+	for _, s := range selected {
+		isSelected[s] = true
+	}
+
+	// Base names of the selected tracked files, keyed by directory
+	stems := make(map[string]bool)
+	for _, f := range files {
+		if isSelected[f.Path] && f.Status != "??" {
+			stems[filepath.Dir(f.Path)+"/"+baseStem(f.Path)] = true
+		}
+	}
+
+	added := addedLines(diff)
+
+	var related []string
+	for _, f := range files {
+		if f.Status != "??" || isSelected[f.Path] {
+			continue
+		}
+		if stems[filepath.Dir(f.Path)+"/"+baseStem(f.Path)] || strings.Contains(added, filepath.Base(f.Path)) {
+			related = append(related, f.Path)
+		}
+	}
+
+	sort.Strings(related)
+	return related
+}
+
+// PairedTests returns changed files that pair with a selected file as its
+// test or as the file under test: foo.go and foo_test.go in the same
+// directory. Pairs must share an extension, so foo.ts doesn't pull in
+// foo_test.go.
+func PairedTests(files []FileStatus, selected []string) []string {
+	isSelected := make(map[string]bool)
+	for _, s := range selected {
+		isSelected[s] = true
+	}
+
+	var paired []string
+	for _, f := range files {
+		if isSelected[f.Path] {
+			continue
+		}
+		for _, s := range selected {
+			if isTestPair(f.Path, s) {
+				paired = append(paired, f.Path)
+				break
+			}
+		}
+	}
+	sort.Strings(paired)
+	return paired
+}
+
+// isTestPair reports whether exactly one of a and b is the test of the other
+func isTestPair(a, b string) bool {
+	if filepath.Dir(a) != filepath.Dir(b) || filepath.Ext(a) != filepath.Ext(b) || baseStem(a) != baseStem(b) {
+		return false
+	}
+	return isTestName(a) != isTestName(b)
+}
+
+// isTestName reports whether a file name carries a test marker
+func isTestName(path string) bool {
+	name := filepath.Base(path)
+	return baseStem(path) != strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// baseStem strips the extension and any test marker from a file name
+func baseStem(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	for _, marker := range testMarkers {
+		if strings.HasPrefix(marker, "test") {
+			name = strings.TrimPrefix(name, marker)
+		} else {
+			name = strings.TrimSuffix(name, marker)
+		}
+	}
+	return name
+}
+
+// addedLines returns the content of all added lines in a diff
+func addedLines(diff string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,171 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RewordCommits resolves a reword target to commit hashes, oldest first.
+// An empty spec means HEAD, a spec containing ".." is a revision range such
+// as HEAD~3.. and anything else names a single commit.
+func (r *Repository) RewordCommits(spec string) ([]string, error) {
+	if spec == "" {
+		spec = "HEAD"
+	}
+
+	if !strings.Contains(spec, "..") {
+		hash, err := r.revParse(spec + "^{commit}")
+		if err != nil {
+			return nil, fmt.Errorf("unknown commit %q", spec)
+		}
+		return []string{hash}, nil
+	}
+
+	cmd := exec.Command("git", "rev-list", "--reverse", spec)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("invalid range %q", spec)
+	}
+	hashes := strings.Fields(string(out))
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("range %q contains no commits", spec)
+	}
+	return hashes, nil
+}
+
+// CommitMessage returns the full message of a commit
+func (r *Repository) CommitMessage(rev string) (string, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%B", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git log failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CommitFiles returns the paths a commit touched
+func (r *Repository) CommitFiles(rev string) ([]string, error) {
+	cmd := exec.Command("git", "diff-tree", "--root", "--no-commit-id", "--name-only", "--no-renames", "-r", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff-tree failed: %w", err)
+	}
+	return outputLines(out), nil
+}
+
+// IsPushed reports whether a commit is already on the upstream branch
+func (r *Repository) IsPushed(rev string) bool {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", rev, "@{upstream}")
+	return cmd.Run() == nil
+}
+
+// RewriteMessages replaces the messages of commits between the oldest of
+// them and HEAD without touching their trees, like a non-interactive
+// rebase that only rewords. Authors and dates are kept, the index and
+// working tree are left alone and ORIG_HEAD points at the old HEAD.
+func (r *Repository) RewriteMessages(messages map[string]string) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	head, err := r.HeadHash()
+	if err != nil {
+		return err
+	}
+
+	// Every commit from HEAD back to the oldest reworded one is recreated
+	cmd := exec.Command("git", "rev-list", "--first-parent", "--parents", "HEAD")
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git rev-list failed: %w", err)
+	}
+	var chain [][]string // newest first: hash followed by its parents
+	remaining := len(messages)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return fmt.Errorf("cannot reword across merge commit %s", fields[0][:7])
+		}
+		chain = append(chain, fields)
+		if _, ok := messages[fields[0]]; ok {
+			remaining--
+		}
+		if remaining == 0 {
+			break
+		}
+	}
+	if remaining > 0 {
+		return fmt.Errorf("commits to reword must be ancestors of HEAD")
+	}
+
+	oldest := chain[len(chain)-1]
+	parent := ""
+	if len(oldest) == 2 {
+		parent = oldest[1]
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		hash := chain[i][0]
+		message, ok := messages[hash]
+		if !ok {
+			if message, err = r.CommitMessage(hash); err != nil {
+				return err
+			}
+		}
+		if parent, err = r.recommit(hash, parent, message); err != nil {
+			return err
+		}
+	}
+
+	cmd = exec.Command("git", "update-ref", "-m", "commity: reword", "HEAD", parent, head)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git update-ref failed: %s", strings.TrimSpace(string(out)))
+	}
+	_ = exec.Command("git", "update-ref", "ORIG_HEAD", head).Run()
+	return nil
+}
+
+// recommit creates a copy of a commit with a new parent and message
+func (r *Repository) recommit(hash, parent, message string) (string, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%an%x00%ae%x00%aD", hash)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git log failed: %w", err)
+	}
+	author := strings.SplitN(strings.TrimSuffix(string(out), "\n"), "\x00", 3)
+	if len(author) != 3 {
+		return "", fmt.Errorf("unexpected author of %s", hash[:7])
+	}
+
+	// commit-tree only signs when asked to, whatever commit.gpgsign says
+	args := []string{"commit-tree", hash + "^{tree}"}
+	if r.Signs() {
+		args = append(args, "-S")
+	}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	cmd = exec.Command("git", args...)
+	cmd.Stdin = strings.NewReader(message + "\n")
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+author[0],
+		"GIT_AUTHOR_EMAIL="+author[1],
+		"GIT_AUTHOR_DATE="+author[2],
+	)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err = cmd.Output()
+	if err != nil {
+		return "", r.commitError("git commit-tree", []byte(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (r *Repository) revParse(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", rev)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// signingFailures are fragments of git and gpg output that mean the commit
+// couldn't be signed, as opposed to hooks or an empty index
+var signingFailures = []string{
+	"gpg failed to sign",
+	"cannot run gpg",
+	"no secret key",
+	"unsupported value for gpg.format",
+	"ssh-keygen",
+	"couldn't load public key",
+	"failed to sign",
+	"signing failed",
+}
+
+// SigningError is returned when a commit couldn't be signed
+type SigningError struct {
+	Output string // what git printed
+	SSH    bool   // the key is an SSH key rather than a GPG key
+}
+
+func (e *SigningError) Error() string {
+	reason := "git could not sign the commit"
+	for _, line := range strings.Split(e.Output, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "error:") {
+			reason = strings.TrimSpace(strings.TrimPrefix(line, "error:"))
+			break
+		}
+	}
+	return "commit signing failed: " + reason
+}
+
+// SetSign makes commits signed with -S even when git's commit.gpgsign is off
+func (r *Repository) SetSign(on bool) {
+	r.sign = on
+}
+
+// Signs reports whether commits are signed, by SetSign or commit.gpgsign
+func (r *Repository) Signs() bool {
+	if r.sign {
+		return true
+	}
+	out, err := exec.Command("git", "config", "--bool", "commit.gpgsign").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// commitArgs inserts -S after the git subcommand when signing is requested,
+// and --no-verify when noVerify skips the pre-commit and commit-msg hooks
+func (r *Repository) commitArgs(noVerify bool, args ...string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	flags := []string{args[0]}
+	if r.sign {
+		flags = append(flags, "-S")
+	}
+	if noVerify {
+		flags = append(flags, "--no-verify")
+	}
+	return append(flags, args[1:]...)
+}
+
+// commitError turns a failed commit into a SigningError when signing was
+// the problem, and a plain error with git's output otherwise
+func (r *Repository) commitError(op string, out []byte) error {
+	output := strings.TrimSpace(string(out))
+	lower := strings.ToLower(output)
+	for _, f := range signingFailures {
+		if strings.Contains(lower, f) {
+			return &SigningError{Output: output, SSH: r.signFormat() == "ssh" || strings.Contains(lower, "ssh")}
+		}
+	}
+	return fmt.Errorf("%s failed: %s", op, output)
+}
+
+// signFormat returns git's gpg.format, "openpgp" when unset
+func (r *Repository) signFormat() string {
+	out, err := exec.Command("git", "config", "gpg.format").Output()
+	if err != nil {
+		return "openpgp"
+	}
+	return strings.TrimSpace(string(out))
+}
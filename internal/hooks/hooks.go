@@ -0,0 +1,75 @@
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+// Commit describes a created commit for hook templates, e.g.
+// "./scripts/notify.sh {{.Hash}} '{{.Subject}}'"
+type Commit struct {
+	Hash      string
+	ShortHash string
+	Type      string
+	Subject   string
+	Body      string
+	Message   string // full message as committed
+	Branch    string
+	Files     []string
+}
+
+// Render expands template variables in a hook command
+func Render(command string, c Commit) (string, error) {
+	tmpl, err := template.New("hook").Option("missingkey=error").Parse(command)
+	if err != nil {
+		return "", fmt.Errorf("invalid hook %q: %w", command, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, c); err != nil {
+		return "", fmt.Errorf("invalid hook %q: %w", command, err)
+	}
+	return buf.String(), nil
+}
+
+// Env returns the commit as COMMITY_* environment variables, a quoting-safe
+// alternative to template variables in shell commands
+func Env(c Commit) []string {
+	return []string{
+		"COMMITY_HASH=" + c.Hash,
+		"COMMITY_SHORT_HASH=" + c.ShortHash,
+		"COMMITY_TYPE=" + c.Type,
+		"COMMITY_SUBJECT=" + c.Subject,
+		"COMMITY_BODY=" + c.Body,
+		"COMMITY_MESSAGE=" + c.Message,
+		"COMMITY_BRANCH=" + c.Branch,
+		"COMMITY_FILES=" + strings.Join(c.Files, "\n"),
+	}
+}
+
+// RunPost runs each post-commit command with sh in dir. All commands run
+// even if one fails; the errors are joined.
+func RunPost(commands []string, c Commit, dir string) error {
+	var errs []string
+	for _, command := range commands {
+		rendered, err := Render(command, c)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		cmd := exec.Command("sh", "-c", rendered)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), Env(c)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			errs = append(errs, fmt.Sprintf("hook %q failed: %v: %s", rendered, err, strings.TrimSpace(string(out))))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
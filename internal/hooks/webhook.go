@@ -0,0 +1,71 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// Session summarizes the commits created in one run for a webhook
+type Session struct {
+	Repo    string
+	Branch  string
+	Commits []Commit
+}
+
+// Text renders the session as a short message, e.g.
+// "commity@main: 2 commits\n`0123456` feat: add hooks"
+func (s Session) Text() string {
+	noun := "commits"
+	if len(s.Commits) == 1 {
+		noun = "commit"
+	}
+	lines := []string{fmt.Sprintf("%s@%s: %d %s", s.Repo, s.Branch, len(s.Commits), noun)}
+	for _, c := range s.Commits {
+		subject := c.Subject
+		if c.Type != "" {
+			subject = c.Type + ": " + subject
+		}
+		lines = append(lines, fmt.Sprintf("`%s` %s", c.ShortHash, subject))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WebhookPayload builds the JSON body for a webhook URL. Discord expects
+// "content"; Slack and most other services accept "text".
+func WebhookPayload(webhook string, s Session) ([]byte, error) {
+	key := "text"
+	if u, err := url.Parse(webhook); err == nil {
+		host := strings.ToLower(u.Hostname())
+		if host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com") {
+			key = "content"
+		}
+	}
+	return json.Marshal(map[string]string{key: s.Text()})
+}
+
+// PostWebhook sends the session summary to a Slack or Discord webhook
+func PostWebhook(webhook string, s Session) error {
+	body, err := WebhookPayload(webhook, s)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook failed: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+// Package keychain keeps secrets in the operating system's credential
+// store: the macOS Keychain, the Windows Credential Manager, or a Secret
+// Service provider such as GNOME Keyring via libsecret elsewhere.
+package keychain
+
+import "errors"
+
+// Service is the service name commity's entries are stored under
+const Service = "commity"
+
+// ErrNotFound is returned when the store has no matching entry
+var ErrNotFound = errors.New("no keychain entry")
+
+// Get returns the secret stored for account under service
+func Get(service, account string) (string, error) {
+	return get(service, account)
+}
+
+// Set stores secret for account under service, replacing any existing one.
+// It fails when no credential store is available.
+func Set(service, account, secret string) error {
+	return set(service, account, secret)
+}
@@ -0,0 +1,43 @@
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// get reads a generic password with the security tool
+func get(service, account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		// Exit status 44 is errSecItemNotFound
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("reading keychain: %w", err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// set adds or updates a generic password. The command is fed to security's
+// interactive mode so the secret never shows up in the process list.
+func set(service, account, secret string) error {
+	for _, s := range []string{service, account, secret} {
+		if strings.ContainsAny(s, "\"\\\n") {
+			return fmt.Errorf("writing keychain: unsupported characters")
+		}
+	}
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s \"%s\" -a \"%s\" -w \"%s\"\n", service, account, secret))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("writing keychain: %s", strings.TrimSpace(string(out)))
+	}
+	// Interactive mode exits with 0 even when the command fails
+	if stored, err := get(service, account); err != nil || stored != secret {
+		return fmt.Errorf("writing keychain: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+//go:build !darwin && !windows
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// get looks the secret up with secret-tool from libsecret
+func get(service, account string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", fmt.Errorf("secret-tool not found; install libsecret-tools to use the keychain")
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		// secret-tool exits with 1 and no output when nothing matches
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("reading keychain: %w", err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// set stores the secret with secret-tool, which reads it from stdin so it
+// never shows up in the process list
+func set(service, account, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label="+service, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("secret-tool not found; install libsecret-tools to use the keychain")
+		}
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("writing keychain: %s", msg)
+		}
+		return fmt.Errorf("writing keychain: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Issue is an added line that probably shouldn't be committed
+type Issue struct {
+	Kind string // "conflict marker", "debug statement" or "do-not-commit tag"
+	File string
+	Line int // line number in the new version of the file
+	Text string
+}
+
+// Location renders the issue as file:line
+func (i Issue) Location() string {
+	return fmt.Sprintf("%s:%d", i.File, i.Line)
+}
+
+var checks = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"conflict marker", regexp.MustCompile(`^(<{7}|={7}|>{7}|\|{7})(\s|$)`)},
+	{"debug statement", regexp.MustCompile(`(?i)(fmt\.Print(ln|f)?\(\s*"DEBUG|log\.Print(ln|f)?\(\s*"DEBUG|\bconsole\.(log|debug)\(|^\s*debugger;?\s*$|\bdbg!\(|binding\.pry|\bpdb\.set_trace\(\)|^\s*breakpoint\(\)|\bvar_dump\(|\bprint\(\s*["']DEBUG)`)},
+	{"do-not-commit tag", regexp.MustCompile(`(?i)\b(nocommit|do ?not ?commit|don'?t commit|(todo|fixme|remove|fix)[: ]+before (commit|committing|merge))\b`)},
+}
+
+// hunkHeader captures the start line of the new side of a hunk
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)`)
+
+// Scan reports conflict markers, debug statements and do-not-commit tags
+// in the added lines of a unified diff
+func Scan(diff string) []Issue {
+	var issues []Issue
+	var file string
+	line := 0
+	for _, l := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(l, "diff --git "):
+			file = ""
+			if j := strings.LastIndex(l, " b/"); j != -1 {
+				file = l[j+3:]
+			}
+		case strings.HasPrefix(l, "+++ "), strings.HasPrefix(l, "--- "):
+		case strings.HasPrefix(l, "@@"):
+			if m := hunkHeader.FindStringSubmatch(l); m != nil {
+				line, _ = strconv.Atoi(m[1])
+			}
+		case strings.HasPrefix(l, "+"):
+			for _, c := range checks {
+				if c.re.MatchString(l[1:]) {
+					issues = append(issues, Issue{Kind: c.kind, File: file, Line: line, Text: strings.TrimSpace(l[1:])})
+					break
+				}
+			}
+			line++
+		case strings.HasPrefix(l, " "):
+			line++
+		}
+	}
+	return issues
+}
+
+// In returns the issues in the given files
+func In(issues []Issue, files []string) []Issue {
+	var in []Issue
+	for _, i := range issues {
+		for _, f := range files {
+			if i.File == f {
+				in = append(in, i)
+				break
+			}
+		}
+	}
+	return in
+}
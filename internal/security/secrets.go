@@ -0,0 +1,136 @@
+package security
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Finding is a likely secret detected in a diff
+type Finding struct {
+	Kind string // e.g. "AWS access key"
+	File string // file the diff section belongs to
+}
+
+// secretPattern matches a secret; Group selects the part to redact (0 = whole match)
+type secretPattern struct {
+	kind  string
+	re    *regexp.Regexp
+	group int
+}
+
+var secretPatterns = []secretPattern{
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), 0},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`), 0},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), 0},
+	{"Stripe key", regexp.MustCompile(`\b[sr]k_(?:live|test)_[0-9A-Za-z]{24,}`), 0},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`), 0},
+	{"API key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`), 0},
+	{"JWT", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`), 0},
+	// KEY=value assignments in .env files, configs and code. The value ends at
+	// a quote, separator or space, so comments and other code may follow.
+	{"secret assignment", regexp.MustCompile(`(?i)(secret|token|passw(?:or)?d|api_?key|private_?key|access_?key)[a-z0-9_.-]*["']?\s*[:=]\s*(["']?)([A-Za-z0-9+/=_\-.~]{8,})(?:["',;\s]|$)`), 3},
+}
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// Scan reports likely secrets in a unified diff
+func Scan(diff string) []Finding {
+	_, findings := Redact(diff)
+	return findings
+}
+
+// Redact replaces likely secrets in a unified diff with placeholders and
+// reports what it found. Diff headers are left untouched.
+func Redact(diff string) (string, []Finding) {
+	var findings []Finding
+	var file string
+	inKey := false
+	inHeader := true // file headers until the first hunk
+
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "diff --git ") {
+			if j := strings.LastIndex(line, " b/"); j != -1 {
+				file = line[j+3:]
+			}
+			inKey = false
+			inHeader = true
+			continue
+		}
+		if strings.HasPrefix(line, "@@ ") {
+			inHeader = false
+			continue
+		}
+		if inHeader && isHeader(line) {
+			continue
+		}
+
+		// Private keys span lines: keep the markers, drop the body
+		if inKey {
+			if privateKeyEnd.MatchString(line) {
+				inKey = false
+			} else if len(line) > 0 {
+				lines[i] = line[:1] + "[REDACTED]"
+			}
+			continue
+		}
+		if privateKeyBegin.MatchString(line) {
+			findings = append(findings, Finding{Kind: "private key", File: file})
+			inKey = !privateKeyEnd.MatchString(line)
+			continue
+		}
+
+		for _, p := range secretPatterns {
+			locs := p.re.FindAllStringSubmatchIndex(line, -1)
+			if p.group > 0 {
+				locs = slices.DeleteFunc(locs, func(loc []int) bool {
+					return !credential(line, loc)
+				})
+			}
+			if len(locs) == 0 {
+				continue
+			}
+			findings = append(findings, Finding{Kind: p.kind, File: file})
+			// Replace from the end so earlier offsets stay valid
+			for k := len(locs) - 1; k >= 0; k-- {
+				start, end := locs[k][2*p.group], locs[k][2*p.group+1]
+				line = line[:start] + "[REDACTED]" + line[end:]
+			}
+		}
+		lines[i] = line
+	}
+
+	return strings.Join(lines, "\n"), findings
+}
+
+// credential reports whether a matched assignment holds a credential rather
+// than configuration. Plain words and numbers (token_type = "bearer",
+// max_tokens: 100000) are settings, but a quoted password may be any word.
+func credential(line string, loc []int) bool {
+	key := strings.ToLower(line[loc[2]:loc[3]])
+	quoted := loc[5] > loc[4]
+	value := line[loc[6]:loc[7]]
+	return mixedValue(value) || (quoted && strings.HasPrefix(key, "passw"))
+}
+
+// mixedValue reports whether an assigned value mixes letters and digits
+func mixedValue(v string) bool {
+	return strings.IndexFunc(v, unicode.IsLetter) != -1 && strings.IndexFunc(v, unicode.IsDigit) != -1
+}
+
+// isHeader reports whether a line of a file's header is metadata rather
+// than content. Within hunks, removed lines such as "-- comment" only look
+// like headers.
+func isHeader(line string) bool {
+	for _, prefix := range []string{"+++ ", "--- ", "index ", "new file mode", "deleted file mode", "similarity index", "rename ", "Binary files"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// History entry kinds
+const (
+	HistoryGenerated = "generated" // proposed by the AI
+	HistoryEdited    = "edited"    // changed by the user
+	HistoryCommitted = "committed" // used for a commit
+)
+
+// maxHistory is how many messages are kept; older ones are dropped
+const maxHistory = 1000
+
+// HistoryEntry is a commit message seen in a session
+type HistoryEntry struct {
+	Time    time.Time `json:"time"`
+	Repo    string    `json:"repo"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// HistoryPath returns the file holding the message history of all
+// repositories, one JSON entry per line
+func HistoryPath() string {
+	return filepath.Join(xdg.DataHome, "commity", "history.jsonl")
+}
+
+// AppendHistory records a message. Once the history outgrows maxHistory
+// entries, the oldest are dropped.
+func AppendHistory(e HistoryEntry) error {
+	path := HistoryPath()
+	// Messages describe private code
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines) <= maxHistory+1 { // the last element is empty
+		return nil
+	}
+	return os.WriteFile(path, bytes.Join(lines[len(lines)-1-maxHistory:], nil), 0600)
+}
+
+// LoadHistory returns the recorded messages, newest first. Lines that can't
+// be read are skipped.
+func LoadHistory() ([]HistoryEntry, error) {
+	f, err := os.Open(HistoryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil && e.Message != "" {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
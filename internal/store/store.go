@@ -0,0 +1,127 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/adrg/xdg"
+)
+
+// Repo holds what commity remembers about a single repository.
+type Repo struct {
+	Path    string              `json:"path"`
+	Presets map[string][]string `json:"presets,omitempty"` // named file selections
+
+	LastCommit string `json:"last_commit,omitempty"` // hash of the last commit made by commity
+	Notes      []Note `json:"notes,omitempty"`       // private reminders shown on the next run
+
+	ModelPicks map[string]int `json:"model_picks,omitempty"` // how often each model won a comparison
+
+	LastSelection map[string][]string `json:"last_selection,omitempty"` // files last submitted, by branch
+}
+
+// RememberSelection records the files submitted on branch
+func (r *Repo) RememberSelection(branch string, files []string) {
+	if r.LastSelection == nil {
+		r.LastSelection = make(map[string][]string)
+	}
+	r.LastSelection[branch] = append([]string(nil), files...)
+}
+
+// RecordPick counts a comparison won by model
+func (r *Repo) RecordPick(model string) {
+	if r.ModelPicks == nil {
+		r.ModelPicks = make(map[string]int)
+	}
+	r.ModelPicks[model]++
+}
+
+// Note is a private reminder attached to a session, e.g. an unfinished TODO
+type Note struct {
+	Text    string    `json:"text"`
+	Created time.Time `json:"created"`
+}
+
+// AddNote stores a reminder for the next session in this repository
+func (r *Repo) AddNote(text string, now time.Time) {
+	r.Notes = append(r.Notes, Note{Text: text, Created: now})
+}
+
+// ClearNotes forgets all reminders
+func (r *Repo) ClearNotes() {
+	r.Notes = nil
+}
+
+// Dir returns the directory holding per-repository state files
+func Dir() string {
+	return filepath.Join(xdg.StateHome, "commity", "repos")
+}
+
+// FilePath returns the state file for a repository, keyed by a hash of its path
+func FilePath(repoPath string) string {
+	sum := sha256.Sum256([]byte(repoPath))
+	return filepath.Join(Dir(), hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load reads the state for a repository. A missing file yields empty state.
+func Load(repoPath string) (*Repo, error) {
+	r := &Repo{Path: repoPath}
+
+	data, err := os.ReadFile(FilePath(repoPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	r.Path = repoPath
+	return r, nil
+}
+
+// Save writes the repository state to disk
+func (r *Repo) Save() error {
+	path := FilePath(r.Path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// SavePreset stores a named file selection, replacing any existing one
+func (r *Repo) SavePreset(name string, files []string) {
+	if r.Presets == nil {
+		r.Presets = make(map[string][]string)
+	}
+	r.Presets[name] = append([]string(nil), files...)
+}
+
+// Preset returns the files of a named selection
+func (r *Repo) Preset(name string) ([]string, bool) {
+	files, ok := r.Presets[name]
+	return files, ok
+}
+
+// PresetNames returns the names of all saved selections in sorted order
+func (r *Repo) PresetNames() []string {
+	names := make([]string, 0, len(r.Presets))
+	for name := range r.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,156 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// Description says exactly what is collected. It is shown in settings and
+// by `commity telemetry status`.
+const Description = "Anonymous counters only: the AI provider type (e.g. openai), " +
+	"categories of errors (e.g. timeout) and which features are used (e.g. split). " +
+	"No code, diffs, file names, commit messages, repository names or identifiers are collected."
+
+// reportInterval is the minimum time between reports
+const reportInterval = 24 * time.Hour
+
+// namePattern restricts counters to fixed categories and short slugs, so
+// nothing identifying can end up in a counter name
+var namePattern = regexp.MustCompile(`^(provider|error|feature|command)\.[a-z0-9_-]{1,32}$`)
+
+// Counters are the counts kept locally between reports
+type Counters struct {
+	Counts   map[string]int `json:"counts"`
+	LastSent time.Time      `json:"last_sent,omitzero"`
+}
+
+// Report is the payload sent to the endpoint
+type Report struct {
+	Version string         `json:"version"`
+	Counts  map[string]int `json:"counts"`
+}
+
+// Path returns the file holding the local counters
+func Path() string {
+	return filepath.Join(xdg.StateHome, "commity", "telemetry.json")
+}
+
+// Load reads the local counters. A missing file yields no counts.
+func Load() (*Counters, error) {
+	c := &Counters{Counts: make(map[string]int)}
+	data, err := os.ReadFile(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	if c.Counts == nil {
+		c.Counts = make(map[string]int)
+	}
+	return c, nil
+}
+
+// Save writes the local counters
+func (c *Counters) Save() error {
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Count increments a counter such as "feature.split" when telemetry is
+// enabled. Failures are ignored; telemetry never gets in the way.
+func Count(cfg config.TelemetryConfig, name string) {
+	if !cfg.Enabled || !namePattern.MatchString(name) {
+		return
+	}
+	c, err := Load()
+	if err != nil {
+		return
+	}
+	c.Counts[name]++
+	_ = c.Save()
+}
+
+// Categorize maps an error to a coarse category, never its message
+func Categorize(err error) string {
+	if err == nil {
+		return ""
+	}
+	var netErr net.Error
+	msg := strings.ToLower(err.Error())
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || strings.Contains(msg, "timed out"):
+		return "timeout"
+	case strings.Contains(msg, "401") || strings.Contains(msg, "403") || strings.Contains(msg, "api key") || strings.Contains(msg, "unauthorized"):
+		return "auth"
+	case strings.Contains(msg, "429") || strings.Contains(msg, "rate limit"):
+		return "rate_limit"
+	case errors.As(err, &netErr) || strings.Contains(msg, "connection refused") || strings.Contains(msg, "no such host"):
+		return "network"
+	case strings.HasPrefix(msg, "git ") || strings.Contains(msg, "not a git repository"):
+		return "git"
+	case strings.Contains(msg, "config"):
+		return "config"
+	}
+	return "other"
+}
+
+// Flush sends the counters to the configured endpoint at most once a day
+// and resets them. Without an endpoint they are only kept locally.
+func Flush(cfg config.TelemetryConfig, version string, now time.Time) error {
+	if !cfg.Enabled || cfg.Endpoint == "" {
+		return nil
+	}
+	c, err := Load()
+	if err != nil || len(c.Counts) == 0 || now.Sub(c.LastSent) < reportInterval {
+		return err
+	}
+
+	body, err := json.Marshal(Report{Version: version, Counts: c.Counts})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("telemetry report failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry report failed: %s", resp.Status)
+	}
+
+	c.Counts = make(map[string]int)
+	c.LastSent = now
+	return c.Save()
+}
@@ -0,0 +1,48 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// StartAmend switches to rewording the last commit: its diff is sent for
+// a new message, which replaces the old one with git commit --amend
+func (m *Model) StartAmend() error {
+	info, err := m.repo.LastCommit()
+	if err != nil {
+		return err
+	}
+	m.amend = info
+	m.count("feature.amend")
+	m.selected = info.Files
+	m.commits = nil
+	m.currentIndex = 0
+	m.state = stateGenerating
+	return nil
+}
+
+// initAmendOfferForm offers to amend when there is nothing new to commit
+func (m *Model) initAmendOfferForm(subject string) {
+	m.amendChoice = true
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("No changes to commit").
+				Description("Reword the last commit instead?\n" + subject).
+				Affirmative("Amend").
+				Negative("Quit").
+				Value(&m.amendChoice),
+		),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+}
+
+// completeAmendOfferForm starts amending or quits
+func (m *Model) completeAmendOfferForm() (tea.Model, tea.Cmd) {
+	if !m.amendChoice {
+		return m, tea.Quit
+	}
+	if err := m.StartAmend(); err != nil {
+		return m.setError(err)
+	}
+	return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+}
@@ -0,0 +1,153 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"github.com/hluaguo/commity/internal/ai"
+)
+
+// Choices on the branch offer screen
+const (
+	branchCreate = "create"
+	branchStay   = "stay"
+	branchBack   = "back"
+)
+
+type branchSuggestMsg struct {
+	name string
+}
+
+// beginCommit commits the current message, first offering a new branch
+// when committing on a protected branch
+func (m *Model) beginCommit() (tea.Model, tea.Cmd) {
+	if cmd := m.offerBranch(); cmd != nil {
+		return m, cmd
+	}
+	m.state = stateCommitting
+	return m, tea.Batch(m.spinner.Tick, m.doCommit())
+}
+
+// offerBranch starts suggesting a branch name, once per session. It returns
+// nil when the current branch isn't protected.
+func (m *Model) offerBranch() tea.Cmd {
+	if m.branchOffered || m.amend != nil {
+		return nil
+	}
+	m.branchOffered = true
+	m.currentBranch = m.repo.Branch()
+	if !m.cfg.Branch.Protects(m.currentBranch) {
+		return nil
+	}
+
+	m.screens.push(&branchScreen{formScreen{
+		loading: "Suggesting a branch name...",
+		render:  viewBranch,
+		done:    completeBranch,
+		back:    leaveBranch,
+	}})
+	var subjects, files []string
+	for _, c := range m.commits[m.currentIndex:] {
+		subjects = append(subjects, c.Header())
+		files = append(files, c.Files...)
+	}
+	commitType := m.commits[m.currentIndex].Type
+	fallback := ai.BranchName(m.commits[m.currentIndex].Subject, m.cfg.Branch.Prefix, commitType)
+
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		if m.aiClient == nil {
+			return branchSuggestMsg{name: fallback}
+		}
+		// The diff is only a hint; leave it out rather than ask about secrets again
+		var hint string
+		if diff, err := m.promptDiff(); err == nil {
+			if safe, findings := m.protectDiff(diff); len(findings) == 0 {
+				hint = safe
+			}
+		}
+		name, err := m.aiClient.SuggestBranchName(context.Background(), subjects, files, hint)
+		if err != nil {
+			return branchSuggestMsg{name: fallback}
+		}
+		return branchSuggestMsg{name: ai.BranchName(name, m.cfg.Branch.Prefix, commitType)}
+	})
+}
+
+// branchScreen offers a new branch once its name is suggested
+type branchScreen struct {
+	formScreen
+}
+
+func (s *branchScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(branchSuggestMsg); ok {
+		s.form = m.branchForm(msg.name)
+		return m, s.form.Init()
+	}
+	return s.formScreen.update(m, msg)
+}
+
+// branchForm shows the suggested name for editing
+func (m *Model) branchForm(name string) *huh.Form {
+	m.branchName = name
+	m.branchChoice = branchCreate
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Branch name").
+				Value(&m.branchName),
+			huh.NewSelect[string]().
+				Title(fmt.Sprintf("You are about to commit on %s", m.currentBranch)).
+				Options(
+					huh.NewOption("Create the branch and commit there", branchCreate),
+					huh.NewOption(fmt.Sprintf("Commit on %s", m.currentBranch), branchStay),
+					huh.NewOption("Back", branchBack),
+				).
+				Value(&m.branchChoice),
+		),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+}
+
+// completeBranch switches branches if asked and continues committing
+func completeBranch(m *Model) (tea.Model, tea.Cmd) {
+	switch m.branchChoice {
+	case branchBack:
+		return leaveBranch(m)
+	case branchCreate:
+		name := strings.TrimSpace(m.branchName)
+		if name == "" {
+			return m.setError(fmt.Errorf("branch name is empty"))
+		}
+		if err := m.repo.CreateBranch(name); err != nil {
+			return m.setError(err)
+		}
+		m.notice = "Switched to new branch " + name
+	}
+	return m.beginCommit()
+}
+
+// leaveBranch goes back to the message or plan, offering the branch again
+// on the next commit
+func leaveBranch(m *Model) (tea.Model, tea.Cmd) {
+	m.branchOffered = false
+	if m.commitAll {
+		m.commitAll = false
+		m.state = statePlan
+		m.initPlanForm()
+		return m, m.form.Init()
+	}
+	m.state = stateConfirm
+	m.initConfirmForm()
+	return m, m.confirmForm.Init()
+}
+
+// viewBranch renders the branch offer
+func viewBranch(m *Model, form string) string {
+	return form + "\n" +
+		m.renderKeyHint("[tab]", "next") + "  " +
+		m.renderKeyHint("[enter]", "confirm") + "  " +
+		m.renderKeyHint("[esc]", "back")
+}
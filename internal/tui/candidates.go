@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxCandidates is how many generated messages are kept per selection
+const maxCandidates = 10
+
+// keepCandidate adds a freshly generated message to the attempts for the
+// current selection, so regenerating never loses an earlier one. Attempts
+// for another selection, or split plans, start over.
+func (m *Model) keepCandidate() {
+	key := strings.Join(m.selected, "\x00")
+	if m.amend != nil {
+		key = m.amend.Hash
+	}
+	if m.isSplit || len(m.commits) != 1 {
+		m.candidates, m.candidatesKey = nil, ""
+		return
+	}
+	if key != m.candidatesKey {
+		m.candidates, m.candidatesKey = nil, key
+	}
+	m.candidates = append(m.candidates, m.commits[0])
+	if len(m.candidates) > maxCandidates {
+		m.candidates = m.candidates[1:]
+	}
+	m.candidate = len(m.candidates) - 1
+}
+
+// switchCandidate shows the attempt delta steps away, keeping edits made to
+// the one shown
+func (m *Model) switchCandidate(delta int) {
+	if len(m.candidates) < 2 || m.currentIndex != 0 {
+		return
+	}
+	m.candidates[m.candidate] = m.commits[0]
+	m.candidate = (m.candidate + delta + len(m.candidates)) % len(m.candidates)
+	m.commits[0] = m.candidates[m.candidate]
+}
+
+// viewCandidates says which attempt is shown when there are several
+func (m *Model) viewCandidates() string {
+	if len(m.candidates) < 2 || m.currentIndex != 0 {
+		return ""
+	}
+	return m.styles.Dim.Render(fmt.Sprintf("Attempt %d of %d", m.candidate+1, len(m.candidates))) + "  " +
+		m.renderKeyHint("[←→]", "switch")
+}
@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/git"
+	"github.com/hluaguo/commity/internal/lint"
+)
+
+// compareMsg carries the results of both models of a comparison
+type compareMsg struct {
+	models  [2]string
+	results [2]*ai.GenerateResult
+	errs    [2]error
+	hunks   map[string]git.FileDiff
+	issues  []lint.Issue
+}
+
+// SetCompare generates every message with a second client as well and lets
+// the user pick the better result
+func (m *Model) SetCompare(client *ai.Client) {
+	m.compareClient = client
+}
+
+// generateBoth asks both models at once
+func (m *Model) generateBoth(ctx context.Context, pc ai.PromptContext, hunks map[string]git.FileDiff, issues []lint.Issue) tea.Msg {
+	msg := compareMsg{
+		models: [2]string{m.aiClient.Model(), m.compareClient.Model()},
+		hunks:  hunks,
+		issues: issues,
+	}
+	var wg sync.WaitGroup
+	for i, client := range []*ai.Client{m.aiClient, m.compareClient} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg.results[i], msg.errs[i] = client.GenerateCommitMessage(ctx, pc)
+		}()
+	}
+	wg.Wait()
+	return msg
+}
+
+// startCompare shows both results, or goes on with the one that worked
+func (m *Model) startCompare(msg compareMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.errs[0] != nil && msg.errs[1] != nil:
+		return m.setError(msg.errs[0])
+	case msg.errs[0] != nil || msg.errs[1] != nil:
+		ok := 0
+		if msg.errs[0] != nil {
+			ok = 1
+		}
+		m.notice = fmt.Sprintf("%s failed, using %s: %v", msg.models[1-ok], msg.models[ok], msg.errs[1-ok])
+		return m, m.useResult(msg, ok)
+	}
+	m.screens.push(&compareScreen{cmp: msg})
+	return m, nil
+}
+
+// compareScreen shows the results of both models side by side
+type compareScreen struct {
+	cmp compareMsg
+}
+
+// update picks a result with 1 or 2 and records the winner
+func (c *compareScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	pick := -1
+	switch key.String() {
+	case "1", "left":
+		pick = 0
+	case "2", "right":
+		pick = 1
+	}
+	if pick < 0 {
+		return m, nil
+	}
+
+	m.screens.pop()
+	m.repoState.RecordPick(c.cmp.models[pick])
+	if err := m.repoState.Save(); err != nil {
+		m.notice = "Failed to record the pick: " + err.Error()
+	}
+	return m, m.useResult(c.cmp, pick)
+}
+
+// dismiss drops both results and returns to file select
+func (c *compareScreen) dismiss(m *Model) (tea.Model, tea.Cmd) {
+	m.screens.pop()
+	m.state = stateFileSelect
+	m.initFileSelectFormWith(m.selected)
+	return m, m.form.Init()
+}
+
+// useResult continues with one result as if it had been generated alone
+func (m *Model) useResult(cmp compareMsg, i int) tea.Cmd {
+	return func() tea.Msg {
+		return generateMsg{result: cmp.results[i], hunks: cmp.hunks, issues: cmp.issues}
+	}
+}
+
+func (c *compareScreen) view(m *Model) string {
+	var s strings.Builder
+	cmp := c.cmp
+	width := max((m.termWidth-6)/2, minMessageWidth)
+	var columns [2]string
+	for i := range cmp.results {
+		columns[i] = m.renderCandidate(i+1, cmp.models[i], cmp.results[i], width)
+	}
+	s.WriteString("Which message is better?\n\n")
+	s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, columns[0], "  ", columns[1]))
+	s.WriteString("\n\n")
+
+	picks := m.repoState.ModelPicks
+	if picks[cmp.models[0]]+picks[cmp.models[1]] > 0 {
+		s.WriteString(m.styles.Dim.Render(fmt.Sprintf("Picked in this repository: %s %d, %s %d",
+			cmp.models[0], picks[cmp.models[0]], cmp.models[1], picks[cmp.models[1]])))
+		s.WriteString("\n\n")
+	}
+	s.WriteString(m.renderKeyHint("[1]", "left") + "  " +
+		m.renderKeyHint("[2]", "right") + "  " +
+		m.renderKeyHint("[esc]", "back"))
+	return s.String()
+}
+
+// renderCandidate renders one model's messages in a bordered column
+func (m *Model) renderCandidate(n int, model string, result *ai.GenerateResult, width int) string {
+	var b strings.Builder
+	b.WriteString(m.styles.Title.Render(fmt.Sprintf("%d. %s", n, model)))
+	b.WriteString("\n")
+	usage := fmt.Sprintf("%d commit(s), %d+%d tokens", len(result.Commits), result.Usage.PromptTokens, result.Usage.CompletionTokens)
+	b.WriteString(m.styles.Dim.Render(usage))
+	b.WriteString("\n")
+	for _, c := range result.Commits {
+		b.WriteString("\n")
+		b.WriteString(c.String())
+		b.WriteString("\n")
+		if result.IsSplit {
+			b.WriteString(m.styles.Dim.Render(strings.Join(c.Files, ", ")))
+			b.WriteString("\n")
+		}
+	}
+	return m.styles.Message.Width(width).Render(strings.TrimRight(b.String(), "\n"))
+}
@@ -15,8 +15,14 @@ type ConfirmModel struct {
 	input     textinput.Model
 	theme     *Theme
 	submitted bool
-	action    string // "commit", "cancel", "regenerate"
+	action    string // "commit", "cancel", "regenerate", "edit", "instruct", "merge", "model", "hooks", "push", "files", "fresh", "history", "diff", "previous", "next"
 	feedback  string
+	canPush   bool // a fork remote is configured for "commit & push"
+
+	canReselect bool // nothing was committed yet, so the selection may change
+	cached      bool // the message came from the result cache
+
+	keys keyMap
 }
 
 func NewConfirmModel(theme *Theme) *ConfirmModel {
@@ -63,7 +69,7 @@ func (m *ConfirmModel) Update(msg tea.Msg) (*ConfirmModel, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
+		switch resolve(msg, m.keys.Edit, m.keys.Regenerate, m.keys.Diff, m.keys.SkipHooks) {
 		case "up", "k":
 			if m.cursor > 0 {
 				m.cursor--
@@ -97,6 +103,73 @@ func (m *ConfirmModel) Update(msg tea.Msg) (*ConfirmModel, tea.Cmd) {
 			m.submitted = true
 			m.action = "edit"
 			return m, nil
+
+		case "r", "R":
+			m.submitted = true
+			m.action = "regenerate"
+			m.feedback = m.input.Value()
+			return m, nil
+
+		case "i", "I":
+			m.submitted = true
+			m.action = "instruct"
+			return m, nil
+
+		case "m":
+			m.submitted = true
+			m.action = "model"
+			return m, nil
+
+		case "M":
+			m.submitted = true
+			m.action = "merge"
+			return m, nil
+
+		case "!":
+			m.submitted = true
+			m.action = "hooks"
+			return m, nil
+
+		case "p", "P":
+			if m.canPush {
+				m.submitted = true
+				m.action = "push"
+			}
+			return m, nil
+
+		case "f", "F":
+			if m.canReselect {
+				m.submitted = true
+				m.action = "files"
+			}
+			return m, nil
+
+		case "left":
+			m.submitted = true
+			m.action = "previous"
+			return m, nil
+
+		case "right":
+			m.submitted = true
+			m.action = "next"
+			return m, nil
+
+		case "d", "D":
+			m.submitted = true
+			m.action = "diff"
+			return m, nil
+
+		case "h", "H":
+			m.submitted = true
+			m.action = "history"
+			return m, nil
+
+		case "g", "G":
+			if m.cached {
+				m.submitted = true
+				m.action = "fresh"
+			}
+			return m, nil
 		}
 	}
 
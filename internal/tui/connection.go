@@ -0,0 +1,148 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/telemetry"
+)
+
+// connectionMsg reports the outcome of testing the configured model
+type connectionMsg struct {
+	model   string
+	elapsed time.Duration
+	err     error
+}
+
+// testConnection sends the model of the completed settings form a tiny
+// request before anything is saved, so a wrong URL, key or model shows up
+// now instead of at the first generation
+func (m *Model) testConnection() (tea.Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.screens.push(&connectionScreen{cancel: cancel})
+	aiCfg, err := m.cfg.EffectiveAI(m.remoteHost)
+	return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+		if err != nil {
+			return connectionMsg{model: aiCfg.Model, err: err}
+		}
+		client, err := ai.New(&aiCfg)
+		if err != nil {
+			return connectionMsg{model: aiCfg.Model, err: err}
+		}
+		start := time.Now()
+		err = client.Ping(ctx)
+		if ctx.Err() != nil {
+			return nil // cancelled, nobody is waiting
+		}
+		return connectionMsg{model: client.Model(), elapsed: time.Since(start), err: err}
+	})
+}
+
+// connectionScreen shows the running test over the completed form, then why
+// it failed
+type connectionScreen struct {
+	cancel context.CancelFunc // stops the request
+	done   bool               // the model answered or the request failed
+	err    error
+	model  string
+}
+
+func (c *connectionScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case spinner.TickMsg:
+		if !c.done {
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			return m, cmd
+		}
+	case connectionMsg:
+		return c.answered(m, msg)
+	case tea.KeyMsg:
+		if !c.done {
+			return m, nil
+		}
+		switch msg.String() {
+		case "e", "E":
+			return c.dismiss(m)
+		case "s", "S":
+			m.screens.pop()
+			return m.saveSettings(m.state)
+		}
+	}
+	return m, nil
+}
+
+// answered saves the settings once the model answered, or shows why it
+// didn't
+func (c *connectionScreen) answered(m *Model, msg connectionMsg) (tea.Model, tea.Cmd) {
+	c.done = true
+	c.cancel()
+	if msg.err != nil {
+		m.count("error." + telemetry.Categorize(msg.err))
+		c.err = msg.err
+		c.model = msg.model
+		return m, nil
+	}
+	m.screens.pop()
+	m.notice = fmt.Sprintf("Connection OK: %s answered in %s", msg.model, msg.elapsed.Round(time.Millisecond))
+	return m.saveSettings(m.state)
+}
+
+// dismiss cancels a running test and goes back to the form, which still
+// holds the values entered
+func (c *connectionScreen) dismiss(m *Model) (tea.Model, tea.Cmd) {
+	m.screens.pop()
+	if !c.done {
+		c.cancel()
+		m.notice = "Connection test cancelled"
+	}
+	if m.state == stateInit {
+		m.initFirstRunForm()
+	} else {
+		m.initSettingsForm()
+	}
+	return m, m.form.Init()
+}
+
+// view renders the running test or its failure with a hint
+func (c *connectionScreen) view(m *Model) string {
+	if !c.done {
+		return m.spinner.View() + " Testing the connection...\n\n" +
+			m.renderKeyHint("[esc]", "cancel")
+	}
+	var s strings.Builder
+	s.WriteString(wrapText(m.styles.Error.Render("Connection failed: "+c.err.Error()), m.termWidth-2))
+	s.WriteString("\n\n")
+	aiCfg, _ := m.cfg.EffectiveAI(m.remoteHost)
+	aiCfg.Model = c.model
+	if hint := ai.PingHint(c.err, aiCfg); hint != "" {
+		s.WriteString(wrapText("• "+hint, m.termWidth-2))
+		s.WriteString("\n\n")
+	}
+	s.WriteString(m.renderKeyHint("[e]", "edit settings") + "  " +
+		m.renderKeyHint("[s]", "save anyway") + "  " +
+		m.renderKeyHint("[esc]", "back"))
+	return s.String()
+}
+
+// saveSettings applies the completed first-run or settings form
+func (m *Model) saveSettings(from state) (tea.Model, tea.Cmd) {
+	if err := m.applyConfigChanges(); err != nil {
+		return m.setError(err)
+	}
+	if from == stateInit {
+		return m, func() tea.Msg { return initCompleteMsg{} }
+	}
+	m.state = m.previousState
+	if m.state == stateError {
+		return m, nil // keep the selection for a retry
+	}
+	m.initFileSelectForm()
+	return m, m.form.Init()
+}
@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/ai"
+)
+
+// proposal is a generation the user went back to file selection from, kept
+// so adding a file only sends that file's diff
+type proposal struct {
+	files   []string
+	commits []ai.CommitMessage
+	diff    string // prompt diff of files, to tell whether they changed since
+}
+
+// canReselect reports whether the user may go back to file selection from
+// the confirm screen, which needs that nothing was committed yet
+func (m *Model) canReselect() bool {
+	return m.amend == nil && len(m.created) == 0
+}
+
+// reselect returns to file selection, keeping the proposal for a delta prompt
+func (m *Model) reselect() (tea.Model, tea.Cmd) {
+	m.proposal = nil
+	if diff, err := m.promptDiffFor(m.selected); err == nil {
+		m.proposal = &proposal{files: slices.Clone(m.selected), commits: m.commits, diff: diff}
+	}
+	m.commits = nil
+	m.isSplit = false
+	m.state = stateFileSelect
+	m.initFileSelectFormWith(m.selected)
+	return m, m.form.Init()
+}
+
+// deltaBase returns the earlier proposal and the file added to the selection
+// since, when that is the only change. Proposals with split files are left
+// out: the model could not keep hunks it no longer sees.
+func (m *Model) deltaBase() (*proposal, []string) {
+	p := m.proposal
+	m.proposal = nil
+	if p == nil || m.amend != nil || m.compareClient != nil {
+		return nil, nil
+	}
+	if slices.ContainsFunc(p.commits, func(c ai.CommitMessage) bool { return len(c.Hunks) > 0 }) {
+		return nil, nil
+	}
+	var added []string
+	for _, f := range m.selected {
+		if !slices.Contains(p.files, f) {
+			added = append(added, f)
+		}
+	}
+	if len(added) != 1 || len(m.selected) != len(p.files)+1 {
+		return nil, nil
+	}
+	return p, added
+}
+
+// deltaContext turns pc into a delta prompt sending only the added file's
+// diff, protected like the full one. ok is false when the earlier files
+// changed since the proposal.
+func (m *Model) deltaContext(pc ai.PromptContext, p *proposal, added []string) (ai.PromptContext, bool) {
+	if diff, err := m.promptDiffFor(p.files); err != nil || diff != p.diff {
+		return pc, false
+	}
+	diff, err := m.promptDiffFor(added)
+	if err != nil {
+		return pc, false
+	}
+	// The added file's secrets get the same treatment as in the full diff
+	diff, findings := m.protectDiff(diff)
+	if len(findings) > 0 {
+		return pc, false
+	}
+	pc.Diff = diff
+	pc.Previous = p.commits
+	pc.Added = added
+	pc.Moves = nil
+	return pc, true
+}
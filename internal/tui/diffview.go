@@ -0,0 +1,95 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openDiff shows the diff of the current commit's files
+func (m *Model) openDiff() (tea.Model, tea.Cmd) {
+	commit := m.commits[m.currentIndex]
+	files := commit.Files
+	if len(files) == 0 {
+		files = m.selected
+	}
+	diff, err := m.selectionDiff(files)
+	if err != nil {
+		return m.setError(err)
+	}
+
+	d := &diffScreen{viewport: viewport.New(0, previewHeight), diff: diff, files: len(files)}
+	d.resize(m, m.termWidth)
+	m.screens.push(d)
+	return m, nil
+}
+
+// colorDiff colors added and removed lines, hunk and file headers, and cuts
+// lines to width so the viewport doesn't wrap them
+func (m *Model) colorDiff(diff string, width int) string {
+	added := lipgloss.NewStyle().Foreground(m.theme.Success)
+	removed := lipgloss.NewStyle().Foreground(m.theme.Error)
+	hunk := lipgloss.NewStyle().Foreground(m.theme.Primary)
+	file := lipgloss.NewStyle().Foreground(m.theme.Primary).Bold(true)
+
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		line = truncate(strings.ReplaceAll(line, "\t", "    "), width)
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			sb.WriteString("\n" + file.Render(line))
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "),
+			strings.HasPrefix(line, "index "), strings.HasPrefix(line, "new file"),
+			strings.HasPrefix(line, "deleted file"), strings.HasPrefix(line, "similarity"),
+			strings.HasPrefix(line, "rename "):
+			sb.WriteString(m.styles.Dim.Render(line))
+		case strings.HasPrefix(line, "@@"):
+			sb.WriteString(hunk.Render(line))
+		case strings.HasPrefix(line, "+"):
+			sb.WriteString(added.Render(line))
+		case strings.HasPrefix(line, "-"):
+			sb.WriteString(removed.Render(line))
+		default:
+			sb.WriteString(line)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimLeft(sb.String(), "\n")
+}
+
+// diffScreen scrolls through the diff of a commit
+type diffScreen struct {
+	viewport viewport.Model
+	diff     string
+	files    int
+}
+
+// resize fits the viewport to a terminal termWidth columns wide, recutting
+// the lines
+func (d *diffScreen) resize(m *Model, termWidth int) {
+	width := max(termWidth-editAreaPadding, 1)
+	d.viewport.Width = width
+	d.viewport.SetContent(m.colorDiff(d.diff, width))
+}
+
+func (d *diffScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		d.resize(m, size.Width)
+		return m, nil
+	}
+	var cmd tea.Cmd
+	d.viewport, cmd = d.viewport.Update(msg)
+	return m, cmd
+}
+
+func (d *diffScreen) view(m *Model) string {
+	title := fmt.Sprintf("Diff of %d file(s)  %3.f%%", d.files, d.viewport.ScrollPercent()*100)
+	return m.styles.Dim.Render(title) + "\n\n" +
+		d.viewport.View() + "\n\n" +
+		m.renderKeyHint("[↑↓]", "scroll") + "  " +
+		m.renderKeyHint("[pgup/pgdn]", "page") + "  " +
+		m.renderKeyHint("[esc]", "back")
+}
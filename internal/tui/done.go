@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// holdDone keeps the done screen up while there are commits to copy from,
+// and quits otherwise
+func (m *Model) holdDone() tea.Cmd {
+	if len(m.created) == 0 {
+		return tea.Quit
+	}
+	return nil
+}
+
+// ended reports whether the session is over, rather than offering a push
+// over the done screen
+func (m *Model) ended() bool {
+	return m.state == stateDone && m.screens.top() == nil
+}
+
+// updateDone copies the hashes or messages of the session's commits; any
+// other key quits. Until the webhook answers, only ctrl+c does, so that
+// quitting doesn't cut the post short.
+func (m *Model) updateDone(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.webhookPending {
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+	var text, what string
+	switch msg.String() {
+	case "c":
+		var hashes []string
+		for _, c := range m.created {
+			hashes = append(hashes, c.Hash)
+		}
+		text, what = strings.Join(hashes, "\n"), "hash"
+	case "y":
+		var messages []string
+		for _, c := range m.created {
+			messages = append(messages, c.Message)
+		}
+		text, what = strings.Join(messages, "\n\n"), "message"
+	case "?":
+		return m.openHelp()
+	default:
+		return m, tea.Quit
+	}
+	if len(m.created) > 1 {
+		what += "s"
+	}
+	m.copyErr = clipboard.WriteAll(text)
+	if m.copyErr == nil {
+		m.notice = "Copied the commit " + what
+	}
+	m.count("feature.copy_done")
+	return m, nil
+}
+
+// viewCreated lists the session's commits with their hash and changes
+func (m *Model) viewCreated(s *strings.Builder) {
+	for _, c := range m.created {
+		header, _, _ := strings.Cut(c.Message, "\n")
+		s.WriteString("  " + m.styles.Title.Render(c.ShortHash) + " " + m.styles.Dim.Render(header))
+		s.WriteString("\n")
+		if stat := m.stats[c.Hash]; stat != "" {
+			s.WriteString(m.styles.Dim.Render(fmt.Sprintf("  %s %s", strings.Repeat(" ", len(c.ShortHash)), stat)))
+			s.WriteString("\n")
+		}
+	}
+}
+
+// viewDoneKeys renders the copy keys below the done screen
+func (m *Model) viewDoneKeys(s *strings.Builder) {
+	if !m.ended() || len(m.created) == 0 {
+		return
+	}
+	if m.webhookPending {
+		s.WriteString("\n")
+		s.WriteString(m.styles.Dim.Render("Notifying the webhook..."))
+		s.WriteString("\n")
+		return
+	}
+	if m.copyErr != nil {
+		s.WriteString("\n")
+		s.WriteString(wrapText(m.styles.Error.Render(fmt.Sprintf("copy to clipboard failed: %v", m.copyErr)), m.termWidth-2))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(m.renderKeyHint("[c]", "copy hash") + "  " +
+		m.renderKeyHint("[y]", "copy message") + "  " +
+		m.renderKeyHint("[any key]", "exit"))
+	s.WriteString("\n")
+}
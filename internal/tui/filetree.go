@@ -0,0 +1,385 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// treeRows is how many rows of the file tree are shown at once
+	treeRows = 20
+	// treeExpandLimit is the number of files up to which every directory
+	// starts expanded; beyond it only directories with checked files are
+	treeExpandLimit = 40
+)
+
+// treeFile is a changed file listed in the tree
+type treeFile struct {
+	path  string
+	label string // status and file name, e.g. "[M] model.go"
+}
+
+// treeDir is a directory of the tree. Chains of directories holding a single
+// directory and no files are merged into one, e.g. "internal/tui".
+type treeDir struct {
+	name  string
+	path  string
+	dirs  []*treeDir
+	files []treeFile
+}
+
+// treeRow is a visible line of the tree: a directory or a file
+type treeRow struct {
+	dir   *treeDir
+	file  *treeFile
+	depth int
+}
+
+// FileTree is a huh field selecting files grouped by directory. Directories
+// collapse and expand, and toggling one toggles every file below it.
+type FileTree struct {
+	title     string
+	root      *treeDir
+	value     *[]string
+	order     []string // all paths, in tree order
+	checked   map[string]bool
+	collapsed map[string]bool
+	rows      []treeRow
+	cursor    int
+	offset    int
+	focused   bool
+	theme     *huh.Theme
+	keys      keyMap
+}
+
+// NewFileTree builds the tree from files, in display order, with checked
+// paths selected. The selection is written to value as it changes.
+func NewFileTree(title string, files []treeFile, checked []string, value *[]string) *FileTree {
+	t := &FileTree{
+		title:     title,
+		root:      &treeDir{},
+		value:     value,
+		checked:   make(map[string]bool),
+		collapsed: make(map[string]bool),
+		theme:     huh.ThemeBase(),
+	}
+	for _, p := range checked {
+		t.checked[p] = true
+	}
+	for _, f := range files {
+		t.root.insert(f)
+	}
+	for _, d := range t.root.dirs {
+		d.compress()
+	}
+	t.root.walk(func(f treeFile) { t.order = append(t.order, f.path) })
+
+	// Large change sets start with the untouched directories folded
+	if len(files) > treeExpandLimit {
+		t.root.eachDir(func(d *treeDir) {
+			t.collapsed[d.path] = t.count(d) == 0
+		})
+	}
+	t.refresh()
+	return t
+}
+
+// insert adds f under the directories of its path
+func (d *treeDir) insert(f treeFile) {
+	dir := path.Dir(f.path)
+	if dir == "." {
+		d.files = append(d.files, f)
+		return
+	}
+	node := d
+	for i, part := range strings.Split(dir, "/") {
+		next := slices.IndexFunc(node.dirs, func(c *treeDir) bool { return c.name == part })
+		if next < 0 {
+			prefix := strings.Join(strings.Split(dir, "/")[:i+1], "/")
+			node.dirs = append(node.dirs, &treeDir{name: part, path: prefix})
+			next = len(node.dirs) - 1
+		}
+		node = node.dirs[next]
+	}
+	node.files = append(node.files, f)
+}
+
+// compress merges single-directory chains below d
+func (d *treeDir) compress() {
+	for len(d.dirs) == 1 && len(d.files) == 0 {
+		child := d.dirs[0]
+		d.name += "/" + child.name
+		d.path, d.dirs, d.files = child.path, child.dirs, child.files
+	}
+	for _, c := range d.dirs {
+		c.compress()
+	}
+}
+
+// walk calls fn for the files below d, directories first
+func (d *treeDir) walk(fn func(treeFile)) {
+	for _, c := range d.dirs {
+		c.walk(fn)
+	}
+	for _, f := range d.files {
+		fn(f)
+	}
+}
+
+// eachDir calls fn for every directory below d
+func (d *treeDir) eachDir(fn func(*treeDir)) {
+	for _, c := range d.dirs {
+		fn(c)
+		c.eachDir(fn)
+	}
+}
+
+// count returns how many files below d are checked
+func (t *FileTree) count(d *treeDir) int {
+	n := 0
+	d.walk(func(f treeFile) {
+		if t.checked[f.path] {
+			n++
+		}
+	})
+	return n
+}
+
+// refresh rebuilds the visible rows and writes the selection
+func (t *FileTree) refresh() {
+	t.rows = t.rows[:0]
+	var add func(d *treeDir, depth int)
+	add = func(d *treeDir, depth int) {
+		for _, c := range d.dirs {
+			t.rows = append(t.rows, treeRow{dir: c, depth: depth})
+			if !t.collapsed[c.path] {
+				add(c, depth+1)
+			}
+		}
+		for i := range d.files {
+			t.rows = append(t.rows, treeRow{file: &d.files[i], depth: depth})
+		}
+	}
+	add(t.root, 0)
+	t.cursor = min(t.cursor, max(len(t.rows)-1, 0))
+
+	selected := []string{}
+	for _, p := range t.order {
+		if t.checked[p] {
+			selected = append(selected, p)
+		}
+	}
+	*t.value = selected
+}
+
+// toggle checks or unchecks the row under the cursor; a directory is
+// checked whole unless all its files already are
+func (t *FileTree) toggle() {
+	if len(t.rows) == 0 {
+		return
+	}
+	row := t.rows[t.cursor]
+	if row.file != nil {
+		t.checked[row.file.path] = !t.checked[row.file.path]
+		return
+	}
+	total := 0
+	row.dir.walk(func(treeFile) { total++ })
+	check := t.count(row.dir) < total
+	row.dir.walk(func(f treeFile) { t.checked[f.path] = check })
+}
+
+// checkAll sets whether each file is checked from whether it was
+func (t *FileTree) checkAll(check func(checked bool) bool) {
+	for _, p := range t.order {
+		t.checked[p] = check(t.checked[p])
+	}
+}
+
+// parent returns the row of the directory holding the row at i
+func (t *FileTree) parent(i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if t.rows[j].dir != nil && t.rows[j].depth < t.rows[i].depth {
+			return j
+		}
+	}
+	return i
+}
+
+func (t *FileTree) Init() tea.Cmd { return nil }
+
+func (t *FileTree) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || !t.focused {
+		return t, nil
+	}
+	switch resolve(keyMsg, t.keys.SelectAll) {
+	case "up", "k":
+		t.cursor = max(t.cursor-1, 0)
+	case "down", "j":
+		t.cursor = min(t.cursor+1, len(t.rows)-1)
+	case "home", "g":
+		t.cursor = 0
+	case "end", "G":
+		t.cursor = len(t.rows) - 1
+	case " ", "x":
+		t.toggle()
+	case "left":
+		// Fold the directory, or jump to the one holding the file
+		if row := t.rows[t.cursor]; row.dir != nil && !t.collapsed[row.dir.path] {
+			t.collapsed[row.dir.path] = true
+		} else {
+			t.cursor = t.parent(t.cursor)
+		}
+	case "right":
+		if row := t.rows[t.cursor]; row.dir != nil {
+			t.collapsed[row.dir.path] = false
+		}
+	case "ctrl+a":
+		all := len(*t.value) < len(t.order)
+		t.checkAll(func(bool) bool { return all })
+	case "a":
+		t.checkAll(func(bool) bool { return true })
+	case "n":
+		t.checkAll(func(bool) bool { return false })
+	case "i":
+		t.checkAll(func(checked bool) bool { return !checked })
+	case "enter":
+		return t, huh.NextField
+	default:
+		return t, nil
+	}
+	t.refresh()
+	return t, nil
+}
+
+func (t *FileTree) View() string {
+	styles := t.theme.Focused
+	if !t.focused {
+		styles = t.theme.Blurred
+	}
+
+	var sb strings.Builder
+	sb.WriteString(styles.Title.Render(fmt.Sprintf("%s (%d of %d)", t.title, len(*t.value), len(t.order))))
+	sb.WriteString("\n")
+
+	// Keep the cursor in the visible window
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+treeRows {
+		t.offset = t.cursor - treeRows + 1
+	}
+	t.offset = max(min(t.offset, len(t.rows)-treeRows), 0)
+
+	selector := styles.MultiSelectSelector.String()
+	for i := t.offset; i < min(t.offset+treeRows, len(t.rows)); i++ {
+		row := t.rows[i]
+		if i == t.cursor {
+			sb.WriteString(selector)
+		} else {
+			sb.WriteString(strings.Repeat(" ", lipgloss.Width(selector)))
+		}
+		sb.WriteString(strings.Repeat("  ", row.depth))
+
+		if row.file != nil {
+			if t.checked[row.file.path] {
+				sb.WriteString(styles.SelectedPrefix.String() + styles.SelectedOption.Render(row.file.label))
+			} else {
+				sb.WriteString(styles.UnselectedPrefix.String() + styles.UnselectedOption.Render(row.file.label))
+			}
+			sb.WriteString("\n")
+			continue
+		}
+
+		total := 0
+		row.dir.walk(func(treeFile) { total++ })
+		n := t.count(row.dir)
+		fold := "▾ "
+		if t.collapsed[row.dir.path] {
+			fold = "▸ "
+		}
+		label := fmt.Sprintf("%s%s/ (%d/%d)", fold, row.dir.name, n, total)
+		switch {
+		case n == total:
+			sb.WriteString(styles.SelectedPrefix.String() + styles.SelectedOption.Render(label))
+		case n > 0:
+			sb.WriteString(styles.SelectedOption.Render("[-] " + label))
+		default:
+			sb.WriteString(styles.UnselectedPrefix.String() + styles.UnselectedOption.Render(label))
+		}
+		sb.WriteString("\n")
+	}
+	if len(t.rows) > treeRows {
+		sb.WriteString(styles.Description.Render(fmt.Sprintf("  %d-%d of %d rows", t.offset+1, min(t.offset+treeRows, len(t.rows)), len(t.rows))))
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (t *FileTree) Focus() tea.Cmd {
+	t.focused = true
+	return nil
+}
+
+func (t *FileTree) Blur() tea.Cmd {
+	t.focused = false
+	return nil
+}
+
+func (t *FileTree) Error() error { return nil }
+
+func (t *FileTree) Run() error { return huh.Run(t) }
+
+func (t *FileTree) RunAccessible(io.Writer, io.Reader) error {
+	return fmt.Errorf("the file tree has no accessible mode")
+}
+
+func (t *FileTree) Skip() bool { return false }
+
+func (t *FileTree) Zoom() bool { return false }
+
+func (t *FileTree) KeyBinds() []key.Binding {
+	return []key.Binding{
+		key.NewBinding(key.WithKeys("space"), key.WithHelp("space", "toggle")),
+		key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←/→", "fold")),
+		t.keys.SelectAll.Binding,
+		key.NewBinding(key.WithKeys("n", "i"), key.WithHelp("n/i", "none/invert")),
+		key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "submit")),
+	}
+}
+
+func (t *FileTree) WithTheme(theme *huh.Theme) huh.Field {
+	if theme != nil {
+		t.theme = theme
+	}
+	return t
+}
+
+// WithKeys sets the remapped keys the tree honours
+func (t *FileTree) WithKeys(keys keyMap) *FileTree {
+	t.keys = keys
+	return t
+}
+
+func (t *FileTree) WithAccessible(bool) huh.Field { return t }
+
+func (t *FileTree) WithKeyMap(*huh.KeyMap) huh.Field { return t }
+
+func (t *FileTree) WithWidth(int) huh.Field { return t }
+
+func (t *FileTree) WithHeight(int) huh.Field { return t }
+
+func (t *FileTree) WithPosition(huh.FieldPosition) huh.Field { return t }
+
+func (t *FileTree) GetKey() string { return "" }
+
+func (t *FileTree) GetValue() any { return *t.value }
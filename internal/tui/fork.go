@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/git"
+)
+
+// forkPushMsg is sent once the branch is pushed to the fork
+type forkPushMsg struct {
+	url string // page for opening the pull request, "" if unknown
+	err error
+}
+
+// canPushFork reports whether "commit & push" is offered: a fork remote is
+// configured and nothing is amended, which would need a force push
+func (m *Model) canPushFork() bool {
+	return m.cfg.PR.Fork != "" && m.amend == nil
+}
+
+// commitAndPush commits like the commit action and pushes the branch to the
+// fork once the last commit of the session is made
+func (m *Model) commitAndPush() (tea.Model, tea.Cmd) {
+	m.forkPush = true
+	m.count("feature.fork_push")
+	return m.beginCommit()
+}
+
+// pushFork pushes the current branch to the fork and works out where to
+// open the pull request
+func (m *Model) pushFork() tea.Cmd {
+	m.pushing = true
+	m.pushTarget = m.cfg.PR.Fork
+	m.state = stateCommitting
+	fork := m.cfg.PR.Fork
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		branch := m.repo.Branch()
+		if err := m.repo.PushBranch(fork, branch); err != nil {
+			return forkPushMsg{err: err}
+		}
+		base := m.cfg.PR.Base
+		if base == "" {
+			base, _ = m.repo.DefaultBranch()
+		}
+		base = strings.TrimPrefix(base, "origin/") // the branch name on the upstream repository
+		upstream := m.repo.UpstreamRemote(fork)
+		url := git.CompareURL(m.repo.RemoteURL(upstream), m.repo.RemoteURL(fork), base, branch)
+		return forkPushMsg{url: url}
+	})
+}
+
+// afterForkPush finishes the session with the push result on the done screen
+func (m *Model) afterForkPush(msg forkPushMsg) (tea.Model, tea.Cmd) {
+	m.pushing = false
+	m.forkPushed = msg.err == nil
+	m.forkURL = msg.url
+	m.forkErr = msg.err
+	return m.finish()
+}
+
+// viewForkPush renders the push result on the done screen
+func (m *Model) viewForkPush(s *strings.Builder) {
+	switch {
+	case m.forkErr != nil:
+		s.WriteString("\n")
+		s.WriteString(wrapText(m.styles.Error.Render(m.forkErr.Error()), m.termWidth-2))
+		s.WriteString("\n")
+	case m.forkPushed:
+		s.WriteString("\n")
+		s.WriteString(m.styles.Success.Render("Pushed " + m.repo.Branch() + " to " + m.cfg.PR.Fork))
+		s.WriteString("\n")
+		if m.forkURL != "" {
+			s.WriteString("Open a pull request: " + m.forkURL + "\n")
+		}
+	}
+}
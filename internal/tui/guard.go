@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"github.com/hluaguo/commity/internal/git"
+)
+
+// guardForm asks for the branch name before history is rewritten, so a
+// stray enter can't amend, undo or reword commits
+func guardForm(theme *Theme, action, branch string, value *string) *huh.Form {
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(action).
+				Description(fmt.Sprintf("This rewrites history on %s. Type the branch name to continue.", branch)).
+				Placeholder(branch).
+				Value(value).
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) != branch {
+						return fmt.Errorf("type %s to confirm", branch)
+					}
+					return nil
+				}),
+		),
+	).WithTheme(theme.GetHuhTheme()).WithShowHelp(false)
+}
+
+// GuardBranch returns the name to type in the guard: the current branch,
+// or HEAD when detached
+func GuardBranch(repo *git.Repository) string {
+	if b := repo.Branch(); b != "" && b != "unknown" {
+		return b
+	}
+	return "HEAD"
+}
+
+// ConfirmRewrite runs the guard on its own, for commands outside the TUI.
+// It returns false if the user backed out.
+func ConfirmRewrite(theme, action, branch string) (bool, error) {
+	var typed string
+	if err := guardForm(GetTheme(theme), action, branch, &typed).Run(); err != nil {
+		if errors.Is(err, huh.ErrUserAborted) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// guard shows the guard and runs then once the branch name is typed. Esc
+// returns to the screen the action was started from.
+func (m *Model) guard(action string, then func() (tea.Model, tea.Cmd)) (tea.Model, tea.Cmd) {
+	var typed string
+	return m, m.pushForm(&formScreen{
+		form:   guardForm(m.theme, action, GuardBranch(m.repo), &typed),
+		render: viewGuard,
+		done:   func(*Model) (tea.Model, tea.Cmd) { return then() },
+		back:   leaveGuard,
+	})
+}
+
+// leaveGuard puts the confirm form, which the action was chosen on, back
+func leaveGuard(m *Model) (tea.Model, tea.Cmd) {
+	if m.state == stateConfirm {
+		m.initConfirmForm()
+		return m, m.confirmForm.Init()
+	}
+	return m, nil
+}
+
+// viewGuard renders the guard prompt
+func viewGuard(m *Model, form string) string {
+	return form + "\n" +
+		m.renderKeyHint("[enter]", "confirm") + "  " +
+		m.renderKeyHint("[esc]", "back")
+}
@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// helpKey is a binding listed in the help overlay
+func helpKey(keys, desc string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys), key.WithHelp(keys, desc))
+}
+
+// helpBindings lists every key of the current state in columns, or nothing
+// for states that take text or have no keys of their own
+func (m *Model) helpBindings() [][]key.Binding {
+	general := []key.Binding{helpKey("ctrl+k", "commands"), helpKey("?", "this help"), m.keys.Quit.Binding}
+
+	switch m.state {
+	case stateFileSelect:
+		selection := []key.Binding{
+			helpKey("↑/↓", "navigate"),
+			helpKey("←/→", "fold"),
+			helpKey("space", "toggle"),
+			m.keys.SelectAll.Binding,
+			helpKey("n", "select none"),
+			helpKey("i", "invert"),
+			helpKey("ctrl+a", "toggle all"),
+			helpKey("enter", "submit"),
+		}
+		actions := []key.Binding{
+			helpKey("h", "hunks"),
+			helpKey("A", "apply staging"),
+			helpKey("p", "presets"),
+			helpKey("N", "note"),
+			helpKey("m", "switch model"),
+			m.keys.Settings.Binding,
+		}
+		if len(m.related) > 0 {
+			actions = append(actions, helpKey("r", "add related"))
+		}
+		if m.shallow {
+			actions = append(actions, helpKey("D", "deepen history"))
+		}
+		return [][]key.Binding{selection, actions, general}
+
+	case stateConfirm:
+		if m.confirmForm.input.Focused() {
+			return nil
+		}
+		choice := []key.Binding{helpKey("↑/↓", "navigate"), helpKey("enter", "select")}
+		if len(m.candidates) > 1 {
+			choice = append(choice, helpKey("←/→", "switch attempt"))
+		}
+		message := []key.Binding{
+			m.keys.Edit.Binding,
+			m.keys.Regenerate.Binding,
+			helpKey("i", "instruct"),
+			helpKey("ctrl+f", "fix warnings"),
+			helpKey("ctrl+o", "open issue"),
+			m.keys.Diff.Binding,
+			helpKey("h", "history"),
+			helpKey("m", "switch model"),
+			m.keys.SkipHooks.Binding,
+		}
+		if m.cached {
+			message = append(message, helpKey("g", "generate anew"))
+		}
+		if m.isSplit {
+			message = append(message, helpKey("M", "merge remaining"))
+		}
+		if m.canPushFork() {
+			choice = append(choice, helpKey("p", "commit & push"))
+		}
+		if m.canReselect() {
+			choice = append(choice, helpKey("f", "files"))
+		}
+		return [][]key.Binding{choice, message, append([]key.Binding{helpKey("ctrl+r", "refresh index")}, general...)}
+
+	case statePlan:
+		return [][]key.Binding{
+			{helpKey("↑/↓", "navigate"), helpKey("enter", "select"), helpKey("m", "move files")},
+			general,
+		}
+
+	case stateGenerating:
+		return [][]key.Binding{{helpKey("esc", "cancel"), helpKey("ctrl+c", "quit")}}
+
+	case stateSigning:
+		return [][]key.Binding{{helpKey("r", "retry"), helpKey("b", "back"), m.keys.Quit.Binding}}
+
+	case stateDone:
+		if len(m.created) == 0 {
+			return nil
+		}
+		return [][]key.Binding{{helpKey("c", "copy hash"), helpKey("y", "copy message"), helpKey("any key", "exit")}}
+
+	case stateError:
+		keys := []key.Binding{helpKey("b", "back to files"), m.keys.Settings.Binding, m.keys.Quit.Binding}
+		if m.retry != nil {
+			keys = append([]key.Binding{helpKey("r", "retry")}, keys...)
+		}
+		return [][]key.Binding{keys}
+	}
+	return nil
+}
+
+// openHelp shows the keys of the current state
+func (m *Model) openHelp() (tea.Model, tea.Cmd) {
+	return m.showHelp(m.helpBindings())
+}
+
+// showHelp lists groups of keys in the help overlay
+func (m *Model) showHelp(groups [][]key.Binding) (tea.Model, tea.Cmd) {
+	if len(groups) == 0 {
+		return m, nil
+	}
+	h := help.New()
+	h.Width = m.termWidth
+	h.FullSeparator = "      "
+	h.Styles.FullKey = lipgloss.NewStyle().Foreground(m.theme.Primary).Bold(true)
+	h.Styles.FullDesc = lipgloss.NewStyle().Foreground(m.theme.Secondary)
+	h.Styles.FullSeparator = lipgloss.NewStyle()
+	m.screens.push(&helpScreen{help: h, groups: groups})
+	return m, nil
+}
+
+// helpScreen lists the keys of the state underneath
+type helpScreen struct {
+	help   help.Model
+	groups [][]key.Binding
+}
+
+func (h *helpScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "?" {
+		m.screens.pop()
+	}
+	return m, nil
+}
+
+func (h *helpScreen) view(m *Model) string {
+	return m.styles.Dim.Render("Keys") + "\n\n" +
+		h.help.FullHelpView(h.groups) + "\n\n" +
+		m.renderKeyHint("[esc/?]", "close")
+}
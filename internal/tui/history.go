@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/store"
+)
+
+// historyRows is how many history entries are listed at once
+const historyRows = 8
+
+// recordHistory keeps a message for the history browser. The history is a
+// convenience, so failing to write it is not an error.
+func (m *Model) recordHistory(kind, message string) {
+	if strings.TrimSpace(message) == "" {
+		return
+	}
+	_ = store.AppendHistory(store.HistoryEntry{
+		Time:    time.Now(),
+		Repo:    m.repo.Path(),
+		Kind:    kind,
+		Message: message,
+	})
+}
+
+// openHistory lists earlier messages, those of this repository first
+func (m *Model) openHistory() (tea.Model, tea.Cmd) {
+	entries, err := store.LoadHistory()
+	if err != nil {
+		return m.setError(fmt.Errorf("failed to read message history: %w", err))
+	}
+
+	// Each message once, at its latest use
+	seen := make(map[string]bool)
+	var here, elsewhere []store.HistoryEntry
+	for _, e := range entries {
+		if seen[e.Message] {
+			continue
+		}
+		seen[e.Message] = true
+		if e.Repo == m.repo.Path() {
+			here = append(here, e)
+		} else {
+			elsewhere = append(elsewhere, e)
+		}
+	}
+
+	ti := textinput.New()
+	ti.Placeholder = "search messages..."
+	ti.CharLimit = 100
+	ti.Width = 30
+	ti.Focus()
+	h := &historyScreen{input: ti, entries: append(here, elsewhere...)}
+	h.filter()
+	m.screens.push(h)
+	return m, textinput.Blink
+}
+
+// useHistory replaces the current message with one from the history
+func (m *Model) useHistory(message string) (tea.Model, tea.Cmd) {
+	m.replaceMessage(message)
+	m.notice = "Using a message from history"
+	m.initConfirmForm()
+	return m, m.confirmForm.Init()
+}
+
+// historyScreen searches the message history; enter uses the highlighted
+// message for the current commit
+type historyScreen struct {
+	input    textinput.Model
+	entries  []store.HistoryEntry
+	filtered []store.HistoryEntry
+	cursor   int
+}
+
+// filter keeps the entries containing every word of the search
+func (h *historyScreen) filter() {
+	words := strings.Fields(strings.ToLower(h.input.Value()))
+	h.filtered = nil
+	for _, e := range h.entries {
+		text := strings.ToLower(e.Message)
+		match := true
+		for _, w := range words {
+			match = match && strings.Contains(text, w)
+		}
+		if match {
+			h.filtered = append(h.filtered, e)
+		}
+	}
+	h.cursor = min(h.cursor, max(len(h.filtered)-1, 0))
+}
+
+func (h *historyScreen) typing() bool {
+	return true
+}
+
+func (h *historyScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch key.String() {
+		case "up", "ctrl+p":
+			if h.cursor > 0 {
+				h.cursor--
+			}
+			return m, nil
+		case "down", "ctrl+n":
+			if h.cursor < len(h.filtered)-1 {
+				h.cursor++
+			}
+			return m, nil
+		case "enter":
+			if len(h.filtered) == 0 {
+				return m, nil
+			}
+			m.screens.pop()
+			return m.useHistory(h.filtered[h.cursor].Message)
+		}
+	}
+
+	var cmd tea.Cmd
+	h.input, cmd = h.input.Update(msg)
+	h.filter()
+	return m, cmd
+}
+
+func (h *historyScreen) view(m *Model) string {
+	var s strings.Builder
+	s.WriteString(m.styles.Dim.Render("Message history"))
+	s.WriteString("\n\n")
+	s.WriteString(h.input.View())
+	s.WriteString("\n\n")
+
+	if len(h.filtered) == 0 {
+		s.WriteString(m.styles.Dim.Render("  no matching messages"))
+		s.WriteString("\n")
+	}
+	// Keep the cursor in the listed window
+	start := max(0, min(h.cursor-historyRows/2, len(h.filtered)-historyRows))
+	end := min(start+historyRows, len(h.filtered))
+	width := max(m.termWidth-editAreaPadding-30, minMessageWidth)
+	for i := start; i < end; i++ {
+		e := h.filtered[i]
+		subject, _, _ := strings.Cut(e.Message, "\n")
+		line := "  " + truncate(subject, width)
+		if i == h.cursor {
+			line = m.styles.Title.Render("> " + truncate(subject, width))
+		}
+		meta := e.Kind + " " + e.Time.Format("Jan 2")
+		if e.Repo != m.repo.Path() {
+			meta += " · " + filepath.Base(e.Repo)
+		}
+		s.WriteString(line + "  " + m.styles.Dim.Render(meta) + "\n")
+	}
+
+	if len(h.filtered) > 0 {
+		s.WriteString("\n")
+		s.WriteString(m.styles.Message.Width(max(m.termWidth-messagePadding, minMessageWidth)).Render(h.filtered[h.cursor].Message))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(m.renderKeyHint("[↑↓]", "navigate") + "  " +
+		m.renderKeyHint("[enter]", "use") + "  " +
+		m.renderKeyHint("[esc]", "back"))
+	return s.String()
+}
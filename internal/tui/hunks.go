@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// hunkSummaryWidth truncates the changed line shown next to each hunk
+const hunkSummaryWidth = 50
+
+// openHunks lists the unstaged hunks of the selected files, all checked,
+// over file select. It tells when there is nothing to choose from.
+func (m *Model) openHunks() (tea.Model, tea.Cmd) {
+	m.hunkDiffs = nil
+	m.hunkChoice = nil
+
+	var options []huh.Option[string]
+	for _, path := range m.selected {
+		fd, err := m.repo.UnstagedDiff(path)
+		if err != nil {
+			return m.setError(err)
+		}
+		if fd == nil || len(fd.Hunks) == 0 {
+			continue
+		}
+		m.hunkDiffs = append(m.hunkDiffs, *fd)
+		for i, h := range fd.Hunks {
+			key := fmt.Sprintf("%d:%d", len(m.hunkDiffs)-1, i)
+			label := fmt.Sprintf("%s %s  %s", fd.Path, hunkRange(h.Header), truncate(h.Summary(), hunkSummaryWidth))
+			options = append(options, huh.NewOption(label, key).Selected(true))
+			m.hunkChoice = append(m.hunkChoice, key)
+		}
+	}
+	if len(options) == 0 {
+		m.notice = "No unstaged hunks in the selected files"
+		return m, nil
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Select hunks to commit").
+				Options(options...).
+				Value(&m.hunkChoice),
+		),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+	m.count("feature.hunks")
+	return m, m.pushForm(&formScreen{
+		form: form,
+		render: func(m *Model, form string) string {
+			return form + "\n" +
+				m.renderKeyHint("[space]", "toggle") + "  " +
+				m.renderKeyHint("[↑↓]", "navigate") + "  " +
+				m.renderKeyHint("[enter]", "stage") + "  " +
+				m.renderKeyHint("[esc]", "back")
+		},
+		done: completeHunks,
+		help: [][]key.Binding{
+			{helpKey("↑/↓", "navigate"), helpKey("space", "toggle"), helpKey("enter", "stage"), helpKey("esc", "back")},
+		},
+	})
+}
+
+// completeHunks stages partially selected files hunk by hunk. Fully
+// selected files are staged whole at commit time.
+func completeHunks(m *Model) (tea.Model, tea.Cmd) {
+	chosen := make(map[int][]int)
+	for _, key := range m.hunkChoice {
+		file, hunk, ok := strings.Cut(key, ":")
+		if !ok {
+			continue
+		}
+		fi, _ := strconv.Atoi(file)
+		hi, _ := strconv.Atoi(hunk)
+		chosen[fi] = append(chosen[fi], hi)
+	}
+
+	staged := 0
+	for i, fd := range m.hunkDiffs {
+		hunks := chosen[i]
+		slices.Sort(hunks)
+
+		switch len(hunks) {
+		case len(fd.Hunks):
+			delete(m.partial, fd.Path)
+		case 0:
+			// Keep files whose earlier hunks are already staged
+			if !m.partial[fd.Path] {
+				m.selected = slices.DeleteFunc(m.selected, func(p string) bool { return p == fd.Path })
+			}
+		default:
+			if err := m.repo.ApplyCached(fd.Patch(hunks)); err != nil {
+				return m.setError(err)
+			}
+			m.partial[fd.Path] = true
+			staged += len(hunks)
+		}
+	}
+
+	m.refreshIndexCounts()
+	m.initFileSelectFormWith(m.selected)
+	if staged > 0 {
+		m.notice = fmt.Sprintf("Staged %d hunks; remaining changes stay unstaged", staged)
+	}
+	return m, m.form.Init()
+}
+
+// partialFiles splits files into those staged hunk by hunk and the rest
+func (m *Model) partialFiles(files []string) (partial, whole []string) {
+	for _, f := range files {
+		if m.partial[f] {
+			partial = append(partial, f)
+		} else {
+			whole = append(whole, f)
+		}
+	}
+	return partial, whole
+}
+
+// hunkRange returns the "@@ -a,b +c,d @@" part of a hunk header
+func hunkRange(header string) string {
+	if i := strings.Index(header[2:], "@@"); i != -1 {
+		return header[:i+4]
+	}
+	return header
+}
+
+// truncate shortens s to width runes, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width < 1 {
+		return ""
+	}
+	return string(r[:width-1]) + "…"
+}
@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/git"
+)
+
+// Choices on the gitignore offer screen
+const (
+	ignoreCommit = "commit"
+	ignoreAdd    = "add"
+	ignoreKeep   = "keep"
+	ignoreBack   = "back"
+)
+
+type ignoreSuggestMsg struct {
+	patterns []string
+}
+
+// offerIgnore checks the selection for build artifacts and editor junk and,
+// unless declined this session, asks for gitignore patterns instead of
+// committing them. It returns nil when there is nothing to offer.
+func (m *Model) offerIgnore() tea.Cmd {
+	if m.ignoreDeclined {
+		return nil
+	}
+	m.junk = git.JunkFiles(m.files, m.selected)
+	if len(m.junk) == 0 {
+		return nil
+	}
+	m.screens.push(&ignoreScreen{formScreen{
+		loading: "Suggesting .gitignore patterns...",
+		render:  viewIgnore,
+		done:    completeIgnore,
+		back:    leaveIgnore,
+	}})
+	junk := m.junk
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		if m.aiClient != nil {
+			if patterns, err := m.aiClient.SuggestIgnorePatterns(context.Background(), junk); err == nil {
+				return ignoreSuggestMsg{patterns: patterns}
+			}
+		}
+		// Fall back to patterns derived from the paths
+		return ignoreSuggestMsg{patterns: git.IgnorePatterns(junk)}
+	})
+}
+
+// ignoreScreen offers the gitignore patterns once they are suggested
+type ignoreScreen struct {
+	formScreen
+}
+
+func (s *ignoreScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(ignoreSuggestMsg); ok {
+		s.form = m.ignoreForm(msg.patterns)
+		return m, s.form.Init()
+	}
+	return s.formScreen.update(m, msg)
+}
+
+// ignoreForm lets the user pick the suggested patterns and what to do
+func (m *Model) ignoreForm(patterns []string) *huh.Form {
+	m.ignorePatterns = slices.Clone(patterns)
+	m.ignoreChoice = ignoreCommit
+
+	options := make([]huh.Option[string], len(patterns))
+	for i, p := range patterns {
+		options[i] = huh.NewOption(p, p).Selected(true)
+	}
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Patterns to add to .gitignore").
+				Options(options...).
+				Value(&m.ignorePatterns),
+			huh.NewSelect[string]().
+				Title("These files look like build output or editor leftovers").
+				Options(
+					huh.NewOption("Ignore them and commit .gitignore separately", ignoreCommit),
+					huh.NewOption("Ignore them, leave .gitignore uncommitted", ignoreAdd),
+					huh.NewOption("Commit them anyway", ignoreKeep),
+					huh.NewOption("Back to file selection", ignoreBack),
+				).
+				Value(&m.ignoreChoice),
+		),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+}
+
+// completeIgnore applies the choice and continues with what is left of the
+// selection
+func completeIgnore(m *Model) (tea.Model, tea.Cmd) {
+	switch m.ignoreChoice {
+	case ignoreBack:
+		return leaveIgnore(m)
+	case ignoreKeep:
+		m.ignoreDeclined = true
+		return m.startGenerating()
+	}
+
+	added, err := m.repo.AddIgnorePatterns(m.ignorePatterns)
+	if err != nil {
+		return m.setError(err)
+	}
+	if m.ignoreChoice == ignoreCommit && len(added) > 0 {
+		msg := ai.CommitMessage{Subject: "update gitignore"}
+		if m.cfg.CommitRules().Conventional {
+			msg.Type = "chore"
+		} else {
+			msg.Subject = "Update .gitignore"
+		}
+		msg = ai.AddTrailers(ai.EnforceSubject(msg, m.subjectRules()), m.trailers())
+		if err := m.repo.CommitGitignore(msg.String(), m.cfg.Commit.NoVerify); err != nil {
+			return m.setError(err)
+		}
+	}
+
+	// Files now ignored drop out of the status and the selection
+	files, err := m.repo.Status()
+	if err != nil {
+		return m.setError(err)
+	}
+	m.files = files
+	m.refreshIndexCounts()
+	m.selected = slices.DeleteFunc(m.selected, func(path string) bool {
+		return !slices.ContainsFunc(files, func(f git.FileStatus) bool { return f.Path == path })
+	})
+	m.notice = fmt.Sprintf("Added %d patterns to .gitignore", len(added))
+
+	if len(m.selected) == 0 {
+		if len(m.files) == 0 {
+			m.state = stateDone
+			return m, tea.Quit
+		}
+		m.initFileSelectFormWith(nil)
+		return m, m.form.Init()
+	}
+	return m.startGenerating()
+}
+
+// leaveIgnore returns to file select with the selection unchanged
+func leaveIgnore(m *Model) (tea.Model, tea.Cmd) {
+	m.initFileSelectFormWith(m.selected)
+	return m, m.form.Init()
+}
+
+// viewIgnore lists the junk files above the pattern picker
+func viewIgnore(m *Model, form string) string {
+	var s strings.Builder
+	for _, f := range m.junk {
+		s.WriteString(m.styles.Dim.Render("  " + f))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(form)
+	s.WriteString("\n")
+	s.WriteString(m.renderKeyHint("[space]", "toggle") + "  " +
+		m.renderKeyHint("[tab]", "next") + "  " +
+		m.renderKeyHint("[enter]", "confirm") + "  " +
+		m.renderKeyHint("[esc]", "back"))
+	return s.String()
+}
@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// binding is a remappable key binding. The key handlers switch on the
+// default keys; resolve maps a remapped key back to them.
+type binding struct {
+	key.Binding
+	defaults []string
+	remapped bool
+}
+
+// keyMap holds the bindings of the actions [ui.keys] can remap
+type keyMap struct {
+	Quit       binding
+	Settings   binding
+	Edit       binding
+	Regenerate binding
+	SelectAll  binding
+	Diff       binding
+	SkipHooks  binding
+}
+
+// newKeyMap returns the default bindings with those of custom, action to
+// key, replacing them
+func newKeyMap(custom map[string]string) keyMap {
+	bind := func(action, help string, keys ...string) binding {
+		b := binding{defaults: keys}
+		switch k := custom[action]; k {
+		case "":
+			b.Binding = key.NewBinding(key.WithKeys(keys...), key.WithHelp(keys[0], help))
+		case "space":
+			b.Binding = key.NewBinding(key.WithKeys(" "), key.WithHelp(k, help))
+			b.remapped = true
+		default:
+			b.Binding = key.NewBinding(key.WithKeys(k), key.WithHelp(k, help))
+			b.remapped = true
+		}
+		return b
+	}
+	return keyMap{
+		Quit:       bind(config.KeyQuit, "quit", "q"),
+		Settings:   bind(config.KeySettings, "settings", "s", "S"),
+		Edit:       bind(config.KeyEdit, "edit", "e", "E"),
+		Regenerate: bind(config.KeyRegenerate, "regenerate", "r", "R"),
+		SelectAll:  bind(config.KeySelectAll, "select all", "a"),
+		Diff:       bind(config.KeyDiff, "diff", "d", "D"),
+		SkipHooks:  bind(config.KeySkipHooks, "skip or run commit hooks", "!"),
+	}
+}
+
+// resolve returns the key a handler of the bindings in scope should see for
+// msg: the default key of a remapped binding it matches, nothing for the
+// default keys of remapped bindings, and the key itself otherwise
+func resolve(msg tea.KeyMsg, scope ...binding) string {
+	s := msg.String()
+	for _, b := range scope {
+		if b.remapped && key.Matches(msg, b.Binding) {
+			return b.defaults[0]
+		}
+	}
+	for _, b := range scope {
+		if b.remapped && slices.Contains(b.defaults, s) {
+			return ""
+		}
+	}
+	return s
+}
+
+// hint renders b as a key hint, e.g. "[e] edit"
+func (m *Model) hint(b binding) string {
+	return m.renderKeyHint("["+b.Help().Key+"]", b.Help().Desc)
+}
+
+// ShortHelp implements help.KeyMap
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Edit.Binding, k.Regenerate.Binding, k.Diff.Binding, k.Quit.Binding}
+}
+
+// FullHelp implements help.KeyMap
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.SelectAll.Binding, k.Settings.Binding},
+		{k.Edit.Binding, k.Regenerate.Binding, k.Diff.Binding},
+		{k.Quit.Binding},
+	}
+}
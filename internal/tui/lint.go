@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/lint"
+)
+
+// maxShownIssues is how many lint issues the confirm screen lists
+const maxShownIssues = 5
+
+// editorMsg is sent when the editor opened on an issue exits
+type editorMsg struct{ err error }
+
+// scanSelection looks for conflict markers, debug statements and
+// do-not-commit tags in the selection. Amends are skipped, since their
+// content is already committed.
+func (m *Model) scanSelection() []lint.Issue {
+	if m.amend != nil {
+		return nil
+	}
+	partial, whole := m.partialFiles(m.selected)
+	diff, err := m.repo.DiffAll(whole)
+	if err != nil {
+		return nil
+	}
+	if len(partial) > 0 {
+		staged, _ := m.repo.Diff(partial, true)
+		diff += staged
+	}
+	return lint.Scan(diff)
+}
+
+// commitIssues returns the lint issues in the files of the current commit
+func (m *Model) commitIssues() []lint.Issue {
+	if m.currentIndex >= len(m.commits) {
+		return nil
+	}
+	files := m.commits[m.currentIndex].Files
+	if len(files) == 0 {
+		files = m.selected
+	}
+	return lint.In(m.issues, files)
+}
+
+// openIssue opens the editor at the first issue of the current commit
+func (m *Model) openIssue() (tea.Model, tea.Cmd) {
+	issues := m.commitIssues()
+	if len(issues) == 0 {
+		return m, nil
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Editors may be configured with arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	args = append(args, fmt.Sprintf("+%d", issues[0].Line), filepath.Join(m.repo.Path(), issues[0].File))
+	cmd := exec.Command(args[0], args[1:]...)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorMsg{err: err}
+	})
+}
+
+// afterEditor rescans the selection once the editor exits, so fixed issues
+// disappear from the confirm screen
+func (m *Model) afterEditor(msg editorMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.notice = "Editor failed: " + msg.err.Error()
+		return m, nil
+	}
+	m.issues = m.scanSelection()
+	m.computeCommitStats()
+	if len(m.commitIssues()) == 0 {
+		m.notice = "No issues left in this commit"
+	}
+	return m, nil
+}
+
+// viewIssues renders the lint warnings of the current commit
+func (m *Model) viewIssues(s *strings.Builder) {
+	issues := m.commitIssues()
+	if len(issues) == 0 {
+		return
+	}
+	s.WriteString(m.styles.Error.Render("Check before committing:"))
+	s.WriteString("\n")
+	for _, i := range issues[:min(len(issues), maxShownIssues)] {
+		text := i.Text
+		if len(text) > 50 {
+			text = text[:47] + "..."
+		}
+		s.WriteString(m.styles.Error.Render(fmt.Sprintf("  %s  %s", i.Location(), i.Kind)))
+		s.WriteString(m.styles.Dim.Render("  " + text))
+		s.WriteString("\n")
+	}
+	if n := len(issues) - maxShownIssues; n > 0 {
+		s.WriteString(m.styles.Dim.Render(fmt.Sprintf("  ... and %d more", n)))
+		s.WriteString("\n")
+	}
+	s.WriteString(m.renderKeyHint("[ctrl+o]", "open in editor"))
+	s.WriteString("\n\n")
+}
@@ -1,13 +1,21 @@
 package tui
 
 import (
+	"cmp"
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
@@ -15,6 +23,11 @@ import (
 	"github.com/hluaguo/commity/internal/ai"
 	"github.com/hluaguo/commity/internal/config"
 	"github.com/hluaguo/commity/internal/git"
+	"github.com/hluaguo/commity/internal/hooks"
+	"github.com/hluaguo/commity/internal/lint"
+	"github.com/hluaguo/commity/internal/security"
+	"github.com/hluaguo/commity/internal/store"
+	"github.com/hluaguo/commity/internal/telemetry"
 )
 
 // ---------------------------------------------------------------------------
@@ -31,7 +44,11 @@ const (
 	stateEdit // editing commit message
 	stateCommitting
 	stateDone
-	stateSettings // settings page
+	stateSettings   // settings page
+	statePlan       // overview of a split plan before committing
+	stateRollback   // cancelled mid-way through a split, offering to undo
+	stateAmendOffer // nothing to commit, offering to amend the last commit
+	stateSigning    // the commit couldn't be signed, with guidance
 	stateError
 )
 
@@ -41,14 +58,29 @@ const (
 	actionCancel     = "cancel"
 	actionRegenerate = "regenerate"
 	actionEdit       = "edit"
+	actionInstruct   = "instruct"
+	actionMerge      = "merge"
+	actionModel      = "model"
+	actionHooks      = "hooks"
+	actionPush       = "push"  // commit, then push to the fork
+	actionFiles      = "files" // back to file selection, see reselect
+	actionFresh      = "fresh" // generate again, bypassing the result cache
+	actionHistory    = "history"
+	actionDiff       = "diff"     // show the diff of the commit
+	actionPrevious   = "previous" // show the previous attempt, see switchCandidate
+	actionNext       = "next"
 )
 
+// deepenCommits is how much history to fetch when deepening a shallow clone
+const deepenCommits = 50
+
 // Layout constants
 const (
 	minMessageWidth = 40
 	messagePadding  = 8
 	editAreaHeight  = 10
 	editAreaPadding = 4
+	previewHeight   = 20
 )
 
 // ---------------------------------------------------------------------------
@@ -58,25 +90,113 @@ const (
 // Model is the main Bubble Tea model for the commity TUI.
 type Model struct {
 	state         state
-	previousState state // for returning from settings
+	previousState state                       // for returning from settings
+	retry         func() (tea.Model, tea.Cmd) // repeats the step that failed, see failed
+	genStarted    time.Time                   // when the running generation started
+	genCancel     context.CancelFunc          // stops the running generation, see startProgress
 	cfg           *config.Config
 	repo          *git.Repository
 	aiClient      *ai.Client
+	compareClient *ai.Client // second model for side-by-side comparison, nil when off
 	isFirstRun    bool
+	shallow       bool   // repository is a shallow clone with limited history
+	remoteHost    string // origin host, used for host policies
+	repoState     *store.Repo
 
 	files    []git.FileStatus
 	selected []string
 	feedback string // user feedback for regeneration
 
+	// Extra instruction for this session only, never saved to config
+	sessionInstruction string
+
+	// Untracked files related to the selection (hint in file select)
+	related    []string
+	relatedKey string // selection the hint was computed for
+
+	// Secrets detected in the diff and how the user chose to handle them
+	secretFindings  []security.Finding
+	secretsChoice   string
+	secretsDecision string // empty until confirmed for the current selection
+
+	// Junk files in the selection and the gitignore patterns offered for them
+	junk           []string
+	ignorePatterns []string
+	ignoreChoice   string
+	ignoreDeclined bool // committing junk was confirmed this session
+
+	// New branch offered before the first commit on a protected branch
+	branchOffered bool
+	currentBranch string
+	branchName    string
+	branchChoice  string
+
+	// Hunk selection: diffs being chosen from, and files staged hunk by hunk
+	hunkDiffs  []git.FileDiff
+	hunkChoice []string
+	partial    map[string]bool
+	hunkSource map[string]git.FileDiff // unstaged hunks as numbered in the prompt
+
+	// Selection presets form values
+	presetChoice string
+	presetName   string
+
 	// Commit handling (supports split commits)
 	commits      []ai.CommitMessage
 	currentIndex int
 	isSplit      bool
 	completed    []bool // track which commits are done
+	planChoice   string
+	commitAll    bool // commit the remaining plan without confirming each
+
+	reassignCursor int // file row selected while reassigning
+	plan           ai.PlanEstimate
+	fallback       string             // set when generation missed its deadline
+	cached         bool               // the messages were reused from the result cache
+	offlineErr     error              // why there is no AI client, or why the last request failed
+	noCache        bool               // the next generation must ask the model
+	candidates     []ai.CommitMessage // generated attempts for the selection, see keepCandidate
+	candidate      int                // attempt shown
+	candidatesKey  string             // selection the attempts are for
+	commitStats    []diffStat         // lines added/removed per proposed commit
+
+	unexpectedStaged []string          // staged files outside the current commit
+	issues           []lint.Issue      // conflict markers and debug statements in the selection
+	index            git.IndexCounts   // shown in the index panel
+	hookErrs         []error           // failed post-commit hooks, shown when done
+	created          []hooks.Commit    // commits made this session, for the webhook
+	stats            map[string]string // shortstat of each created commit, by hash
+	copyErr          error
+	webhookErr       error
+	webhookPending   bool     // the session webhook hasn't answered yet
+	unassigned       []string // split coverage: selected files in no commit
+	coverageNote     string   // what was corrected in the split plan
+	rollbackChoice   bool
+	amend            *git.CommitInfo // commit being reworded, nil when committing
+	sessionID        string          // shared by the commits of this run, for trailer templates
+	signErr          *git.SigningError
+	amendChoice      bool
+	rolledBack       int    // commits undone after cancelling a split
+	forkPush         bool   // push to the fork once the last commit is made
+	pushing          bool   // the fork or session push is running
+	pushTarget       string // remote being pushed to
+	pushChoice       bool
+	pushed           bool // the branch was pushed after the last commit
+	pushErr          error
+	skipHooks        bool // commit the current message with --no-verify
+	forkPushed       bool
+	forkURL          string // where to open the pull request
+	forkErr          error
+	warming          bool                // the model is being loaded, see warmUp
+	msgWarnings      []ai.MessageWarning // convention problems of the current message
+	proposal         *proposal           // generation left for file selection, see reselect
+	testConn         bool                // test the connection when the settings form completes
 
 	form        *huh.Form
 	confirmForm *ConfirmModel
+	screens     router // overlays such as the command palette
 	editArea    textarea.Model
+	notice      string // result of the last palette action
 	spinner     spinner.Model
 	err         error
 	termWidth   int
@@ -84,25 +204,59 @@ type Model struct {
 	// Theming
 	theme  *Theme
 	styles *Styles
+	keys   keyMap // remappable keys, see [ui.keys]
 }
 
 // Messages for async operations
 type generateMsg struct {
 	result *ai.GenerateResult
+	hunks  map[string]git.FileDiff
+	issues []lint.Issue
+	delta  bool // only the added file's diff was sent
 	err    error
 }
 
 type commitMsg struct {
+	err     error
+	staged  bool // the files were staged before err, so a retry only commits
+	created hooks.Commit
+	stat    string // shortstat of the new commit, for the done screen
+	hookErr error  // post-commit hook failure; the commit itself succeeded
+}
+
+type webhookMsg struct {
 	err error
 }
 
 type initCompleteMsg struct{}
 
+// diffStat holds lines added and removed for a set of files
+type diffStat struct {
+	added, removed int
+}
+
+type deepenMsg struct {
+	err error
+}
+
+type secretsMsg struct {
+	findings []security.Finding
+}
+
+type pushMsg struct {
+	err error
+}
+
+type undoMsg struct {
+	err error
+}
+
 // ---------------------------------------------------------------------------
 // Constructor
 // ---------------------------------------------------------------------------
 
 func New(cfg *config.Config, repo *git.Repository, aiClient *ai.Client, isFirstRun bool) (*Model, error) {
+	var err error
 	theme := GetTheme(cfg.UI.Theme)
 	styles := NewStyles(theme)
 
@@ -119,6 +273,18 @@ func New(cfg *config.Config, repo *git.Repository, aiClient *ai.Client, isFirstR
 		isFirstRun: isFirstRun,
 		theme:      theme,
 		styles:     styles,
+		keys:       newKeyMap(cfg.UI.Keys),
+		skipHooks:  cfg.Commit.NoVerify,
+		remoteHost: repo.RemoteHost(),
+		partial:    make(map[string]bool),
+		stats:      make(map[string]string),
+		sessionID:  newSessionID(),
+	}
+
+	// Per-repo state is best effort; fall back to empty state
+	m.repoState, err = store.Load(repo.Path())
+	if err != nil {
+		m.repoState = &store.Repo{Path: repo.Path()}
 	}
 
 	// First run - show setup
@@ -135,12 +301,21 @@ func New(cfg *config.Config, repo *git.Repository, aiClient *ai.Client, isFirstR
 	}
 
 	if len(files) == 0 {
-		return nil, fmt.Errorf("no changes to commit")
+		last, err := repo.LastCommit()
+		if err != nil {
+			return nil, fmt.Errorf("no changes to commit")
+		}
+		subject, _, _ := strings.Cut(last.Message, "\n")
+		m.state = stateAmendOffer
+		m.initAmendOfferForm(subject)
+		return m, nil
 	}
 
 	m.files = files
+	m.shallow = repo.IsShallow()
+	m.refreshIndexCounts()
 	m.state = stateFileSelect
-	m.initFileSelectForm()
+	m.initFileSelectFormWith(m.launchSelection())
 	return m, nil
 }
 
@@ -149,25 +324,34 @@ func New(cfg *config.Config, repo *git.Repository, aiClient *ai.Client, isFirstR
 // ---------------------------------------------------------------------------
 
 func (m *Model) initFileSelectForm() {
-	options, selectedPaths := m.buildFileTreeOptions()
+	m.initFileSelectFormWith(nil)
+}
+
+// initFileSelectFormWith builds the file selector with the given paths
+// pre-checked. A nil selection defaults to the currently staged files.
+func (m *Model) initFileSelectFormWith(preselect []string) {
+	files, selectedPaths := m.buildFileTreeOptions(preselect)
 
 	m.selected = selectedPaths
 
 	m.form = huh.NewForm(
 		huh.NewGroup(
-			huh.NewMultiSelect[string]().
-				Title("Select files to commit").
-				Options(options...).
-				Value(&m.selected),
+			NewFileTree("Select files to commit", files, selectedPaths, &m.selected).WithKeys(m.keys),
 		),
 	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
 }
 
-// buildFileTreeOptions creates options for the file selector
-func (m *Model) buildFileTreeOptions() ([]huh.Option[string], []string) {
-	var options []huh.Option[string]
+// buildFileTreeOptions lists the changed files for the file tree and the
+// paths to pre-check
+func (m *Model) buildFileTreeOptions(preselect []string) ([]treeFile, []string) {
+	var options []treeFile
 	var selectedPaths []string
 
+	checked := make(map[string]bool)
+	for _, p := range preselect {
+		checked[p] = true
+	}
+
 	// Sort files by path for consistent display
 	files := make([]git.FileStatus, len(m.files))
 	copy(files, m.files)
@@ -176,9 +360,16 @@ func (m *Model) buildFileTreeOptions() ([]huh.Option[string], []string) {
 	})
 
 	for _, f := range files {
-		label := fmt.Sprintf("[%s] %s", f.Status, f.Path)
-		options = append(options, huh.NewOption(label, f.Path).Selected(f.Staged))
-		if f.Staged {
+		label := fmt.Sprintf("[%s] %s", f.Status, path.Base(f.Path))
+		if m.partial[f.Path] {
+			label += " (partial)"
+		}
+		isSelected := f.Staged
+		if preselect != nil {
+			isSelected = checked[f.Path]
+		}
+		options = append(options, treeFile{path: f.Path, label: label})
+		if isSelected {
 			selectedPaths = append(selectedPaths, f.Path)
 		}
 	}
@@ -200,8 +391,75 @@ func (m *Model) getThemeOptions() []huh.Option[string] {
 	return options
 }
 
+// getOllamaModelOptions lists models from the Ollama /api/tags endpoint
+func (m *Model) getOllamaModelOptions() []huh.Option[string] {
+	models, err := ai.ListOllamaModels(context.Background(), m.cfg.AI.BaseURL)
+	if err != nil || len(models) == 0 {
+		// Keep the configured model selectable when the server is unreachable
+		if m.cfg.AI.Model != "" {
+			return huh.NewOptions(m.cfg.AI.Model)
+		}
+		return []huh.Option[string]{huh.NewOption("(no models found - is ollama running?)", "")}
+	}
+	return huh.NewOptions(models...)
+}
+
 func (m *Model) initConfirmForm() {
 	m.confirmForm = NewConfirmModel(m.theme)
+	m.confirmForm.keys = m.keys
+	m.confirmForm.canPush = m.canPushFork()
+	m.confirmForm.canReselect = m.canReselect()
+	m.confirmForm.cached = m.cached && m.currentIndex == 0
+	m.refreshIndexStatus()
+	m.lintMessage()
+}
+
+// refreshIndexStatus records files staged outside commity that would land
+// in the current commit, since git commit takes the whole index
+func (m *Model) refreshIndexStatus() {
+	m.refreshIndexCounts()
+	m.unexpectedStaged = nil
+	if m.currentIndex >= len(m.commits) {
+		return
+	}
+	staged, err := m.repo.StagedFiles()
+	if err != nil {
+		return
+	}
+
+	files := m.commits[m.currentIndex].Files
+	if len(files) == 0 {
+		files = m.selected
+	}
+	for _, f := range staged {
+		if !slices.Contains(files, f) {
+			m.unexpectedStaged = append(m.unexpectedStaged, f)
+		}
+	}
+}
+
+// recordLastCommit remembers the commit for commity undo. Amended commits
+// aren't recorded, since undoing one would also drop the original commit.
+func (m *Model) recordLastCommit(hash string) {
+	if m.amend != nil {
+		hash = ""
+	}
+	m.repoState.LastCommit = hash
+	_ = m.repoState.Save() // best effort, like the rest of per-repo state
+}
+
+// refreshIndexCounts updates the index panel; call it after anything that
+// changes the index
+func (m *Model) refreshIndexCounts() {
+	if counts, err := m.repo.IndexSummary(); err == nil {
+		m.index = counts
+	}
+}
+
+// renderIndexPanel summarizes the index, which commity changes on the user's behalf
+func (m *Model) renderIndexPanel() string {
+	return m.styles.Dim.Render(fmt.Sprintf("index: %d staged · %d unstaged · %d untracked",
+		m.index.Staged, m.index.Unstaged, m.index.Untracked))
 }
 
 // ---------------------------------------------------------------------------
@@ -219,23 +477,44 @@ func (m *Model) applyConfigChanges() error {
 	m.styles = NewStyles(m.theme)
 	m.spinner.Style = lipgloss.NewStyle().Foreground(m.theme.Primary)
 
-	// Reinitialize AI client with new config
-	newClient, err := ai.New(&m.cfg.AI)
+	// Reinitialize AI client with new config, keeping host policies in force
+	aiCfg, err := m.cfg.EffectiveAI(m.remoteHost)
 	if err != nil {
 		return err
 	}
+	newClient, err := ai.New(&aiCfg)
+	if err != nil {
+		return err
+	}
+	if m.aiClient != nil {
+		newClient.SetCacheDir(m.aiClient.CacheDir())
+	}
 	m.aiClient = newClient
 
 	return nil
 }
 
+// SetOffline starts the session without an AI client; generated messages
+// are derived locally from the diff and err says why
+func (m *Model) SetOffline(err error) {
+	m.offlineErr = err
+}
+
 // setError transitions to error state and returns the model with no command
 func (m *Model) setError(err error) (tea.Model, tea.Cmd) {
+	m.count("error." + telemetry.Categorize(err))
 	m.state = stateError
 	m.err = err
+	m.retry = nil
+	m.screens.reset()
 	return m, nil
 }
 
+// count records a usage counter; it does nothing unless telemetry is enabled
+func (m *Model) count(name string) {
+	telemetry.Count(m.cfg.Telemetry, name)
+}
+
 // updateForm updates the form and returns the command
 func (m *Model) updateForm(msg tea.Msg) tea.Cmd {
 	form, cmd := m.form.Update(msg)
@@ -245,6 +524,74 @@ func (m *Model) updateForm(msg tea.Msg) tea.Cmd {
 	return cmd
 }
 
+// relatedMsg carries the related untracked files of a selection
+type relatedMsg struct {
+	key     string // selection they were computed for, see relatedKey
+	related []string
+	err     error
+}
+
+// refreshRelated recomputes related untracked file suggestions in the
+// background when the selection changes
+func (m *Model) refreshRelated() tea.Cmd {
+	key := strings.Join(m.selected, "\x00")
+	if key == m.relatedKey {
+		return nil
+	}
+	m.relatedKey = key
+
+	// Only diff tracked files; untracked content is not needed for hints
+	var tracked []string
+	for _, path := range m.selected {
+		if m.getFileStatus(path) != "??" {
+			tracked = append(tracked, path)
+		}
+	}
+
+	repo, files, selected := m.repo, m.files, slices.Clone(m.selected)
+	return func() tea.Msg {
+		var diff string
+		if len(tracked) > 0 {
+			staged, err := repo.Diff(tracked, true)
+			if err != nil {
+				return relatedMsg{key: key, err: err}
+			}
+			unstaged, err := repo.Diff(tracked, false)
+			if err != nil {
+				return relatedMsg{key: key, err: err}
+			}
+			diff = staged + unstaged
+		}
+		return relatedMsg{key: key, related: git.RelatedUntracked(files, selected, diff)}
+	}
+}
+
+// afterRelated shows the suggestions unless the selection changed since
+func (m *Model) afterRelated(msg relatedMsg) (tea.Model, tea.Cmd) {
+	if msg.key != m.relatedKey {
+		return m, nil
+	}
+	m.related = msg.related
+	if msg.err != nil && m.state == stateFileSelect {
+		m.notice = "Finding related files failed: " + msg.err.Error()
+	}
+	return m, nil
+}
+
+// includePairedTests adds the changed tests of selected files, and the
+// changed files under test of selected tests, since they belong together
+func (m *Model) includePairedTests() {
+	if !m.cfg.General.PairTests {
+		return
+	}
+	paired := git.PairedTests(m.files, m.selected)
+	if len(paired) == 0 {
+		return
+	}
+	m.selected = append(m.selected, paired...)
+	m.notice = "Also selected " + strings.Join(paired, ", ")
+}
+
 // getFileStatus returns the git status for a file path
 func (m *Model) getFileStatus(path string) string {
 	for _, f := range m.files {
@@ -268,11 +615,31 @@ func (m *Model) initConfigForm(showWelcome bool) {
 		))
 	}
 
-	// API settings group
+	if m.cfg.AI.Provider == "" {
+		m.cfg.AI.Provider = config.ProviderOpenAI
+	}
+
+	// Provider group
+	groups = append(groups, huh.NewGroup(
+		huh.NewSelect[string]().
+			Title("Provider").
+			Options(huh.NewOptions(config.Providers()...)...).
+			Value(&m.cfg.AI.Provider),
+	))
+
+	// API settings group (OpenAI-compatible)
 	groups = append(groups, huh.NewGroup(
 		huh.NewInput().
 			Title("API Base URL").
-			Description("OpenAI-compatible API endpoint").
+			DescriptionFunc(func() string {
+				switch m.cfg.AI.Provider {
+				case config.ProviderGemini:
+					return "Leave empty for Google AI Studio"
+				case config.ProviderAzure:
+					return "https://<resource>.openai.azure.com"
+				}
+				return "OpenAI-compatible API endpoint"
+			}, &m.cfg.AI.Provider).
 			Value(&m.cfg.AI.BaseURL),
 		huh.NewInput().
 			Title("API Key").
@@ -280,9 +647,40 @@ func (m *Model) initConfigForm(showWelcome bool) {
 			EchoMode(huh.EchoModePassword),
 		huh.NewInput().
 			Title("Model").
-			Description("e.g., gpt-4o-mini, claude-3-sonnet").
+			Description("e.g., gpt-4o-mini, claude-3-sonnet, gemini-2.5-flash").
 			Value(&m.cfg.AI.Model),
-	))
+	).WithHideFunc(func() bool {
+		return m.cfg.AI.Provider == config.ProviderOllama
+	}))
+
+	// Azure OpenAI settings group
+	groups = append(groups, huh.NewGroup(
+		huh.NewInput().
+			Title("Deployment").
+			Description("Azure OpenAI deployment name").
+			Value(&m.cfg.AI.Deployment),
+		huh.NewInput().
+			Title("API Version").
+			Placeholder(ai.AzureDefaultAPIVersion).
+			Value(&m.cfg.AI.APIVersion),
+	).WithHideFunc(func() bool {
+		return m.cfg.AI.Provider != config.ProviderAzure
+	}))
+
+	// Ollama settings group with installed model discovery
+	groups = append(groups, huh.NewGroup(
+		huh.NewInput().
+			Title("Ollama URL").
+			Placeholder(ai.OllamaDefaultURL).
+			Value(&m.cfg.AI.BaseURL),
+		huh.NewSelect[string]().
+			Title("Model").
+			Description("Models installed on the Ollama server").
+			OptionsFunc(m.getOllamaModelOptions, &m.cfg.AI.BaseURL).
+			Value(&m.cfg.AI.Model),
+	).WithHideFunc(func() bool {
+		return m.cfg.AI.Provider != config.ProviderOllama
+	}))
 
 	// Commit settings group
 	groups = append(groups, huh.NewGroup(
@@ -295,6 +693,12 @@ func (m *Model) initConfigForm(showWelcome bool) {
 			Title("Theme").
 			Options(m.getThemeOptions()...).
 			Value(&m.cfg.UI.Theme),
+		huh.NewConfirm().
+			Title("Share anonymous usage metrics?").
+			Description(telemetry.Description).
+			Affirmative("Yes").
+			Negative("No").
+			Value(&m.cfg.Telemetry.Enabled),
 	))
 
 	// Custom instructions group
@@ -306,6 +710,17 @@ func (m *Model) initConfigForm(showWelcome bool) {
 			CharLimit(1000),
 	))
 
+	// Connection test before saving, see testConnection
+	m.testConn = true
+	groups = append(groups, huh.NewGroup(
+		huh.NewConfirm().
+			Title("Test the connection before saving?").
+			Description("Sends the model a tiny request").
+			Affirmative("Yes").
+			Negative("No").
+			Value(&m.testConn),
+	))
+
 	m.form = huh.NewForm(groups...).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
 }
 
@@ -322,31 +737,138 @@ func (m *Model) initFirstRunForm() {
 // ---------------------------------------------------------------------------
 
 func (m *Model) Init() tea.Cmd {
+	if m.state == stateGenerating {
+		return tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+	}
+	if m.state == stateFileSelect && m.canWarmUp() {
+		return tea.Batch(m.form.Init(), m.spinner.Tick, m.warmUp())
+	}
 	return tea.Batch(m.form.Init(), m.spinner.Tick)
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m.notice = ""
+	}
+	return m.route(msg)
+}
+
+// updateState handles messages for the state machine, see route
+func (m *Model) updateState(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Forms below size themselves from the same message
+		if msg.Width > 0 {
+			m.termWidth = msg.Width
+		}
+
 	case tea.KeyMsg:
-		switch msg.String() {
+		if m.state == stateDone {
+			return m.updateDone(msg)
+		}
+		switch resolve(msg, m.keys.Quit, m.keys.Settings) {
 		case "ctrl+c":
 			return m, tea.Quit
+		case "ctrl+k":
+			if items := m.paletteItems(); len(items) > 0 {
+				m.screens.push(&paletteScreen{palette: NewPaletteModel(m.theme, items)})
+				m.count("feature.palette")
+				return m, textinput.Blink
+			}
 		case "q":
+			if m.state == stateConfirm {
+				return m.cancel()
+			}
+			// Overlays are screens, see route; only the setup forms take text
 			if m.state != stateInit && m.state != stateSettings {
 				return m, tea.Quit
 			}
-		case "s", "S":
-			// Open settings from file select
+		case "r", "R":
+			if m.state == stateSigning {
+				return m.retrySigned()
+			}
+			if m.state == stateError && m.retry != nil {
+				return m.retryFailed()
+			}
+			// Add suggested related untracked files to the selection
+			if m.state == stateFileSelect && len(m.related) > 0 {
+				m.initFileSelectFormWith(append(m.selected, m.related...))
+				return m, tea.Batch(m.form.Init(), m.refreshRelated())
+			}
+		case "p", "P":
+			// Open saved selections from file select
 			if m.state == stateFileSelect {
+				return m, m.openPresets()
+			}
+		case "esc":
+			if m.state == stateGenerating {
+				return m.cancelGeneration()
+			}
+		case "s", "S":
+			// Open settings from file select, or to fix what caused an error
+			if m.state == stateFileSelect || m.state == stateError {
 				m.previousState = m.state
 				m.state = stateSettings
 				m.initSettingsForm()
 				return m, m.form.Init()
 			}
+		case "ctrl+r":
+			// Re-check the index for changes staged outside commity
+			if m.state == stateConfirm {
+				m.refreshIndexStatus()
+				m.notice = "Index status refreshed"
+				return m, nil
+			}
+		case "ctrl+o":
+			// Jump to the first conflict marker or debug statement
+			if m.state == stateConfirm {
+				return m.openIssue()
+			}
+		case "ctrl+f":
+			// Fix the message's convention warnings
+			if m.state == stateConfirm {
+				return m.fixMessage()
+			}
+		case "m", "M":
+			// Move files between commits of the split plan
+			if m.state == statePlan {
+				return m.openReassign()
+			}
+			// Switch the model for this session
+			if m.state == stateFileSelect {
+				return m.openModelPicker()
+			}
+		case "h", "H":
+			// Pick individual hunks of the selected files
+			if m.state == stateFileSelect {
+				return m.openHunks()
+			}
+		case "?":
+			// List the keys of the current state
+			if groups := m.helpBindings(); len(groups) > 0 {
+				return m.openHelp()
+			}
+		case "A":
+			// Stage the selection and unstage the rest
+			if m.state == stateFileSelect {
+				return m.applyStaging()
+			}
+		case "N":
+			// Plain n selects no files, see FileTree
+			if m.state == stateFileSelect {
+				return m.startNote()
+			}
+		case "D":
+			// Fetch more history for a shallow clone
+			if m.state == stateFileSelect && m.shallow {
+				return m, m.deepenHistory()
+			}
 		case "b", "B":
 			// Go back from error state
-			if m.state == stateError {
+			if m.state == stateError || m.state == stateSigning {
 				m.err = nil
+				m.retry = nil
+				m.signErr = nil
 				m.state = stateFileSelect
 				m.initFileSelectForm()
 				return m, m.form.Init()
@@ -363,38 +885,165 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.setError(fmt.Errorf("no changes to commit"))
 		}
 		m.files = files
+		m.shallow = m.repo.IsShallow()
+		m.refreshIndexCounts()
 		m.state = stateFileSelect
 		m.initFileSelectForm()
 		return m, m.form.Init()
 
-	case generateMsg:
+	case editorMsg:
+		return m.afterEditor(msg)
+
+	case deepenMsg:
+		if msg.err != nil {
+			return m.setError(msg.err)
+		}
+		m.shallow = m.repo.IsShallow()
+		return m, nil
+
+	case branchSuggestMsg, ignoreSuggestMsg, connectionMsg:
+		// Handled by their screens; left over when those were closed
+		return m, nil
+
+	case warmUpMsg:
+		return m.afterWarmUp(msg)
+
+	case relatedMsg:
+		return m.afterRelated(msg)
+
+	case secretsMsg:
+		if !m.finishProgress() {
+			return m, nil
+		}
+		m.secretFindings = msg.findings
+		return m, m.openSecrets()
+
+	case pushMsg:
+		if msg.err != nil {
+			return m.setError(msg.err)
+		}
+		m.notice = "Pushed " + m.repo.Branch()
+		return m, nil
+
+	case undoMsg:
+		m.refreshIndexCounts()
 		if msg.err != nil {
 			return m.setError(msg.err)
 		}
+		m.recordLastCommit("")
+		files, err := m.repo.Status()
+		if err != nil {
+			return m.setError(err)
+		}
+		m.files = files
+		m.initFileSelectForm()
+		m.notice = "Undid last commit, its changes are staged"
+		return m, m.form.Init()
+
+	case compareMsg:
+		if !m.finishProgress() {
+			return m, nil
+		}
+		return m.startCompare(msg)
+
+	case generateMsg:
+		if !m.finishProgress() {
+			return m, nil
+		}
+		if msg.err != nil {
+			return m.failed(msg.err, m.retryGenerate)
+		}
 		m.commits = msg.result.Commits
+		m.hunkSource = msg.hunks
+		m.issues = msg.issues
 		m.isSplit = msg.result.IsSplit
+		m.count("provider." + m.cfg.AI.Provider)
+		if m.isSplit {
+			m.count("feature.split")
+		}
+		if msg.delta {
+			m.count("feature.delta")
+		}
+		m.plan = ai.EstimatePlan(msg.result)
+		m.fallback = msg.result.Fallback
+		m.cached = msg.result.Cached
+		for _, c := range m.commits {
+			m.recordHistory(store.HistoryGenerated, c.String())
+		}
+		if m.fallback == ai.FallbackOffline {
+			m.offlineErr = msg.result.Err
+			m.count("feature.offline")
+		}
+		m.computeCommitStats()
 		m.currentIndex = 0
 		m.completed = make([]bool, len(m.commits))
+		m.keepCandidate()
+
+		// Never silently drop files the model forgot or double-booked
+		if m.isSplit {
+			if cov := ai.CheckCoverage(m.commits, m.selected); !cov.OK() {
+				return m.correctCoverage(cov)
+			}
+		}
+
+		// Show the whole split plan before anything is committed
+		if m.isSplit && len(m.commits) > 1 {
+			m.state = statePlan
+			m.initPlanForm()
+			return m, m.form.Init()
+		}
 		m.state = stateConfirm
 		m.initConfirmForm()
 		return m, m.confirmForm.Init()
 
 	case commitMsg:
+		m.refreshIndexCounts()
+		var signErr *git.SigningError
+		if errors.As(msg.err, &signErr) {
+			return m.signingFailed(signErr)
+		}
+		if msg.err != nil && msg.staged {
+			return m.failed(msg.err, m.recommit)
+		}
 		if msg.err != nil {
-			return m.setError(msg.err)
+			return m.failed(msg.err, m.retryCommit)
 		}
 		m.completed[m.currentIndex] = true
+		m.recordHistory(store.HistoryCommitted, msg.created.Message)
 		m.currentIndex++
+		m.created = append(m.created, msg.created)
+		m.stats[msg.created.Hash] = msg.stat
+		m.recordLastCommit(msg.created.Hash)
+		if msg.hookErr != nil {
+			m.hookErrs = append(m.hookErrs, msg.hookErr)
+		}
 
 		// Check if more commits to process
+		if m.currentIndex < len(m.commits) && m.commitAll {
+			return m, tea.Batch(m.spinner.Tick, m.doCommit())
+		}
 		if m.currentIndex < len(m.commits) {
 			m.state = stateConfirm
+			m.skipHooks = m.cfg.Commit.NoVerify // the toggle is per commit
 			m.initConfirmForm()
 			return m, m.confirmForm.Init()
 		}
 
-		m.state = stateDone
-		return m, tea.Quit
+		if m.forkPush {
+			return m, m.pushFork()
+		}
+		return m.finish()
+
+	case forkPushMsg:
+		return m.afterForkPush(msg)
+
+	case sessionPushMsg:
+		return m.afterSessionPush(msg)
+
+	case webhookMsg:
+		m.webhookErr = msg.err
+		m.webhookPending = false
+		return m, m.holdDone()
 
 	case spinner.TickMsg:
 		// Only update spinner when in states that show it
@@ -406,37 +1055,56 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Forms under a screen are left alone: huh's messages aren't addressed to
+	// a form, so the screen's form would move them too
+	if m.screens.top() != nil {
+		return m, nil
+	}
+
 	switch m.state {
-	case stateInit:
+	case stateInit, stateSettings:
 		cmd := m.updateForm(msg)
 		if m.form.State == huh.StateCompleted {
-			if err := m.applyConfigChanges(); err != nil {
-				return m.setError(err)
+			if m.testConn {
+				return m.testConnection()
 			}
-			return m, func() tea.Msg { return initCompleteMsg{} }
+			return m.saveSettings(m.state)
 		}
 		return m, cmd
 
-	case stateSettings:
+	case statePlan:
 		cmd := m.updateForm(msg)
 		if m.form.State == huh.StateCompleted {
-			if err := m.applyConfigChanges(); err != nil {
-				return m.setError(err)
-			}
-			m.state = m.previousState
-			m.initFileSelectForm()
-			return m, m.form.Init()
+			return m.completePlanForm()
 		}
 		return m, cmd
 
-	case stateFileSelect:
+	case stateRollback:
 		cmd := m.updateForm(msg)
+		if m.form.State == huh.StateCompleted {
+			return m.completeRollbackForm()
+		}
+		return m, cmd
+
+	case stateAmendOffer:
+		cmd := m.updateForm(msg)
+		if m.form.State == huh.StateCompleted {
+			return m.completeAmendOfferForm()
+		}
+		return m, cmd
+
+	case stateFileSelect:
+		cmd := tea.Batch(m.updateForm(msg), m.refreshRelated())
 		if m.form.State == huh.StateCompleted {
 			if len(m.selected) == 0 {
 				return m.setError(fmt.Errorf("no files selected"))
 			}
-			m.state = stateGenerating
-			return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+			m.includePairedTests()
+			m.rememberSelection()
+			if cmd := m.offerIgnore(); cmd != nil {
+				return m, cmd
+			}
+			return m.startGenerating()
 		}
 		return m, cmd
 
@@ -448,22 +1116,66 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.feedback = m.confirmForm.Feedback()
 			switch m.confirmForm.Action() {
 			case actionCommit:
-				m.state = stateCommitting
-				return m, tea.Batch(m.spinner.Tick, m.doCommit())
+				if m.amend != nil {
+					return m.guard("Amend the last commit?", m.beginCommit)
+				}
+				return m.beginCommit()
+			case actionPush:
+				if m.canPushFork() {
+					return m.commitAndPush()
+				}
+				m.initConfirmForm()
+				return m, m.confirmForm.Init()
 			case actionCancel:
-				return m, tea.Quit
+				return m.cancel()
+			case actionFiles:
+				if m.canReselect() {
+					return m.reselect()
+				}
+				m.initConfirmForm()
+				return m, m.confirmForm.Init()
 			case actionRegenerate:
 				m.state = stateGenerating
 				return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+			case actionPrevious, actionNext:
+				if m.confirmForm.Action() == actionPrevious {
+					m.switchCandidate(-1)
+				} else {
+					m.switchCandidate(1)
+				}
+				m.initConfirmForm()
+				return m, m.confirmForm.Init()
+			case actionDiff:
+				m.initConfirmForm()
+				return m.openDiff()
+			case actionHistory:
+				m.initConfirmForm()
+				return m.openHistory()
+			case actionFresh:
+				// The same request as before, without the cached answer
+				m.commits = nil
+				m.noCache = true
+				m.state = stateGenerating
+				return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
 			case actionEdit:
-				m.state = stateEdit
-				ta := textarea.New()
-				ta.SetValue(m.commits[m.currentIndex].String())
-				ta.Focus()
-				ta.SetWidth(m.termWidth - editAreaPadding)
-				ta.SetHeight(editAreaHeight)
-				m.editArea = ta
-				return m, textarea.Blink
+				return m, m.startEdit()
+			case actionModel:
+				m.initConfirmForm()
+				return m.openModelPicker()
+			case actionHooks:
+				m.skipHooks = !m.skipHooks
+				m.initConfirmForm()
+				return m, m.confirmForm.Init()
+			case actionMerge:
+				if m.isSplit {
+					m.mergeRemaining()
+				}
+				m.initConfirmForm()
+				return m, m.confirmForm.Init()
+			case actionInstruct:
+				m.initConfirmForm()
+				return m, m.pushInput("Instruction for this session (not saved to config):",
+					"e.g. mention the migration, use scope api", m.sessionInstruction, setInstruction)
 			}
 		}
 
@@ -481,11 +1193,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "ctrl+s":
 				// Save edit
 				newMsg := m.editArea.Value()
-				// Update the commit message (just subject for simplicity)
-				m.commits[m.currentIndex] = ai.CommitMessage{
-					Subject: newMsg,
-					Files:   m.commits[m.currentIndex].Files,
-				}
+				m.replaceMessage(newMsg)
+				m.recordHistory(store.HistoryEdited, newMsg)
 				m.state = stateConfirm
 				m.initConfirmForm()
 				return m, m.confirmForm.Init()
@@ -504,6 +1213,33 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// setInstruction sets the instruction applied to every regeneration
+func setInstruction(m *Model, text string) (tea.Model, tea.Cmd) {
+	m.sessionInstruction = strings.TrimSpace(text)
+	return m, nil
+}
+
+// replaceMessage sets the text of the current commit message, split back
+// into type, scope, subject, body and trailers. The commit keeps its files.
+func (m *Model) replaceMessage(text string) {
+	current := m.commits[m.currentIndex]
+	msg := ai.ParseMessage(text, m.subjectRules())
+	msg.Files, msg.Hunks = current.Files, current.Hunks
+	m.commits[m.currentIndex] = msg
+}
+
+// startEdit opens the editor for the current commit message
+func (m *Model) startEdit() tea.Cmd {
+	m.state = stateEdit
+	ta := textarea.New()
+	ta.SetValue(m.commits[m.currentIndex].String())
+	ta.Focus()
+	ta.SetWidth(m.termWidth - editAreaPadding)
+	ta.SetHeight(editAreaHeight)
+	m.editArea = ta
+	return textarea.Blink
+}
+
 // ---------------------------------------------------------------------------
 // View Helpers
 // ---------------------------------------------------------------------------
@@ -522,7 +1258,14 @@ func (m *Model) viewConfirm(s *strings.Builder) {
 	// Show branch
 	branch := m.repo.Branch()
 	branchStyle := lipgloss.NewStyle().Foreground(m.theme.Primary).Bold(true)
-	s.WriteString(fmt.Sprintf("Branch: %s\n\n", branchStyle.Render(branch)))
+	s.WriteString(fmt.Sprintf("Branch: %s\n", branchStyle.Render(branch)))
+	if profile := m.cfg.Profile(); profile != "" {
+		s.WriteString(fmt.Sprintf("Profile: %s\n", branchStyle.Render(profile)))
+	}
+	if m.amend != nil {
+		s.WriteString(fmt.Sprintf("Amending: %s\n", branchStyle.Render(m.amend.Hash[:min(7, len(m.amend.Hash))])))
+	}
+	s.WriteString("\n")
 
 	// Get files for this commit
 	commit := m.commits[m.currentIndex]
@@ -541,16 +1284,53 @@ func (m *Model) viewConfirm(s *strings.Builder) {
 	}
 
 	// Show diff stats
-	added, removed := m.repo.DiffStats(commitFiles)
 	statsStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
-	addStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
-	removeStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
 	s.WriteString(statsStyle.Render(fmt.Sprintf("\n%d files, ", len(commitFiles))))
-	s.WriteString(addStyle.Render(fmt.Sprintf("+%d", added)))
-	s.WriteString(statsStyle.Render(" "))
-	s.WriteString(removeStyle.Render(fmt.Sprintf("-%d", removed)))
+	s.WriteString(m.renderDiffStat(m.commitStats[m.currentIndex]))
 	s.WriteString("\n\n")
 
+	// Show every proposed commit with its size so odd groupings stand out
+	if m.isSplit {
+		s.WriteString(m.renderSplitProgress())
+		s.WriteString("\n")
+	}
+
+	// Warn when the index holds more than this commit is meant to contain
+	if len(m.unexpectedStaged) > 0 {
+		s.WriteString(m.styles.Error.Render("Also staged outside commity, will be included:"))
+		s.WriteString("\n")
+		for _, f := range m.unexpectedStaged {
+			s.WriteString(m.styles.Error.Render("  " + f))
+			s.WriteString("\n")
+		}
+		s.WriteString(m.renderKeyHint("[ctrl+r]", "refresh"))
+		s.WriteString("\n\n")
+	}
+
+	// Conflict markers and debug statements are rarely meant to be committed
+	m.viewIssues(s)
+	m.viewMessageWarnings(s)
+
+	// Explain why the message may be less precise than usual
+	switch m.fallback {
+	case ai.FallbackShortPrompt:
+		s.WriteString(m.styles.Dim.Render("AI missed the deadline; generated from a shortened diff"))
+		s.WriteString("\n\n")
+	case ai.FallbackHeuristic:
+		s.WriteString(m.styles.Dim.Render("AI missed the deadline; heuristic message derived locally from the diff"))
+		s.WriteString("\n\n")
+	case ai.FallbackOffline:
+		s.WriteString(m.styles.Error.Render("AI unavailable; heuristic message derived locally from the diff"))
+		s.WriteString("\n")
+		s.WriteString(m.styles.Dim.Render(m.offlineErr.Error()))
+		s.WriteString("\n\n")
+	}
+	if m.confirmForm.cached {
+		s.WriteString(m.styles.Dim.Render("Reused the message generated earlier for this diff") + "  " +
+			m.renderKeyHint("[g]", "generate anew"))
+		s.WriteString("\n\n")
+	}
+
 	// Show commit message
 	if m.isSplit {
 		s.WriteString(fmt.Sprintf("Commit %d of %d:\n\n", m.currentIndex+1, len(m.commits)))
@@ -563,39 +1343,178 @@ func (m *Model) viewConfirm(s *strings.Builder) {
 	}
 	s.WriteString(m.styles.Message.Width(msgWidth).Render(commit.String()))
 	s.WriteString("\n\n")
+	if attempts := m.viewCandidates(); attempts != "" {
+		s.WriteString(attempts)
+		s.WriteString("\n\n")
+	}
 	s.WriteString(m.confirmForm.View())
 	s.WriteString("\n\n")
-	s.WriteString(m.renderKeyHint("[↑↓]", "navigate") + "  " +
+	if m.sessionInstruction != "" {
+		s.WriteString(m.styles.Dim.Render("Session instruction: " + m.sessionInstruction))
+		s.WriteString("\n\n")
+	}
+	if m.skipHooks {
+		s.WriteString(m.styles.Error.Render("Commit hooks will be skipped (--no-verify)"))
+		s.WriteString("\n\n")
+	}
+	hints := m.renderKeyHint("[↑↓]", "navigate") + "  " +
 		m.renderKeyHint("[enter]", "select") + "  " +
-		m.renderKeyHint("[e]", "edit"))
+		m.hint(m.keys.Edit) + "  " +
+		m.hint(m.keys.Regenerate) + "  " +
+		m.renderKeyHint("[i]", "instruct") + "  " +
+		m.hint(m.keys.Diff) + "  " +
+		m.renderKeyHint("[h]", "history") + "  " +
+		m.renderKeyHint("[m]", "model") + "  "
+	if hooks := "[" + m.keys.SkipHooks.Help().Key + "]"; m.skipHooks {
+		hints += m.renderKeyHint(hooks, "run hooks") + "  "
+	} else {
+		hints += m.renderKeyHint(hooks, "skip hooks") + "  "
+	}
+	if m.canPushFork() {
+		hints += m.renderKeyHint("[p]", "commit & push to "+m.cfg.PR.Fork) + "  "
+	}
+	if m.canReselect() {
+		hints += m.renderKeyHint("[f]", "files") + "  "
+	}
+	s.WriteString(hints + m.renderKeyHint("[ctrl+k]", "commands") + "  " + m.renderKeyHint("[?]", "help"))
+}
+
+// computeCommitStats caches diff stats for each proposed commit. Stats
+// must be captured before committing since committed files no longer diff.
+func (m *Model) computeCommitStats() {
+	m.commitStats = make([]diffStat, len(m.commits))
+	if m.amend != nil {
+		for i := range m.commitStats {
+			m.commitStats[i] = diffStat{added: m.amend.Added, removed: m.amend.Removed}
+		}
+		return
+	}
+	for i, c := range m.commits {
+		files := c.Files
+		if len(files) == 0 {
+			files = m.selected
+		}
+		added, removed := m.repo.DiffStats(files)
+		m.commitStats[i] = diffStat{added: added, removed: removed}
+	}
+}
+
+// renderDiffStat renders colored +added -removed counts
+func (m *Model) renderDiffStat(st diffStat) string {
+	addStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	removeStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+	return addStyle.Render(fmt.Sprintf("+%d", st.added)) + " " +
+		removeStyle.Render(fmt.Sprintf("-%d", st.removed))
+}
+
+// renderSplitProgress renders the breadcrumb of all proposed split commits
+func (m *Model) renderSplitProgress() string {
+	var s strings.Builder
+	currentStyle := lipgloss.NewStyle().Foreground(m.theme.Primary).Bold(true)
+	for i, c := range m.commits {
+		subject := c.String()
+		if idx := strings.Index(subject, "\n"); idx != -1 {
+			subject = subject[:idx]
+		}
+
+		marker := "  "
+		line := m.styles.Dim.Render(fmt.Sprintf("%d. %s", i+1, subject))
+		switch {
+		case m.completed[i]:
+			marker = m.styles.Success.Render("✓ ")
+		case i == m.currentIndex:
+			marker = currentStyle.Render("> ")
+			line = currentStyle.Render(fmt.Sprintf("%d. %s", i+1, subject))
+		}
+		s.WriteString(fmt.Sprintf("%s%s  %s\n", marker, line, m.renderDiffStat(m.commitStats[i])))
+	}
+	return s.String()
+}
+
+// renderPlanEstimate summarizes the cost of a split plan; accepting it
+// makes no API calls, so only a regeneration costs anything
+func (m *Model) renderPlanEstimate() string {
+	return fmt.Sprintf("Plan: %d commits · no further API calls · regenerate %s",
+		m.plan.Commits, m.formatUsage(m.plan.Regenerate))
+}
+
+// formatUsage renders token counts and, when prices are configured, cost
+func (m *Model) formatUsage(u ai.Usage) string {
+	approx := ""
+	if u.Estimated {
+		approx = "~"
+	}
+	s := fmt.Sprintf("%s%s in / %s%s out tokens",
+		approx, formatTokens(u.PromptTokens), approx, formatTokens(u.CompletionTokens))
+	if m.cfg.AI.InputCost > 0 || m.cfg.AI.OutputCost > 0 {
+		s += fmt.Sprintf(" ≈ $%.4f", u.Cost(m.cfg.AI.InputCost, m.cfg.AI.OutputCost))
+	}
+	return s
+}
+
+// formatTokens abbreviates token counts (e.g. 3200 -> 3.2k)
+func formatTokens(n int) string {
+	if n >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprintf("%d", n)
 }
 
 // viewDone renders the completion view
 func (m *Model) viewDone(s *strings.Builder) {
-	if m.isSplit {
-		s.WriteString(m.styles.Success.Render(fmt.Sprintf("Created %d commits successfully!", len(m.commits))))
+	if m.rolledBack > 0 {
+		s.WriteString(m.styles.Success.Render(fmt.Sprintf("Rolled back %d commits; their changes are still staged", m.rolledBack)))
+		s.WriteString("\n")
+		return
+	}
+	if m.amend != nil {
+		s.WriteString(m.styles.Success.Render("Amended the last commit! Force-push if it was already pushed"))
+	} else if m.isSplit {
+		s.WriteString(m.styles.Success.Render(fmt.Sprintf("Created %d commits successfully!", m.currentIndex)))
 	} else {
-		s.WriteString(m.styles.Success.Render("Committed successfully! Do not forget to push"))
+		done := "Committed successfully!"
+		if m.ended() && !m.pushed {
+			done += " Do not forget to push"
+		}
+		s.WriteString(m.styles.Success.Render(done))
 	}
 	s.WriteString("\n\n")
-	for i, c := range m.commits {
-		if m.completed[i] {
-			msg := c.String()
-			if idx := strings.Index(msg, "\n"); idx != -1 {
-				msg = msg[:idx]
-			}
-			s.WriteString(m.styles.Dim.Render(fmt.Sprintf("  %s", msg)))
-			s.WriteString("\n")
-		}
+	m.viewCreated(s)
+	for _, err := range m.hookErrs {
+		s.WriteString("\n")
+		s.WriteString(wrapText(m.styles.Error.Render(fmt.Sprintf("Post-commit hook: %v", err)), m.termWidth-2))
+		s.WriteString("\n")
+	}
+	if m.webhookErr != nil {
+		s.WriteString("\n")
+		s.WriteString(wrapText(m.styles.Error.Render(m.webhookErr.Error()), m.termWidth-2))
+		s.WriteString("\n")
 	}
+	m.viewForkPush(s)
+	m.viewPush(s)
+	m.viewDoneKeys(s)
 }
 
 func (m *Model) View() string {
 	var s strings.Builder
 
 	s.WriteString(m.styles.Title.Render("commity"))
+	if m.state != stateInit {
+		s.WriteString("  " + m.renderIndexPanel())
+	}
 	s.WriteString("\n\n")
 
+	if top := m.screens.top(); top != nil {
+		s.WriteString(top.view(m))
+		s.WriteString("\n")
+		return s.String()
+	}
+
+	if m.notice != "" {
+		s.WriteString(m.styles.Success.Render(m.notice))
+		s.WriteString("\n\n")
+	}
+
 	switch m.state {
 	case stateInit:
 		s.WriteString(m.form.View())
@@ -612,18 +1531,61 @@ func (m *Model) View() string {
 			m.renderKeyHint("[enter]", "next"))
 
 	case stateFileSelect:
+		if p := m.cfg.PolicyFor(m.remoteHost); p != nil {
+			s.WriteString(m.styles.Dim.Render(fmt.Sprintf("Host policy for %s applied", m.remoteHost)))
+			s.WriteString("\n\n")
+		}
+		m.viewNotes(&s)
+		if m.cfg.Commitlint.File != "" {
+			s.WriteString(m.styles.Dim.Render("Following the commitlint rules in " + m.cfg.Commitlint.File))
+			s.WriteString("\n\n")
+		}
+		if !m.cfg.SplitAllowed() && m.cfg.Repo.SplitNote != "" {
+			s.WriteString(m.styles.Dim.Render(wrapText("Split commits are off here: "+m.cfg.Repo.SplitNote, m.termWidth-2)))
+			s.WriteString("\n\n")
+		}
+		if m.warming {
+			s.WriteString(m.styles.Dim.Render(m.spinner.View() + " Loading " + m.aiClient.Model() + "…"))
+			s.WriteString("\n\n")
+		}
+		if m.shallow {
+			s.WriteString(m.styles.Dim.Render("Shallow clone: history-based features are limited."))
+			s.WriteString(" " + m.renderKeyHint("[D]", "deepen"))
+			s.WriteString("\n\n")
+		}
 		s.WriteString(m.form.View())
 		s.WriteString("\n")
+		if len(m.related) > 0 {
+			s.WriteString(m.styles.Dim.Render("Related untracked: " + strings.Join(m.related, ", ")))
+			s.WriteString(" " + m.renderKeyHint("[r]", "add"))
+			s.WriteString("\n")
+		}
 		s.WriteString(m.renderKeyHint("[space]", "toggle") + "  " +
-			m.renderKeyHint("[ctrl+a]", "all") + "  " +
+			m.renderKeyHint("["+m.keys.SelectAll.Help().Key+"/n/i]", "all/none/invert") + "  " +
 			m.renderKeyHint("[↑↓]", "navigate") + "  " +
+			m.renderKeyHint("[←→]", "fold") + "  " +
 			m.renderKeyHint("[enter]", "submit") + "  " +
-			m.renderKeyHint("[s]", "settings") + "  " +
-			m.renderKeyHint("[q]", "quit"))
+			m.renderKeyHint("[h]", "hunks") + "  " +
+			m.renderKeyHint("[A]", "apply staging") + "  " +
+			m.renderKeyHint("[p]", "presets") + "  " +
+			m.renderKeyHint("[N]", "note") + "  " +
+			m.renderKeyHint("[m]", "model") + "  " +
+			m.hint(m.keys.Settings) + "  " +
+			m.renderKeyHint("[ctrl+k]", "commands") + "  " +
+			m.renderKeyHint("[?]", "help") + "  " +
+			m.hint(m.keys.Quit))
+
+	case statePlan:
+		m.viewPlan(&s)
+
+	case stateRollback, stateAmendOffer:
+		s.WriteString(m.form.View())
+		s.WriteString("\n")
+		s.WriteString(m.renderKeyHint("[←→]", "choose") + "  " +
+			m.renderKeyHint("[enter]", "confirm"))
 
 	case stateGenerating:
-		s.WriteString(m.spinner.View())
-		s.WriteString(" Generating commit message...")
+		m.viewGenerating(&s)
 
 	case stateConfirm:
 		m.viewConfirm(&s)
@@ -637,15 +1599,29 @@ func (m *Model) View() string {
 
 	case stateCommitting:
 		s.WriteString(m.spinner.View())
-		s.WriteString(" Committing...")
+		if m.pushing {
+			s.WriteString(" Pushing to " + m.pushTarget + "...")
+		} else if m.commitAll {
+			s.WriteString(fmt.Sprintf(" Committing %d of %d...", m.currentIndex+1, len(m.commits)))
+		} else {
+			s.WriteString(" Committing...")
+		}
 
 	case stateDone:
 		m.viewDone(&s)
 
+	case stateSigning:
+		m.viewSigning(&s)
+
 	case stateError:
 		s.WriteString(wrapText(m.styles.Error.Render(fmt.Sprintf("Error: %v", m.err)), m.termWidth-2))
 		s.WriteString("\n\n")
-		s.WriteString(m.renderKeyHint("[b]", "back") + "  " + m.renderKeyHint("[q]", "quit"))
+		if m.retry != nil {
+			s.WriteString(m.renderKeyHint("[r]", "retry") + "  ")
+		}
+		s.WriteString(m.renderKeyHint("[b]", "back to files") + "  " +
+			m.hint(m.keys.Settings) + "  " +
+			m.hint(m.keys.Quit))
 	}
 
 	s.WriteString("\n")
@@ -656,40 +1632,290 @@ func (m *Model) View() string {
 // Commands
 // ---------------------------------------------------------------------------
 
+// startGenerating generates messages for a freshly confirmed selection
+func (m *Model) startGenerating() (tea.Model, tea.Cmd) {
+	m.secretsDecision = ""
+	m.state = stateGenerating
+	return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+}
+
 func (m *Model) generateCommitMessage() tea.Cmd {
 	// Capture previous message for regeneration context
 	var previousMsg string
 	if len(m.commits) > 0 && m.currentIndex < len(m.commits) {
 		previousMsg = m.commits[m.currentIndex].String()
+	} else if m.amend != nil {
+		previousMsg = m.amend.Message
 	}
 	feedback := m.feedback
+	base, added := m.deltaBase()
+	noCache := m.noCache
+	m.noCache = false
+	ctx := m.startProgress()
 
 	return func() tea.Msg {
+		diff, err := m.promptDiff()
+		if err != nil {
+			return generateMsg{err: err}
+		}
+		diff, findings := m.protectDiff(diff)
+		if len(findings) > 0 {
+			return secretsMsg{findings: findings}
+		}
+
+		hunks := m.hunkSources()
+		issues := m.scanSelection()
+		pc := m.promptContext(diff, previousMsg, feedback)
+		pc.NoCache = noCache
+		// Without the AI a message derived from the diff keeps commity usable
 		if m.aiClient == nil {
-			return generateMsg{err: fmt.Errorf("AI client not initialized")}
+			return generateMsg{result: ai.Offline(pc, cmp.Or(m.offlineErr, errors.New("AI client not initialized"))), hunks: hunks, issues: issues}
 		}
+		if m.compareClient != nil {
+			return m.generateBoth(ctx, pc, hunks, issues)
+		}
+		full, delta := pc, false
+		if base != nil {
+			pc, delta = m.deltaContext(pc, base, added)
+		}
+		result, err := m.aiClient.GenerateCommitMessage(ctx, pc)
+		if err != nil {
+			return generateMsg{result: ai.Offline(full, err), hunks: hunks, issues: issues}
+		}
+
+		return generateMsg{result: result, hunks: hunks, issues: issues, delta: delta}
+	}
+}
 
-		diff, err := m.repo.DiffAll(m.selected)
+// promptDiff returns the diff of the selection with files covered by the
+// privacy policy reduced to a summary. Diffs bound for the AI must come from here.
+func (m *Model) promptDiff() (string, error) {
+	return m.promptDiffFor(m.selected)
+}
+
+// promptDiffFor is promptDiff for some of the selected files
+func (m *Model) promptDiffFor(files []string) (string, error) {
+	diff, err := m.selectionDiff(files)
+	if err != nil {
+		return "", err
+	}
+	return ai.Withhold(diff, m.cfg.Privacy.NeverSend), nil
+}
+
+// selectionDiff returns what committing files would record, or the diff of
+// the commit being amended. It is the full diff, not fit for the AI.
+func (m *Model) selectionDiff(files []string) (string, error) {
+	if m.amend != nil {
+		return m.repo.DiffOfCommit(m.amend.Hash)
+	}
+	partial, whole := m.partialFiles(files)
+	diff, err := m.repo.DiffAll(whole)
+	if err != nil {
+		return "", err
+	}
+	// Only the staged hunks of partially staged files will be committed
+	if len(partial) > 0 {
+		staged, err := m.repo.Diff(partial, true)
 		if err != nil {
-			return generateMsg{err: err}
+			return "", err
+		}
+		diff += staged
+	}
+	return diff, nil
+}
+
+// hunkSources records the unstaged hunks of the selected files, numbered as
+// the model saw them, so split commits can stage parts of a file. Files with
+// staged changes appear twice in the prompt and are always staged whole.
+func (m *Model) hunkSources() map[string]git.FileDiff {
+	if m.amend != nil {
+		return nil
+	}
+	_, whole := m.partialFiles(m.selected)
+	staged, err := m.repo.StagedFiles()
+	if err != nil {
+		return nil
+	}
+	unstaged, err := m.repo.Diff(whole, false)
+	if err != nil {
+		return nil
+	}
+
+	sources := make(map[string]git.FileDiff)
+	for _, fd := range git.ParseDiff(unstaged) {
+		if !slices.Contains(staged, fd.Path) {
+			sources[fd.Path] = fd
 		}
+	}
+	return sources
+}
+
+// customInstructions combines configured instructions with the session one
+func (m *Model) customInstructions() string {
+	parts := []string{}
+	for _, s := range []string{m.cfg.AI.CustomInstructions, m.sessionInstruction} {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// promptContext collects the prompt inputs for the current selection
+func (m *Model) promptContext(diff, previousMsg, feedback string) ai.PromptContext {
+	branch := m.repo.Branch()
+	commit := m.cfg.CommitRules()
+	return ai.PromptContext{
+		Files:              m.selected,
+		Diff:               diff,
+		Conventional:       commit.Conventional,
+		Types:              commit.TypeNames(),
+		TypeDescriptions:   commit.TypeDescriptions(),
+		CustomInstructions: m.customInstructions(),
+		PreviousMsg:        previousMsg,
+		Feedback:           feedback,
+		Exclude:            m.cfg.AI.Exclude,
+		Single:             m.amend != nil || !m.cfg.SplitAllowed(),
+		Subject:            m.subjectRules(),
+		Trailers:           m.trailers(),
+		Branch:             branch,
+		Ticket:             m.cfg.Commit.Ref(branch),
+		TicketAdded:        m.cfg.Commit.RefFooter || m.cfg.Commit.RefPrefix != "",
+		Moves:              m.moveHistory(diff),
+		Examples:           m.styleExamples(),
+		Language:           commit.Language,
+	}
+}
+
+// styleExamples returns the recent commit subjects shown to the model
+func (m *Model) styleExamples() []string {
+	if m.cfg.AI.StyleExamples <= 0 {
+		return nil
+	}
+	subjects, _ := m.repo.RecentSubjects(m.cfg.AI.StyleExamples)
+	return subjects
+}
+
+// moveHistoryCommits is how many commits of each file a move shows
+const moveHistoryCommits = 3
+
+// moveHistory looks up the recent commits of both ends of code moved in
+// the diff, so refactors are described with their history in mind
+func (m *Model) moveHistory(diff string) []ai.MoveHistory {
+	var history []ai.MoveHistory
+	for _, mv := range ai.DetectMoves(diff) {
+		from, _ := m.repo.FileLog(mv.From, moveHistoryCommits)
+		to, _ := m.repo.FileLog(mv.To, moveHistoryCommits)
+		history = append(history, ai.MoveHistory{Move: mv, FromLog: from, ToLog: to})
+	}
+	return history
+}
+
+// subjectRules returns the configured first-line constraints, tightened by
+// the repository's commitlint rules
+func (m *Model) subjectRules() ai.SubjectRules {
+	commit := m.cfg.CommitRules()
+	var types []string
+	if commit.Conventional {
+		types = commit.TypeNames()
+	}
+	return ai.SubjectRules{
+		Types:       types,
+		MaxLength:   commit.SubjectMaxLength,
+		Prefix:      commit.SubjectPrefixFor(m.repo.Branch()),
+		Emoji:       commit.EmojiFor(),
+		RequireBody: commit.RequireBodyFor,
+		Scopes:      commit.Scopes,
+		ScopeEnum:   commit.ScopeEnum,
+	}
+}
+
+// trailers returns the configured trailers for commits on the current branch
+func (m *Model) trailers() []string {
+	return m.cfg.Commit.TrailersFor(config.TrailerData{
+		Branch:   m.repo.Branch(),
+		Session:  m.sessionID,
+		Repo:     filepath.Base(m.repo.Path()),
+		Remote:   git.RemoteSlug(m.repo.RemoteURL("origin")),
+		Identity: git.Identity(m.repo.Path()),
+	})
+}
+
+// newSessionID returns a random UUID identifying one run of commity
+func newSessionID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// createdCommit describes the commit just made for hooks and the webhook
+func (m *Model) createdCommit(commit ai.CommitMessage, files []string) hooks.Commit {
+	hash, _ := m.repo.HeadHash()
+	return hooks.Commit{
+		Hash:      hash,
+		ShortHash: hash[:min(7, len(hash))],
+		Type:      commit.Type,
+		Subject:   commit.Subject,
+		Body:      commit.Body,
+		Message:   commit.String(),
+		Branch:    m.repo.Branch(),
+		Files:     files,
+	}
+}
+
+// runPostHooks runs the configured post-commit commands for the new commit
+func (m *Model) runPostHooks(c hooks.Commit) error {
+	if len(m.cfg.Hooks.Post) == 0 {
+		return nil
+	}
+	if c.Hash == "" {
+		return fmt.Errorf("could not resolve the new commit")
+	}
+	return hooks.RunPost(m.cfg.Hooks.Post, c, m.repo.Path())
+}
+
+// finish shows the done screen, posting the session webhook first if set
+func (m *Model) finish() (tea.Model, tea.Cmd) {
+	m.state = stateDone
+	if m.pushMode() != config.PushNever {
+		return m.offerPush()
+	}
+	return m.endSession()
+}
 
-		result, err := m.aiClient.GenerateCommitMessage(
-			context.Background(),
-			m.selected,
-			diff,
-			m.cfg.Commit.Conventional,
-			m.cfg.Commit.Types,
-			m.cfg.AI.CustomInstructions,
-			previousMsg,
-			feedback,
-		)
+// endSession shows the done screen once the webhook is notified
+func (m *Model) endSession() (tea.Model, tea.Cmd) {
+	m.state = stateDone
+	if m.cfg.Hooks.Webhook != "" {
+		m.webhookPending = true
+		return m, m.postWebhook()
+	}
+	return m, m.holdDone()
+}
 
-		return generateMsg{result: result, err: err}
+// postWebhook notifies the configured webhook of the commits made this session
+func (m *Model) postWebhook() tea.Cmd {
+	session := hooks.Session{
+		Repo:    filepath.Base(m.repo.Path()),
+		Branch:  m.repo.Branch(),
+		Commits: m.created,
+	}
+	return func() tea.Msg {
+		return webhookMsg{err: hooks.PostWebhook(m.cfg.Hooks.Webhook, session)}
+	}
+}
+
+// deepenHistory fetches more commits so history-based features have context
+func (m *Model) deepenHistory() tea.Cmd {
+	return func() tea.Msg {
+		return deepenMsg{err: m.repo.Deepen(deepenCommits)}
 	}
 }
 
 func (m *Model) doCommit() tea.Cmd {
+	noVerify := m.skipHooks
 	return func() tea.Msg {
 		commit := m.commits[m.currentIndex]
 		files := commit.Files
@@ -697,14 +1923,53 @@ func (m *Model) doCommit() tea.Cmd {
 			files = m.selected // fallback for single commit
 		}
 
-		if err := m.repo.Add(files); err != nil {
-			return commitMsg{err: err}
+		if m.amend != nil {
+			return m.commitStaged(commit, files, noVerify)
 		}
 
-		if err := m.repo.Commit(commit.String()); err != nil {
-			return commitMsg{err: err}
+		// Partially staged files are already in the index as chosen; files
+		// shared between split commits are staged hunk by hunk
+		_, whole := m.partialFiles(files)
+		var add []string
+		for _, f := range whole {
+			hunks := commit.HunksFor(f)
+			src, ok := m.hunkSource[f]
+			if !ok || len(hunks) == 0 {
+				add = append(add, f)
+				continue
+			}
+			idx := make([]int, len(hunks))
+			for i, n := range hunks {
+				idx[i] = n - 1
+			}
+			if err := m.repo.ApplyCached(src.Patch(idx)); err != nil {
+				return commitMsg{err: fmt.Errorf("failed to stage hunks of %s: %w", f, err)}
+			}
+		}
+		if len(add) > 0 {
+			if err := m.repo.Add(add); err != nil {
+				return commitMsg{err: err}
+			}
 		}
 
-		return commitMsg{}
+		return m.commitStaged(commit, files, noVerify)
+	}
+}
+
+// commitStaged commits the index with the message, or rewords the last
+// commit when amending, and runs the post-commit hooks. noVerify skips the
+// commit hooks; it's read on the UI goroutine before the command runs.
+func (m *Model) commitStaged(commit ai.CommitMessage, files []string, noVerify bool) tea.Msg {
+	var err error
+	if m.amend != nil {
+		err = m.repo.AmendCommit(commit.String(), noVerify)
+	} else {
+		err = m.repo.Commit(commit.String(), noVerify)
+	}
+	if err != nil {
+		return commitMsg{err: err, staged: true}
 	}
+	created := m.createdCommit(commit, files)
+	stat, _ := m.repo.ShortStat(created.Hash)
+	return commitMsg{created: created, stat: stat, hookErr: m.runPostHooks(created)}
 }
@@ -0,0 +1,195 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/ai"
+)
+
+// modelRows is how many models the picker lists at once
+const modelRows = 10
+
+// modelsMsg carries the models offered by the endpoint
+type modelsMsg struct {
+	models []string
+	err    error
+}
+
+// modelChoice is a model offered by the picker, with the alias it has in
+// [ai.models], if any
+type modelChoice struct {
+	alias string
+	model string
+}
+
+// openModelPicker lists the configured aliases right away and the
+// endpoint's models once they arrive
+func (m *Model) openModelPicker() (tea.Model, tea.Cmd) {
+	var choices []modelChoice
+	for _, alias := range slices.Sorted(maps.Keys(m.cfg.AI.Models)) {
+		choices = append(choices, modelChoice{alias: alias, model: m.cfg.AI.Models[alias]})
+	}
+
+	ti := textinput.New()
+	ti.Placeholder = "filter models..."
+	ti.CharLimit = 100
+	ti.Width = 30
+	ti.Focus()
+	p := &modelScreen{input: ti, choices: choices, loading: m.aiClient != nil}
+	p.filter()
+	m.screens.push(p)
+	m.count("feature.model_switch")
+
+	cmds := []tea.Cmd{textinput.Blink}
+	if client := m.aiClient; client != nil {
+		cmds = append(cmds, func() tea.Msg {
+			models, err := client.ListModels(context.Background())
+			return modelsMsg{models: models, err: err}
+		})
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// switchModel replaces the AI client with one for model, for this session
+// only; the config keeps its model
+func (m *Model) switchModel(model string) error {
+	aiCfg, err := m.cfg.EffectiveAI(m.remoteHost)
+	if err != nil {
+		return err
+	}
+	aiCfg.Model = model
+	client, err := ai.New(&aiCfg)
+	if err != nil {
+		return err
+	}
+	if m.aiClient != nil {
+		client.SetCacheDir(m.aiClient.CacheDir())
+	}
+	m.aiClient = client
+	m.offlineErr = nil
+	return nil
+}
+
+// modelScreen picks the model for the rest of the session
+type modelScreen struct {
+	input    textinput.Model
+	choices  []modelChoice
+	filtered []modelChoice
+	cursor   int
+	loading  bool
+	err      error // listing the endpoint's models or switching failed
+}
+
+// filter keeps the models whose alias or name contains the search
+func (p *modelScreen) filter() {
+	search := strings.ToLower(strings.TrimSpace(p.input.Value()))
+	p.filtered = nil
+	for _, c := range p.choices {
+		if strings.Contains(strings.ToLower(c.alias+" "+c.model), search) {
+			p.filtered = append(p.filtered, c)
+		}
+	}
+	p.cursor = min(p.cursor, max(len(p.filtered)-1, 0))
+}
+
+func (p *modelScreen) typing() bool {
+	return true
+}
+
+func (p *modelScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case modelsMsg:
+		p.loading = false
+		p.err = msg.err
+		for _, model := range msg.models {
+			if !slices.ContainsFunc(p.choices, func(c modelChoice) bool { return c.model == model }) {
+				p.choices = append(p.choices, modelChoice{model: model})
+			}
+		}
+		p.filter()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "ctrl+p":
+			p.cursor = max(p.cursor-1, 0)
+			return m, nil
+		case "down", "ctrl+n":
+			p.cursor = min(p.cursor+1, max(len(p.filtered)-1, 0))
+			return m, nil
+		case "enter":
+			// A model not listed can be typed in full
+			model := strings.TrimSpace(p.input.Value())
+			if len(p.filtered) > 0 {
+				model = p.filtered[p.cursor].model
+			}
+			if model == "" {
+				return m, nil
+			}
+			if err := m.switchModel(model); err != nil {
+				p.err = err
+				return m, nil
+			}
+			m.screens.pop()
+			m.notice = fmt.Sprintf("Using %s for this session", model)
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	p.filter()
+	return m, cmd
+}
+
+func (p *modelScreen) view(m *Model) string {
+	var s strings.Builder
+	current := "none"
+	if m.aiClient != nil {
+		current = m.aiClient.Model()
+	}
+	s.WriteString(m.styles.Dim.Render("Model for this session (currently " + current + ", config unchanged)"))
+	s.WriteString("\n\n")
+	s.WriteString(p.input.View())
+	s.WriteString("\n\n")
+
+	switch {
+	case p.loading && len(p.filtered) == 0:
+		s.WriteString(m.styles.Dim.Render("  loading models..."))
+		s.WriteString("\n")
+	case len(p.filtered) == 0:
+		s.WriteString(m.styles.Dim.Render("  no matching models; enter uses the name typed"))
+		s.WriteString("\n")
+	}
+	start := max(0, min(p.cursor-modelRows/2, len(p.filtered)-modelRows))
+	for i := start; i < min(start+modelRows, len(p.filtered)); i++ {
+		c := p.filtered[i]
+		label := c.model
+		if c.alias != "" {
+			label = c.alias + "  " + m.styles.Dim.Render(c.model)
+		}
+		if i == p.cursor {
+			s.WriteString(m.styles.Title.Render("> ") + label)
+		} else {
+			s.WriteString("  " + label)
+		}
+		s.WriteString("\n")
+	}
+	if p.err != nil {
+		s.WriteString("\n")
+		s.WriteString(wrapText(m.styles.Error.Render(p.err.Error()), m.termWidth-2))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(m.renderKeyHint("[↑↓]", "navigate") + "  " +
+		m.renderKeyHint("[enter]", "use") + "  " +
+		m.renderKeyHint("[esc]", "back"))
+	return s.String()
+}
@@ -0,0 +1,52 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/ai"
+)
+
+// lintMessage checks the current message against the commit conventions
+func (m *Model) lintMessage() {
+	m.msgWarnings = nil
+	if m.currentIndex < len(m.commits) {
+		m.msgWarnings = ai.LintMessage(m.commits[m.currentIndex], m.subjectRules())
+	}
+}
+
+// fixMessage applies the fixes of the fixable warnings to the current message
+func (m *Model) fixMessage() (tea.Model, tea.Cmd) {
+	if !slices.ContainsFunc(m.msgWarnings, func(w ai.MessageWarning) bool { return w.Fixable }) {
+		return m, nil
+	}
+	m.commits[m.currentIndex] = ai.FixMessage(m.commits[m.currentIndex], m.subjectRules())
+	m.lintMessage()
+	m.notice = "Message fixed"
+	if len(m.msgWarnings) > 0 {
+		m.notice = "Fixed what could be fixed automatically"
+	}
+	return m, nil
+}
+
+// viewMessageWarnings renders the convention warnings of the current message
+func (m *Model) viewMessageWarnings(s *strings.Builder) {
+	if len(m.msgWarnings) == 0 {
+		return
+	}
+	s.WriteString(m.styles.Dim.Render("Message conventions:"))
+	s.WriteString("\n")
+	fixable := false
+	for _, w := range m.msgWarnings {
+		s.WriteString(m.styles.Dim.Render("  • " + w.Text))
+		s.WriteString("\n")
+		fixable = fixable || w.Fixable
+	}
+	if fixable {
+		s.WriteString(m.renderKeyHint("[ctrl+f]", "fix"))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+}
@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startNote opens the input for a private note shown on the next run
+func (m *Model) startNote() (tea.Model, tea.Cmd) {
+	return m, m.pushInput("Note for the next time you run commity here (stored locally):",
+		"e.g. revisit the TODO in parser.go", "", saveNote)
+}
+
+// saveNote stores a note for the next session
+func saveNote(m *Model, text string) (tea.Model, tea.Cmd) {
+	if text = strings.TrimSpace(text); text == "" {
+		return m, nil
+	}
+	m.repoState.AddNote(text, time.Now())
+	if err := m.repoState.Save(); err != nil {
+		return m.setError(fmt.Errorf("failed to save note: %w", err))
+	}
+	m.notice = "Note saved; it will be shown next time"
+	return m, nil
+}
+
+// clearNotes forgets the notes once they've been dealt with
+func (m *Model) clearNotes() (tea.Model, tea.Cmd) {
+	m.repoState.ClearNotes()
+	if err := m.repoState.Save(); err != nil {
+		return m.setError(fmt.Errorf("failed to clear notes: %w", err))
+	}
+	m.notice = "Notes cleared"
+	return m, nil
+}
+
+// viewNotes lists notes left in earlier sessions
+func (m *Model) viewNotes(s *strings.Builder) {
+	if len(m.repoState.Notes) == 0 {
+		return
+	}
+	s.WriteString(m.styles.Dim.Render("Notes:"))
+	s.WriteString("\n")
+	for _, n := range m.repoState.Notes {
+		s.WriteString(fmt.Sprintf("  %s %s\n", m.styles.Dim.Render(n.Created.Format("Jan 2")), n.Text))
+	}
+	s.WriteString("\n")
+}
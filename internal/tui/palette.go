@@ -0,0 +1,337 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/security"
+)
+
+// Palette actions
+const (
+	paletteSettings   = "settings"
+	paletteRegenerate = "regenerate"
+	paletteEdit       = "edit"
+	paletteCopy       = "copy"
+	palettePush       = "push"
+	paletteUndo       = "undo"
+	palettePreview    = "preview"
+	paletteRefresh    = "refresh"
+	paletteMerge      = "merge"
+	paletteNote       = "note"
+	paletteClearNotes = "clear-notes"
+	paletteHistory    = "history"
+	paletteDiff       = "diff"
+	paletteStaging    = "staging"
+	paletteModel      = "model"
+)
+
+// paletteItem is an action listed in the command palette
+type paletteItem struct {
+	id    string
+	title string
+	key   string // direct shortcut, if any
+}
+
+// PaletteModel is a ctrl+k overlay that fuzzy-searches available actions.
+type PaletteModel struct {
+	input    textinput.Model
+	items    []paletteItem
+	filtered []paletteItem
+	cursor   int
+	theme    *Theme
+	chosen   string
+	closed   bool
+}
+
+func NewPaletteModel(theme *Theme, items []paletteItem) *PaletteModel {
+	ti := textinput.New()
+	ti.Placeholder = "type a command..."
+	ti.CharLimit = 50
+	ti.Width = 30
+	ti.Focus()
+
+	return &PaletteModel{
+		input:    ti,
+		items:    items,
+		filtered: items,
+		theme:    theme,
+	}
+}
+
+func (m *PaletteModel) Update(msg tea.Msg) (*PaletteModel, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch keyMsg.String() {
+		case "esc", "ctrl+k":
+			m.closed = true
+			return m, nil
+		case "up", "ctrl+p":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+			return m, nil
+		case "down", "ctrl+n":
+			if m.cursor < len(m.filtered)-1 {
+				m.cursor++
+			}
+			return m, nil
+		case "enter":
+			if len(m.filtered) > 0 {
+				m.chosen = m.filtered[m.cursor].id
+			}
+			m.closed = true
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+
+	m.filtered = nil
+	for _, item := range m.items {
+		if fuzzyMatch(m.input.Value(), item.title) {
+			m.filtered = append(m.filtered, item)
+		}
+	}
+	m.cursor = min(m.cursor, max(len(m.filtered)-1, 0))
+	return m, cmd
+}
+
+func (m *PaletteModel) View() string {
+	var s strings.Builder
+
+	selectedStyle := lipgloss.NewStyle().Foreground(m.theme.Primary).Bold(true)
+	normalStyle := lipgloss.NewStyle().Foreground(m.theme.Secondary)
+	dimStyle := lipgloss.NewStyle().Foreground(m.theme.Dim)
+
+	s.WriteString(m.input.View())
+	s.WriteString("\n\n")
+
+	if len(m.filtered) == 0 {
+		s.WriteString(dimStyle.Render("  no matching commands"))
+	}
+	for i, item := range m.filtered {
+		cursor := "  "
+		style := normalStyle
+		if m.cursor == i {
+			cursor = "> "
+			style = selectedStyle
+		}
+		line := cursor + style.Render(item.title)
+		if item.key != "" {
+			line += " " + dimStyle.Render(fmt.Sprintf("[%s]", item.key))
+		}
+		s.WriteString(line + "\n")
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1)
+	return box.Render(strings.TrimRight(s.String(), "\n"))
+}
+
+// Closed reports whether the palette was dismissed or an action chosen
+func (m *PaletteModel) Closed() bool {
+	return m.closed
+}
+
+// Chosen returns the selected action, or "" if dismissed
+func (m *PaletteModel) Chosen() string {
+	return m.chosen
+}
+
+// fuzzyMatch reports whether all characters of query appear in s in order
+func fuzzyMatch(query, s string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(query) {
+		i := strings.IndexRune(s, r)
+		if i == -1 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+// paletteScreen shows the palette and runs the chosen action once it closes
+type paletteScreen struct {
+	palette *PaletteModel
+}
+
+func (p *paletteScreen) typing() bool {
+	return true
+}
+
+func (p *paletteScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	p.palette, cmd = p.palette.Update(msg)
+	if !p.palette.Closed() {
+		return m, cmd
+	}
+	m.screens.pop()
+	return m.runPaletteAction(p.palette.Chosen())
+}
+
+func (p *paletteScreen) view(m *Model) string {
+	return p.palette.View() + "\n\n" +
+		m.renderKeyHint("[↑↓]", "navigate") + "  " +
+		m.renderKeyHint("[enter]", "run") + "  " +
+		m.renderKeyHint("[esc]", "close")
+}
+
+// paletteItems lists the actions available in the current state
+func (m *Model) paletteItems() []paletteItem {
+	var items []paletteItem
+	switch m.state {
+	case stateFileSelect:
+		items = append(items, paletteItem{paletteSettings, "Open settings", "s"})
+		if len(m.selected) > 0 {
+			items = append(items, paletteItem{palettePreview, "Preview prompt", ""})
+		}
+		items = append(items,
+			paletteItem{paletteStaging, "Stage selection, unstage the rest", "A"},
+			paletteItem{palettePush, "Push current branch", ""},
+			paletteItem{paletteUndo, "Undo last commit (keep changes)", ""},
+			paletteItem{paletteNote, "Attach a note for next time", "N"},
+			paletteItem{paletteModel, "Switch model for this session", "m"},
+		)
+		if len(m.repoState.Notes) > 0 {
+			items = append(items, paletteItem{paletteClearNotes, "Clear notes", ""})
+		}
+	case stateConfirm:
+		items = append(items,
+			paletteItem{paletteRegenerate, "Regenerate message", ""},
+			paletteItem{paletteEdit, "Edit message", "e"},
+			paletteItem{paletteDiff, "View diff", "d"},
+			paletteItem{paletteHistory, "Browse message history", "h"},
+			paletteItem{paletteCopy, "Copy message to clipboard", ""},
+			paletteItem{palettePreview, "Preview prompt", ""},
+			paletteItem{paletteRefresh, "Refresh index status", "ctrl+r"},
+			paletteItem{paletteModel, "Switch model for this session", "m"},
+		)
+		items = append(items, paletteItem{paletteNote, "Attach a note for next time", ""})
+		if m.isSplit {
+			items = append(items, paletteItem{paletteMerge, "Merge remaining commits into one", "M"})
+		}
+		items = append(items,
+			paletteItem{palettePush, "Push current branch", ""},
+		)
+	}
+	return items
+}
+
+// runPaletteAction performs the action chosen in the command palette
+func (m *Model) runPaletteAction(action string) (tea.Model, tea.Cmd) {
+	switch action {
+	case paletteSettings:
+		m.previousState = m.state
+		m.state = stateSettings
+		m.initSettingsForm()
+		return m, m.form.Init()
+	case paletteRegenerate:
+		m.feedback = ""
+		m.state = stateGenerating
+		return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+	case paletteEdit:
+		return m, m.startEdit()
+	case paletteCopy:
+		if err := clipboard.WriteAll(m.commits[m.currentIndex].String()); err != nil {
+			return m.setError(fmt.Errorf("copy to clipboard failed: %w", err))
+		}
+		m.notice = "Copied commit message"
+		return m, nil
+	case palettePush:
+		m.notice = "Pushing..."
+		return m, func() tea.Msg { return pushMsg{err: m.repo.Push()} }
+	case paletteUndo:
+		return m.guard("Undo the last commit?", func() (tea.Model, tea.Cmd) {
+			return m, func() tea.Msg { return undoMsg{err: m.repo.UndoLastCommit()} }
+		})
+	case palettePreview:
+		return m.openPreview()
+	case paletteDiff:
+		return m.openDiff()
+	case paletteStaging:
+		return m.applyStaging()
+	case paletteModel:
+		return m.openModelPicker()
+	case paletteHistory:
+		return m.openHistory()
+	case paletteNote:
+		return m.startNote()
+	case paletteClearNotes:
+		return m.clearNotes()
+	case paletteMerge:
+		m.mergeRemaining()
+		m.initConfirmForm()
+		return m, m.confirmForm.Init()
+	case paletteRefresh:
+		m.refreshIndexStatus()
+		m.notice = "Index status refreshed"
+		return m, nil
+	}
+	return m, nil
+}
+
+// openPreview shows the exact prompt that would be sent for the selection
+func (m *Model) openPreview() (tea.Model, tea.Cmd) {
+	diff, err := m.promptDiff()
+	if err != nil {
+		return m.setError(err)
+	}
+
+	var previousMsg string
+	if m.state == stateConfirm {
+		previousMsg = m.commits[m.currentIndex].String()
+	}
+	// Show what would be sent, assuming redaction while undecided
+	diff, pending := m.protectDiff(diff)
+	if len(pending) > 0 {
+		diff, _ = security.Redact(diff)
+	}
+
+	pc := m.promptContext(diff, previousMsg, m.feedback)
+	pc.MaxDiffTokens = m.cfg.AI.MaxDiffTokens
+	pc.Minimize = m.cfg.AI.MinimizeDiff
+	pc.ContextLines = m.cfg.AI.ContextLines
+	var templates *ai.PromptTemplates
+	if m.aiClient != nil {
+		pc.Model = m.aiClient.Model()
+		templates = m.aiClient.Templates()
+	}
+
+	system, user, err := templates.Prompts(pc)
+	if err != nil {
+		return m.setError(err)
+	}
+	content := system + "\n\n" + user
+	vp := viewport.New(m.termWidth-editAreaPadding, previewHeight)
+	vp.SetContent(wrapText(content, m.termWidth-editAreaPadding))
+	m.screens.push(&previewScreen{viewport: vp})
+	return m, nil
+}
+
+// previewScreen scrolls through the prompt
+type previewScreen struct {
+	viewport viewport.Model
+}
+
+func (p *previewScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	p.viewport, cmd = p.viewport.Update(msg)
+	return m, cmd
+}
+
+func (p *previewScreen) view(m *Model) string {
+	return m.styles.Dim.Render("Prompt preview") + "\n\n" +
+		p.viewport.View() + "\n\n" +
+		m.renderKeyHint("[↑↓]", "scroll") + "  " + m.renderKeyHint("[esc]", "back")
+}
@@ -0,0 +1,106 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"github.com/hluaguo/commity/internal/ai"
+)
+
+// Choices on the split plan screen
+const (
+	planCommitAll  = "commit-all"
+	planReview     = "review"
+	planMerge      = "merge"
+	planRegenerate = "regenerate"
+	planCancel     = "cancel"
+)
+
+// initPlanForm asks what to do with a split plan before any commit is made
+func (m *Model) initPlanForm() {
+	m.planChoice = planCommitAll
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Apply this plan?").
+				Options(
+					huh.NewOption(fmt.Sprintf("Commit all %d", len(m.commits)), planCommitAll),
+					huh.NewOption("Review one by one", planReview),
+					huh.NewOption("Merge into one commit", planMerge),
+					huh.NewOption("Regenerate", planRegenerate),
+					huh.NewOption("Cancel", planCancel),
+				).
+				Value(&m.planChoice),
+		),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+}
+
+// completePlanForm acts on the plan decision
+func (m *Model) completePlanForm() (tea.Model, tea.Cmd) {
+	switch m.planChoice {
+	case planCommitAll:
+		m.commitAll = true
+		return m.beginCommit()
+	case planRegenerate:
+		m.feedback = ""
+		m.state = stateGenerating
+		return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+	case planCancel:
+		return m, tea.Quit
+	case planMerge:
+		m.mergeRemaining()
+	}
+
+	m.state = stateConfirm
+	m.initConfirmForm()
+	return m, m.confirmForm.Init()
+}
+
+// viewPlan lists every proposed commit with its files
+func (m *Model) viewPlan(s *strings.Builder) {
+	s.WriteString(fmt.Sprintf("Proposed plan: %d commits\n\n", len(m.commits)))
+	for i, c := range m.commits {
+		subject := c.String()
+		if idx := strings.Index(subject, "\n"); idx != -1 {
+			subject = subject[:idx]
+		}
+		s.WriteString(fmt.Sprintf("%d. %s  %s\n", i+1, subject, m.renderDiffStat(m.commitStats[i])))
+		for _, f := range c.Files {
+			line := "   " + f
+			if hunks := c.HunksFor(f); len(hunks) > 0 {
+				line += fmt.Sprintf(" (hunks %s)", joinInts(hunks))
+			}
+			s.WriteString(m.styles.Dim.Render(line))
+			s.WriteString("\n")
+		}
+	}
+	s.WriteString("\n")
+	s.WriteString(m.styles.Dim.Render(m.renderPlanEstimate()))
+	s.WriteString("\n\n")
+	s.WriteString(m.form.View())
+	s.WriteString("\n")
+	s.WriteString(m.renderKeyHint("[↑↓]", "navigate") + "  " +
+		m.renderKeyHint("[enter]", "select") + "  " +
+		m.renderKeyHint("[m]", "move files"))
+}
+
+func joinInts(ns []int) string {
+	parts := make([]string, len(ns))
+	for i, n := range ns {
+		parts[i] = fmt.Sprint(n)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mergeRemaining collapses the commits not yet created into a single one
+func (m *Model) mergeRemaining() {
+	// Enforcing again picks the emoji and scope of the merged type and files
+	merged := ai.EnforceSubject(ai.MergeCommits(m.commits[m.currentIndex:]), m.subjectRules())
+	m.commits = append(m.commits[:m.currentIndex], merged)
+	m.completed = m.completed[:len(m.commits)]
+	m.isSplit = len(m.commits) > 1
+	m.computeCommitStats()
+}
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// presetSaveNew is the preset form choice for saving the current selection
+const presetSaveNew = "\x00save"
+
+// openPresets lists the saved selections of this repo over file select
+func (m *Model) openPresets() tea.Cmd {
+	m.presetChoice = ""
+	m.presetName = ""
+
+	options := []huh.Option[string]{
+		huh.NewOption(fmt.Sprintf("+ Save current selection (%d files)", len(m.selected)), presetSaveNew),
+	}
+	for _, name := range m.repoState.PresetNames() {
+		files, _ := m.repoState.Preset(name)
+		options = append(options, huh.NewOption(fmt.Sprintf("%s (%d files)", name, len(files)), name))
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Selection presets").
+				Options(options...).
+				Value(&m.presetChoice),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Preset name").
+				Description("e.g., backend, docs-only").
+				Value(&m.presetName).
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return fmt.Errorf("name is required")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool {
+			return m.presetChoice != presetSaveNew
+		}),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+
+	return m.pushForm(&formScreen{
+		form: form,
+		render: func(m *Model, form string) string {
+			return form + "\n" +
+				m.renderKeyHint("[↑↓]", "navigate") + "  " +
+				m.renderKeyHint("[enter]", "select") + "  " +
+				m.renderKeyHint("[esc]", "back")
+		},
+		done: completePresets,
+	})
+}
+
+// completePresets saves or applies the chosen preset
+func completePresets(m *Model) (tea.Model, tea.Cmd) {
+	if m.presetChoice == presetSaveNew {
+		m.repoState.SavePreset(strings.TrimSpace(m.presetName), m.selected)
+		if err := m.repoState.Save(); err != nil {
+			return m.setError(fmt.Errorf("failed to save preset: %w", err))
+		}
+		return m, nil
+	}
+
+	if err := m.ApplyPreset(m.presetChoice); err != nil {
+		return m.setError(err)
+	}
+	return m, m.form.Init()
+}
+
+// ApplyPreset pre-checks the files of a saved selection in the file selector.
+// Files from the preset without current changes are ignored.
+func (m *Model) ApplyPreset(name string) error {
+	if m.state != stateFileSelect {
+		return fmt.Errorf("selection presets are only available in file selection")
+	}
+
+	files, ok := m.repoState.Preset(name)
+	if !ok {
+		return fmt.Errorf("unknown selection preset %q", name)
+	}
+
+	changed := make(map[string]bool)
+	for _, f := range m.files {
+		changed[f.Path] = true
+	}
+
+	selected := []string{}
+	for _, f := range files {
+		if changed[f] {
+			selected = append(selected, f)
+		}
+	}
+
+	m.initFileSelectFormWith(selected)
+	return nil
+}
+
+// rememberSelection stores the submitted selection for the next launch on
+// this branch. Like presets it is a convenience, so errors are ignored.
+func (m *Model) rememberSelection() {
+	if m.amend != nil {
+		return
+	}
+	m.repoState.RememberSelection(m.repo.Branch(), m.selected)
+	_ = m.repoState.Save()
+}
+
+// launchSelection returns the staged files plus those selected last time
+// on this branch that still have changes, or nil for just the staged files
+func (m *Model) launchSelection() []string {
+	last := m.repoState.LastSelection[m.repo.Branch()]
+	if len(last) == 0 {
+		return nil
+	}
+	var selected []string
+	for _, f := range m.files {
+		if f.Staged || slices.Contains(last, f.Path) {
+			selected = append(selected, f.Path)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// startProgress times a generation and returns the context that esc
+// cancels
+func (m *Model) startProgress() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.genStarted = time.Now()
+	m.genCancel = cancel
+	return ctx
+}
+
+// finishProgress reports whether a generation's result is still wanted,
+// which it isn't once esc cancelled it, and releases its context
+func (m *Model) finishProgress() bool {
+	if m.genCancel != nil {
+		m.genCancel()
+		m.genCancel = nil
+	}
+	return m.state == stateGenerating
+}
+
+// cancelGeneration stops the request and returns to the message shown
+// before, or to file selection when there is none
+func (m *Model) cancelGeneration() (tea.Model, tea.Cmd) {
+	m.finishProgress()
+	m.notice = "Generation cancelled"
+	if m.currentIndex < len(m.commits) {
+		m.state = stateConfirm
+		m.initConfirmForm()
+		return m, m.confirmForm.Init()
+	}
+	m.state = stateFileSelect
+	m.initFileSelectFormWith(m.selected)
+	return m, m.form.Init()
+}
+
+// viewGenerating shows which model is working and for how long
+func (m *Model) viewGenerating(s *strings.Builder) {
+	model := "offline heuristic"
+	if m.aiClient != nil {
+		model = m.aiClient.Model()
+		if m.compareClient != nil {
+			model += " and " + m.compareClient.Model()
+		}
+	}
+	s.WriteString(m.spinner.View())
+	s.WriteString(" Generating commit message with " + model + "...")
+	s.WriteString(m.styles.Dim.Render(fmt.Sprintf(" %ds", int(time.Since(m.genStarted).Seconds()))))
+	s.WriteString("\n\n")
+	s.WriteString(m.renderKeyHint("[esc]", "cancel"))
+}
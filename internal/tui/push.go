@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"github.com/hluaguo/commity/internal/config"
+)
+
+// sessionPushMsg is sent once the branch is pushed after the last commit
+type sessionPushMsg struct {
+	err error
+}
+
+// pushMode returns whether to push after the session's commits: never for
+// amended or rolled back sessions, a branch already pushed to a fork, or
+// a repository without a remote
+func (m *Model) pushMode() string {
+	if len(m.created) == 0 || m.amend != nil || m.forkPush || m.rolledBack > 0 {
+		return config.PushNever
+	}
+	if m.repo.PushRemote() == "" {
+		return config.PushNever
+	}
+	return m.cfg.Commit.Push
+}
+
+// offerPush asks whether to push the branch, or pushes it right away when
+// configured to
+func (m *Model) offerPush() (tea.Model, tea.Cmd) {
+	if m.cfg.Commit.Push == config.PushAlways {
+		return m, m.pushSession()
+	}
+	m.pushChoice = true
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Push " + m.repo.Branch() + " to " + m.repo.PushRemote() + "?").
+				Affirmative("Push").
+				Negative("Not now").
+				Value(&m.pushChoice),
+		),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+	return m, m.pushForm(&formScreen{
+		form: form,
+		render: func(m *Model, form string) string {
+			var s strings.Builder
+			m.viewDone(&s)
+			s.WriteString("\n")
+			s.WriteString(form)
+			s.WriteString("\n")
+			s.WriteString(m.renderKeyHint("[←→]", "choose") + "  " +
+				m.renderKeyHint("[enter]", "confirm"))
+			return s.String()
+		},
+		done:  completePushOffer,
+		back:  func(m *Model) (tea.Model, tea.Cmd) { return m.endSession() },
+		yesNo: true,
+	})
+}
+
+// completePushOffer pushes or ends the session
+func completePushOffer(m *Model) (tea.Model, tea.Cmd) {
+	if !m.pushChoice {
+		return m.endSession()
+	}
+	return m, m.pushSession()
+}
+
+// pushSession pushes the current branch, setting its upstream if needed
+func (m *Model) pushSession() tea.Cmd {
+	m.pushing = true
+	m.pushTarget = m.repo.PushRemote()
+	m.state = stateCommitting
+	m.count("feature.push")
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		return sessionPushMsg{err: m.repo.Push()}
+	})
+}
+
+// afterSessionPush ends the session with the push result on the done screen
+func (m *Model) afterSessionPush(msg sessionPushMsg) (tea.Model, tea.Cmd) {
+	m.pushing = false
+	m.pushed = msg.err == nil
+	m.pushErr = msg.err
+	return m.endSession()
+}
+
+// viewPush renders the push result on the done screen
+func (m *Model) viewPush(s *strings.Builder) {
+	switch {
+	case m.pushErr != nil:
+		s.WriteString("\n")
+		s.WriteString(wrapText(m.styles.Error.Render(m.pushErr.Error()), m.termWidth-2))
+		s.WriteString("\n")
+	case m.pushed:
+		s.WriteString("\n")
+		s.WriteString(m.styles.Success.Render("Pushed " + m.repo.Branch() + " to " + m.pushTarget))
+		s.WriteString("\n")
+	}
+}
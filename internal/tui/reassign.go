@@ -0,0 +1,240 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/hluaguo/commity/internal/ai"
+)
+
+// unassignedCommit marks rows of files that are in no commit yet
+const unassignedCommit = -1
+
+// reassignRow is a file within a proposed commit
+type reassignRow struct {
+	commit int
+	file   string
+}
+
+// correctCoverage fixes what it can of a split that doesn't cover the
+// selection and lets the user assign the leftover files
+func (m *Model) correctCoverage(cov ai.Coverage) (tea.Model, tea.Cmd) {
+	m.commits, m.unassigned = ai.FixCoverage(m.commits, cov)
+
+	var notes []string
+	if len(cov.Missing) > 0 {
+		notes = append(notes, fmt.Sprintf("%d files were left out of every commit", len(cov.Missing)))
+	}
+	if len(cov.Duplicates) > 0 {
+		notes = append(notes, fmt.Sprintf("%s listed in several commits, kept in the first", strings.Join(cov.Duplicates, ", ")))
+	}
+	if len(cov.Unknown) > 0 {
+		notes = append(notes, fmt.Sprintf("dropped unselected %s", strings.Join(cov.Unknown, ", ")))
+	}
+	m.coverageNote = strings.Join(notes, "; ")
+
+	if len(m.commits) == 0 {
+		// Nothing usable survived; start with one commit for all leftovers
+		msg := ai.HeuristicMessage(m.unassigned, m.cfg.CommitRules().Conventional)
+		m.commits = []ai.CommitMessage{ai.AddTrailers(ai.EnforceSubject(msg, m.subjectRules()), m.trailers())}
+		m.unassigned = nil
+	}
+
+	return m.openReassign()
+}
+
+// reassignScreen moves files between the commits of a split plan
+type reassignScreen struct{}
+
+// openReassign shows the plan file by file
+func (m *Model) openReassign() (tea.Model, tea.Cmd) {
+	m.reassignCursor = 0
+	m.screens.push(&reassignScreen{})
+	return m, nil
+}
+
+func (r *reassignScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok {
+		return m.updateReassign(key)
+	}
+	return m, nil
+}
+
+func (r *reassignScreen) view(m *Model) string {
+	var s strings.Builder
+	m.viewReassign(&s)
+	return s.String()
+}
+
+// dismiss is done, as long as every file is in a commit
+func (r *reassignScreen) dismiss(m *Model) (tea.Model, tea.Cmd) {
+	return m.finishReassign()
+}
+
+// reassignRows flattens the plan into one row per file, unassigned first
+func (m *Model) reassignRows() []reassignRow {
+	var rows []reassignRow
+	for _, f := range m.unassigned {
+		rows = append(rows, reassignRow{commit: unassignedCommit, file: f})
+	}
+	for i, c := range m.commits {
+		for _, f := range c.Files {
+			rows = append(rows, reassignRow{commit: i, file: f})
+		}
+	}
+	return rows
+}
+
+// updateReassign moves the file under the cursor between commits
+func (m *Model) updateReassign(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := m.reassignRows()
+	if len(rows) == 0 {
+		return m, nil
+	}
+	m.reassignCursor = min(m.reassignCursor, len(rows)-1)
+	row := rows[m.reassignCursor]
+
+	switch msg.String() {
+	case "up", "k":
+		m.reassignCursor = max(m.reassignCursor-1, 0)
+	case "down", "j":
+		m.reassignCursor = min(m.reassignCursor+1, len(rows)-1)
+	case "left", "h":
+		if row.commit == unassignedCommit {
+			m.moveFile(row, len(m.commits)-1)
+		} else if row.commit > 0 {
+			m.moveFile(row, row.commit-1)
+		}
+	case "right", "l":
+		if row.commit < len(m.commits)-1 {
+			m.moveFile(row, row.commit+1)
+		}
+	case "n":
+		// Split the file into a commit of its own
+		if row.commit == unassignedCommit || len(m.commits[row.commit].Files) > 1 {
+			// Named after the file until edited on the confirm screen
+			msg := ai.HeuristicMessage([]string{row.file}, m.cfg.CommitRules().Conventional)
+			m.commits = append(m.commits, ai.AddTrailers(ai.EnforceSubject(msg, m.subjectRules()), m.trailers()))
+			m.moveFile(row, len(m.commits)-1)
+		}
+	case "enter":
+		return m.finishReassign()
+	}
+	return m, nil
+}
+
+// finishReassign goes on with the corrected plan once every file is in a
+// commit
+func (m *Model) finishReassign() (tea.Model, tea.Cmd) {
+	if len(m.unassigned) > 0 {
+		return m, nil
+	}
+	m.screens.pop()
+	m.coverageNote = ""
+	m.isSplit = len(m.commits) > 1
+	m.completed = make([]bool, len(m.commits))
+	m.computeCommitStats()
+	m.plan.Commits = len(m.commits)
+	if !m.isSplit {
+		m.state = stateConfirm
+		m.initConfirmForm()
+		return m, m.confirmForm.Init()
+	}
+	m.state = statePlan
+	m.initPlanForm()
+	return m, m.form.Init()
+}
+
+// moveFile moves a file (whole, dropping any hunk split) to another commit,
+// removing commits left without files
+func (m *Model) moveFile(row reassignRow, to int) {
+	if row.commit == unassignedCommit {
+		m.unassigned = slices.DeleteFunc(m.unassigned, func(f string) bool { return f == row.file })
+		if !slices.Contains(m.commits[to].Files, row.file) {
+			m.commits[to].Files = append(m.commits[to].Files, row.file)
+		}
+		m.focusFile(row.file)
+		return
+	}
+
+	src := &m.commits[row.commit]
+	src.Files = slices.DeleteFunc(src.Files, func(f string) bool { return f == row.file })
+	src.Hunks = slices.DeleteFunc(src.Hunks, func(h ai.FileHunks) bool { return h.File == row.file })
+
+	dst := &m.commits[to]
+	dst.Hunks = slices.DeleteFunc(dst.Hunks, func(h ai.FileHunks) bool { return h.File == row.file })
+	if !slices.Contains(dst.Files, row.file) {
+		dst.Files = append(dst.Files, row.file)
+	}
+
+	if len(src.Files) == 0 {
+		m.commits = slices.Delete(m.commits, row.commit, row.commit+1)
+	}
+
+	m.focusFile(row.file)
+}
+
+// focusFile keeps the cursor on a file after it moved
+func (m *Model) focusFile(file string) {
+	for i, r := range m.reassignRows() {
+		if r.file == file {
+			m.reassignCursor = i
+			break
+		}
+	}
+}
+
+// viewReassign renders the plan with the file under the cursor highlighted
+func (m *Model) viewReassign(s *strings.Builder) {
+	s.WriteString(m.styles.Dim.Render("Move files between commits"))
+	s.WriteString("\n\n")
+	if m.coverageNote != "" {
+		s.WriteString(wrapText(m.styles.Error.Render("The split plan didn't cover the selection: "+m.coverageNote), m.termWidth-2))
+		s.WriteString("\n\n")
+	}
+
+	selectedStyle := lipgloss.NewStyle().Foreground(m.theme.Primary).Bold(true)
+	rows := m.reassignRows()
+	renderRow := func(j int, r reassignRow) {
+		if j == m.reassignCursor {
+			s.WriteString(selectedStyle.Render("  > " + r.file))
+		} else {
+			s.WriteString(m.styles.Dim.Render("    " + r.file))
+		}
+		s.WriteString("\n")
+	}
+
+	if len(m.unassigned) > 0 {
+		s.WriteString("Not in any commit\n")
+		for j, r := range rows {
+			if r.commit == unassignedCommit {
+				renderRow(j, r)
+			}
+		}
+	}
+	for i, c := range m.commits {
+		subject := c.String()
+		if idx := strings.Index(subject, "\n"); idx != -1 {
+			subject = subject[:idx]
+		}
+		s.WriteString(fmt.Sprintf("%d. %s\n", i+1, subject))
+		for j, r := range rows {
+			if r.commit == i {
+				renderRow(j, r)
+			}
+		}
+	}
+	s.WriteString("\n")
+	s.WriteString(m.renderKeyHint("[↑↓]", "file") + "  " +
+		m.renderKeyHint("[←→]", "move to commit") + "  " +
+		m.renderKeyHint("[n]", "new commit") + "  " +
+		m.renderKeyHint("[enter]", "done"))
+	if len(m.unassigned) > 0 {
+		s.WriteString("\n")
+		s.WriteString(m.styles.Dim.Render("Assign every file to a commit to continue"))
+	}
+}
@@ -0,0 +1,33 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// failed is setError for a step the error screen can retry once whatever
+// broke it is fixed, in settings or outside commity
+func (m *Model) failed(err error, retry func() (tea.Model, tea.Cmd)) (tea.Model, tea.Cmd) {
+	model, cmd := m.setError(err)
+	m.retry = retry
+	return model, cmd
+}
+
+// retryFailed repeats the step that led to the error screen
+func (m *Model) retryFailed() (tea.Model, tea.Cmd) {
+	retry := m.retry
+	m.err = nil
+	m.retry = nil
+	return retry()
+}
+
+// retryGenerate generates the messages for the selection again
+func (m *Model) retryGenerate() (tea.Model, tea.Cmd) {
+	m.state = stateGenerating
+	return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+}
+
+// retryCommit stages and commits the current message again
+func (m *Model) retryCommit() (tea.Model, tea.Cmd) {
+	m.state = stateCommitting
+	return m, tea.Batch(m.spinner.Tick, m.doCommit())
+}
@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+)
+
+const (
+	rewordUseNew   = "new"
+	rewordKeep     = "keep"
+	rewordEdit     = "edit"
+	rewordAbortAll = "abort"
+)
+
+// Reword pairs an existing commit message with its proposed replacement
+type Reword struct {
+	Hash   string
+	Before string
+	After  string
+}
+
+// ReviewRewords shows each commit's old and new message and lets the user
+// take, keep or edit the new one. Kept commits get After set to Before.
+// It returns false if the user aborted the whole reword.
+func ReviewRewords(theme string, rewords []Reword, pushed bool) (bool, error) {
+	t := GetTheme(theme)
+	for i := range rewords {
+		rw := &rewords[i]
+		choice := rewordUseNew
+
+		description := fmt.Sprintf("Before:\n%s\n\nAfter:\n%s", indent(rw.Before), indent(rw.After))
+		if pushed && i == 0 {
+			description += "\n\nThese commits are already pushed; rewording them rewrites shared history."
+		}
+		form := huh.NewForm(
+			huh.NewGroup(
+				huh.NewNote().
+					Title(fmt.Sprintf("Commit %d/%d  %s", i+1, len(rewords), rw.Hash[:7])).
+					Description(description),
+				huh.NewSelect[string]().
+					Options(
+						huh.NewOption("Use the new message", rewordUseNew),
+						huh.NewOption("Keep the original", rewordKeep),
+						huh.NewOption("Edit the new message", rewordEdit),
+						huh.NewOption("Abort without rewording", rewordAbortAll),
+					).
+					Value(&choice),
+			),
+		).WithTheme(t.GetHuhTheme()).WithShowHelp(false)
+		if err := form.Run(); err != nil {
+			if errors.Is(err, huh.ErrUserAborted) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		switch choice {
+		case rewordKeep:
+			rw.After = rw.Before
+		case rewordEdit:
+			edit := huh.NewForm(
+				huh.NewGroup(
+					huh.NewText().
+						Title("Commit message").
+						Value(&rw.After),
+				),
+			).WithTheme(t.GetHuhTheme()).WithShowHelp(false)
+			if err := edit.Run(); err != nil {
+				if errors.Is(err, huh.ErrUserAborted) {
+					return false, nil
+				}
+				return false, err
+			}
+			rw.After = strings.TrimSpace(rw.After)
+			if rw.After == "" {
+				rw.After = rw.Before
+			}
+		case rewordAbortAll:
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
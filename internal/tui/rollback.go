@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// cancel quits, first offering to undo the commits already created when a
+// split sequence is abandoned part-way
+func (m *Model) cancel() (tea.Model, tea.Cmd) {
+	if !m.isSplit || len(m.created) == 0 {
+		return m, tea.Quit
+	}
+
+	m.state = stateRollback
+	m.rollbackChoice = true
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(fmt.Sprintf("%d of %d commits were already created", len(m.created), len(m.commits))).
+				Description("Undo them so the split is all-or-nothing? Their changes stay staged.").
+				Affirmative("Undo").
+				Negative("Keep").
+				Value(&m.rollbackChoice),
+		),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+	return m, m.form.Init()
+}
+
+// completeRollbackForm resets to before the first created commit if asked to
+func (m *Model) completeRollbackForm() (tea.Model, tea.Cmd) {
+	if !m.rollbackChoice {
+		m.state = stateDone
+		return m, tea.Quit
+	}
+
+	// Only roll back if nothing else was committed in the meantime
+	head, err := m.repo.HeadHash()
+	if err != nil {
+		return m.setError(err)
+	}
+	if last := m.created[len(m.created)-1]; head != last.Hash {
+		return m.setError(fmt.Errorf("HEAD moved since %s was created; not rolling back", last.ShortHash))
+	}
+	if err := m.repo.ResetSoft(m.created[0].Hash + "~1"); err != nil {
+		return m.setError(err)
+	}
+
+	m.refreshIndexCounts()
+	m.recordLastCommit("")
+	m.rolledBack = len(m.created)
+	m.created = nil
+	m.state = stateDone
+	return m, tea.Quit
+}
@@ -0,0 +1,196 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+)
+
+// screen is a view pushed over the current state with a model of its own,
+// such as the command palette or the prompt preview. New overlays should be
+// screens rather than states: the state underneath keeps its forms, so
+// leaving a screen returns to exactly where the user was.
+type screen interface {
+	update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd)
+	view(m *Model) string
+}
+
+// dismisser is a screen that does more than close when it is left, such as
+// going back to file selection. dismiss pops the screen itself.
+type dismisser interface {
+	dismiss(m *Model) (tea.Model, tea.Cmd)
+}
+
+// typist is a screen that may take text; while typing, q reaches it instead
+// of leaving it
+type typist interface {
+	typing() bool
+}
+
+// router is the stack of screens over the state machine. The top screen gets
+// all keys but the ones that leave it: esc, and q unless the screen takes
+// text. Other messages reach both the top screen and the state machine, so
+// background work keeps running while a screen is open; only the forms of
+// the state underneath wait.
+type router struct {
+	stack []screen
+}
+
+func (r *router) push(s screen) {
+	r.stack = append(r.stack, s)
+}
+
+func (r *router) pop() {
+	if len(r.stack) > 0 {
+		r.stack = r.stack[:len(r.stack)-1]
+	}
+}
+
+// top returns the screen shown, or nil when the state machine is
+func (r *router) top() screen {
+	if len(r.stack) == 0 {
+		return nil
+	}
+	return r.stack[len(r.stack)-1]
+}
+
+// reset closes all screens, e.g. when an error takes over
+func (r *router) reset() {
+	r.stack = nil
+}
+
+// route sends msg to the top screen and, unless it is a key, to the state
+// machine as well
+func (m *Model) route(msg tea.Msg) (tea.Model, tea.Cmd) {
+	top := m.screens.top()
+	if top == nil {
+		return m.updateState(msg)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok {
+		switch resolve(key, m.keys.Quit) {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return m.leave(top)
+		case "q":
+			if t, ok := top.(typist); !ok || !t.typing() {
+				return m.leave(top)
+			}
+		}
+		return top.update(m, msg)
+	}
+	_, screenCmd := top.update(m, msg)
+	model, cmd := m.updateState(msg)
+	return model, tea.Batch(screenCmd, cmd)
+}
+
+// leave closes the top screen, letting a dismisser decide where to go
+func (m *Model) leave(top screen) (tea.Model, tea.Cmd) {
+	if d, ok := top.(dismisser); ok {
+		return d.dismiss(m)
+	}
+	m.screens.pop()
+	return m, nil
+}
+
+// formScreen shows a huh form over the current state. The form's values are
+// bound to the model; done runs once it is completed and back when it is
+// left, or it just closes when back is nil.
+type formScreen struct {
+	form    *huh.Form                          // nil while loading
+	loading string                             // shown next to the spinner until the form is set
+	render  func(m *Model, form string) string // the form with what goes around it
+	done    func(m *Model) (tea.Model, tea.Cmd)
+	back    func(m *Model) (tea.Model, tea.Cmd)
+	help    [][]key.Binding // listed by ?, nothing when empty
+	yesNo   bool            // the form only asks yes or no, so q leaves it
+}
+
+// pushForm opens s and starts its form
+func (m *Model) pushForm(s *formScreen) tea.Cmd {
+	m.screens.push(s)
+	return s.form.Init()
+}
+
+func (s *formScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if s.form == nil {
+		var cmd tea.Cmd
+		if tick, ok := msg.(spinner.TickMsg); ok {
+			m.spinner, cmd = m.spinner.Update(tick)
+		}
+		return m, cmd
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "?" && len(s.help) > 0 {
+		return m.showHelp(s.help)
+	}
+	form, cmd := s.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		s.form = f
+	}
+	if s.form.State != huh.StateCompleted {
+		return m, cmd
+	}
+	m.screens.pop()
+	return s.done(m)
+}
+
+func (s *formScreen) view(m *Model) string {
+	if s.form == nil {
+		return m.spinner.View() + " " + s.loading
+	}
+	return s.render(m, s.form.View())
+}
+
+func (s *formScreen) dismiss(m *Model) (tea.Model, tea.Cmd) {
+	m.screens.pop()
+	if s.back == nil {
+		return m, nil
+	}
+	return s.back(m)
+}
+
+// Selects filter and inputs take names, so q is typed into them
+func (s *formScreen) typing() bool {
+	return !s.yesNo
+}
+
+// inputScreen asks for a line of text and hands it to save on enter
+type inputScreen struct {
+	title string
+	input textinput.Model
+	save  func(m *Model, text string) (tea.Model, tea.Cmd)
+}
+
+// pushInput opens an inputScreen prefilled with value
+func (m *Model) pushInput(title, placeholder, value string, save func(m *Model, text string) (tea.Model, tea.Cmd)) tea.Cmd {
+	ti := textinput.New()
+	ti.Placeholder = placeholder
+	ti.CharLimit = 200
+	ti.Width = m.termWidth - editAreaPadding
+	ti.SetValue(value)
+	ti.Focus()
+	m.screens.push(&inputScreen{title: title, input: ti, save: save})
+	return textinput.Blink
+}
+
+func (s *inputScreen) typing() bool {
+	return true
+}
+
+func (s *inputScreen) update(m *Model, msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == "enter" {
+		m.screens.pop()
+		return s.save(m, s.input.Value())
+	}
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	return m, cmd
+}
+
+func (s *inputScreen) view(m *Model) string {
+	return m.styles.Dim.Render(s.title) + "\n\n" +
+		s.input.View() + "\n\n" +
+		m.renderKeyHint("[enter]", "save") + "  " + m.renderKeyHint("[esc]", "cancel")
+}
@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/security"
+)
+
+// Choices on the secrets warning screen
+const (
+	secretsRedact = "redact"
+	secretsSend   = "send"
+	secretsCancel = "cancel"
+)
+
+// protectDiff applies the secrets policy to a diff bound for the AI. Findings
+// are returned, with the diff unchanged, when the user has to decide first.
+func (m *Model) protectDiff(diff string) (string, []security.Finding) {
+	if m.cfg.AI.Secrets == config.SecretsOff || m.cfg.StaysLocal(m.remoteHost, m.compareClient != nil) {
+		return diff, nil
+	}
+
+	redacted, findings := security.Redact(diff)
+	if len(findings) == 0 || m.secretsDecision == secretsSend {
+		return diff, nil
+	}
+	if m.cfg.AI.Secrets == config.SecretsConfirm && m.secretsDecision == "" {
+		return diff, findings
+	}
+	return redacted, nil
+}
+
+// openSecrets asks how to handle secrets found in the diff
+func (m *Model) openSecrets() tea.Cmd {
+	m.secretsChoice = secretsRedact
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Possible secrets found in the diff").
+				Options(
+					huh.NewOption("Redact and send", secretsRedact),
+					huh.NewOption("Send unredacted", secretsSend),
+					huh.NewOption("Cancel", secretsCancel),
+				).
+				Value(&m.secretsChoice),
+		),
+	).WithTheme(m.theme.GetHuhTheme()).WithShowHelp(false)
+
+	return m.pushForm(&formScreen{
+		form: form,
+		render: func(m *Model, form string) string {
+			return m.renderSecretFindings() + "\n" + form + "\n" +
+				m.renderKeyHint("[↑↓]", "navigate") + "  " +
+				m.renderKeyHint("[enter]", "select") + "  " +
+				m.renderKeyHint("[esc]", "cancel")
+		},
+		done: completeSecrets,
+		back: cancelSecrets,
+	})
+}
+
+// completeSecrets generates with the user's decision or returns to file select
+func completeSecrets(m *Model) (tea.Model, tea.Cmd) {
+	if m.secretsChoice == secretsCancel {
+		return cancelSecrets(m)
+	}
+
+	m.secretsDecision = m.secretsChoice
+	m.state = stateGenerating
+	return m, tea.Batch(m.spinner.Tick, m.generateCommitMessage())
+}
+
+// cancelSecrets returns to file select without sending the diff
+func cancelSecrets(m *Model) (tea.Model, tea.Cmd) {
+	m.state = stateFileSelect
+	m.initFileSelectFormWith(m.selected)
+	return m, m.form.Init()
+}
+
+// renderSecretFindings lists what was detected, one line per file and kind
+func (m *Model) renderSecretFindings() string {
+	var s strings.Builder
+	seen := make(map[security.Finding]bool)
+	for _, f := range m.secretFindings {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		s.WriteString(m.styles.Error.Render(fmt.Sprintf("  %s in %s", f.Kind, f.File)))
+		s.WriteString("\n")
+	}
+	return s.String()
+}
@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/hluaguo/commity/internal/git"
+)
+
+// signingFailed shows what went wrong signing the commit and how to fix it.
+// Files are already staged, so a retry only runs git commit again.
+func (m *Model) signingFailed(err *git.SigningError) (tea.Model, tea.Cmd) {
+	m.count("error.signing")
+	m.signErr = err
+	m.state = stateSigning
+	return m, nil
+}
+
+// retrySigned commits the current message again once signing is fixed
+func (m *Model) retrySigned() (tea.Model, tea.Cmd) {
+	m.signErr = nil
+	return m.recommit()
+}
+
+// recommit commits the current message again with the files it already staged
+func (m *Model) recommit() (tea.Model, tea.Cmd) {
+	m.state = stateCommitting
+	commit := m.commits[m.currentIndex]
+	files := commit.Files
+	if len(files) == 0 {
+		files = m.selected
+	}
+	noVerify := m.skipHooks
+	return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+		return m.commitStaged(commit, files, noVerify)
+	})
+}
+
+// signingHints returns fixes for the usual signing failures
+func signingHints(ssh bool) []string {
+	var hints []string
+	if ssh {
+		hints = append(hints,
+			"Point user.signingkey at your key: git config user.signingkey ~/.ssh/id_ed25519.pub",
+			"Make sure ssh-agent holds the key: ssh-add -l",
+		)
+	} else {
+		hints = append(hints,
+			"Let pinentry ask for your passphrase: export GPG_TTY=$(tty)",
+			"Check the key exists: git config user.signingkey, gpg --list-secret-keys",
+			"Unlock the key once in another terminal: echo test | gpg --clearsign",
+		)
+	}
+	return append(hints, "To commit unsigned, set sign = false under [commit] and run git config commit.gpgsign false")
+}
+
+// viewSigning renders the signing failure with guidance
+func (m *Model) viewSigning(s *strings.Builder) {
+	s.WriteString(wrapText(m.styles.Error.Render("Error: "+m.signErr.Error()), m.termWidth-2))
+	s.WriteString("\n\n")
+	for _, h := range signingHints(m.signErr.SSH) {
+		s.WriteString(wrapText("• "+h, m.termWidth-2))
+		s.WriteString("\n")
+	}
+	if m.signErr.Output != "" {
+		s.WriteString("\n")
+		s.WriteString(m.styles.Dim.Render(wrapText(m.signErr.Output, m.termWidth-2)))
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+	s.WriteString(m.renderKeyHint("[r]", "retry") + "  " +
+		m.renderKeyHint("[b]", "back") + "  " +
+		m.renderKeyHint("[q]", "quit"))
+}
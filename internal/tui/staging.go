@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// applyStaging makes the index match the selection: selected files are
+// staged and other staged files unstaged, so git status agrees with commity.
+// Files staged hunk by hunk are left as they are.
+func (m *Model) applyStaging() (tea.Model, tea.Cmd) {
+	var stage, unstage []string
+	for _, f := range m.files {
+		selected := slices.Contains(m.selected, f.Path)
+		switch {
+		case m.partial[f.Path]:
+		case selected:
+			stage = append(stage, f.Path)
+		case f.Staged:
+			unstage = append(unstage, f.Path)
+		}
+	}
+
+	if len(stage) > 0 {
+		if err := m.repo.Add(stage); err != nil {
+			return m.setError(err)
+		}
+	}
+	if len(unstage) > 0 {
+		if err := m.repo.Unstage(unstage); err != nil {
+			return m.setError(err)
+		}
+	}
+
+	files, err := m.repo.Status()
+	if err != nil {
+		return m.setError(err)
+	}
+	m.files = files
+	m.refreshIndexCounts()
+	m.initFileSelectFormWith(m.selected)
+	m.notice = fmt.Sprintf("Index updated: %d staged, %d unstaged", len(stage), len(unstage))
+	m.count("feature.staging")
+	return m, m.form.Init()
+}
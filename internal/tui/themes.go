@@ -5,54 +5,83 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Theme defines the color scheme for the TUI.
+// Theme defines the color scheme for the TUI. Colors adapt to the terminal
+// background, so dark themes stay readable on a light one.
 type Theme struct {
 	Name      string
-	Primary   lipgloss.Color
-	Secondary lipgloss.Color
-	Success   lipgloss.Color
-	Error     lipgloss.Color
-	Dim       lipgloss.Color
-	Border    lipgloss.Color
+	Primary   lipgloss.AdaptiveColor
+	Secondary lipgloss.AdaptiveColor
+	Success   lipgloss.AdaptiveColor
+	Error     lipgloss.AdaptiveColor
+	Dim       lipgloss.AdaptiveColor
+	Border    lipgloss.AdaptiveColor
 	HuhTheme  *huh.Theme
 }
 
+// adaptive pairs a color for light backgrounds with one for dark backgrounds
+func adaptive(light, dark string) lipgloss.AdaptiveColor {
+	return lipgloss.AdaptiveColor{Light: light, Dark: dark}
+}
+
+// fixed is the same color on any background, for themes made for one
+func fixed(c string) lipgloss.AdaptiveColor {
+	return adaptive(c, c)
+}
+
 var themes = map[string]*Theme{
 	"tokyonight": {
 		Name:      "tokyonight",
-		Primary:   lipgloss.Color("#7aa2f7"),
-		Secondary: lipgloss.Color("#bb9af7"),
-		Success:   lipgloss.Color("#9ece6a"),
-		Error:     lipgloss.Color("#f7768e"),
-		Dim:       lipgloss.Color("#565f89"),
-		Border:    lipgloss.Color("#3b4261"),
+		Primary:   adaptive("#2e7de9", "#7aa2f7"),
+		Secondary: adaptive("#9854f1", "#bb9af7"),
+		Success:   adaptive("#587539", "#9ece6a"),
+		Error:     adaptive("#f52a65", "#f7768e"),
+		Dim:       adaptive("#6172b0", "#565f89"),
+		Border:    adaptive("#a8aecb", "#3b4261"),
 	},
 	"dracula": {
 		Name:      "dracula",
-		Primary:   lipgloss.Color("#bd93f9"),
-		Secondary: lipgloss.Color("#ff79c6"),
-		Success:   lipgloss.Color("#50fa7b"),
-		Error:     lipgloss.Color("#ff5555"),
-		Dim:       lipgloss.Color("#6272a4"),
-		Border:    lipgloss.Color("#44475a"),
+		Primary:   adaptive("#644ac9", "#bd93f9"),
+		Secondary: adaptive("#a3144d", "#ff79c6"),
+		Success:   adaptive("#14710a", "#50fa7b"),
+		Error:     adaptive("#cb3a2a", "#ff5555"),
+		Dim:       adaptive("#635d97", "#6272a4"),
+		Border:    adaptive("#cfcfde", "#44475a"),
 	},
 	"catppuccin": {
 		Name:      "catppuccin",
-		Primary:   lipgloss.Color("#cba6f7"),
-		Secondary: lipgloss.Color("#f5c2e7"),
-		Success:   lipgloss.Color("#a6e3a1"),
-		Error:     lipgloss.Color("#f38ba8"),
-		Dim:       lipgloss.Color("#6c7086"),
-		Border:    lipgloss.Color("#45475a"),
+		Primary:   adaptive("#8839ef", "#cba6f7"),
+		Secondary: adaptive("#ea76cb", "#f5c2e7"),
+		Success:   adaptive("#40a02b", "#a6e3a1"),
+		Error:     adaptive("#d20f39", "#f38ba8"),
+		Dim:       adaptive("#6c6f85", "#6c7086"),
+		Border:    adaptive("#bcc0cc", "#45475a"),
 	},
 	"nord": {
 		Name:      "nord",
-		Primary:   lipgloss.Color("#88c0d0"),
-		Secondary: lipgloss.Color("#81a1c1"),
-		Success:   lipgloss.Color("#a3be8c"),
-		Error:     lipgloss.Color("#bf616a"),
-		Dim:       lipgloss.Color("#4c566a"),
-		Border:    lipgloss.Color("#3b4252"),
+		Primary:   adaptive("#5e81ac", "#88c0d0"),
+		Secondary: adaptive("#b48ead", "#81a1c1"),
+		Success:   adaptive("#6e8a57", "#a3be8c"),
+		Error:     adaptive("#bf616a", "#bf616a"),
+		Dim:       adaptive("#7b88a1", "#4c566a"),
+		Border:    adaptive("#d8dee9", "#3b4252"),
+	},
+	"solarized-light": {
+		Name:      "solarized-light",
+		Primary:   fixed("#268bd2"),
+		Secondary: fixed("#6c71c4"),
+		Success:   fixed("#859900"),
+		Error:     fixed("#dc322f"),
+		Dim:       fixed("#93a1a1"),
+		Border:    fixed("#93a1a1"),
+	},
+	"github-light": {
+		Name:      "github-light",
+		Primary:   fixed("#0969da"),
+		Secondary: fixed("#8250df"),
+		Success:   fixed("#1a7f37"),
+		Error:     fixed("#cf222e"),
+		Dim:       fixed("#6e7781"),
+		Border:    fixed("#d0d7de"),
 	},
 }
 
@@ -64,7 +93,7 @@ func GetTheme(name string) *Theme {
 }
 
 func GetThemeNames() []string {
-	return []string{"tokyonight", "dracula", "catppuccin", "nord"}
+	return []string{"tokyonight", "dracula", "catppuccin", "nord", "solarized-light", "github-light"}
 }
 
 func (t *Theme) GetHuhTheme() *huh.Theme {
@@ -0,0 +1,43 @@
+package tui
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// warmUpMsg reports that the model finished loading
+type warmUpMsg struct {
+	err error
+}
+
+// canWarmUp reports whether to load the model while files are selected.
+// Remote models are always loaded, and a tiny request would still cost money.
+func (m *Model) canWarmUp() bool {
+	if !m.cfg.AI.WarmUp || m.aiClient == nil {
+		return false
+	}
+	effective, err := m.cfg.EffectiveAI(m.remoteHost)
+	return err == nil && effective.IsLocal()
+}
+
+// warmUp sends the model a tiny request in the background
+func (m *Model) warmUp() tea.Cmd {
+	m.warming = true
+	client := m.aiClient
+	return func() tea.Msg {
+		return warmUpMsg{err: client.WarmUp(context.Background())}
+	}
+}
+
+// afterWarmUp records the outcome; a failure only matters once generating
+func (m *Model) afterWarmUp(msg warmUpMsg) (tea.Model, tea.Cmd) {
+	m.warming = false
+	if msg.err != nil {
+		m.count("warmup.error")
+		if m.state == stateFileSelect {
+			m.notice = "Model warm-up failed: " + msg.err.Error()
+		}
+	}
+	return m, nil
+}
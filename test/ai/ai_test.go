@@ -1,11 +1,25 @@
 package ai_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	openai "github.com/sashabaranov/go-openai"
 
 	"github.com/hluaguo/commity/internal/ai"
+	"github.com/hluaguo/commity/internal/auth"
+	"github.com/hluaguo/commity/internal/config"
 )
 
 func TestCommitMessageString(t *testing.T) {
@@ -47,6 +61,44 @@ func TestCommitMessageString(t *testing.T) {
 			},
 			expected: "Update dependencies",
 		},
+		{
+			name: "breaking with description",
+			msg: ai.CommitMessage{
+				Type:                "feat",
+				Subject:             "rename config section to [ai]",
+				Body:                "Groups all model settings.",
+				Breaking:            true,
+				BreakingDescription: "the [openai] section is no longer read; rename it to [ai].",
+			},
+			expected: "feat!: rename config section to [ai]\n\nGroups all model settings.\n\nBREAKING CHANGE: the [openai] section is no longer read; rename it to [ai].",
+		},
+		{
+			name: "breaking without description",
+			msg: ai.CommitMessage{
+				Type:     "refactor",
+				Subject:  "drop Go 1.21 support",
+				Breaking: true,
+			},
+			expected: "refactor!: drop Go 1.21 support",
+		},
+		{
+			name: "trailers after the breaking change footer",
+			msg: ai.CommitMessage{
+				Type:                "feat",
+				Subject:             "drop v1 API",
+				BreakingDescription: "v1 endpoints are gone.",
+				Trailers:            []string{"Refs: PROJ-1", "Signed-off-by: Ada <ada@example.com>"},
+			},
+			expected: "feat!: drop v1 API\n\nBREAKING CHANGE: v1 endpoints are gone.\nRefs: PROJ-1\nSigned-off-by: Ada <ada@example.com>",
+		},
+		{
+			name: "breaking without type",
+			msg: ai.CommitMessage{
+				Subject:  "Remove the --legacy flag",
+				Breaking: true,
+			},
+			expected: "Remove the --legacy flag\n\nBREAKING CHANGE: Remove the --legacy flag",
+		},
 	}
 
 	for _, tt := range tests {
@@ -171,7 +223,7 @@ func TestBuildPromptNonConventional(t *testing.T) {
 
 func TestBuildPromptDiffTruncation(t *testing.T) {
 	files := []string{"large.go"}
-	// Create a large diff with proper structure (700 lines to exceed MaxDiffLines threshold)
+	// Create a large diff with proper structure (700 lines to exceed DefaultDiffTokens)
 	var largeDiff strings.Builder
 	largeDiff.WriteString("diff --git a/large.go b/large.go\n")
 	largeDiff.WriteString("--- a/large.go\n")
@@ -213,7 +265,7 @@ func TestBuildPromptDiffTruncation(t *testing.T) {
 
 func TestBuildPromptNoTruncationUnderThreshold(t *testing.T) {
 	files := []string{"small.go"}
-	// Create a diff under MaxDiffLines (600) - should not be truncated
+	// Create a diff under DefaultDiffTokens - should not be truncated
 	var diff strings.Builder
 	diff.WriteString("diff --git a/small.go b/small.go\n")
 	diff.WriteString("--- a/small.go\n")
@@ -336,3 +388,1606 @@ func TestGenerateResultStructure(t *testing.T) {
 		t.Errorf("expected 2 commits, got %d", len(splitResult.Commits))
 	}
 }
+
+func TestOllamaURL(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "")
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		expected string
+	}{
+		{"default", "", ai.OllamaDefaultURL},
+		{"explicit", "http://gpu-box:11434", "http://gpu-box:11434"},
+		{"trailing slash", "http://gpu-box:11434/", "http://gpu-box:11434"},
+		{"missing scheme", "gpu-box:11434", "http://gpu-box:11434"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ai.OllamaURL(tt.baseURL); got != tt.expected {
+				t.Errorf("OllamaURL(%q) = %q, want %q", tt.baseURL, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOllamaURLFromEnv(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "127.0.0.1:11500")
+
+	if got := ai.OllamaURL(""); got != "http://127.0.0.1:11500" {
+		t.Errorf("OllamaURL should use OLLAMA_HOST, got %q", got)
+	}
+}
+
+func TestNewOllamaWithoutAPIKey(t *testing.T) {
+	cfg := &config.AIConfig{Provider: config.ProviderOllama, Model: "llama3.2"}
+
+	if _, err := ai.New(cfg); err != nil {
+		t.Errorf("ollama provider should not require an API key: %v", err)
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	cfg := &config.AIConfig{Provider: "bogus", APIKey: "key"}
+
+	if _, err := ai.New(cfg); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestGeminiFunctionDeclarations(t *testing.T) {
+	tools := []openai.Tool{
+		{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        "submit_commit",
+				Description: "Submit a single commit",
+				Parameters:  map[string]any{"type": "object"},
+			},
+		},
+		{Type: openai.ToolTypeFunction}, // no function definition, skipped
+	}
+
+	decls := ai.GeminiFunctionDeclarations(tools)
+
+	if len(decls) != 1 {
+		t.Fatalf("expected 1 declaration, got %d", len(decls))
+	}
+	if decls[0].Name != "submit_commit" {
+		t.Errorf("expected name 'submit_commit', got %q", decls[0].Name)
+	}
+	if decls[0].Description != "Submit a single commit" {
+		t.Errorf("expected description to be kept, got %q", decls[0].Description)
+	}
+	if decls[0].Parameters == nil {
+		t.Error("expected parameters schema to be kept")
+	}
+}
+
+func TestNewGeminiRequiresAPIKey(t *testing.T) {
+	cfg := &config.AIConfig{Provider: config.ProviderGemini}
+
+	if _, err := ai.New(cfg); err == nil {
+		t.Error("expected error when gemini API key is missing")
+	}
+
+	cfg.APIKey = "gemini-key"
+	if _, err := ai.New(cfg); err != nil {
+		t.Errorf("unexpected error with API key: %v", err)
+	}
+}
+
+func TestNewAzureRequiresEndpoint(t *testing.T) {
+	cfg := &config.AIConfig{
+		Provider:   config.ProviderAzure,
+		APIKey:     "azure-key",
+		Deployment: "gpt-4o-prod",
+	}
+
+	if _, err := ai.New(cfg); err == nil {
+		t.Error("expected error when Azure endpoint is missing")
+	}
+
+	cfg.BaseURL = "https://myresource.openai.azure.com"
+	if _, err := ai.New(cfg); err != nil {
+		t.Errorf("unexpected error with endpoint configured: %v", err)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{strings.Repeat("x", 4000), 1000},
+	}
+
+	for _, tt := range tests {
+		if got := ai.EstimateTokens(tt.text); got != tt.expected {
+			t.Errorf("EstimateTokens(%d chars) = %d, want %d", len(tt.text), got, tt.expected)
+		}
+	}
+}
+
+func TestEstimateTokensFor(t *testing.T) {
+	text := strings.Repeat("x", 7000)
+	if got := ai.EstimateTokensFor("gpt-4o", text); got != 1750 {
+		t.Errorf("gpt-4o: got %d tokens, want 1750", got)
+	}
+	if got := ai.EstimateTokensFor("anthropic/claude-sonnet-4", text); got != 2000 {
+		t.Errorf("claude: got %d tokens, want 2000", got)
+	}
+	if got := ai.EstimateTokensFor("mistral-large:latest", text); got <= ai.EstimateTokensFor("gpt-4o", text) {
+		t.Errorf("mistral's smaller vocabulary should count more tokens, got %d", got)
+	}
+	if got := ai.EstimateTokensFor("some-new-model", text); got != ai.EstimateTokens(text) {
+		t.Errorf("unknown family should use the default ratio, got %d", got)
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	u := ai.Usage{PromptTokens: 2_000_000, CompletionTokens: 500_000}
+
+	// $0.15 per 1M input, $0.60 per 1M output
+	got := u.Cost(0.15, 0.60)
+	if got < 0.5999 || got > 0.6001 {
+		t.Errorf("Cost() = %f, want 0.60", got)
+	}
+}
+
+func TestEstimatePlan(t *testing.T) {
+	result := &ai.GenerateResult{
+		Commits: []ai.CommitMessage{
+			{Type: "feat", Subject: "add feature"},
+			{Type: "fix", Subject: "fix bug"},
+		},
+		IsSplit: true,
+		Usage:   ai.Usage{PromptTokens: 3000, CompletionTokens: 200},
+	}
+
+	est := ai.EstimatePlan(result)
+
+	if est.Commits != 2 {
+		t.Errorf("expected 2 commits, got %d", est.Commits)
+	}
+	if est.Regenerate.PromptTokens != 3000 {
+		t.Errorf("regeneration should cost about the original request, got %d prompt tokens", est.Regenerate.PromptTokens)
+	}
+}
+
+func TestClassifyChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		expected ai.ChangeKind
+	}{
+		{"docs only", []string{"README.md", "docs/guide.rst", "LICENSE"}, ai.ChangeDocs},
+		{"config only", []string{".golangci.yml", "config/app.toml", ".gitignore"}, ai.ChangeConfig},
+		{"code", []string{"main.go"}, ai.ChangeCode},
+		{"docs and code", []string{"README.md", "main.go"}, ai.ChangeCode},
+		{"docs and config", []string{"README.md", "app.yaml"}, ai.ChangeCode},
+		{"empty", nil, ai.ChangeCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ai.ClassifyChanges(tt.files); got != tt.expected {
+				t.Errorf("ClassifyChanges(%v) = %v, want %v", tt.files, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildPromptDocsOnly(t *testing.T) {
+	prompt := ai.BuildPrompt([]string{"README.md"}, "some diff", true, []string{"docs", "feat"}, "", "", "")
+
+	if !strings.Contains(prompt, "only touch documentation") {
+		t.Error("docs-only prompt should mention documentation")
+	}
+	if strings.Contains(prompt, "split_commits") {
+		t.Error("docs-only prompt should not ask about splitting")
+	}
+}
+
+func TestSystemPromptFor(t *testing.T) {
+	if ai.SystemPromptFor(ai.ChangeCode) != ai.SystemPrompt() {
+		t.Error("code changes should use the full system prompt")
+	}
+
+	focused := ai.SystemPromptFor(ai.ChangeDocs)
+	if len(focused) >= len(ai.SystemPrompt()) {
+		t.Error("focused system prompt should be shorter than the full prompt")
+	}
+	if strings.Contains(focused, "split_commits") {
+		t.Error("focused system prompt should not mention split_commits")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"empty", "", 0, false},
+		{"seconds", "7", 7 * time.Second, true},
+		{"http date", "Wed, 01 Jan 2025 12:00:30 GMT", 30 * time.Second, true},
+		{"date in past", "Wed, 01 Jan 2025 11:00:00 GMT", 0, true},
+		{"garbage", "soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ai.ParseRetryAfter(tt.value, now)
+			if ok != tt.ok || got != tt.expected {
+				t.Errorf("ParseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base := time.Second
+
+	if got := ai.Backoff(base, 0); got != time.Second {
+		t.Errorf("Backoff(0) = %v, want 1s", got)
+	}
+	if got := ai.Backoff(base, 2); got != 4*time.Second {
+		t.Errorf("Backoff(2) = %v, want 4s", got)
+	}
+	if got := ai.Backoff(base, 20); got != time.Minute {
+		t.Errorf("Backoff should be capped at 1m, got %v", got)
+	}
+	if got := ai.Backoff(0, 3); got != 0 {
+		t.Errorf("zero base should disable backoff, got %v", got)
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected int
+		ok       bool
+	}{
+		{"gpt-4o-mini", 128000, true},
+		{"gpt-4", 8192, true},
+		{"gpt-4-turbo-preview", 128000, true},
+		{"openai/gpt-4o", 128000, true},
+		{"llama3.1:8b", 131072, true},
+		{"llama3:8b", 8192, true},
+		{"my-custom-model", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, ok := ai.ContextWindow(tt.model)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("ContextWindow(%q) = %d, %v; want %d, %v", tt.model, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDiffTokenBudget(t *testing.T) {
+	if got := ai.DiffTokenBudget("", 0); got != ai.DefaultDiffTokens {
+		t.Errorf("unknown model should use default budget, got %d", got)
+	}
+	if got := ai.DiffTokenBudget("gpt-4o", 0); got <= 100000 {
+		t.Errorf("128k model should get a large budget, got %d", got)
+	}
+	if got := ai.DiffTokenBudget("gpt-4o", 20000); got != 20000 {
+		t.Errorf("limit should cap the budget, got %d", got)
+	}
+}
+
+func TestBuildPromptNoTruncationForLargeContextModel(t *testing.T) {
+	var diff strings.Builder
+	diff.WriteString("diff --git a/large.go b/large.go\n")
+	diff.WriteString("@@ -1,2000 +1,2000 @@\n")
+	for i := 0; i < 2000; i++ {
+		diff.WriteString(fmt.Sprintf("+line %d content here\n", i))
+	}
+
+	prompt := ai.BuildPromptFrom(ai.PromptContext{
+		Files: []string{"large.go"},
+		Diff:  diff.String(),
+		Model: "gpt-4o",
+	})
+
+	if strings.Contains(prompt, "lines skipped") {
+		t.Error("diff within a 128k context window should not be truncated")
+	}
+
+	small := ai.BuildPromptFrom(ai.PromptContext{
+		Files: []string{"large.go"},
+		Diff:  diff.String(),
+		Model: "gpt-4",
+	})
+	if !strings.Contains(small, "lines skipped") {
+		t.Error("diff exceeding an 8k context window should be truncated")
+	}
+}
+
+func TestBuildPromptExcludesLockfiles(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n" +
+		"@@ -1 +1 @@\n" +
+		"-old\n" +
+		"+new\n" +
+		"diff --git a/go.sum b/go.sum\n" +
+		"--- a/go.sum\n" +
+		"+++ b/go.sum\n" +
+		"@@ -1,2 +1,3 @@\n" +
+		"-example.com/a v1 h1:old\n" +
+		"+example.com/a v2 h1:new\n" +
+		"+example.com/b v1 h1:new\n"
+
+	prompt := ai.BuildPromptFrom(ai.PromptContext{
+		Files:   []string{"main.go", "go.sum"},
+		Diff:    diff,
+		Exclude: []string{"go.sum", "*.lock"},
+	})
+
+	if strings.Contains(prompt, "example.com/a") {
+		t.Error("excluded file content should not be in the prompt")
+	}
+	if !strings.Contains(prompt, "+new") {
+		t.Error("non-excluded file content should be in the prompt")
+	}
+	if !strings.Contains(prompt, "[content excluded from prompt: +2 -1 lines]") {
+		t.Errorf("excluded file should be summarized, got:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "- go.sum") {
+		t.Error("excluded file should still be listed")
+	}
+}
+
+func TestHeuristicMessage(t *testing.T) {
+	tests := []struct {
+		files   []string
+		want    string
+		wantTyp string
+	}{
+		{[]string{"internal/ai/openai.go"}, "update openai.go", "chore"},
+		{[]string{"internal/ai/a.go", "internal/ai/b.go"}, "update 2 files in internal/ai", "chore"},
+		{[]string{"internal/ai/a.go", "internal/git/b.go"}, "update 2 files in internal", "chore"},
+		{[]string{"main.go", "internal/b.go"}, "update 2 files", "chore"},
+		{[]string{"README.md", "docs/guide.md"}, "update 2 files", "docs"},
+		{[]string{"test/ai/ai_test.go"}, "update ai_test.go", "test"},
+	}
+
+	for _, tt := range tests {
+		msg := ai.HeuristicMessage(tt.files, true)
+		if msg.Subject != tt.want {
+			t.Errorf("HeuristicMessage(%v) subject = %q, want %q", tt.files, msg.Subject, tt.want)
+		}
+		if msg.Type != tt.wantTyp {
+			t.Errorf("HeuristicMessage(%v) type = %q, want %q", tt.files, msg.Type, tt.wantTyp)
+		}
+	}
+
+	if msg := ai.HeuristicMessage([]string{"main.go"}, false); msg.Type != "" {
+		t.Errorf("non-conventional message should have no type, got %q", msg.Type)
+	}
+}
+
+func TestHeuristicFromDiff(t *testing.T) {
+	diff := "diff --git a/internal/ai/cache.go b/internal/ai/cache.go\n" +
+		"new file mode 100644\n" +
+		"--- /dev/null\n" +
+		"+++ b/internal/ai/cache.go\n" +
+		"@@ -0,0 +1,3 @@\n" +
+		"+package ai\n" +
+		"+\n" +
+		"+func cached() {}\n" +
+		"diff --git a/README.md b/README.md\n" +
+		"new file mode 100644\n" +
+		"--- /dev/null\n" +
+		"+++ b/README.md\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+# cache\n"
+	files := []string{"internal/ai/cache.go", "README.md"}
+
+	msg := ai.HeuristicFromDiff(files, diff, true)
+	if msg.Type != "feat" || msg.Subject != "add cache.go and 1 other file" {
+		t.Errorf("got %q", msg.String())
+	}
+	if msg.Body != "2 files changed, 4 insertions(+), 0 deletions(-)" {
+		t.Errorf("unexpected body %q", msg.Body)
+	}
+
+	// Without a diff only the file names are used
+	if msg := ai.HeuristicFromDiff(files, "", true); msg.Subject != "update 2 files" {
+		t.Errorf("got %q", msg.Subject)
+	}
+
+	result := ai.Offline(ai.PromptContext{Files: files, Diff: diff, Trailers: []string{"Refs: #1"}}, ai.ErrNoAPIKey)
+	if result.Fallback != ai.FallbackOffline || !errors.Is(result.Err, ai.ErrNoAPIKey) {
+		t.Errorf("offline result should carry the reason, got %+v", result)
+	}
+	if got := result.Commits[0].Trailers; len(got) != 1 {
+		t.Errorf("trailers should still be added, got %v", got)
+	}
+}
+
+func TestWithhold(t *testing.T) {
+	diff := "diff --git a/secrets/prod.yaml b/secrets/prod.yaml\n" +
+		"@@ -1 +1 @@\n" +
+		"-password: old\n" +
+		"+password: new\n" +
+		"diff --git a/app.go b/app.go\n" +
+		"@@ -1 +1 @@\n" +
+		"+func main() {}\n"
+
+	got := ai.Withhold(diff, []string{"secrets/**", "*.pem"})
+
+	if strings.Contains(got, "password") {
+		t.Errorf("withheld file content leaked: %s", got)
+	}
+	if !strings.Contains(got, "diff --git a/secrets/prod.yaml b/secrets/prod.yaml") {
+		t.Error("withheld file should still be named")
+	}
+	if !strings.Contains(got, "[content withheld by privacy policy: +1 -1 lines]") {
+		t.Errorf("withheld file should be summarized, got:\n%s", got)
+	}
+	if !strings.Contains(got, "func main()") {
+		t.Error("other files should be unchanged")
+	}
+
+	if ai.Withhold(diff, nil) != diff {
+		t.Error("no patterns should leave the diff unchanged")
+	}
+}
+
+func TestValidateResult(t *testing.T) {
+	files := []string{"a.go", "b.go"}
+
+	valid := &ai.GenerateResult{
+		IsSplit: true,
+		Commits: []ai.CommitMessage{
+			{Type: "feat", Subject: "add a", Files: []string{"a.go"}},
+			{Type: "fix", Subject: "fix b", Files: []string{"b.go"}},
+		},
+	}
+	if problems := ai.ValidateResult(valid, files); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+
+	invalid := &ai.GenerateResult{
+		IsSplit: true,
+		Commits: []ai.CommitMessage{
+			{Subject: "add a", Files: []string{"a.go", "c.go"}},
+			{Type: "fix", Files: nil},
+		},
+	}
+	problems := ai.ValidateResult(invalid, files)
+	want := []string{
+		`commit 1 is missing the required "type" field`,
+		`commit 1 lists "c.go", which is not one of the changed files`,
+		`commit 2 is missing the required "subject" field`,
+		`commit 2 has no files`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("ValidateResult() = %v, want %v", problems, want)
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, problems[i], want[i])
+		}
+	}
+
+	if problems := ai.ValidateResult(&ai.GenerateResult{}, files); len(problems) != 1 {
+		t.Errorf("empty result should be invalid, got %v", problems)
+	}
+}
+
+func TestUsageAdd(t *testing.T) {
+	got := ai.Usage{PromptTokens: 100, CompletionTokens: 10}.Add(ai.Usage{PromptTokens: 50, CompletionTokens: 5, Estimated: true})
+	if got.PromptTokens != 150 || got.CompletionTokens != 15 || !got.Estimated {
+		t.Errorf("Add() = %+v", got)
+	}
+}
+
+// The fixtures are synthetic: chat completion replies written by hand in the
+// shapes models were seen to misuse tool calls in, not captured responses.
+func TestParseToolCallsReplyShapes(t *testing.T) {
+	files := []string{"internal/ai/retry.go", "README.md"}
+
+	tests := []struct {
+		fixture  string
+		isSplit  bool
+		subjects []string
+	}{
+		{"synthetic_multiple_submit_commit_with_files.json", true, []string{"add retry transport for API requests", "document retry settings"}},
+		{"synthetic_multiple_submit_commit_without_files.json", false, []string{"handle empty diff in prompt builder"}},
+		{"synthetic_repeated_split_commits.json", true, []string{"add retry transport for API requests", "document retry settings"}},
+		{"synthetic_split_and_submit_commit.json", true, []string{"add retry transport for API requests", "document retry settings"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+			var resp openai.ChatCompletionResponse
+			if err := json.Unmarshal(data, &resp); err != nil {
+				t.Fatalf("failed to decode fixture: %v", err)
+			}
+
+			calls := ai.ToolCallsFromOpenAI(resp.Choices[0].Message)
+			result, err := ai.ParseToolCalls(calls, files)
+			if err != nil {
+				t.Fatalf("ParseToolCalls failed: %v", err)
+			}
+			var messages []string
+			for _, c := range result.Commits {
+				messages = append(messages, c.String())
+			}
+			// Every subject and body the model sent ends up in a message
+			for _, call := range calls {
+				if call.Name != "submit_commit" {
+					continue
+				}
+				var sent ai.CommitMessage
+				if err := json.Unmarshal([]byte(call.Arguments), &sent); err != nil {
+					t.Fatal(err)
+				}
+				for _, text := range []string{sent.Subject, sent.Body} {
+					if !strings.Contains(strings.Join(messages, "\n"), text) {
+						t.Errorf("%q was lost, got:\n%s", text, strings.Join(messages, "\n---\n"))
+					}
+				}
+			}
+			if result.IsSplit != tt.isSplit {
+				t.Errorf("IsSplit = %v, want %v", result.IsSplit, tt.isSplit)
+			}
+			if len(result.Commits) != len(tt.subjects) {
+				t.Fatalf("got %d commits, want %d", len(result.Commits), len(tt.subjects))
+			}
+			for i, subject := range tt.subjects {
+				if result.Commits[i].Subject != subject {
+					t.Errorf("commit %d subject = %q, want %q", i, result.Commits[i].Subject, subject)
+				}
+			}
+			if !result.IsSplit && len(result.Commits[0].Files) != len(files) {
+				t.Errorf("single commit should cover all files, got %v", result.Commits[0].Files)
+			}
+			if problems := ai.ValidateResult(result, files); len(problems) != 0 {
+				t.Errorf("merged result should be valid, got %v", problems)
+			}
+		})
+	}
+}
+
+func TestParseToolCallsInvalidArguments(t *testing.T) {
+	_, err := ai.ParseToolCalls([]ai.ToolCall{{Name: "submit_commit", Arguments: "{not json"}}, []string{"a.go"})
+	if err == nil {
+		t.Error("expected error for invalid arguments")
+	}
+}
+
+func TestCommitMessageHunksFor(t *testing.T) {
+	c := ai.CommitMessage{
+		Files: []string{"a.go", "b.go"},
+		Hunks: []ai.FileHunks{{File: "a.go", Hunks: []int{1, 3}}},
+	}
+	if got := c.HunksFor("a.go"); len(got) != 2 || got[1] != 3 {
+		t.Errorf("HunksFor(a.go) = %v", got)
+	}
+	if got := c.HunksFor("b.go"); got != nil {
+		t.Errorf("HunksFor(b.go) = %v, want nil for whole file", got)
+	}
+
+	result := &ai.GenerateResult{IsSplit: true, Commits: []ai.CommitMessage{{
+		Type: "fix", Subject: "x", Files: []string{"a.go"},
+		Hunks: []ai.FileHunks{{File: "b.go", Hunks: []int{0}}},
+	}}}
+	if problems := ai.ValidateResult(result, []string{"a.go", "b.go"}); len(problems) != 2 {
+		t.Errorf("expected 2 hunk problems, got %v", problems)
+	}
+}
+
+func TestSupportsStructuredOutputs(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected bool
+	}{
+		{"gpt-4o", true},
+		{"gpt-4o-mini", true},
+		{"gpt-4o-2024-05-13", false},
+		{"gpt-4.1-nano", true},
+		{"openai/gpt-5-mini", true},
+		{"o1-mini", false},
+		{"o3-mini", true},
+		{"gpt-4-turbo", false},
+		{"llama3.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := ai.SupportsStructuredOutputs(tt.model); got != tt.expected {
+			t.Errorf("SupportsStructuredOutputs(%q) = %v, want %v", tt.model, got, tt.expected)
+		}
+	}
+}
+
+func TestStructuredToolCalls(t *testing.T) {
+	files := []string{"a.go", "b.go"}
+	single := `{"commits":[{"type":"fix","subject":"fix a","body":"","files":["a.go"],"hunks":[]}]}`
+	split := `{"commits":[{"type":"fix","subject":"fix a","body":"","files":["a.go"],"hunks":[]},{"type":"docs","subject":"doc b","body":"","files":["b.go"],"hunks":[]}]}`
+
+	result, err := ai.ParseToolCalls(ai.StructuredToolCalls(single, true), files)
+	if err != nil || result.IsSplit || len(result.Commits[0].Files) != 2 {
+		t.Errorf("single structured commit should cover all files, got %+v (err %v)", result, err)
+	}
+
+	result, err = ai.ParseToolCalls(ai.StructuredToolCalls(split, true), files)
+	if err != nil || !result.IsSplit || len(result.Commits) != 2 {
+		t.Errorf("expected split of 2, got %+v (err %v)", result, err)
+	}
+
+	result, err = ai.ParseToolCalls(ai.StructuredToolCalls(split, false), files)
+	if err != nil || result.IsSplit || result.Commits[0].Subject != "fix a" {
+		t.Errorf("split not allowed should keep the first commit, got %+v (err %v)", result, err)
+	}
+
+	if _, err := ai.ParseToolCalls(ai.StructuredToolCalls("{oops", true), files); err == nil {
+		t.Error("malformed structured reply should fail to parse")
+	}
+}
+
+func TestMergeCommits(t *testing.T) {
+	merged := ai.MergeCommits([]ai.CommitMessage{
+		{Type: "docs", Subject: "document flags", Files: []string{"README.md"}},
+		{Type: "feat", Subject: "add flags", Files: []string{"main.go", "README.md"}},
+	})
+
+	if merged.Type != "feat" {
+		t.Errorf("expected most significant type feat, got %q", merged.Type)
+	}
+	if merged.Subject != "document flags" {
+		t.Errorf("expected first subject, got %q", merged.Subject)
+	}
+	if want := "- docs: document flags\n- feat: add flags"; merged.Body != want {
+		t.Errorf("body = %q, want %q", merged.Body, want)
+	}
+	if len(merged.Files) != 2 {
+		t.Errorf("expected files deduplicated, got %v", merged.Files)
+	}
+
+	single := ai.CommitMessage{Type: "fix", Subject: "x", Files: []string{"a"}}
+	if got := ai.MergeCommits([]ai.CommitMessage{single}); got.Subject != "x" || got.Body != "" {
+		t.Errorf("single commit should be returned unchanged, got %+v", got)
+	}
+}
+
+func TestBreakingChangeToolCall(t *testing.T) {
+	calls := []ai.ToolCall{{
+		Name:      "submit_commit",
+		Arguments: `{"type":"feat","subject":"return errors from Load","breaking":true,"breaking_description":"Load now returns (*Config, error)."}`,
+	}}
+	result, err := ai.ParseToolCalls(calls, []string{"config.go"})
+	if err != nil {
+		t.Fatalf("ParseToolCalls failed: %v", err)
+	}
+	c := result.Commits[0]
+	if !c.Breaking || c.BreakingDescription != "Load now returns (*Config, error)." {
+		t.Errorf("breaking fields not parsed: %+v", c)
+	}
+	if want := "feat!: return errors from Load"; c.Header() != want {
+		t.Errorf("Header() = %q, want %q", c.Header(), want)
+	}
+
+	merged := ai.MergeCommits([]ai.CommitMessage{
+		{Type: "docs", Subject: "document Load"},
+		c,
+	})
+	if !merged.IsBreaking() || !strings.HasSuffix(merged.String(), "BREAKING CHANGE: Load now returns (*Config, error).") {
+		t.Errorf("merging should keep the breaking change, got:\n%s", merged.String())
+	}
+}
+
+func TestPromptBranchAndTicket(t *testing.T) {
+	prompt := ai.BuildPromptFrom(ai.PromptContext{
+		Files:  []string{"login.go"},
+		Diff:   "+x",
+		Branch: "feature/PROJ-123-login",
+		Ticket: "PROJ-123",
+	})
+	if !strings.Contains(prompt, "Branch: feature/PROJ-123-login\nTicket: PROJ-123 (reference it") {
+		t.Errorf("prompt should ask to reference a ticket nothing adds, got:\n%s", prompt)
+	}
+	prompt = ai.BuildPromptFrom(ai.PromptContext{
+		Files:       []string{"login.go"},
+		Diff:        "+x",
+		Ticket:      "PROJ-123",
+		TicketAdded: true,
+	})
+	if !strings.Contains(prompt, "Ticket: PROJ-123 (added to the message automatically; don't repeat it)") {
+		t.Errorf("prompt should leave out a ticket the trailer adds, got:\n%s", prompt)
+	}
+	if strings.Contains(ai.BuildPromptFrom(ai.PromptContext{Files: []string{"a.go"}}), "Ticket:") {
+		t.Error("no ticket line without a ticket")
+	}
+}
+
+func TestAddTrailers(t *testing.T) {
+	msg := ai.CommitMessage{Type: "fix", Subject: "x", Trailers: []string{"Signed-off-by: Ada <ada@example.com>"}}
+	got := ai.AddTrailers(msg, []string{"signed-off-by: Ada <ada@example.com>", "Refs: PROJ-1"})
+	if want := "fix: x\n\nSigned-off-by: Ada <ada@example.com>\nRefs: PROJ-1"; got.String() != want {
+		t.Errorf("String() = %q, want %q", got.String(), want)
+	}
+	if len(msg.Trailers) != 1 {
+		t.Error("AddTrailers should not modify the original message")
+	}
+}
+
+func TestBuildStandupPrompt(t *testing.T) {
+	prompt := ai.BuildStandupPrompt([]ai.StandupRepo{
+		{Name: "commity", Subjects: []string{"feat: add today", "fix: typo"}},
+		{Name: "empty"},
+	})
+
+	if !strings.Contains(prompt, "## commity\n- feat: add today\n- fix: typo\n") {
+		t.Errorf("expected repository section with subjects, got:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "empty") {
+		t.Error("repositories without commits should be omitted")
+	}
+}
+
+func TestCheckCoverage(t *testing.T) {
+	files := []string{"a.go", "b.go", "c.go", "d.go"}
+	commits := []ai.CommitMessage{
+		{Type: "feat", Subject: "one", Files: []string{"a.go", "b.go", "x.go"}},
+		{Type: "fix", Subject: "two", Files: []string{"b.go", "d.go"}, Hunks: []ai.FileHunks{{File: "d.go", Hunks: []int{1}}}},
+		{Type: "docs", Subject: "three", Files: []string{"d.go"}, Hunks: []ai.FileHunks{{File: "d.go", Hunks: []int{2}}}},
+	}
+
+	cov := ai.CheckCoverage(commits, files)
+	if cov.OK() {
+		t.Fatal("expected coverage problems")
+	}
+	if len(cov.Missing) != 1 || cov.Missing[0] != "c.go" {
+		t.Errorf("Missing = %v, want [c.go]", cov.Missing)
+	}
+	if len(cov.Duplicates) != 1 || cov.Duplicates[0] != "b.go" {
+		t.Errorf("Duplicates = %v, want [b.go] (d.go is split by hunks)", cov.Duplicates)
+	}
+	if len(cov.Unknown) != 1 || cov.Unknown[0] != "x.go" {
+		t.Errorf("Unknown = %v, want [x.go]", cov.Unknown)
+	}
+
+	fixed, leftover := ai.FixCoverage(commits, cov)
+	if len(fixed) != 3 {
+		t.Fatalf("expected 3 commits after fixing, got %d", len(fixed))
+	}
+	if got := strings.Join(fixed[0].Files, ","); got != "a.go,b.go" {
+		t.Errorf("first commit files = %s, want a.go,b.go", got)
+	}
+	if got := strings.Join(fixed[1].Files, ","); got != "d.go" {
+		t.Errorf("second commit files = %s, want d.go", got)
+	}
+	if len(leftover) != 1 || leftover[0] != "c.go" {
+		t.Errorf("leftover = %v, want [c.go]", leftover)
+	}
+	if len(commits[0].Files) != 3 {
+		t.Error("FixCoverage should not modify its input")
+	}
+
+	if !ai.CheckCoverage(fixed, []string{"a.go", "b.go", "d.go"}).OK() {
+		t.Error("fixed plan should cover the selection")
+	}
+}
+
+func TestShortenSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		max     int
+		want    string
+	}{
+		{"add login page", 50, "add login page"},
+		{"add login page.", 50, "add login page"},
+		{"add login page and session handling", 22, "add login page"},
+		{"add login page with remember me", 20, "add login page"},
+		{"supercalifragilistic", 5, "super"},
+	}
+	for _, tt := range tests {
+		if got := ai.ShortenSubject(tt.subject, tt.max); got != tt.want {
+			t.Errorf("ShortenSubject(%q, %d) = %q, want %q", tt.subject, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestEnforceSubject(t *testing.T) {
+	rules := ai.SubjectRules{MaxLength: 30, Prefix: "[PROJ-1] "}
+
+	msg := ai.EnforceSubject(ai.CommitMessage{Type: "feat", Subject: "[PROJ-1] add login page and session handling"}, rules)
+	if got := msg.Header(); got != "[PROJ-1] feat: add login page" {
+		t.Errorf("Header() = %q", got)
+	}
+	if len(msg.Header()) > 30 {
+		t.Errorf("header exceeds the limit: %d", len(msg.Header()))
+	}
+
+	short := ai.EnforceSubject(ai.CommitMessage{Type: "fix", Subject: "typo", Body: "details"}, ai.SubjectRules{})
+	if short.String() != "fix: typo\n\ndetails" {
+		t.Errorf("default rules should leave short messages alone, got %q", short.String())
+	}
+}
+
+func TestEnforceSubjectEmoji(t *testing.T) {
+	rules := ai.SubjectRules{Emoji: map[string]string{"feat": "✨", "fix": "🐛", "security": "🔒️"}}
+
+	msg := ai.EnforceSubject(ai.CommitMessage{Type: "feat", Subject: "add search", Breaking: true}, rules)
+	if got := msg.Header(); got != "✨ feat!: add search" {
+		t.Errorf("Header() = %q", got)
+	}
+
+	// The model may pick a more specific emoji from the table
+	msg = ai.EnforceSubject(ai.CommitMessage{Type: "fix", Subject: "🔒️ escape user input"}, rules)
+	if got := msg.Header(); got != "🔒️ fix: escape user input" {
+		t.Errorf("Header() = %q", got)
+	}
+
+	msg = ai.EnforceSubject(ai.CommitMessage{Type: "chore", Subject: "bump deps"}, rules)
+	if got := msg.Header(); got != "chore: bump deps" {
+		t.Errorf("types without an emoji should be left alone, got %q", got)
+	}
+
+	prompt := ai.BuildPromptFrom(ai.PromptContext{Files: []string{"main.go"}, Diff: "+x", Subject: rules})
+	if !strings.Contains(prompt, "🔒️ security") || !strings.Contains(prompt, "gitmoji") {
+		t.Errorf("prompt should list the emoji table, got:\n%s", prompt)
+	}
+}
+
+func TestSubjectProblems(t *testing.T) {
+	rules := ai.SubjectRules{MaxLength: 20, Prefix: "[X] "}
+	commits := []ai.CommitMessage{
+		{Type: "fix", Subject: "typo"},
+		{Type: "feat", Subject: "add a much longer subject"},
+	}
+	problems := ai.SubjectProblems(commits, rules)
+	if len(problems) != 1 || !strings.Contains(problems[0], "commit 2") || !strings.Contains(problems[0], "16") {
+		t.Errorf("expected one problem for commit 2 with a limit of 16, got %v", problems)
+	}
+}
+
+func TestTypeProblemsAndDescriptions(t *testing.T) {
+	rules := ai.SubjectRules{Types: []string{"feat", "fix", "perf"}}
+	commits := []ai.CommitMessage{{Type: "feat", Subject: "a"}, {Type: "feature", Subject: "b"}}
+	problems := ai.TypeProblems(commits, rules)
+	if len(problems) != 1 || !strings.Contains(problems[0], `"feature"`) {
+		t.Errorf("expected one problem for the unknown type, got %v", problems)
+	}
+	if p := ai.TypeProblems(commits, ai.SubjectRules{}); len(p) != 0 {
+		t.Errorf("without a type list any type is allowed, got %v", p)
+	}
+
+	pc := ai.PromptContext{
+		Files: []string{"main.go"}, Diff: "+x", Conventional: true,
+		Types:            []string{"feat", "perf"},
+		TypeDescriptions: map[string]string{"perf": "measurable speedups"},
+	}
+	prompt := ai.BuildPromptFrom(pc)
+	if !strings.Contains(prompt, "- perf: measurable speedups\n") || !strings.Contains(prompt, "- feat\n") {
+		t.Errorf("prompt should list the types with their descriptions, got:\n%s", prompt)
+	}
+}
+
+func TestBodyProblems(t *testing.T) {
+	rules := ai.SubjectRules{RequireBody: []string{"feat", "fix"}}
+	commits := []ai.CommitMessage{
+		{Type: "feat", Subject: "add search", Body: "Users asked to find old sessions."},
+		{Type: "fix", Subject: "handle empty diff", Body: "  "},
+		{Type: "chore", Subject: "bump deps"},
+	}
+	problems := ai.BodyProblems(commits, rules)
+	if len(problems) != 1 || !strings.Contains(problems[0], "commit 2") {
+		t.Errorf("expected one problem for the fix without a body, got %v", problems)
+	}
+	if p := ai.BodyProblems(commits, ai.SubjectRules{}); len(p) != 0 {
+		t.Errorf("no types should require a body by default, got %v", p)
+	}
+
+	prompt := ai.BuildPromptFrom(ai.PromptContext{Files: []string{"main.go"}, Diff: "+x", Subject: rules})
+	if !strings.Contains(prompt, "feat, fix must have a body") {
+		t.Errorf("prompt should name the types that need a body, got:\n%s", prompt)
+	}
+}
+
+func TestBuildPromptSingle(t *testing.T) {
+	pc := ai.PromptContext{Files: []string{"main.go", "util.go"}, Diff: "+x", Single: true}
+	prompt := ai.BuildPromptFrom(pc)
+	if !strings.Contains(prompt, "single commit") || strings.Contains(prompt, "`split_commits`") {
+		t.Errorf("a single commit should be asked for, got:\n%s", prompt)
+	}
+}
+
+func TestTypeHint(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"internal/git/git_test.go", "test/git/helpers.go"}, "test"},
+		{[]string{"docs/guide.md", "docs/images/flow.png"}, "docs"},
+		{[]string{".github/workflows/ci.yml"}, "ci"},
+		{[]string{"go.mod", "go.sum"}, "build"},
+		{[]string{"main.go", "main_test.go"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got, _ := ai.TypeHint(tt.files); got != tt.want {
+			t.Errorf("TypeHint(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+
+	pc := ai.PromptContext{Files: []string{"a_test.go"}, Diff: "+x", Conventional: true, Types: []string{"feat", "test"}}
+	if !strings.Contains(ai.BuildPromptFrom(pc), "likely `test`") {
+		t.Error("prompt should carry the type hint")
+	}
+	pc.Files = []string{".github/workflows/ci.yml"}
+	if strings.Contains(ai.BuildPromptFrom(pc), "Hint from the file paths") {
+		t.Error("types that aren't allowed should not be hinted")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	rules := ai.SubjectRules{Scopes: map[string]string{
+		"pkg/**":      "core",
+		"pkg/api/**":  "api",
+		"web/**":      "web",
+		"docs/api/**": "api",
+	}}
+	if got := rules.InferScope([]string{"pkg/api/handler.go", "docs/api/index.md"}); got != "api" {
+		t.Errorf("InferScope = %q, want api from the most specific globs", got)
+	}
+	if got := rules.InferScope([]string{"pkg/api/handler.go", "web/app.js"}); got != "" {
+		t.Errorf("files in several scopes should have none, got %q", got)
+	}
+
+	msg := ai.EnforceSubject(ai.CommitMessage{Type: "fix", Scope: "backend", Subject: "retry", Files: []string{"pkg/store/db.go"}}, rules)
+	if msg.Header() != "fix(core): retry" {
+		t.Errorf("scope should follow the files, got %q", msg.Header())
+	}
+	msg = ai.EnforceSubject(ai.CommitMessage{Type: "feat", Scope: "backend", Subject: "sync", Files: []string{"pkg/a.go", "web/b.js"}}, rules)
+	if msg.Header() != "feat: sync" {
+		t.Errorf("invented scopes should be dropped, got %q", msg.Header())
+	}
+	msg = ai.EnforceSubject(ai.CommitMessage{Type: "feat", Scope: "web", Subject: "sync", Files: []string{"pkg/a.go", "web/b.js"}}, rules)
+	if msg.Header() != "feat(web): sync" {
+		t.Errorf("a configured scope the model chose should be kept, got %q", msg.Header())
+	}
+
+	prompt := ai.BuildPromptFrom(ai.PromptContext{Files: []string{"web/app.js", "main.go"}, Diff: "+x", Subject: rules})
+	if !strings.Contains(prompt, "api, core, web") || !strings.Contains(prompt, "- web/app.js: web") || !strings.Contains(prompt, "- main.go: (none)") {
+		t.Errorf("prompt should list the scopes of the files, got:\n%s", prompt)
+	}
+
+	// Scopes allowed without globs, such as commitlint's scope-enum
+	enum := ai.SubjectRules{ScopeEnum: []string{"cli", "tui"}}
+	if msg := ai.EnforceSubject(ai.CommitMessage{Type: "fix", Scope: "core", Subject: "retry"}, enum); msg.Scope != "" {
+		t.Errorf("scopes outside the enum should be dropped, got %q", msg.Header())
+	}
+	if msg := ai.EnforceSubject(ai.CommitMessage{Type: "fix", Scope: "tui", Subject: "retry"}, enum); msg.Scope != "tui" {
+		t.Errorf("scopes in the enum should be kept, got %q", msg.Header())
+	}
+	prompt = ai.BuildPromptFrom(ai.PromptContext{Files: []string{"main.go"}, Diff: "+x", Subject: enum})
+	if !strings.Contains(prompt, "Use only these scopes: cli, tui.") {
+		t.Errorf("prompt should list the allowed scopes, got:\n%s", prompt)
+	}
+}
+
+func TestBuildPromptSubjectRules(t *testing.T) {
+	pc := ai.PromptContext{Files: []string{"main.go"}, Diff: "+x", Subject: ai.SubjectRules{MaxLength: 50, Prefix: "JIRA-1 "}}
+	prompt := ai.BuildPromptFrom(pc)
+	if !strings.Contains(prompt, "within 43 characters") || !strings.Contains(prompt, `"JIRA-1 "`) {
+		t.Errorf("prompt should state the limit and prefix, got:\n%s", prompt)
+	}
+
+	pc.Subject = ai.SubjectRules{}
+	if strings.Contains(ai.BuildPromptFrom(pc), "Keep the first line") {
+		t.Error("default rules should not add a length instruction")
+	}
+}
+
+func TestParseIgnorePatterns(t *testing.T) {
+	content := "```gitignore\n# build output\n- dist/\n* *.log\n`node_modules/`\n\nThese patterns ignore the files.\n```"
+	got := ai.ParseIgnorePatterns(content)
+	want := []string{"dist/", "*.log", "node_modules/"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ParseIgnorePatterns() = %v, want %v", got, want)
+	}
+}
+
+func TestBranchName(t *testing.T) {
+	tests := []struct {
+		text, prefix, commitType, want string
+	}{
+		{"Add OAuth login", "", "", "add-oauth-login"},
+		{"  fix: crash on empty config!\nextra line", "", "", "fix-crash-on-empty-config"},
+		{"add-oauth-login", "feat/", "", "feat/add-oauth-login"},
+		{"add-oauth-login", "{type}/", "feat", "feat/add-oauth-login"},
+		{"add-oauth-login", "{type}/", "", "add-oauth-login"},
+		{"!!!", "feat/", "", ""},
+		{strings.Repeat("word ", 20), "", "", strings.TrimSuffix(strings.Repeat("word-", 10), "-")},
+	}
+	for _, tt := range tests {
+		if got := ai.BranchName(tt.text, tt.prefix, tt.commitType); got != tt.want {
+			t.Errorf("BranchName(%q, %q, %q) = %q, want %q", tt.text, tt.prefix, tt.commitType, got, tt.want)
+		}
+	}
+}
+
+func TestParsePullRequest(t *testing.T) {
+	calls := []ai.ToolCall{{
+		Name:      "submit_pull_request",
+		Arguments: `{"title": " Add OAuth login ", "body": "Adds login.\n\n## Changes\n- device flow\n"}`,
+	}}
+	pr, err := ai.ParsePullRequest(calls, "")
+	if err != nil {
+		t.Fatalf("ParsePullRequest failed: %v", err)
+	}
+	if pr.Title != "Add OAuth login" || !strings.HasPrefix(pr.Body, "Adds login.") {
+		t.Errorf("unexpected pull request: %+v", pr)
+	}
+
+	// Plain replies use the first line as the title
+	pr, err = ai.ParsePullRequest(nil, "# Fix crash\n\nHandles empty config.")
+	if err != nil || pr.Title != "Fix crash" || pr.Body != "Handles empty config." {
+		t.Errorf("content fallback = %+v, %v", pr, err)
+	}
+
+	if _, err := ai.ParsePullRequest([]ai.ToolCall{{Name: "submit_pull_request", Arguments: `{"body": "x"}`}}, ""); err == nil {
+		t.Error("expected an error for a missing title")
+	}
+}
+
+func TestMinimizeDiff(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n")
+	// Hunk 1: reindented block, one real change, lots of context
+	sb.WriteString("@@ -1,30 +1,30 @@\n")
+	for i := 0; i < 10; i++ {
+		sb.WriteString(fmt.Sprintf(" line %d\n", i))
+	}
+	for i := 0; i < 8; i++ {
+		sb.WriteString(fmt.Sprintf("-\tcall(%d)\n", i))
+	}
+	for i := 0; i < 8; i++ {
+		sb.WriteString(fmt.Sprintf("+    call( %d )\n", i))
+	}
+	sb.WriteString("-return nil\n+return err\n")
+	for i := 10; i < 20; i++ {
+		sb.WriteString(fmt.Sprintf(" line %d\n", i))
+	}
+	// Hunk 2: two functions swapped
+	sb.WriteString("@@ -40,4 +40,4 @@\n-func a() {}\n-func b() {}\n+func b() {}\n+func a() {}\n")
+	diff := sb.String()
+
+	got := ai.MinimizeDiff(diff, 1)
+
+	if strings.Count(got, "\n@@") != 2 {
+		t.Errorf("hunk headers must be kept:\n%s", got)
+	}
+	for _, want := range []string{"-return nil\n+return err\n", "[8 whitespace-only changed lines omitted]", "[2 lines moved, content unchanged]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, " line 0\n") || strings.Contains(got, " line 19\n") {
+		t.Errorf("distant context should be dropped:\n%s", got)
+	}
+	if ai.EstimateTokens(got)*10 > ai.EstimateTokens(diff)*6 {
+		t.Errorf("expected at least 40%% of the tokens saved: %d -> %d", ai.EstimateTokens(diff), ai.EstimateTokens(got))
+	}
+
+	pc := ai.PromptContext{Files: []string{"main.go"}, Diff: diff, Minimize: true, ContextLines: 1}
+	if strings.Contains(ai.BuildPromptFrom(pc), "call( 3 )") {
+		t.Error("BuildPromptFrom should minimize when asked")
+	}
+}
+
+func TestDetectMoves(t *testing.T) {
+	diff := `diff --git a/util.go b/util.go
+--- a/util.go
++++ b/util.go
+@@ -1,8 +1,2 @@
+ package x
+-
+-func parse(s string) int {
+-	n, _ := strconv.Atoi(s)
+-	return n
+-}
+diff --git a/parse.go b/parse.go
+new file mode 100644
+--- /dev/null
++++ b/parse.go
+@@ -0,0 +1,6 @@
++package x
++
++func parse(s string) int {
++	n, _ := strconv.Atoi(s)
++	return n
++}
+diff --git a/old.go b/new.go
+similarity index 100%
+rename from old.go
+rename to new.go
+diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+-	return n
++	return n + 1
+`
+	moves := ai.DetectMoves(diff)
+	want := []ai.Move{{From: "old.go", To: "new.go"}, {From: "util.go", To: "parse.go"}}
+	if len(moves) != len(want) {
+		t.Fatalf("DetectMoves = %+v, want %+v", moves, want)
+	}
+	for i := range want {
+		if moves[i] != want[i] {
+			t.Errorf("move %d = %+v, want %+v", i, moves[i], want[i])
+		}
+	}
+
+	prompt := ai.BuildPromptFrom(ai.PromptContext{
+		Files: []string{"util.go", "parse.go"},
+		Diff:  diff,
+		Moves: []ai.MoveHistory{{Move: want[1], FromLog: []string{"abc1234 add parse helper"}}},
+	})
+	if !strings.Contains(prompt, "- util.go -> parse.go\n  util.go:\n    abc1234 add parse helper\n  parse.go: no earlier commits\n") {
+		t.Errorf("prompt should list the move history, got:\n%s", prompt)
+	}
+}
+
+func TestLintAndFixMessage(t *testing.T) {
+	rules := ai.SubjectRules{MaxLength: 72, Types: []string{"feat", "fix"}}
+	c := ai.CommitMessage{
+		Type:    "fix",
+		Subject: "Fixed crash on empty config.",
+		Body:    strings.Repeat("word ", 20) + "end\n\nSee https://example.com/" + strings.Repeat("x", 80),
+	}
+
+	warnings := ai.LintMessage(c, rules)
+	if len(warnings) != 4 {
+		t.Fatalf("expected 4 warnings, got %v", warnings)
+	}
+	if warnings[3].Fixable {
+		t.Errorf("long URL line should not be fixable: %v", warnings[3])
+	}
+
+	fixed := ai.FixMessage(c, rules)
+	if fixed.Subject != "Fix crash on empty config" {
+		t.Errorf("subject = %q", fixed.Subject)
+	}
+	for _, line := range strings.Split(fixed.Body, "\n") {
+		if len(line) > ai.BodyWidth && !strings.Contains(line, "://") {
+			t.Errorf("line not wrapped: %q", line)
+		}
+	}
+	if warnings := ai.LintMessage(fixed, rules); len(warnings) != 1 {
+		t.Errorf("expected only the URL warning after fixing, got %v", warnings)
+	}
+
+	if w := ai.LintMessage(ai.CommitMessage{Type: "wip", Subject: "add things"}, rules); len(w) != 1 || w[0].Fixable {
+		t.Errorf("unknown type should be an unfixable warning, got %v", w)
+	}
+	if got := ai.WrapBody("- "+strings.Repeat("aaaa ", 20), 40); !strings.Contains(got, "\n  aaaa") {
+		t.Errorf("list continuation not indented: %q", got)
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	rules := ai.SubjectRules{Prefix: "[PROJ-1] ", Emoji: map[string]string{"feat": "✨"}}
+	text := "[PROJ-1] ✨ feat(api)!: drop v1 endpoints\n\nClients moved to v2 long ago.\n\nSecond paragraph.\n\nBREAKING CHANGE: v1 is gone\nRefs: #12"
+
+	c := ai.ParseMessage(text, rules)
+	if c.Prefix != "[PROJ-1] " || c.Emoji != "✨" || c.Type != "feat" || c.Scope != "api" || !c.Breaking {
+		t.Errorf("header not parsed: %+v", c)
+	}
+	if c.Subject != "drop v1 endpoints" || c.Body != "Clients moved to v2 long ago.\n\nSecond paragraph." {
+		t.Errorf("subject/body = %q / %q", c.Subject, c.Body)
+	}
+	if c.BreakingDescription != "v1 is gone" || len(c.Trailers) != 1 || c.Trailers[0] != "Refs: #12" {
+		t.Errorf("footer not parsed: %q %v", c.BreakingDescription, c.Trailers)
+	}
+	if c.String() != text {
+		t.Errorf("String() = %q, want %q", c.String(), text)
+	}
+
+	plain := ai.ParseMessage("Update the readme\n", ai.SubjectRules{})
+	if plain.Type != "" || plain.Subject != "Update the readme" || plain.Body != "" {
+		t.Errorf("plain message = %+v", plain)
+	}
+}
+
+func TestWarmUpOllama(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"model":"llama3.1","done":true}`))
+	}))
+	defer server.Close()
+
+	client, err := ai.New(&config.AIConfig{Provider: config.ProviderOllama, Model: "llama3.1", BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WarmUp(context.Background()); err != nil {
+		t.Fatalf("WarmUp: %v", err)
+	}
+	if got["model"] != "llama3.1" || got["prompt"] != nil {
+		t.Errorf("expected a load request without a prompt, got %v", got)
+	}
+}
+
+func TestBuildPromptDelta(t *testing.T) {
+	pc := ai.PromptContext{
+		Files: []string{"api.go", "api_test.go"},
+		Diff:  "diff --git a/api_test.go b/api_test.go\n+func TestAPI(t *testing.T) {}",
+		Previous: []ai.CommitMessage{{
+			Type: "feat", Subject: "add API", Files: []string{"api.go"},
+			Prefix: "[PROJ-1] ", Trailers: []string{"Refs: PROJ-1"},
+		}},
+		Added: []string{"api_test.go"},
+	}
+	prompt := ai.BuildPromptFrom(pc)
+	for _, want := range []string{"You previously proposed", "feat: add API", "Files: api.go", "added to the selection since: api_test.go", "Diff of the added files"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("delta prompt should contain %q, got:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "PROJ-1") {
+		t.Error("prefixes and trailers are added again and should not be shown to the model")
+	}
+}
+
+func TestBuildPromptExamples(t *testing.T) {
+	pc := ai.PromptContext{Files: []string{"main.go"}, Diff: "+x", Examples: []string{"fix(cli): handle empty input", "feat: add --dry-run"}}
+	prompt := ai.BuildPromptFrom(pc)
+	if !strings.Contains(prompt, "Match their style") || !strings.Contains(prompt, "- fix(cli): handle empty input\n- feat: add --dry-run\n") {
+		t.Errorf("prompt should list the recent subjects, got:\n%s", prompt)
+	}
+
+	pc.Examples = nil
+	if strings.Contains(ai.BuildPromptFrom(pc), "Recent commit subjects") {
+		t.Error("no examples should be shown when turned off")
+	}
+}
+
+func TestBuildPromptLanguage(t *testing.T) {
+	prompt := ai.BuildPromptFrom(ai.PromptContext{Files: []string{"main.go"}, Diff: "+x", Language: "German"})
+	if !strings.Contains(prompt, "Write the subject and body in German.") {
+		t.Errorf("prompt should ask for the configured language, got:\n%s", prompt)
+	}
+}
+
+func TestRequestParams(t *testing.T) {
+	zero, topP := 0.0, 0.9
+	cfg := &config.AIConfig{
+		Temperature: &zero,
+		TopP:        &topP,
+		MaxTokens:   500,
+		ExtraParams: map[string]any{"repetition_penalty": 1.1},
+	}
+	got := ai.RequestParams(cfg)
+	if got["temperature"] != 0.0 || got["top_p"] != 0.9 || got["max_tokens"] != 500 || got["repetition_penalty"] != 1.1 {
+		t.Errorf("openai params = %v", got)
+	}
+
+	cfg.Provider = config.ProviderOllama
+	cfg.ExtraParams = map[string]any{"options": map[string]any{"num_ctx": 8192}, "keep_alive": "10m"}
+	got = ai.RequestParams(cfg)
+	options, _ := got["options"].(map[string]any)
+	if options["temperature"] != 0.0 || options["num_predict"] != 500 || options["num_ctx"] != 8192 || got["keep_alive"] != "10m" {
+		t.Errorf("ollama params = %v", got)
+	}
+
+	cfg.Provider = config.ProviderGemini
+	cfg.ExtraParams = nil
+	got = ai.RequestParams(cfg)
+	if gen, _ := got["generationConfig"].(map[string]any); gen["topP"] != 0.9 || gen["maxOutputTokens"] != 500 {
+		t.Errorf("gemini params = %v", got)
+	}
+
+	if got := ai.RequestParams(&config.AIConfig{}); len(got) != 0 {
+		t.Errorf("no settings should add nothing, got %v", got)
+	}
+}
+
+func TestRequestParamsSent(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"OK"}}]}`))
+	}))
+	defer server.Close()
+
+	zero := 0.0
+	client, err := ai.New(&config.AIConfig{
+		APIKey:      "key",
+		BaseURL:     server.URL,
+		Model:       "local",
+		Temperature: &zero,
+		ExtraParams: map[string]any{"chat_template_kwargs": map[string]any{"enable_thinking": false}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if got["temperature"] != 0.0 || got["model"] != "local" {
+		t.Errorf("temperature 0 must be sent, got %v", got)
+	}
+	if kwargs, _ := got["chat_template_kwargs"].(map[string]any); kwargs["enable_thinking"] != false {
+		t.Errorf("extra params not sent, got %v", got)
+	}
+}
+
+func TestPromptTemplates(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.tmpl")
+	user := filepath.Join(dir, "prompt.tmpl")
+	os.WriteFile(system, []byte("{{.Default}}\nAlways write in lowercase."), 0644)
+	os.WriteFile(user, []byte("Branch {{.Branch}}, {{.Kind}} change to {{range .Files}}{{.}} {{end}}\ntypes: {{join .Types \"|\"}}\n{{.Diff}}"), 0644)
+
+	// Template functions other than the built-in ones aren't available
+	if _, err := ai.LoadPromptTemplates(&config.AIConfig{PromptFile: user}); err == nil {
+		t.Fatal("expected an error for an undefined function")
+	}
+	os.WriteFile(user, []byte("Branch {{.Branch}}, {{.Kind}} change to {{range .Files}}{{.}} {{end}}\n{{.Diff}}"), 0644)
+
+	templates, err := ai.LoadPromptTemplates(&config.AIConfig{SystemPromptFile: system, PromptFile: user})
+	if err != nil {
+		t.Fatalf("LoadPromptTemplates: %v", err)
+	}
+	pc := ai.PromptContext{Files: []string{"main.go"}, Diff: "+package main", Branch: "feat/x"}
+	sys, prompt, err := templates.Prompts(pc)
+	if err != nil {
+		t.Fatalf("Prompts: %v", err)
+	}
+	if !strings.HasPrefix(sys, ai.SystemPrompt()) || !strings.HasSuffix(sys, "Always write in lowercase.") {
+		t.Errorf("system prompt should extend the built-in one, got:\n%s", sys)
+	}
+	if prompt != "Branch feat/x, code change to main.go \n+package main" {
+		t.Errorf("unexpected prompt:\n%q", prompt)
+	}
+
+	// Without templates the built-in prompts are used
+	none, err := ai.LoadPromptTemplates(&config.AIConfig{})
+	if err != nil || none != nil {
+		t.Fatalf("expected no templates, got %v, %v", none, err)
+	}
+	if sys, prompt, _ := none.Prompts(pc); sys != ai.SystemPrompt() || prompt != ai.BuildPromptFrom(pc) {
+		t.Error("nil templates must give the built-in prompts")
+	}
+
+	if _, err := ai.LoadPromptTemplates(&config.AIConfig{PromptFile: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected an error for a missing template file")
+	}
+}
+
+func TestDebugLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"OK"}}],"usage":{"prompt_tokens":12,"completion_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	var log strings.Builder
+	ai.SetDebugLog(&log)
+	defer ai.SetDebugLog(nil)
+
+	client, err := ai.New(&config.AIConfig{APIKey: "key", BaseURL: server.URL, Model: "local", MaxTokens: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	for _, want := range []string{"openai local", `params: {"max_tokens":50}`, "--- system", "Reply with OK.", "12 prompt + 1 completion tokens", "content: OK"} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("debug log should contain %q, got:\n%s", want, log.String())
+		}
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"submit_commit","arguments":"{\"type\":\"fix\",\"subject\":\"handle empty input\",\"body\":\"\"}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := ai.New(&config.AIConfig{APIKey: "key", BaseURL: server.URL, Model: "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetCacheDir(t.TempDir())
+	pc := ai.PromptContext{Files: []string{"main.go"}, Diff: "+package main", Conventional: true}
+
+	first, err := client.GenerateCommitMessage(context.Background(), pc)
+	if err != nil {
+		t.Fatalf("GenerateCommitMessage: %v", err)
+	}
+	second, err := client.GenerateCommitMessage(context.Background(), pc)
+	if err != nil {
+		t.Fatalf("GenerateCommitMessage: %v", err)
+	}
+	if requests != 1 || first.Cached || !second.Cached {
+		t.Fatalf("identical request should be served from the cache, got %d requests", requests)
+	}
+	if second.Commits[0].String() != first.Commits[0].String() {
+		t.Errorf("cached message %q differs from %q", second.Commits[0].String(), first.Commits[0].String())
+	}
+
+	// A different diff, or bypassing the cache, asks the model again
+	pc.Diff = "+package other"
+	if _, err := client.GenerateCommitMessage(context.Background(), pc); err != nil {
+		t.Fatal(err)
+	}
+	pc.NoCache = true
+	result, err := client.GenerateCommitMessage(context.Background(), pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 || result.Cached {
+		t.Errorf("expected 3 requests without cache hits, got %d", requests)
+	}
+}
+
+// chatReply is a chat completion answering "ok"
+const chatReply = `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`
+
+func TestKeyRotationOnQuota(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") == "Bearer key-one-0001" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(chatReply))
+	}))
+	defer server.Close()
+
+	cfg := &config.AIConfig{
+		Model:       "gpt-4o-mini",
+		BaseURL:     server.URL,
+		APIKey:      "key-one-0001",
+		APIKeys:     []string{"key-two-0002", " ", "key-one-0001"},
+		KeyRotation: config.RotateFailover,
+	}
+	client, err := ai.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SummarizeSection(context.Background(), "Added", []string{"x"}); err != nil {
+		t.Fatalf("the request should fail over to the second key: %v", err)
+	}
+	if _, err := client.SummarizeSection(context.Background(), "Added", []string{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Bearer key-one-0001", "Bearer key-two-0002", "Bearer key-two-0002"}
+	if !slices.Equal(seen, want) {
+		t.Errorf("keys sent = %q, want %q", seen, want)
+	}
+
+	usage, err := ai.LoadKeyUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	one, two := usage[ai.KeyFingerprint("key-one-0001")], usage[ai.KeyFingerprint("key-two-0002")]
+	if one.Requests != 1 || one.RateLimited != 1 || two.Requests != 2 || two.RateLimited != 0 {
+		t.Errorf("usage = %+v, %+v", one, two)
+	}
+	if one.Hint != "...0001" {
+		t.Errorf("hint = %q", one.Hint)
+	}
+	data, _ := os.ReadFile(ai.KeyUsagePath())
+	if strings.Contains(string(data), "key-one") {
+		t.Error("the usage file must not contain the keys")
+	}
+
+	// A new client passes over the key that just hit its quota
+	seen = nil
+	client, err = ai.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SummarizeSection(context.Background(), "Added", []string{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(seen, []string{"Bearer key-two-0002"}) {
+		t.Errorf("keys sent = %q, want only the second key", seen)
+	}
+}
+
+func TestOAuthRefreshesRejectedToken(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("PATH", t.TempDir()) // no keychain
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	refreshes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			refreshes++
+			w.Write([]byte(`{"access_token":"fresh","expires_in":3600}`))
+		case r.Header.Get("Authorization") == "Bearer fresh":
+			w.Write([]byte(chatReply))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	gateway := config.OAuthConfig{ClientID: "commity", DeviceAuthURL: server.URL + "/device", TokenURL: server.URL + "/token"}
+	// Revoked on the server, though not expired yet
+	if err := auth.Save(gateway, &auth.Token{AccessToken: "revoked", RefreshToken: "rt", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := ai.New(&config.AIConfig{Model: "gpt-4o-mini", BaseURL: server.URL, OAuth: gateway})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SummarizeSection(context.Background(), "Added", []string{"x"}); err != nil {
+		t.Fatalf("the request should succeed with a refreshed token: %v", err)
+	}
+	if refreshes != 1 {
+		t.Errorf("refreshed %d times, want 1", refreshes)
+	}
+	if token, _ := auth.Load(gateway); token == nil || token.AccessToken != "fresh" {
+		t.Errorf("expected the refreshed token to be stored, got %+v", token)
+	}
+}
+
+func TestKeyRotationRoundRobin(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		w.Write([]byte(chatReply))
+	}))
+	defer server.Close()
+
+	client, err := ai.New(&config.AIConfig{
+		Model:       "gpt-4o-mini",
+		BaseURL:     server.URL,
+		APIKeys:     []string{"a", "b"},
+		KeyRotation: config.RotateRoundRobin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if _, err := client.SummarizeSection(context.Background(), "Added", []string{"x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"Bearer a", "Bearer b", "Bearer a"}; !slices.Equal(seen, want) {
+		t.Errorf("keys sent = %q, want %q", seen, want)
+	}
+}
@@ -0,0 +1,154 @@
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/hluaguo/commity/internal/auth"
+	"github.com/hluaguo/commity/internal/config"
+)
+
+var gateway = config.OAuthConfig{
+	ClientID:      "commity",
+	DeviceAuthURL: "https://gateway.example/device",
+	TokenURL:      "https://gateway.example/token",
+}
+
+// Helper to point the XDG state directory at a temporary location. PATH
+// is emptied so no real keychain is written to.
+func setupStateDir(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+}
+
+func TestParseTokenResponse(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	token, err := auth.ParseTokenResponse([]byte(`{"access_token":"at","refresh_token":"rt","token_type":"Bearer","expires_in":3600}`), 200, now)
+	if err != nil {
+		t.Fatalf("ParseTokenResponse failed: %v", err)
+	}
+	if token.AccessToken != "at" || token.RefreshToken != "rt" {
+		t.Errorf("unexpected token %+v", token)
+	}
+	if want := now.Add(time.Hour); !token.Expiry.Equal(want) {
+		t.Errorf("Expiry = %v, want %v", token.Expiry, want)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"pending", `{"error":"authorization_pending"}`},
+		{"denied", `{"error":"access_denied"}`},
+		{"expired", `{"error":"expired_token"}`},
+		{"other", `{"error":"invalid_client","error_description":"unknown client"}`},
+		{"no token", `{}`},
+		{"not json", `<html>`},
+	}
+	for _, tt := range tests {
+		if _, err := auth.ParseTokenResponse([]byte(tt.body), 400, now); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	now := time.Now()
+
+	if (&auth.Token{}).Expired(now) {
+		t.Error("token without expiry should never expire")
+	}
+	if (&auth.Token{Expiry: now.Add(time.Hour)}).Expired(now) {
+		t.Error("token valid for an hour should not be expired")
+	}
+	if !(&auth.Token{Expiry: now.Add(10 * time.Second)}).Expired(now) {
+		t.Error("token about to expire should be refreshed")
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	setupStateDir(t)
+
+	if token, err := auth.Load(gateway); err != nil || token != nil {
+		t.Fatalf("expected no token before login, got %+v (err %v)", token, err)
+	}
+
+	if err := auth.Save(gateway, &auth.Token{AccessToken: "at", RefreshToken: "rt"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	token, err := auth.Load(gateway)
+	if err != nil || token == nil || token.RefreshToken != "rt" {
+		t.Fatalf("Load() = %+v (err %v), want stored token", token, err)
+	}
+
+	other := gateway
+	other.ClientID = "other"
+	if token, _ := auth.Load(other); token != nil {
+		t.Error("tokens should be kept per gateway")
+	}
+
+	info, err := os.Stat(auth.CredentialsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("credentials file mode = %v, want 0600", perm)
+	}
+
+	// Without a keychain the refresh token stays in the file
+	data, _ := os.ReadFile(auth.CredentialsPath())
+	if !strings.Contains(string(data), `"rt"`) {
+		t.Errorf("expected the refresh token in the file without a keychain, got %s", data)
+	}
+}
+
+func TestSaveKeepsRefreshTokenInKeychain(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("fakes libsecret's secret-tool")
+	}
+	setupStateDir(t)
+
+	// A secret-tool keeping one secret in a file stands in for libsecret
+	bin, store := t.TempDir(), filepath.Join(t.TempDir(), "secret")
+	script := "#!/bin/sh\ncase $1 in\nstore) /bin/cat > " + store + " ;;\nlookup) /bin/cat " + store + " ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(bin, "secret-tool"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	if err := auth.Save(gateway, &auth.Token{AccessToken: "at", RefreshToken: "rt-secret"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, _ := os.ReadFile(auth.CredentialsPath())
+	if strings.Contains(string(data), "rt-secret") {
+		t.Errorf("the refresh token should not be in the file, got %s", data)
+	}
+	if secret, _ := os.ReadFile(store); string(secret) != "rt-secret" {
+		t.Errorf("keychain holds %q, want the refresh token", secret)
+	}
+
+	token, err := auth.Load(gateway)
+	if err != nil || token == nil || token.AccessToken != "at" || token.RefreshToken != "rt-secret" {
+		t.Fatalf("Load() = %+v (err %v), want the token with its refresh token", token, err)
+	}
+}
+
+func TestNewSourceRequiresLogin(t *testing.T) {
+	setupStateDir(t)
+
+	if _, err := auth.NewSource(gateway, nil); err == nil {
+		t.Error("expected an error when not logged in")
+	}
+}
@@ -0,0 +1,79 @@
+package changelog_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hluaguo/commity/internal/changelog"
+	"github.com/hluaguo/commity/internal/git"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		subject, body string
+		want          changelog.Commit
+		ok            bool
+	}{
+		{"feat(api): add login", "", changelog.Commit{Type: "feat", Scope: "api", Subject: "add login"}, true},
+		{"fix!: drop v1 config", "", changelog.Commit{Type: "fix", Subject: "drop v1 config", Breaking: true}, true},
+		{"refactor: split parser", "BREAKING CHANGE: Parse moved", changelog.Commit{Type: "refactor", Subject: "split parser", Breaking: true}, true},
+		{"✨ feat: add search", "", changelog.Commit{Type: "feat", Subject: "add search"}, true},
+		{"Update readme", "", changelog.Commit{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := changelog.Parse("", tt.subject, tt.body)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tt.subject, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGroupAndMarkdown(t *testing.T) {
+	entries := []git.LogEntry{
+		{Hash: "aaaaaaa1", Subject: "fix(ui): keep cursor on resize"},
+		{Hash: "bbbbbbb2", Subject: "feat(cli): add changelog command"},
+		{Hash: "ccccccc3", Subject: "docs: explain changelog"},
+		{Hash: "ddddddd4", Subject: "feat(api)!: rename endpoints"},
+		{Hash: "eeeeeee5", Subject: "feat: add themes"},
+		{Hash: "fffffff6", Subject: "wip"},
+	}
+
+	sections := changelog.Group(entries, false)
+	var titles []string
+	for _, s := range sections {
+		titles = append(titles, s.Title)
+	}
+	if strings.Join(titles, ",") != "Added,Changed,Fixed" {
+		t.Fatalf("sections = %v, want Added,Changed,Fixed", titles)
+	}
+	// Unscoped entries sort first, then by scope
+	if added := sections[0].Commits; len(added) != 2 || added[0].Subject != "add themes" || added[1].Scope != "cli" {
+		t.Errorf("Added = %+v", added)
+	}
+
+	sections[2].Summary = "Resizing no longer loses your place."
+	date := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	md := changelog.Markdown("v1.3.0", date, sections)
+	for _, want := range []string{
+		"## [1.3.0] - 2026-05-04\n",
+		"### Changed\n\n- **BREAKING** **api:** rename endpoints (ddddddd)\n",
+		"### Fixed\n\nResizing no longer loses your place.\n\n- **ui:** keep cursor on resize (aaaaaaa)\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown() missing %q:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "explain changelog") {
+		t.Error("docs commits should be left out by default")
+	}
+
+	withAll := changelog.Group(entries, true)
+	other := withAll[len(withAll)-1]
+	if other.Title != changelog.Other || len(other.Commits) != 2 {
+		t.Errorf("Other = %+v, want docs and wip", other)
+	}
+	if !strings.HasPrefix(changelog.Markdown("", date, withAll), "## [Unreleased]\n") {
+		t.Error("expected an Unreleased heading without a version")
+	}
+}
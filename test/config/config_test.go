@@ -3,6 +3,9 @@ package config_test
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/hluaguo/commity/internal/config"
@@ -20,6 +23,9 @@ func TestDefault(t *testing.T) {
 	}
 
 	// Test AI defaults (empty)
+	if cfg.AI.Provider != config.ProviderOpenAI {
+		t.Errorf("expected default provider %q, got %q", config.ProviderOpenAI, cfg.AI.Provider)
+	}
 	if cfg.AI.Model != "" {
 		t.Errorf("expected empty default model, got %q", cfg.AI.Model)
 	}
@@ -30,6 +36,14 @@ func TestDefault(t *testing.T) {
 		t.Errorf("expected empty default API key, got %q", cfg.AI.APIKey)
 	}
 
+	// Test request resilience defaults
+	if cfg.AI.TimeoutSeconds != 60 {
+		t.Errorf("expected default timeout 60s, got %d", cfg.AI.TimeoutSeconds)
+	}
+	if cfg.AI.MaxRetries != 2 {
+		t.Errorf("expected default max retries 2, got %d", cfg.AI.MaxRetries)
+	}
+
 	// Test commit defaults
 	if !cfg.Commit.Conventional {
 		t.Error("expected conventional commits to be enabled by default")
@@ -39,8 +53,8 @@ func TestDefault(t *testing.T) {
 		t.Errorf("expected %d commit types, got %d", len(expectedTypes), len(cfg.Commit.Types))
 	}
 	for i, typ := range expectedTypes {
-		if cfg.Commit.Types[i] != typ {
-			t.Errorf("expected type %q at index %d, got %q", typ, i, cfg.Commit.Types[i])
+		if cfg.Commit.Types[i].Name != typ {
+			t.Errorf("expected type %q at index %d, got %q", typ, i, cfg.Commit.Types[i].Name)
 		}
 	}
 
@@ -301,3 +315,834 @@ api_key = "config-api-key"
 		t.Errorf("expected model 'config-model' (from config), got %q", cfg.AI.Model)
 	}
 }
+
+func TestLoadGeminiAPIKeyEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+
+	configContent := `
+[ai]
+provider = "gemini"
+model = "gemini-2.5-flash"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	t.Setenv("GEMINI_API_KEY", "gemini-env-key")
+	t.Setenv("OPENAI_API_KEY", "openai-env-key")
+	t.Setenv("OPENAI_MODEL", "gpt-4o")
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.AI.APIKey != "gemini-env-key" {
+		t.Errorf("expected API key from GEMINI_API_KEY, got %q", cfg.AI.APIKey)
+	}
+	if cfg.AI.Model != "gemini-2.5-flash" {
+		t.Errorf("OPENAI_MODEL should not override gemini model, got %q", cfg.AI.Model)
+	}
+}
+
+func TestLoadAzureConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+
+	configContent := `
+[ai]
+provider = "azure"
+deployment = "gpt-4o-prod"
+api_version = "2024-06-01"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	t.Setenv("AZURE_OPENAI_API_KEY", "azure-env-key")
+	t.Setenv("AZURE_OPENAI_ENDPOINT", "https://myresource.openai.azure.com")
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.AI.Deployment != "gpt-4o-prod" {
+		t.Errorf("expected deployment 'gpt-4o-prod', got %q", cfg.AI.Deployment)
+	}
+	if cfg.AI.APIVersion != "2024-06-01" {
+		t.Errorf("expected api_version '2024-06-01', got %q", cfg.AI.APIVersion)
+	}
+	if cfg.AI.APIKey != "azure-env-key" {
+		t.Errorf("expected API key from AZURE_OPENAI_API_KEY, got %q", cfg.AI.APIKey)
+	}
+	if cfg.AI.BaseURL != "https://myresource.openai.azure.com" {
+		t.Errorf("expected endpoint from AZURE_OPENAI_ENDPOINT, got %q", cfg.AI.BaseURL)
+	}
+}
+
+func TestEffectiveAIHostPolicy(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "")
+
+	cfg := config.Default()
+	cfg.AI.APIKey = "cloud-key"
+	cfg.AI.APIKeys = []string{"cloud-key-2"}
+	cfg.AI.BaseURL = "https://api.openai.com/v1"
+	cfg.AI.Model = "gpt-4o-mini"
+	cfg.Policies = []config.HostPolicy{
+		{Host: "github.com"},
+		{Host: "*.corp.example", Provider: config.ProviderOllama, Model: "llama3.2", LocalOnly: true},
+		{Host: "gitlab.strict", LocalOnly: true},
+	}
+
+	// No matching policy keeps the configured provider
+	ai, err := cfg.EffectiveAI("bitbucket.org")
+	if err != nil || ai.Provider != config.ProviderOpenAI {
+		t.Errorf("expected unchanged provider, got %q (err %v)", ai.Provider, err)
+	}
+
+	// Allow-listed host keeps cloud AI
+	ai, err = cfg.EffectiveAI("github.com")
+	if err != nil || ai.Model != "gpt-4o-mini" {
+		t.Errorf("expected cloud model for github.com, got %q (err %v)", ai.Model, err)
+	}
+
+	// Internal host forces a local provider
+	ai, err = cfg.EffectiveAI("gitlab.corp.example")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ai.Provider != config.ProviderOllama || ai.Model != "llama3.2" {
+		t.Errorf("expected forced ollama/llama3.2, got %s/%s", ai.Provider, ai.Model)
+	}
+	// The cloud endpoint and keys stay with the provider they belong to
+	if ai.BaseURL != "" || ai.APIKey != "" || len(ai.APIKeys) > 0 {
+		t.Errorf("expected no cloud endpoint or keys, got %q, %q, %q", ai.BaseURL, ai.APIKey, ai.APIKeys)
+	}
+	if !ai.IsLocal() {
+		t.Error("the forced ollama provider should be local")
+	}
+	if cfg.AI.Provider != config.ProviderOpenAI {
+		t.Error("EffectiveAI must not modify the stored config")
+	}
+
+	// Local-only host without a local provider is refused
+	if _, err := cfg.EffectiveAI("gitlab.strict"); err == nil {
+		t.Error("expected error when local_only policy meets a cloud provider")
+	}
+}
+
+func TestAIConfigIsLocal(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "")
+
+	tests := []struct {
+		name     string
+		ai       config.AIConfig
+		expected bool
+	}{
+		{"ollama default", config.AIConfig{Provider: config.ProviderOllama}, true},
+		{"ollama remote", config.AIConfig{Provider: config.ProviderOllama, BaseURL: "http://gpu-box:11434"}, false},
+		{"openai cloud", config.AIConfig{Provider: config.ProviderOpenAI}, false},
+		{"openai-compatible localhost", config.AIConfig{Provider: config.ProviderOpenAI, BaseURL: "http://localhost:8080/v1"}, true},
+		{"bare loopback", config.AIConfig{Provider: config.ProviderOllama, BaseURL: "127.0.0.1:11434"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ai.IsLocal(); got != tt.expected {
+				t.Errorf("IsLocal() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func loadProfiles(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("OPENAI_BASE_URL", "")
+	t.Setenv("OPENAI_MODEL", "")
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	content := `
+[ai]
+model = "gpt-4o-mini"
+base_url = "https://api.openai.com/v1"
+
+[commit]
+conventional = true
+
+[profiles.work.ai]
+base_url = "https://gateway.corp.example/v1"
+
+[profiles.work.commit]
+conventional = false
+
+[[profile_rules]]
+match = "/home/me/work/**"
+profile = "work"
+
+[[profile_rules]]
+remote = "github.com/acme/*"
+profile = "work"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return cfg
+}
+
+func TestProfileFor(t *testing.T) {
+	cfg := loadProfiles(t)
+
+	tests := []struct {
+		dir, remote, want string
+	}{
+		{"/home/me/work/api", "", "work"},
+		{"/home/me/work/team/api", "github.com/me/api", "work"},
+		{"/home/me/code/api", "github.com/acme/api", "work"},
+		{"/home/me/code/api", "github.com/me/api", ""},
+		{"/home/me/workshop", "", ""},
+	}
+	for _, tt := range tests {
+		if got := cfg.ProfileFor(tt.dir, tt.remote); got != tt.want {
+			t.Errorf("ProfileFor(%q, %q) = %q, want %q", tt.dir, tt.remote, got, tt.want)
+		}
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	cfg := loadProfiles(t)
+
+	work, err := cfg.WithProfile("work")
+	if err != nil {
+		t.Fatalf("WithProfile failed: %v", err)
+	}
+	if work.AI.BaseURL != "https://gateway.corp.example/v1" || work.Commit.Conventional {
+		t.Errorf("profile settings not applied: %+v %+v", work.AI, work.Commit)
+	}
+	if work.AI.Model != "gpt-4o-mini" || len(work.Commit.Types) == 0 {
+		t.Error("settings missing from the profile should keep their base values")
+	}
+	if cfg.AI.BaseURL != "https://api.openai.com/v1" || !cfg.Commit.Conventional {
+		t.Error("WithProfile must not modify the base config")
+	}
+	if work.Profile() != "work" || cfg.Profile() != "" {
+		t.Errorf("Profile() = %q / %q", work.Profile(), cfg.Profile())
+	}
+	if err := work.Save(); err == nil {
+		t.Error("saving a config with a profile applied should fail")
+	}
+
+	if _, err := cfg.WithProfile("missing"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
+func TestCommitTrailers(t *testing.T) {
+	c := config.CommitConfig{
+		Signoff:    true,
+		CoAuthors:  []string{"Ada <ada@example.com>", " "},
+		RefPattern: "[A-Z]+-[0-9]+",
+		RefFooter:  true,
+	}
+	got := c.TrailersFor(config.TrailerData{Identity: "Bob <bob@example.com>", Branch: "feat/PROJ-42-login"})
+	want := []string{
+		"Refs: PROJ-42",
+		"Co-authored-by: Ada <ada@example.com>",
+		"Signed-off-by: Bob <bob@example.com>",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Trailers() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("trailer %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	c = config.CommitConfig{RefPattern: "[A-Z]+-[0-9]+", RefTrailer: "Jira", RefFooter: true}
+	if got := c.TrailersFor(config.TrailerData{Branch: "main"}); len(got) != 0 {
+		t.Errorf("no ticket in the branch should add no trailer, got %q", got)
+	}
+	if got := c.TrailersFor(config.TrailerData{Branch: "PROJ-7"}); len(got) != 1 || got[0] != "Jira: PROJ-7" {
+		t.Errorf("Trailers() = %q, want the configured ref trailer key", got)
+	}
+	c = config.CommitConfig{RefPattern: "[", Signoff: true}
+	if got := c.TrailersFor(config.TrailerData{Branch: "PROJ-7"}); len(got) != 0 {
+		t.Errorf("invalid pattern and missing identity should add nothing, got %q", got)
+	}
+}
+
+func TestTrailerTemplates(t *testing.T) {
+	c := config.CommitConfig{Trailers: []string{
+		"Branch: {{.Branch}}",
+		"Session: {{.Session}}",
+		"Source: {{.Remote}}",  // empty value, skipped
+		"Bad: {{.Nope}}",       // unknown field, skipped
+		"Unclosed: {{.Branch",  // parse error, skipped
+		"no colon {{.Branch}}", // not a trailer, skipped
+	}}
+	got := c.TrailersFor(config.TrailerData{Branch: "feature/x", Session: "1234"})
+	want := []string{"Branch: feature/x", "Session: 1234"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("TrailersFor() = %q, want %q", got, want)
+	}
+}
+
+func TestTicketRef(t *testing.T) {
+	c := config.Default().Commit
+	if !c.RefFooter {
+		t.Error("the ticket trailer should be on by default")
+	}
+	c.RefPattern = `[A-Z]+-\d+`
+	c.RefPrefix = "{ref}: "
+	c.SubjectPrefix = "[web] "
+
+	if got := c.Ref("feature/PROJ-123-login"); got != "PROJ-123" {
+		t.Errorf("Ref() = %q, want PROJ-123", got)
+	}
+	if got := c.SubjectPrefixFor("feature/PROJ-123-login"); got != "[web] PROJ-123: " {
+		t.Errorf("SubjectPrefixFor() = %q, want %q", got, "[web] PROJ-123: ")
+	}
+	if got := c.SubjectPrefixFor("main"); got != "[web] " {
+		t.Errorf("without a ticket only the subject prefix applies, got %q", got)
+	}
+}
+
+func TestEmojiFor(t *testing.T) {
+	c := config.Default().Commit
+	if c.EmojiFor() != nil {
+		t.Error("emoji should be off by default")
+	}
+	c.Emoji = true
+	c.EmojiMap = map[string]string{"feat": "🚀", "chore": ""}
+	table := c.EmojiFor()
+	if table["feat"] != "🚀" || table["fix"] != "🐛" {
+		t.Errorf("expected the override and the defaults, got %v", table)
+	}
+	if _, ok := table["chore"]; ok {
+		t.Error("an empty override should drop the type")
+	}
+	if config.DefaultEmoji["feat"] != "✨" {
+		t.Error("EmojiFor must not modify DefaultEmoji")
+	}
+}
+
+func TestCompareAI(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "")
+	t.Setenv("GEMINI_API_KEY", "gemini-env-key")
+
+	cfg := config.Default()
+	cfg.AI.APIKey = "cloud-key"
+	cfg.AI.Model = "gpt-4o-mini"
+
+	if _, err := cfg.CompareAI(""); err == nil {
+		t.Error("expected error without a [compare] model")
+	}
+
+	// Same provider keeps the key and endpoint of [ai]
+	cfg.Compare.Model = "gpt-4o"
+	ai, err := cfg.CompareAI("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ai.Model != "gpt-4o" || ai.APIKey != "cloud-key" {
+		t.Errorf("got %s with key %q, want gpt-4o with the [ai] key", ai.Model, ai.APIKey)
+	}
+
+	// Another provider starts from its own environment
+	cfg.Compare = config.CompareConfig{Provider: config.ProviderGemini, Model: "gemini-2.0-flash"}
+	ai, err = cfg.CompareAI("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ai.Provider != config.ProviderGemini || ai.APIKey != "gemini-env-key" {
+		t.Errorf("got %s with key %q, want gemini with the env key", ai.Provider, ai.APIKey)
+	}
+	if cfg.AI.Model != "gpt-4o-mini" {
+		t.Error("CompareAI must not modify the stored config")
+	}
+}
+
+func TestStaysLocal(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "")
+
+	cfg := config.Default()
+	cfg.AI.Provider = config.ProviderOllama
+	cfg.AI.BaseURL = ""
+	if !cfg.StaysLocal("", false) {
+		t.Error("a local model alone should stay local")
+	}
+
+	// A remote compare model sees the diff too
+	cfg.Compare = config.CompareConfig{Provider: config.ProviderOpenAI, Model: "gpt-4o"}
+	if cfg.StaysLocal("", true) {
+		t.Error("a remote compare model should not stay local")
+	}
+	if !cfg.StaysLocal("", false) {
+		t.Error("the compare model only counts while comparing")
+	}
+
+	cfg.Compare = config.CompareConfig{Model: "llama3.2"}
+	if !cfg.StaysLocal("", true) {
+		t.Error("two local models should stay local")
+	}
+}
+
+func TestCommitTypeDescriptions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	content := `[commit]
+types = ["feat", {name = "perf", desc = "makes things faster"}, "deploy"]
+
+[profiles.ops.commit]
+types = [{name = "ops", desc = "operational change"}]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	names := cfg.Commit.TypeNames()
+	if len(names) != 3 || names[1] != "perf" || names[2] != "deploy" {
+		t.Fatalf("TypeNames() = %v", names)
+	}
+	descs := cfg.Commit.TypeDescriptions()
+	if descs["perf"] != "makes things faster" || descs["feat"] == "" {
+		t.Errorf("expected configured and built-in descriptions, got %v", descs)
+	}
+	if _, ok := descs["deploy"]; ok {
+		t.Error("custom types without a description should have none")
+	}
+
+	// Profiles are applied by round-tripping through TOML
+	ops, err := cfg.WithProfile("ops")
+	if err != nil {
+		t.Fatalf("WithProfile failed: %v", err)
+	}
+	if len(ops.Commit.Types) != 1 || ops.Commit.Types[0] != (config.CommitType{Name: "ops", Desc: "operational change"}) {
+		t.Errorf("profile types = %+v", ops.Commit.Types)
+	}
+}
+
+func TestWithRepo(t *testing.T) {
+	cfg := config.Default()
+	dir := t.TempDir()
+
+	same, err := cfg.WithRepo(dir)
+	if err != nil || !same.SplitAllowed() {
+		t.Fatalf("without %s split commits should stay allowed (err %v)", config.RepoFile, err)
+	}
+
+	content := "split = false\nsplit_note = \"Reviewers expect one commit per change\"\n"
+	if err := os.WriteFile(filepath.Join(dir, config.RepoFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := cfg.WithRepo(dir)
+	if err != nil {
+		t.Fatalf("WithRepo failed: %v", err)
+	}
+	if repo.SplitAllowed() || repo.Repo.SplitNote != "Reviewers expect one commit per change" {
+		t.Errorf("expected split commits off with the note, got %+v", repo.Repo)
+	}
+	if !cfg.SplitAllowed() {
+		t.Error("WithRepo must not modify the loaded config")
+	}
+
+	// Settings outside the repo subset are refused, not silently applied
+	if err := os.WriteFile(filepath.Join(dir, config.RepoFile), []byte("[hooks]\npost_commit = [\"rm -rf ~\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.WithRepo(dir); err == nil {
+		t.Error("expected an error for settings a repository may not set")
+	}
+}
+
+func TestSelectProfile(t *testing.T) {
+	cfg := config.Default()
+	cfg.ProfileRules = []config.ProfileRule{{Remote: "github.com/acme/*", Profile: "work"}}
+
+	t.Setenv(config.ProfileEnv, "")
+	if got := cfg.SelectProfile("/src/app", "github.com/acme/app"); got != "work" {
+		t.Errorf("without %s the rules should pick the profile, got %q", config.ProfileEnv, got)
+	}
+	t.Setenv(config.ProfileEnv, "personal")
+	if got := cfg.SelectProfile("/src/app", "github.com/acme/app"); got != "personal" {
+		t.Errorf("%s should win over the rules, got %q", config.ProfileEnv, got)
+	}
+}
+
+func TestWithRepoOverrides(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.CustomInstructions = "mine"
+	dir := t.TempDir()
+	content := `types = ["feat", {name = "fix", desc = "bug fixes"}]
+language = "German"
+custom_instructions = "Mention the ticket"
+exclude = ["*.snap"]
+
+[scopes]
+"api/**" = "api"
+`
+	if err := os.WriteFile(filepath.Join(dir, config.RepoFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := cfg.WithRepo(dir)
+	if err != nil {
+		t.Fatalf("WithRepo failed: %v", err)
+	}
+	if names := repo.Commit.TypeNames(); !reflect.DeepEqual(names, []string{"feat", "fix"}) {
+		t.Errorf("types = %v", names)
+	}
+	if repo.Commit.Language != "German" || repo.AI.CustomInstructions != "Mention the ticket" {
+		t.Errorf("language %q, instructions %q", repo.Commit.Language, repo.AI.CustomInstructions)
+	}
+	if !reflect.DeepEqual(repo.AI.Exclude, []string{"*.snap"}) || repo.Commit.Scopes["api/**"] != "api" {
+		t.Errorf("exclude %v, scopes %v", repo.AI.Exclude, repo.Commit.Scopes)
+	}
+	if !repo.SplitAllowed() {
+		t.Error("settings left out of the file should keep their values")
+	}
+	if cfg.AI.CustomInstructions != "mine" || len(cfg.Commit.Types) != 7 {
+		t.Error("WithRepo must not modify the loaded config")
+	}
+	if err := repo.Save(); err == nil {
+		t.Error("saving would copy the repository's settings into the user's config")
+	}
+}
+
+func TestWithRepoPyproject(t *testing.T) {
+	dir := t.TempDir()
+	content := `[project]
+name = "app"
+
+[tool.black]
+line-length = 100
+
+[tool.commity]
+language = "French"
+split = false
+`
+	if err := os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := config.Default().WithRepo(dir)
+	if err != nil {
+		t.Fatalf("WithRepo failed: %v", err)
+	}
+	if repo.Commit.Language != "French" || repo.SplitAllowed() {
+		t.Errorf("expected [tool.commity] applied, got %+v", repo.Repo)
+	}
+
+	content += "hooks = [\"rm -rf ~\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, "pyproject.toml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Default().WithRepo(dir); err == nil {
+		t.Error("expected an error for settings a repository may not set")
+	}
+}
+
+func TestLoadCommitlint(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    config.Commitlint
+	}{
+		{
+			name:    "yaml extending config-conventional",
+			file:    ".commitlintrc.yml",
+			content: "extends:\n  - '@commitlint/config-conventional'\nrules:\n  header-max-length: [2, always, 60]\n  scope-enum: [2, always, [api, ui]]\n",
+			want:    config.Commitlint{Types: []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"}, Scopes: []string{"api", "ui"}, HeaderMaxLength: 60},
+		},
+		{
+			name:    "json with a disabled rule",
+			file:    ".commitlintrc.json",
+			content: `{"extends": "@commitlint/config-conventional", "rules": {"type-enum": [2, "always", ["feat", "fix"]], "header-max-length": [0, "always", 100]}}`,
+			want:    config.Commitlint{Types: []string{"feat", "fix"}},
+		},
+		{
+			name: "javascript",
+			file: "commitlint.config.js",
+			content: `module.exports = {
+  rules: {
+    'type-enum': [2, 'always', ['feat', 'fix', 'chore',]],
+    "header-max-length": [RuleConfigSeverity.Error, "always", 80],
+  },
+};`,
+			want: config.Commitlint{Types: []string{"feat", "fix", "chore"}, HeaderMaxLength: 80},
+		},
+		{
+			name:    "package.json",
+			file:    "package.json",
+			content: `{"name": "app", "commitlint": {"rules": {"scope-enum": [2, "always", ["web"]]}}}`,
+			want:    config.Commitlint{Scopes: []string{"web"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := config.LoadCommitlint(dir)
+			if err != nil {
+				t.Fatalf("LoadCommitlint failed: %v", err)
+			}
+			tt.want.File = tt.file
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommitRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.Commit.Conventional = false
+	cfg.Commit.Types = []config.CommitType{{Name: "feat", Desc: "user-facing features"}}
+	dir := t.TempDir()
+	content := "rules:\n  type-enum: [2, always, [feat, fix]]\n  header-max-length: [2, always, 50]\n"
+	if err := os.WriteFile(filepath.Join(dir, ".commitlintrc"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := cfg.WithRepo(dir)
+	if err != nil {
+		t.Fatalf("WithRepo failed: %v", err)
+	}
+	commit := repo.CommitRules()
+	if !commit.Conventional {
+		t.Error("a type-enum requires conventional commits")
+	}
+	if !reflect.DeepEqual(commit.Types, []config.CommitType{{Name: "feat", Desc: "user-facing features"}, {Name: "fix"}}) {
+		t.Errorf("types = %+v", commit.Types)
+	}
+	if commit.SubjectMaxLength != 50 {
+		t.Errorf("limit = %d, want 50", commit.SubjectMaxLength)
+	}
+
+	// A looser commitlint limit keeps the configured one
+	repo.Commit.SubjectMaxLength = 40
+	if got := repo.CommitRules().SubjectMaxLength; got != 40 {
+		t.Errorf("limit = %d, want 40", got)
+	}
+	if repo.Commit.Conventional || len(repo.Commit.Types) != 1 {
+		t.Error("CommitRules must not modify the config")
+	}
+}
+
+func TestResolveAPIKeyCommand(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	configPath := filepath.Join(dir, "config.toml")
+	content := "[ai]\napi_key_cmd = \"touch " + marker + "; echo cmd-key\"\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Load must not run api_key_cmd")
+	}
+
+	key, err := cfg.AI.ResolveAPIKey()
+	if err != nil {
+		t.Fatalf("ResolveAPIKey failed: %v", err)
+	}
+	if key != "cmd-key" {
+		t.Errorf("key = %q, want cmd-key", key)
+	}
+	if cfg.AI.APIKey != "" {
+		t.Error("ResolveAPIKey must not store the key in the config")
+	}
+
+	// A plain key or env var wins without running the command
+	cfg.AI.APIKey = "plain-key"
+	if key, _ := cfg.AI.ResolveAPIKey(); key != "plain-key" {
+		t.Errorf("key = %q, want plain-key", key)
+	}
+}
+
+func TestResolveAPIKeyCommandFails(t *testing.T) {
+	for _, cmd := range []string{"echo locked >&2; exit 1", "true"} {
+		ai := config.AIConfig{APIKeyCmd: cmd}
+		if _, err := ai.ResolveAPIKey(); err == nil {
+			t.Errorf("%q: expected an error", cmd)
+		}
+	}
+	if key, err := (config.AIConfig{}).ResolveAPIKey(); key != "" || err != nil {
+		t.Errorf("no source: got %q, %v", key, err)
+	}
+}
+
+func TestResolveAPIKeys(t *testing.T) {
+	ai := config.AIConfig{APIKey: "a", APIKeys: []string{"b", " ", "a", "c"}}
+	keys, err := ai.ResolveAPIKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Errorf("keys = %q, want [a b c]", keys)
+	}
+
+	// api_keys alone are enough
+	keys, _ = config.AIConfig{APIKeys: []string{"b"}}.ResolveAPIKeys()
+	if !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Errorf("keys = %q, want [b]", keys)
+	}
+
+	cfg := config.Default()
+	cfg.AI.KeyRotation = "random"
+	if !slices.ContainsFunc(cfg.Problems(), func(p string) bool { return strings.Contains(p, `ai.key_rotation "random"`) }) {
+		t.Errorf("expected a problem about ai.key_rotation, got %q", cfg.Problems())
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	doc := `# my settings
+[ai]
+model = "gpt-4o"   # the model
+future_option = 1
+
+[commit]
+types = [
+  "feat",
+  "fix",
+]
+
+# internal hosts
+[[host_policies]]
+host = "*.corp.example"
+`
+	tests := []struct {
+		key, value string
+		want       string
+	}{
+		{"ai.model", "gpt-5", "[ai]\nmodel = \"gpt-5\"    # the model\nfuture_option = 1\n"},
+		{"ai.max_retries", "5", "future_option = 1\nmax_retries = 5\n\n[commit]"},
+		{"commit.types", `["feat", "perf"]`, "[commit]\ntypes = [\"feat\", \"perf\"]\n\n# internal hosts"},
+		{"commit.subject_prefix", "123", "]\nsubject_prefix = \"123\"\n\n# internal hosts"},
+		{"ui.theme", "nord", "host = \"*.corp.example\"\n\n[ui]\ntheme = \"nord\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			out, err := config.SetValue([]byte(doc), tt.key, tt.value)
+			if err != nil {
+				t.Fatalf("SetValue failed: %v", err)
+			}
+			if !strings.Contains(string(out), tt.want) {
+				t.Errorf("got:\n%s\nwant it to contain:\n%s", out, tt.want)
+			}
+			if !strings.HasPrefix(string(out), "# my settings\n") || !strings.Contains(string(out), "future_option = 1") {
+				t.Errorf("comments and unknown keys must be kept, got:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestSetValueRefuses(t *testing.T) {
+	for _, tt := range []struct{ key, value string }{
+		{"ai.bogus", "1"},
+		{"ai.max_retries", "many"},
+		{"", "x"},
+	} {
+		if _, err := config.SetValue(nil, tt.key, tt.value); err == nil {
+			t.Errorf("%s = %s: expected an error", tt.key, tt.value)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.Model = "gpt-4o"
+	if v, ok := cfg.Lookup("ai.model"); !ok || v != "gpt-4o" {
+		t.Errorf("ai.model = %v, %v", v, ok)
+	}
+	if v, ok := cfg.Lookup("general.split_threshold"); !ok || config.FormatValue(v) != "5" {
+		t.Errorf("general.split_threshold = %v, %v", v, ok)
+	}
+	if v, ok := cfg.Lookup("ui"); !ok || config.FormatValue(v) != `{theme = "tokyonight"}` {
+		t.Errorf("ui = %v, %v", v, ok)
+	}
+	if _, ok := cfg.Lookup("ai.bogus"); ok {
+		t.Error("unknown keys must not be found")
+	}
+}
+
+func TestProblems(t *testing.T) {
+	t.Setenv("OPENAI_MODEL", "")
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	content := `[ai]
+provider = "openai"
+secrets = "maybe"
+modle = "gpt-4o"
+
+[[profile_rules]]
+match = "~/work/**"
+profile = "work"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test config: %v", err)
+	}
+
+	problems, err := config.Problems(configPath)
+	if err != nil {
+		t.Fatalf("Problems failed: %v", err)
+	}
+	for _, want := range []string{`"ai.modle"`, "ai.model is not set", `ai.secrets "maybe"`, `unknown profile "work"`} {
+		if !slices.ContainsFunc(problems, func(p string) bool { return strings.Contains(p, want) }) {
+			t.Errorf("expected a problem mentioning %s, got %q", want, problems)
+		}
+	}
+
+	if problems, err := config.Problems(filepath.Join(t.TempDir(), "missing.toml")); err != nil || len(problems) != 0 {
+		t.Errorf("missing file: got %q, %v", problems, err)
+	}
+}
+
+func TestPushProblems(t *testing.T) {
+	cfg := config.Default()
+	if cfg.Commit.Push != config.PushAsk {
+		t.Errorf("default push = %q, want %q", cfg.Commit.Push, config.PushAsk)
+	}
+	cfg.Commit.Push = "sometimes"
+	if !slices.ContainsFunc(cfg.Problems(), func(p string) bool { return strings.Contains(p, `commit.push "sometimes"`) }) {
+		t.Errorf("expected a problem about commit.push, got %q", cfg.Problems())
+	}
+}
+
+func TestKeyProblems(t *testing.T) {
+	cfg := config.Default()
+	cfg.UI.Keys = map[string]string{"quit": "x", "edit": "x", "undo": "u", "diff-preview": ""}
+
+	problems := cfg.Problems()
+	for _, want := range []string{"ui.keys.undo is not an action", "ui.keys.diff-preview is empty", `ui.keys.edit and ui.keys.quit are both "x"`} {
+		if !slices.ContainsFunc(problems, func(p string) bool { return strings.Contains(p, want) }) {
+			t.Errorf("expected a problem mentioning %s, got %q", want, problems)
+		}
+	}
+
+	cfg.UI.Keys = map[string]string{"quit": "ctrl+q", "diff-preview": "v"}
+	for _, p := range cfg.Problems() {
+		if strings.Contains(p, "ui.keys") {
+			t.Errorf("unexpected problem %q", p)
+		}
+	}
+}
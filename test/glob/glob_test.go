@@ -0,0 +1,80 @@
+package glob_test
+
+import (
+	"testing"
+
+	"github.com/hluaguo/commity/internal/glob"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.lock", "Cargo.lock", true},
+		{"*.lock", "deps/yarn.lock", true},
+		{"package-lock.json", "web/package-lock.json", true},
+		{"*.pb.go", "api/v1/service.pb.go", true},
+		{"*.pb.go", "api/v1/service.go", false},
+		{"secrets/**", "secrets/prod/key.pem", true},
+		{"secrets/**", "config/secrets/key.pem", false},
+		{"**/testdata/*.json", "pkg/a/testdata/golden.json", true},
+		{"**/testdata/*.json", "testdata/golden.json", true},
+		{"vendor/", "vendor/github.com/x/y.go", true},
+		{"/build/*", "build/out.bin", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := glob.Match(tt.pattern, tt.name); got != tt.expected {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	patterns := []string{"*.lock", "go.sum"}
+
+	if !glob.MatchAny(patterns, "go.sum") {
+		t.Error("expected go.sum to match")
+	}
+	if glob.MatchAny(patterns, "main.go") {
+		t.Error("expected main.go not to match")
+	}
+	if glob.MatchAny(nil, "main.go") {
+		t.Error("no patterns should match nothing")
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	patterns := glob.ParseIgnore("# build output\ndist\n\nvendor/\n*.gen.go\n!keep.gen.go\n")
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"dist/app.js", true},
+		{"web/dist/app.js", true},
+		{"vendor/github.com/x/y.go", true},
+		{"api/types.gen.go", true},
+		{"api/keep.gen.go", false},
+		{"main.go", false},
+		{"distance.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := glob.Ignored(patterns, tt.name); got != tt.expected {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestParseIgnore(t *testing.T) {
+	got := glob.ParseIgnore("  # comment\n\nvendor/  \n*.lock\n")
+	if len(got) != 2 || got[0] != "vendor/" || got[1] != "*.lock" {
+		t.Errorf("ParseIgnore() = %q", got)
+	}
+}
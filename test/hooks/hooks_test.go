@@ -0,0 +1,101 @@
+package hooks_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hluaguo/commity/internal/hooks"
+)
+
+var commit = hooks.Commit{
+	Hash:      "0123456789abcdef0123456789abcdef01234567",
+	ShortHash: "0123456",
+	Type:      "feat",
+	Subject:   "add hooks",
+	Branch:    "main",
+	Files:     []string{"a.go", "b.go"},
+}
+
+func TestRender(t *testing.T) {
+	got, err := hooks.Render("./notify.sh {{.ShortHash}} '{{.Subject}}' on {{.Branch}}", commit)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := "./notify.sh 0123456 'add hooks' on main"; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	if _, err := hooks.Render("{{.Unknown}}", commit); err == nil {
+		t.Error("unknown fields should fail")
+	}
+}
+
+func TestRunPost(t *testing.T) {
+	dir := t.TempDir()
+
+	err := hooks.RunPost([]string{
+		`echo "{{.Hash}}" > hash.txt`,
+		`printf '%s' "$COMMITY_SUBJECT" > subject.txt`,
+	}, commit, dir)
+	if err != nil {
+		t.Fatalf("RunPost failed: %v", err)
+	}
+
+	hash, _ := os.ReadFile(filepath.Join(dir, "hash.txt"))
+	if strings.TrimSpace(string(hash)) != commit.Hash {
+		t.Errorf("hash.txt = %q", hash)
+	}
+	subject, _ := os.ReadFile(filepath.Join(dir, "subject.txt"))
+	if string(subject) != commit.Subject {
+		t.Errorf("subject.txt = %q", subject)
+	}
+}
+
+func TestRunPostReportsFailures(t *testing.T) {
+	dir := t.TempDir()
+
+	err := hooks.RunPost([]string{"exit 3", "touch ran.txt"}, commit, dir)
+	if err == nil {
+		t.Fatal("expected error from failing hook")
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "ran.txt")); statErr != nil {
+		t.Error("later hooks should still run after a failure")
+	}
+}
+
+func TestSessionText(t *testing.T) {
+	s := hooks.Session{Repo: "commity", Branch: "main", Commits: []hooks.Commit{commit}}
+	if want := "commity@main: 1 commit\n`0123456` feat: add hooks"; s.Text() != want {
+		t.Errorf("Text() = %q, want %q", s.Text(), want)
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+	s := hooks.Session{Repo: "commity", Branch: "main", Commits: []hooks.Commit{commit, commit}}
+
+	tests := []struct {
+		url string
+		key string
+	}{
+		{"https://hooks.slack.com/services/T0/B0/x", "text"},
+		{"https://discord.com/api/webhooks/1/x", "content"},
+		{"https://discordapp.com/api/webhooks/1/x", "content"},
+		{"https://chat.example.com/hook", "text"},
+	}
+	for _, tt := range tests {
+		body, err := hooks.WebhookPayload(tt.url, s)
+		if err != nil {
+			t.Fatalf("WebhookPayload(%q) failed: %v", tt.url, err)
+		}
+		var payload map[string]string
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("invalid JSON for %q: %v", tt.url, err)
+		}
+		if !strings.HasPrefix(payload[tt.key], "commity@main: 2 commits") {
+			t.Errorf("WebhookPayload(%q) = %s, want summary under %q", tt.url, body, tt.key)
+		}
+	}
+}
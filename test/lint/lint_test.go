@@ -0,0 +1,70 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/hluaguo/commity/internal/lint"
+)
+
+func TestScan(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -10,4 +10,9 @@ func main() {
+ 	cfg := load()
++<<<<<<< HEAD
++	run(cfg)
++=======
++	runAll(cfg)
++>>>>>>> feature
+-	fmt.Println("DEBUG old")
++	fmt.Println("DEBUG", cfg)
+ }
+diff --git a/web/app.js b/web/app.js
+new file mode 100644
+--- /dev/null
++++ b/web/app.js
+@@ -0,0 +1,3 @@
++const x = 1; // NOCOMMIT
++console.log(x);
++// TODO: tidy up later
+`
+	issues := lint.Scan(diff)
+	want := []struct {
+		kind     string
+		location string
+	}{
+		{"conflict marker", "main.go:11"},
+		{"conflict marker", "main.go:13"},
+		{"conflict marker", "main.go:15"},
+		{"debug statement", "main.go:16"},
+		{"do-not-commit tag", "web/app.js:1"},
+		{"debug statement", "web/app.js:2"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("Scan found %d issues, want %d: %+v", len(issues), len(want), issues)
+	}
+	for i, w := range want {
+		if issues[i].Kind != w.kind || issues[i].Location() != w.location {
+			t.Errorf("issue %d = %s at %s, want %s at %s", i, issues[i].Kind, issues[i].Location(), w.kind, w.location)
+		}
+	}
+
+	if in := lint.In(issues, []string{"web/app.js"}); len(in) != 2 {
+		t.Errorf("In should keep the issues of web/app.js, got %+v", in)
+	}
+}
+
+func TestScanIgnoresRemovedAndContextLines(t *testing.T) {
+	diff := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,3 +1,2 @@
+ 	console.log("kept")
+-<<<<<<< HEAD
+ x := 1
+`
+	if issues := lint.Scan(diff); len(issues) != 0 {
+		t.Errorf("only added lines should be scanned, got %+v", issues)
+	}
+}
@@ -0,0 +1,217 @@
+package store_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/hluaguo/commity/internal/store"
+)
+
+// Helper to point the XDG state directory at a temporary location
+func setupStateDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	return dir
+}
+
+func TestLoadMissingState(t *testing.T) {
+	setupStateDir(t)
+
+	r, err := store.Load("/path/to/repo")
+	if err != nil {
+		t.Fatalf("Load should not error for missing state: %v", err)
+	}
+	if r.Path != "/path/to/repo" {
+		t.Errorf("Path = %q, want %q", r.Path, "/path/to/repo")
+	}
+	if len(r.PresetNames()) != 0 {
+		t.Errorf("expected no presets, got %v", r.PresetNames())
+	}
+}
+
+func TestFilePathPerRepository(t *testing.T) {
+	dir := setupStateDir(t)
+
+	a := store.FilePath("/repos/a")
+	b := store.FilePath("/repos/b")
+
+	if a == b {
+		t.Error("different repositories should have different state files")
+	}
+	if !strings.HasPrefix(a, dir) {
+		t.Errorf("state file %q should be under XDG_STATE_HOME %q", a, dir)
+	}
+	if filepath.Ext(a) != ".json" {
+		t.Errorf("state file should be JSON, got %q", a)
+	}
+}
+
+func TestPresetsRoundTrip(t *testing.T) {
+	setupStateDir(t)
+
+	r, err := store.Load("/repos/app")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	r.SavePreset("docs-only", []string{"README.md", "docs/guide.md"})
+	r.SavePreset("backend", []string{"server/main.go"})
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Load("/repos/app")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	names := loaded.PresetNames()
+	if len(names) != 2 || names[0] != "backend" || names[1] != "docs-only" {
+		t.Errorf("PresetNames() = %v, want [backend docs-only]", names)
+	}
+
+	files, ok := loaded.Preset("docs-only")
+	if !ok {
+		t.Fatal("expected docs-only preset to exist")
+	}
+	if len(files) != 2 || files[0] != "README.md" {
+		t.Errorf("unexpected preset files: %v", files)
+	}
+
+	if _, ok := loaded.Preset("missing"); ok {
+		t.Error("missing preset should not be found")
+	}
+}
+
+func TestLastCommitRoundTrip(t *testing.T) {
+	setupStateDir(t)
+
+	r, _ := store.Load("/repos/app")
+	r.LastCommit = "0123456789abcdef0123456789abcdef01234567"
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Load("/repos/app")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.LastCommit != r.LastCommit {
+		t.Errorf("LastCommit = %q, want %q", loaded.LastCommit, r.LastCommit)
+	}
+}
+
+func TestNotesRoundTrip(t *testing.T) {
+	setupStateDir(t)
+
+	r, _ := store.Load("/repos/app")
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	r.AddNote("revisit the retry logic", created)
+	r.AddNote("drop the debug flag", created.Add(time.Hour))
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Load("/repos/app")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Notes) != 2 || loaded.Notes[0].Text != "revisit the retry logic" {
+		t.Fatalf("Notes = %+v, want both notes in order", loaded.Notes)
+	}
+	if !loaded.Notes[0].Created.Equal(created) {
+		t.Errorf("Created = %v, want %v", loaded.Notes[0].Created, created)
+	}
+
+	loaded.ClearNotes()
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	cleared, _ := store.Load("/repos/app")
+	if len(cleared.Notes) != 0 {
+		t.Errorf("Notes after clear = %+v, want none", cleared.Notes)
+	}
+}
+
+func TestModelPicksRoundTrip(t *testing.T) {
+	setupStateDir(t)
+
+	r, _ := store.Load("/repos/app")
+	r.RecordPick("gpt-4o")
+	r.RecordPick("gpt-4o")
+	r.RecordPick("llama3.2")
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Load("/repos/app")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.ModelPicks["gpt-4o"] != 2 || loaded.ModelPicks["llama3.2"] != 1 {
+		t.Errorf("ModelPicks = %v, want gpt-4o 2 and llama3.2 1", loaded.ModelPicks)
+	}
+}
+
+func TestLastSelectionPerBranch(t *testing.T) {
+	setupStateDir(t)
+
+	r, _ := store.Load("/repos/app")
+	files := []string{"main.go", "go.mod"}
+	r.RememberSelection("main", files)
+	r.RememberSelection("feature", []string{"README.md"})
+	files[0] = "changed.go" // the stored selection must not alias the caller's slice
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := store.Load("/repos/app")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := strings.Join(loaded.LastSelection["main"], ","); got != "main.go,go.mod" {
+		t.Errorf("LastSelection[main] = %q, want main.go,go.mod", got)
+	}
+	if got := strings.Join(loaded.LastSelection["feature"], ","); got != "README.md" {
+		t.Errorf("LastSelection[feature] = %q, want README.md", got)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+
+	entries, err := store.LoadHistory()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("missing history should be empty, got %v, %v", entries, err)
+	}
+
+	now := time.Now()
+	for i, msg := range []string{"feat: first", "fix: second\n\nwith a body"} {
+		err := store.AppendHistory(store.HistoryEntry{Time: now.Add(time.Duration(i) * time.Minute), Repo: "/repo", Kind: store.HistoryGenerated, Message: msg})
+		if err != nil {
+			t.Fatalf("AppendHistory: %v", err)
+		}
+	}
+
+	entries, err = store.LoadHistory()
+	if err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Message != "fix: second\n\nwith a body" || entries[1].Repo != "/repo" {
+		t.Errorf("expected newest first, got %+v", entries)
+	}
+	if !strings.HasPrefix(store.HistoryPath(), filepath.Join(xdg.DataHome, "commity")) {
+		t.Errorf("history should be in the data directory, got %s", store.HistoryPath())
+	}
+}
@@ -0,0 +1,102 @@
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"github.com/hluaguo/commity/internal/config"
+	"github.com/hluaguo/commity/internal/telemetry"
+)
+
+// Helper to point the XDG state directory at a temporary location
+func setupStateDir(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+}
+
+func TestCountDisabled(t *testing.T) {
+	setupStateDir(t)
+
+	telemetry.Count(config.TelemetryConfig{}, "feature.split")
+
+	c, err := telemetry.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(c.Counts) != 0 {
+		t.Errorf("nothing should be counted while disabled, got %v", c.Counts)
+	}
+}
+
+func TestCountEnabled(t *testing.T) {
+	setupStateDir(t)
+	cfg := config.TelemetryConfig{Enabled: true}
+
+	telemetry.Count(cfg, "feature.split")
+	telemetry.Count(cfg, "feature.split")
+	telemetry.Count(cfg, "provider.openai")
+	// Names outside the fixed categories are never recorded
+	telemetry.Count(cfg, "repo.my-secret-project")
+	telemetry.Count(cfg, "feature.Has Spaces")
+	telemetry.Count(cfg, "feature.")
+
+	c, err := telemetry.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := map[string]int{"feature.split": 2, "provider.openai": 1}
+	if len(c.Counts) != len(want) {
+		t.Fatalf("Counts = %v, want %v", c.Counts, want)
+	}
+	for name, n := range want {
+		if c.Counts[name] != n {
+			t.Errorf("Counts[%q] = %d, want %d", name, c.Counts[name], n)
+		}
+	}
+}
+
+func TestFlushWithoutEndpoint(t *testing.T) {
+	setupStateDir(t)
+	cfg := config.TelemetryConfig{Enabled: true}
+	telemetry.Count(cfg, "feature.amend")
+
+	if err := telemetry.Flush(cfg, "0.1.0", time.Now()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	c, err := telemetry.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if c.Counts["feature.amend"] != 1 {
+		t.Errorf("counters should stay local without an endpoint, got %v", c.Counts)
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("AI request failed: %w", context.DeadlineExceeded), "timeout"},
+		{errors.New("error, status code: 401, message: invalid api key"), "auth"},
+		{errors.New("error, status code: 429, message: rate limit reached"), "rate_limit"},
+		{errors.New("dial tcp: connection refused"), "network"},
+		{errors.New("git commit failed: exit status 1"), "git"},
+		{errors.New("failed to load config: bad toml"), "config"},
+		{errors.New("no files selected"), "other"},
+	}
+	for _, tt := range tests {
+		if got := telemetry.Categorize(tt.err); got != tt.want {
+			t.Errorf("Categorize(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}